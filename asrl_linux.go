@@ -0,0 +1,432 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+//go:build linux && (386 || amd64 || arm || arm64 || loong64 || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 loong64 riscv64 s390x
+
+package visa
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+func init() {
+	registerTransport("ASRL::INSTR", openASRL)
+	registerFinder(func() []foundRsrc { return foundNames(asrlPorts()) })
+}
+
+// Termios bits and requests the syscall package leaves out, see
+// asm-generic/termbits.h and asm-generic/ioctls.h. They and the layout of
+// syscall.Termios only hold for the architectures in the build constraint,
+// mips and powerpc differ and don't serve serial ports.
+const (
+	termiosCBAUD   = 0x100F
+	termiosCMSPAR  = 0x40000000
+	termiosCRTSCTS = 0x80000000
+
+	ioctlTCSBRK = 0x5409
+	ioctlTCFLSH = 0x540B
+
+	// asrlStateUnknown is STATE_UNKNOWN as stored for an int16 attribute.
+	asrlStateUnknown = 0xFFFF
+)
+
+var asrlBauds = map[uint64]uint32{
+	50: syscall.B50, 75: syscall.B75, 110: syscall.B110, 134: syscall.B134,
+	150: syscall.B150, 200: syscall.B200, 300: syscall.B300, 600: syscall.B600,
+	1200: syscall.B1200, 1800: syscall.B1800, 2400: syscall.B2400,
+	4800: syscall.B4800, 9600: syscall.B9600, 19200: syscall.B19200,
+	38400: syscall.B38400, 57600: syscall.B57600, 115200: syscall.B115200,
+	230400: syscall.B230400, 460800: syscall.B460800, 500000: syscall.B500000,
+	576000: syscall.B576000, 921600: syscall.B921600, 1000000: syscall.B1000000,
+	1152000: syscall.B1152000, 1500000: syscall.B1500000, 2000000: syscall.B2000000,
+	2500000: syscall.B2500000, 3000000: syscall.B3000000, 3500000: syscall.B3500000,
+	4000000: syscall.B4000000,
+}
+
+// asrlPorts lists the serial ports sysfs knows about: ttySn as ASRLn+1,
+// the rest (ttyUSBn, ttyACMn) by device path.
+func asrlPorts() []string {
+	ents, err := os.ReadDir(filepath.Join(sysfsRoot, "class/tty"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range ents {
+		node := e.Name()
+		if _, err := os.Stat(filepath.Join(sysfsRoot, "class/tty", node, "device")); err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(node, "ttyS")); err == nil && strings.HasPrefix(node, "ttyS") {
+			names = append(names, "ASRL"+strconv.Itoa(n+1)+"::INSTR")
+		} else {
+			names = append(names, "ASRL/dev/"+node+"::INSTR")
+		}
+	}
+	return names
+}
+
+// asrlTransport serves ASRL INSTR resources through a termios tty.
+type asrlTransport struct {
+	f   *os.File
+	raw syscall.RawConn
+	rd  *bufio.Reader
+}
+
+// openASRL opens ASRLn::INSTR, the serial port /dev/ttyS(n-1), or a port
+// by path as in ASRL/dev/ttyUSB0::INSTR, and puts it in raw mode with the
+// VISA defaults of 9600 8N1 and no flow control.
+func openASRL(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if len(p.fields) != 0 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	path := p.path
+	if path == "" {
+		if p.board == 0 {
+			return nil, ERROR_RSRC_NFOUND
+		}
+		path = "/dev/ttyS" + strconv.Itoa(int(p.board)-1)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	switch {
+	case errors.Is(err, syscall.EBUSY):
+		return nil, ERROR_RSRC_BUSY
+	case err != nil:
+		return nil, ERROR_RSRC_NFOUND
+	}
+	raw, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, ERROR_RSRC_NFOUND
+	}
+	t := &asrlTransport{f: f, raw: raw, rd: bufio.NewReader(f)}
+
+	s.a.put(ATTR_ASRL_BAUD, 9600)
+	s.a.put(ATTR_ASRL_DATA_BITS, 8)
+	s.a.put(ATTR_ASRL_PARITY, ASRL_PAR_NONE)
+	s.a.put(ATTR_ASRL_STOP_BITS, ASRL_STOP_ONE)
+	s.a.put(ATTR_ASRL_FLOW_CNTRL, ASRL_FLOW_NONE)
+	s.a.put(ATTR_ASRL_END_IN, ASRL_END_TERMCHAR)
+	s.a.put(ATTR_ASRL_END_OUT, ASRL_END_NONE)
+	s.a.put(ATTR_ASRL_XON_CHAR, 0x11)
+	s.a.put(ATTR_ASRL_XOFF_CHAR, 0x13)
+	s.a.put(ATTR_ASRL_BREAK_STATE, STATE_UNASSERTED)
+	s.a.put(ATTR_ASRL_BREAK_LEN, 250)
+	s.a.put(ATTR_ASRL_ALLOW_TRANSMI, TRUE)
+	s.a.put(ATTR_ASRL_AVAIL_NUM, 0)
+	for _, attr := range []uint32{ATTR_ASRL_DTR_STATE, ATTR_ASRL_RTS_STATE,
+		ATTR_ASRL_CTS_STATE, ATTR_ASRL_DSR_STATE, ATTR_ASRL_DCD_STATE, ATTR_ASRL_RI_STATE} {
+		s.a.put(attr, asrlStateUnknown)
+	}
+	s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+	if status := t.applyTermios(s, 0, 0); status != SUCCESS {
+		f.Close()
+		return nil, status
+	}
+	// Ports without modem control lines, like ptys, keep them unknown.
+	if t.setModem(syscall.TIOCM_DTR|syscall.TIOCM_RTS, true) == nil {
+		s.a.put(ATTR_ASRL_DTR_STATE, STATE_ASSERTED)
+		s.a.put(ATTR_ASRL_RTS_STATE, STATE_ASSERTED)
+	}
+	return t, SUCCESS
+}
+
+// ioctl issues a request whose argument is a pointer.
+func (t *asrlTransport) ioctl(req uintptr, arg unsafe.Pointer) error {
+	var errno syscall.Errno
+	err := t.raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ioctlInt issues a request whose argument is an integer.
+func (t *asrlTransport) ioctlInt(req, arg uintptr) error {
+	var errno syscall.Errno
+	err := t.raw.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// applyTermios programs the line settings from the session's attributes,
+// with attr taking the new state (attr 0 applies them as they are).
+func (t *asrlTransport) applyTermios(s *goSession, attr uint32, state uint64) Status {
+	val := func(a uint32) uint64 {
+		if a == attr {
+			return state
+		}
+		return s.a.num(a)
+	}
+	var tio syscall.Termios
+	if err := t.ioctl(syscall.TCGETS, unsafe.Pointer(&tio)); err != nil {
+		return ERROR_RSRC_NFOUND
+	}
+	tio.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON | syscall.IXOFF | syscall.IXANY
+	tio.Oflag &^= syscall.OPOST
+	tio.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	tio.Cflag &^= termiosCBAUD | syscall.CSIZE | syscall.CSTOPB | syscall.PARENB |
+		syscall.PARODD | termiosCMSPAR | termiosCRTSCTS
+	tio.Cflag |= syscall.CREAD | syscall.CLOCAL
+	tio.Cc[syscall.VMIN], tio.Cc[syscall.VTIME] = 1, 0
+
+	baud, ok := asrlBauds[val(ATTR_ASRL_BAUD)]
+	if !ok {
+		return ERROR_NSUP_ATTR_STATE
+	}
+	tio.Cflag |= baud
+	tio.Ispeed, tio.Ospeed = baud, baud
+
+	switch val(ATTR_ASRL_DATA_BITS) {
+	case 5:
+		tio.Cflag |= syscall.CS5
+	case 6:
+		tio.Cflag |= syscall.CS6
+	case 7:
+		tio.Cflag |= syscall.CS7
+	case 8:
+		tio.Cflag |= syscall.CS8
+	default:
+		return ERROR_NSUP_ATTR_STATE
+	}
+	switch val(ATTR_ASRL_PARITY) {
+	case ASRL_PAR_NONE:
+	case ASRL_PAR_ODD:
+		tio.Cflag |= syscall.PARENB | syscall.PARODD
+	case ASRL_PAR_EVEN:
+		tio.Cflag |= syscall.PARENB
+	case ASRL_PAR_MARK:
+		tio.Cflag |= syscall.PARENB | syscall.PARODD | termiosCMSPAR
+	case ASRL_PAR_SPACE:
+		tio.Cflag |= syscall.PARENB | termiosCMSPAR
+	default:
+		return ERROR_NSUP_ATTR_STATE
+	}
+	// With 5 data bits CSTOPB gives 1.5 stop bits.
+	switch val(ATTR_ASRL_STOP_BITS) {
+	case ASRL_STOP_ONE:
+	case ASRL_STOP_ONE5:
+		if val(ATTR_ASRL_DATA_BITS) != 5 {
+			return ERROR_NSUP_ATTR_STATE
+		}
+		tio.Cflag |= syscall.CSTOPB
+	case ASRL_STOP_TWO:
+		tio.Cflag |= syscall.CSTOPB
+	default:
+		return ERROR_NSUP_ATTR_STATE
+	}
+	flow := val(ATTR_ASRL_FLOW_CNTRL)
+	if flow&^(ASRL_FLOW_XON_XOFF|ASRL_FLOW_RTS_CTS) != 0 {
+		return ERROR_NSUP_ATTR_STATE
+	}
+	if flow&ASRL_FLOW_XON_XOFF != 0 {
+		tio.Iflag |= syscall.IXON | syscall.IXOFF
+	}
+	if flow&ASRL_FLOW_RTS_CTS != 0 {
+		tio.Cflag |= termiosCRTSCTS
+	}
+	tio.Cc[syscall.VSTART] = uint8(val(ATTR_ASRL_XON_CHAR))
+	tio.Cc[syscall.VSTOP] = uint8(val(ATTR_ASRL_XOFF_CHAR))
+
+	if err := t.ioctl(syscall.TCSETS, unsafe.Pointer(&tio)); err != nil {
+		return ERROR_NSUP_ATTR_STATE
+	}
+	return SUCCESS
+}
+
+// setModem asserts or unasserts the modem control lines in bits.
+func (t *asrlTransport) setModem(bits int, on bool) error {
+	req := uintptr(syscall.TIOCMBIC)
+	if on {
+		req = syscall.TIOCMBIS
+	}
+	return t.ioctl(req, unsafe.Pointer(&bits))
+}
+
+func (t *asrlTransport) setAttribute(s *goSession, attr uint32, state uint64) Status {
+	switch attr {
+	case ATTR_ASRL_BAUD, ATTR_ASRL_DATA_BITS, ATTR_ASRL_PARITY, ATTR_ASRL_STOP_BITS,
+		ATTR_ASRL_FLOW_CNTRL, ATTR_ASRL_XON_CHAR, ATTR_ASRL_XOFF_CHAR:
+		return t.applyTermios(s, attr, state)
+	case ATTR_ASRL_END_IN, ATTR_ASRL_END_OUT:
+		if state > ASRL_END_BREAK {
+			return ERROR_NSUP_ATTR_STATE
+		}
+	case ATTR_ASRL_DTR_STATE, ATTR_ASRL_RTS_STATE:
+		bits := syscall.TIOCM_DTR
+		if attr == ATTR_ASRL_RTS_STATE {
+			if s.a.num(ATTR_ASRL_FLOW_CNTRL)&ASRL_FLOW_RTS_CTS != 0 {
+				return ERROR_NSUP_ATTR_STATE
+			}
+			bits = syscall.TIOCM_RTS
+		}
+		if state != STATE_ASSERTED && state != STATE_UNASSERTED {
+			return ERROR_NSUP_ATTR_STATE
+		}
+		if t.setModem(bits, state == STATE_ASSERTED) != nil {
+			return ERROR_NSUP_ATTR_STATE
+		}
+	case ATTR_ASRL_BREAK_STATE:
+		req := uintptr(syscall.TIOCCBRK)
+		switch state {
+		case STATE_ASSERTED:
+			req = syscall.TIOCSBRK
+		case STATE_UNASSERTED:
+		default:
+			return ERROR_NSUP_ATTR_STATE
+		}
+		if t.ioctlInt(req, 0) != nil {
+			return ERROR_NSUP_ATTR_STATE
+		}
+	case ATTR_ASRL_BREAK_LEN:
+		if int16(state) < 1 || int16(state) > 500 {
+			return ERROR_NSUP_ATTR_STATE
+		}
+	}
+	return SUCCESS
+}
+
+// refreshAttribute reads the modem lines and the input queue.
+func (t *asrlTransport) refreshAttribute(s *goSession, attr uint32) {
+	lines := map[uint32]int{
+		ATTR_ASRL_CTS_STATE: syscall.TIOCM_CTS,
+		ATTR_ASRL_DSR_STATE: syscall.TIOCM_DSR,
+		ATTR_ASRL_DCD_STATE: syscall.TIOCM_CAR,
+		ATTR_ASRL_RI_STATE:  syscall.TIOCM_RNG,
+	}
+	if bit, ok := lines[attr]; ok {
+		var bits int
+		if t.ioctl(syscall.TIOCMGET, unsafe.Pointer(&bits)) != nil {
+			s.a.put(attr, asrlStateUnknown)
+		} else if bits&bit != 0 {
+			s.a.put(attr, STATE_ASSERTED)
+		} else {
+			s.a.put(attr, STATE_UNASSERTED)
+		}
+	}
+	if attr == ATTR_ASRL_AVAIL_NUM {
+		var n int32
+		t.ioctl(syscall.TIOCINQ, unsafe.Pointer(&n))
+		s.a.put(attr, uint64(int(n)+t.rd.Buffered()))
+	}
+}
+
+// read stops after the termination character when ATTR_ASRL_END_IN is
+// ASRL_END_TERMCHAR or ATTR_TERMCHAR_EN is set, and after a byte with the
+// last data bit set for ASRL_END_LAST_BIT. Breaks aren't detected.
+func (t *asrlTransport) read(s *goSession, buf []byte) (int, Status) {
+	t.f.SetReadDeadline(s.deadline())
+	term, termEn := s.termChar()
+	endIn := s.a.num(ATTR_ASRL_END_IN)
+	lastBit := byte(1) << (s.a.num(ATTR_ASRL_DATA_BITS) - 1)
+	n := 0
+	for n < len(buf) {
+		c, err := t.rd.ReadByte()
+		if err != nil {
+			return n, netStatus(err)
+		}
+		buf[n] = c
+		n++
+		switch {
+		case c == term && (termEn || endIn == ASRL_END_TERMCHAR):
+			return n, SUCCESS_TERM_CHAR
+		case endIn == ASRL_END_LAST_BIT && c&lastBit != 0:
+			return n, SUCCESS
+		}
+	}
+	return n, SUCCESS_MAX_CNT
+}
+
+// write marks the end of the message as ATTR_ASRL_END_OUT says when
+// ATTR_SEND_END_EN is set.
+func (t *asrlTransport) write(s *goSession, buf []byte) (int, Status) {
+	if s.a.num(ATTR_ASRL_ALLOW_TRANSMI) == FALSE {
+		return 0, ERROR_IO
+	}
+	t.f.SetWriteDeadline(s.deadline())
+	out, endOut := buf, uint64(ASRL_END_NONE)
+	if s.sendEnd() {
+		endOut = s.a.num(ATTR_ASRL_END_OUT)
+	}
+	switch {
+	case endOut == ASRL_END_TERMCHAR:
+		term, _ := s.termChar()
+		out = append(buf[:len(buf):len(buf)], term)
+	case endOut == ASRL_END_LAST_BIT && len(buf) > 0:
+		out = append([]byte(nil), buf...)
+		out[len(out)-1] |= byte(1) << (s.a.num(ATTR_ASRL_DATA_BITS) - 1)
+	}
+	n, err := t.f.Write(out)
+	if n > len(buf) {
+		n = len(buf)
+	}
+	if err != nil {
+		return n, netStatus(err)
+	}
+	if endOut == ASRL_END_BREAK {
+		if status := t.sendBreak(s); status != SUCCESS {
+			return n, status
+		}
+	}
+	return n, SUCCESS
+}
+
+// sendBreak waits for the output to drain and holds a break for
+// ATTR_ASRL_BREAK_LEN milliseconds.
+func (t *asrlTransport) sendBreak(s *goSession) Status {
+	if t.ioctlInt(ioctlTCSBRK, 1) != nil || t.ioctlInt(syscall.TIOCSBRK, 0) != nil {
+		return ERROR_IO
+	}
+	time.Sleep(time.Duration(int16(s.a.num(ATTR_ASRL_BREAK_LEN))) * time.Millisecond)
+	if t.ioctlInt(syscall.TIOCCBRK, 0) != nil {
+		return ERROR_IO
+	}
+	return SUCCESS
+}
+
+// clear discards the input and output queues.
+func (t *asrlTransport) clear(s *goSession) Status {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	t.rd.Discard(t.rd.Buffered())
+	if t.ioctlInt(ioctlTCFLSH, syscall.TCIOFLUSH) != nil {
+		return ERROR_IO
+	}
+	return SUCCESS
+}
+
+// abort unblocks a transfer in progress, it fails with ERROR_TMO.
+func (t *asrlTransport) abort(s *goSession) {
+	t.f.SetDeadline(time.Now())
+}
+
+func (t *asrlTransport) close() Status {
+	if err := t.f.Close(); err != nil && !errors.Is(err, fs.ErrClosed) {
+		return ERROR_CLOSING_FAILED
+	}
+	return SUCCESS
+}
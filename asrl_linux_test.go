@@ -0,0 +1,152 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+//go:build linux && (386 || amd64 || arm || arm64 || loong64 || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 loong64 riscv64 s390x
+
+package visa
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openPty opens a pseudo terminal and returns its master side and the
+// ASRL resource name of its slave.
+func openPty(t *testing.T) (*os.File, string) {
+	t.Helper()
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	var n uint32
+	var unlock int32
+	if err := ptyIoctl(m, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ptyIoctl(m, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		t.Fatal(err)
+	}
+	return m, "ASRL/dev/pts/" + strconv.Itoa(int(n)) + "::INSTR"
+}
+
+func ptyIoctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func openASRLTest(t *testing.T) (Object, *os.File) {
+	t.Helper()
+	m, name := openPty(t)
+	rm := openTestRM(t)
+	instr, status := rm.Open(name, NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open(%q): %v", name, status)
+	}
+	t.Cleanup(func() { instr.Close() })
+	return instr, m
+}
+
+func TestASRLTermios(t *testing.T) {
+	instr, m := openASRLTest(t)
+	// TCGETS on the master reports the slave's settings.
+	termios := func() syscall.Termios {
+		t.Helper()
+		var tio syscall.Termios
+		if err := ptyIoctl(m, syscall.TCGETS, unsafe.Pointer(&tio)); err != nil {
+			t.Fatal(err)
+		}
+		return tio
+	}
+
+	tio := termios()
+	if tio.Cflag&termiosCBAUD != syscall.B9600 || tio.Cflag&syscall.CSIZE != syscall.CS8 ||
+		tio.Cflag&(syscall.PARENB|syscall.CSTOPB|termiosCRTSCTS) != 0 || tio.Lflag&syscall.ICANON != 0 {
+		t.Errorf("default cflag %#o lflag %#o, want raw 9600 8N1", tio.Cflag, tio.Lflag)
+	}
+
+	// The pty driver forces CS8 and clears PARENB, the other bits stick.
+	tests := []struct {
+		attr       uint32
+		state      uint32
+		status     Status
+		mask, want uint32
+	}{
+		{ATTR_ASRL_BAUD, 115200, SUCCESS, termiosCBAUD, syscall.B115200},
+		{ATTR_ASRL_BAUD, 12345, ERROR_NSUP_ATTR_STATE, termiosCBAUD, syscall.B115200},
+		{ATTR_ASRL_PARITY, ASRL_PAR_ODD, SUCCESS, syscall.PARODD | termiosCMSPAR, syscall.PARODD},
+		{ATTR_ASRL_PARITY, ASRL_PAR_SPACE, SUCCESS, syscall.PARODD | termiosCMSPAR, termiosCMSPAR},
+		{ATTR_ASRL_PARITY, ASRL_PAR_MARK, SUCCESS, syscall.PARODD | termiosCMSPAR, syscall.PARODD | termiosCMSPAR},
+		{ATTR_ASRL_STOP_BITS, ASRL_STOP_TWO, SUCCESS, syscall.CSTOPB, syscall.CSTOPB},
+		{ATTR_ASRL_STOP_BITS, ASRL_STOP_ONE5, ERROR_NSUP_ATTR_STATE, syscall.CSTOPB, syscall.CSTOPB},
+		{ATTR_ASRL_FLOW_CNTRL, ASRL_FLOW_RTS_CTS, SUCCESS, termiosCRTSCTS, termiosCRTSCTS},
+	}
+	for _, tt := range tests {
+		if status := instr.SetAttribute(tt.attr, tt.state); status != tt.status {
+			t.Errorf("SetAttribute(%s, %d): %v, want %v", attrName(tt.attr), tt.state, status, tt.status)
+		}
+		if got := termios().Cflag & tt.mask; got != tt.want {
+			t.Errorf("after %s %d: cflag bits %#o, want %#o", attrName(tt.attr), tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestASRLReadWrite(t *testing.T) {
+	instr, m := openASRLTest(t)
+
+	instr.SetAttribute(ATTR_ASRL_END_OUT, ASRL_END_TERMCHAR)
+	if n, status := instr.Write([]byte("*IDN?"), 5); status != SUCCESS || n != 5 {
+		t.Fatalf("Write = %d, %v", n, status)
+	}
+	buf := make([]byte, 16)
+	if n, err := m.Read(buf); err != nil || string(buf[:n]) != "*IDN?\n" {
+		t.Errorf("the port received %q, %v, want the termination character appended", buf[:n], err)
+	}
+
+	m.Write([]byte("ACME\nREST"))
+	got, n, status := instr.Read(64)
+	if status != SUCCESS_TERM_CHAR || string(got[:n]) != "ACME\n" {
+		t.Errorf("Read = %q, %v, want \"ACME\\n\", SUCCESS_TERM_CHAR", got[:n], status)
+	}
+	if avail, err := instr.AttrUint32(ATTR_ASRL_AVAIL_NUM); err != nil || avail != 4 {
+		t.Errorf("ATTR_ASRL_AVAIL_NUM = %d, %v, want 4", avail, err)
+	}
+	got, n, status = instr.Read(2)
+	if status != SUCCESS_MAX_CNT || string(got[:n]) != "RE" {
+		t.Errorf("Read(2) = %q, %v, want \"RE\", SUCCESS_MAX_CNT", got[:n], status)
+	}
+
+	instr.SetAttribute(ATTR_TMO_VALUE, 100)
+	start := time.Now()
+	got, n, status = instr.Read(64)
+	if status != ERROR_TMO || string(got[:n]) != "ST" {
+		t.Errorf("Read without a termination character = %q, %v, want \"ST\", ERROR_TMO", got[:n], status)
+	}
+	if d := time.Since(start); d < 80*time.Millisecond || d > time.Second {
+		t.Errorf("Read timed out after %v, want 100ms", d)
+	}
+}
+
+func TestASRLClear(t *testing.T) {
+	instr, m := openASRLTest(t)
+	m.Write([]byte("stale"))
+	time.Sleep(20 * time.Millisecond)
+	if avail, _ := instr.AttrUint32(ATTR_ASRL_AVAIL_NUM); avail != 5 {
+		t.Fatalf("ATTR_ASRL_AVAIL_NUM = %d before Clear, want 5", avail)
+	}
+	if status := instr.Clear(); status != SUCCESS {
+		t.Fatalf("Clear: %v", status)
+	}
+	if avail, _ := instr.AttrUint32(ATTR_ASRL_AVAIL_NUM); avail != 0 {
+		t.Errorf("ATTR_ASRL_AVAIL_NUM = %d after Clear, want 0", avail)
+	}
+}
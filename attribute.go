@@ -0,0 +1,266 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"fmt"
+	"sort"
+	"unsafe"
+)
+
+// The typed accessors look the attribute up in the metadata table and fail
+// with ErrInvParameter if its state isn't of the accessor's type, or with
+// ErrNsupAttr if the attribute is unknown.
+
+// attrState reads attr with the type from the metadata table. Integers
+// are returned as the Go type of the same size, booleans as bool, strings
+// as string, addresses as uintptr and byte arrays as []byte.
+func (instr Object) attrState(attr uint32) (interface{}, Status) {
+	info, ok := attrTable[attr]
+	if !ok {
+		return nil, ERROR_NSUP_ATTR
+	}
+	if info.Type == AttrBytes {
+		return instr.attrBytes(attr)
+	}
+	// Large enough for a string attribute and aligned for any integer.
+	var buf [FIND_BUFLEN/8 + 1]uint64
+	p := unsafe.Pointer(&buf[0])
+	if status := instr.GetAttribute(attr, p); status < SUCCESS {
+		return nil, status
+	}
+	switch info.Type {
+	case AttrUint8:
+		return *(*uint8)(p), SUCCESS
+	case AttrUint16:
+		return *(*uint16)(p), SUCCESS
+	case AttrUint32:
+		return *(*uint32)(p), SUCCESS
+	case AttrUint64:
+		return *(*uint64)(p), SUCCESS
+	case AttrInt16:
+		return *(*int16)(p), SUCCESS
+	case AttrInt32:
+		return *(*int32)(p), SUCCESS
+	case AttrBool:
+		return *(*uint16)(p) != FALSE, SUCCESS
+	case AttrAddr:
+		return *(*uintptr)(p), SUCCESS
+	}
+	return cString((*[FIND_BUFLEN]byte)(p)[:]), SUCCESS
+}
+
+// attrBytes reads a byte array attribute. The array is as long as the
+// state of its length attribute says, up to 64 KiB for USB interrupts, so
+// it's read into a buffer of that size on the heap.
+func (instr Object) attrBytes(attr uint32) ([]byte, Status) {
+	n, status := instr.attrState(attrLengths[attr])
+	if status < SUCCESS {
+		return nil, status
+	}
+	size, _ := n.(uint16)
+	buf := make([]byte, int(size)+1)
+	if status := instr.GetAttribute(attr, unsafe.Pointer(&buf[0])); status < SUCCESS {
+		return nil, status
+	}
+	return buf[:size], SUCCESS
+}
+
+// attrLengths maps byte array attributes to the attribute holding their
+// length.
+var attrLengths = map[uint32]uint32{
+	ATTR_USB_RECV_INTR_DATA: ATTR_USB_RECV_INTR_SIZE,
+}
+
+// typedAttr reads attr after checking that its state is one of types.
+func (instr Object) typedAttr(op string, attr uint32, types ...AttrType) (interface{}, error) {
+	info, ok := attrTable[attr]
+	if !ok {
+		return nil, instr.Wrap(op+" "+attrName(attr), ERROR_NSUP_ATTR)
+	}
+	for _, t := range types {
+		if info.Type == t {
+			v, status := instr.attrState(attr)
+			return v, instr.Wrap(op+" "+info.Name, status)
+		}
+	}
+	return nil, instr.Wrap(op+" "+info.Name, ERROR_INV_PARAMETER)
+}
+
+// setTypedAttr sets attr after checking that it's writable and its state
+// is one of types.
+func (instr Object) setTypedAttr(op string, attr uint32, state uint64, types ...AttrType) error {
+	info, ok := attrTable[attr]
+	if !ok {
+		return instr.Wrap(op+" "+attrName(attr), ERROR_NSUP_ATTR)
+	}
+	if info.ReadOnly {
+		return instr.Wrap(op+" "+info.Name, ERROR_ATTR_READONLY)
+	}
+	for _, t := range types {
+		if info.Type == t {
+			status := backend.SetAttribute(uint32(instr), attr, state)
+			return instr.Wrap(op+" "+info.Name, status)
+		}
+	}
+	return instr.Wrap(op+" "+info.Name, ERROR_INV_PARAMETER)
+}
+
+// AttrUint8 returns the state of a ViUInt8 attribute, e.g. ATTR_TERMCHAR.
+func (instr Object) AttrUint8(attr uint32) (uint8, error) {
+	v, err := instr.typedAttr("AttrUint8", attr, AttrUint8)
+	u, _ := v.(uint8)
+	return u, err
+}
+
+// AttrUint16 returns the state of a ViUInt16 attribute.
+func (instr Object) AttrUint16(attr uint32) (uint16, error) {
+	v, err := instr.typedAttr("AttrUint16", attr, AttrUint16)
+	u, _ := v.(uint16)
+	return u, err
+}
+
+// AttrUint32 returns the state of a ViUInt32 attribute.
+func (instr Object) AttrUint32(attr uint32) (uint32, error) {
+	v, err := instr.typedAttr("AttrUint32", attr, AttrUint32)
+	u, _ := v.(uint32)
+	return u, err
+}
+
+// AttrUint64 returns the state of a ViUInt64 attribute.
+func (instr Object) AttrUint64(attr uint32) (uint64, error) {
+	v, err := instr.typedAttr("AttrUint64", attr, AttrUint64)
+	u, _ := v.(uint64)
+	return u, err
+}
+
+// AttrInt16 returns the state of a ViInt16 attribute.
+func (instr Object) AttrInt16(attr uint32) (int16, error) {
+	v, err := instr.typedAttr("AttrInt16", attr, AttrInt16)
+	i, _ := v.(int16)
+	return i, err
+}
+
+// AttrInt32 returns the state of a ViInt32 attribute.
+func (instr Object) AttrInt32(attr uint32) (int32, error) {
+	v, err := instr.typedAttr("AttrInt32", attr, AttrInt32)
+	i, _ := v.(int32)
+	return i, err
+}
+
+// AttrBool returns the state of a ViBoolean attribute.
+func (instr Object) AttrBool(attr uint32) (bool, error) {
+	v, err := instr.typedAttr("AttrBool", attr, AttrBool)
+	b, _ := v.(bool)
+	return b, err
+}
+
+// AttrString returns the state of a string attribute.
+func (instr Object) AttrString(attr uint32) (string, error) {
+	v, err := instr.typedAttr("AttrString", attr, AttrString)
+	s, _ := v.(string)
+	return s, err
+}
+
+// AttrBusAddress returns the state of an address or size attribute, e.g.
+// ATTR_WIN_BASE_ADDR or ATTR_MEM_SIZE.
+func (instr Object) AttrBusAddress(attr uint32) (BusAddress, error) {
+	v, err := instr.typedAttr("AttrBusAddress", attr, AttrUint32, AttrUint64)
+	switch u := v.(type) {
+	case uint32:
+		return BusAddress(u), err
+	case uint64:
+		return BusAddress(u), err
+	}
+	return 0, err
+}
+
+// SetAttrUint8 sets the state of a ViUInt8 attribute.
+func (instr Object) SetAttrUint8(attr uint32, state uint8) error {
+	return instr.setTypedAttr("SetAttrUint8", attr, uint64(state), AttrUint8)
+}
+
+// SetAttrUint16 sets the state of a ViUInt16 attribute.
+func (instr Object) SetAttrUint16(attr uint32, state uint16) error {
+	return instr.setTypedAttr("SetAttrUint16", attr, uint64(state), AttrUint16)
+}
+
+// SetAttrUint32 sets the state of a ViUInt32 attribute.
+func (instr Object) SetAttrUint32(attr uint32, state uint32) error {
+	return instr.setTypedAttr("SetAttrUint32", attr, uint64(state), AttrUint32)
+}
+
+// SetAttrUint64 sets the state of a ViUInt64 attribute, e.g.
+// ATTR_USER_DATA_64.
+func (instr Object) SetAttrUint64(attr uint32, state uint64) error {
+	return instr.setTypedAttr("SetAttrUint64", attr, state, AttrUint64)
+}
+
+// SetAttrInt16 sets the state of a ViInt16 attribute.
+func (instr Object) SetAttrInt16(attr uint32, state int16) error {
+	return instr.setTypedAttr("SetAttrInt16", attr, uint64(uint16(state)), AttrInt16)
+}
+
+// SetAttrInt32 sets the state of a ViInt32 attribute.
+func (instr Object) SetAttrInt32(attr uint32, state int32) error {
+	return instr.setTypedAttr("SetAttrInt32", attr, uint64(uint32(state)), AttrInt32)
+}
+
+// SetAttrBool sets the state of a ViBoolean attribute.
+func (instr Object) SetAttrBool(attr uint32, state bool) error {
+	return instr.setTypedAttr("SetAttrBool", attr, boolState(state), AttrBool)
+}
+
+// SetAttrBusAddress sets the state of an address or size attribute.
+func (instr Object) SetAttrBusAddress(attr uint32, state BusAddress) error {
+	return instr.setTypedAttr("SetAttrBusAddress", attr, uint64(state), AttrUint32, AttrUint64)
+}
+
+// AttrValue is the state of an attribute read by DumpAttributes.
+type AttrValue struct {
+	Attr  uint32
+	Info  AttrInfo
+	Value interface{} // of the Go type matching Info.Type
+}
+
+func (v AttrValue) String() string {
+	switch s := v.Value.(type) {
+	case bool:
+		if s {
+			return v.Info.Name + " = VI_TRUE"
+		}
+		return v.Info.Name + " = VI_FALSE"
+	case string:
+		return fmt.Sprintf("%s = %q", v.Info.Name, s)
+	case uintptr:
+		return fmt.Sprintf("%s = 0x%X", v.Info.Name, s)
+	case []byte:
+		return fmt.Sprintf("%s = [% X]", v.Info.Name, s)
+	}
+	return fmt.Sprintf("%s = %v", v.Info.Name, v.Value)
+}
+
+// DumpAttributes returns the state of every attribute that applies to the
+// session's interface and resource class, sorted by name, for
+// diagnostics. Attributes the implementation doesn't support are left
+// out.
+func DumpAttributes(obj Object) ([]AttrValue, error) {
+	class, err := obj.AttrString(ATTR_RSRC_CLASS)
+	if err != nil {
+		return nil, err
+	}
+	intf, _ := obj.AttrUint16(ATTR_INTF_TYPE)
+	var vals []AttrValue
+	for attr, info := range attrTable {
+		if !info.AppliesTo(InterfaceType(intf), class) {
+			continue
+		}
+		if v, status := obj.attrState(attr); status >= SUCCESS {
+			vals = append(vals, AttrValue{attr, info, v})
+		}
+	}
+	sort.Slice(vals, func(i, j int) bool { return vals[i].Info.Name < vals[j].Info.Name })
+	return vals, nil
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+// intrBackend is a backend whose objects hold a USB interrupt of data,
+// which it writes whole like NI-VISA does.
+type intrBackend struct {
+	Backend
+	data []byte
+}
+
+func (b intrBackend) GetAttribute(vi, attr uint32, addr unsafe.Pointer) Status {
+	switch attr {
+	case ATTR_USB_RECV_INTR_SIZE:
+		*(*uint16)(addr) = uint16(len(b.data))
+	case ATTR_USB_RECV_INTR_DATA:
+		copy(unsafe.Slice((*byte)(addr), len(b.data)), b.data)
+	default:
+		return ERROR_NSUP_ATTR
+	}
+	return SUCCESS
+}
+
+func TestAttrBytes(t *testing.T) {
+	old := backend
+	t.Cleanup(func() { SetBackend(old) })
+
+	for _, n := range []int{0, 3, 65535} {
+		data := bytes.Repeat([]byte{0xA5}, n)
+		SetBackend(intrBackend{data: data})
+		v, status := Object(1).attrState(ATTR_USB_RECV_INTR_DATA)
+		if b, _ := v.([]byte); status != SUCCESS || !bytes.Equal(b, data) {
+			t.Errorf("%d bytes of ATTR_USB_RECV_INTR_DATA read as %d, %v", n, len(b), status)
+		}
+		attrs := eventAttrs(Object(1))
+		if b, _ := attrs[ATTR_USB_RECV_INTR_DATA].([]byte); !bytes.Equal(b, data) {
+			t.Errorf("eventAttrs read %d of %d bytes of interrupt data", len(b), n)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// AttrType is the type of an attribute's state.
+type AttrType uint8
+
+// Attribute state types.
+const (
+	AttrUint8 AttrType = iota
+	AttrUint16
+	AttrUint32
+	AttrUint64
+	AttrInt16
+	AttrInt32
+	AttrBool   // ViBoolean, TRUE or FALSE
+	AttrAddr   // ViAddr or ViBuf, a C pointer
+	AttrString // at most FIND_BUFLEN-1 bytes
+	AttrBytes  // ViAUInt8, as long as its length attribute says
+)
+
+var attrTypeNames = [...]string{"uint8", "uint16", "uint32", "uint64", "int16",
+	"int32", "bool", "addr", "string", "bytes"}
+
+func (t AttrType) String() string {
+	if int(t) < len(attrTypeNames) {
+		return attrTypeNames[t]
+	}
+	return "AttrType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// AttrInfo describes a VISA attribute.
+type AttrInfo struct {
+	Name     string // VISA name, e.g. VI_ATTR_TMO_VALUE
+	Type     AttrType
+	ReadOnly bool
+	Global   bool // shared by every session to the resource
+
+	// Classes lists the resource types the attribute applies to as
+	// INTF::CLASS, e.g. GPIB::INSTR, where * matches any interface or
+	// class and a lone * any session. Event context attributes list EVENT.
+	Classes []string
+}
+
+// AppliesTo reports whether the attribute applies to sessions to
+// resources of the interface and class, e.g. InterfaceTCPIP and "SOCKET".
+func (info AttrInfo) AppliesTo(intf InterfaceType, class string) bool {
+	for _, c := range info.Classes {
+		if c == "*" {
+			return true
+		}
+		i := strings.Index(c, "::")
+		if i < 0 {
+			continue
+		}
+		if (c[:i] == "*" || c[:i] == intf.String()) &&
+			(c[i+2:] == "*" || strings.EqualFold(c[i+2:], class)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupAttribute returns the description of attr.
+func LookupAttribute(attr uint32) (AttrInfo, bool) {
+	info, ok := attrTable[attr]
+	info.Classes = append([]string(nil), info.Classes...)
+	return info, ok
+}
+
+// attrName returns the VISA name of attr, or its value in hex if it's
+// unknown.
+func attrName(attr uint32) string {
+	if info, ok := attrTable[attr]; ok {
+		return info.Name
+	}
+	return fmt.Sprintf("0x%08X", attr)
+}
+
+// Access and scope of the attributes in attrTable.
+const (
+	attrRO     = true
+	attrRW     = false
+	attrGlobal = true
+	attrLocal  = false
+)
+
+// Resource types of the attributes in attrTable.
+var (
+	anySession = []string{"*"}
+	anyRsrc    = []string{"*::*"}
+	eventAttr  = []string{"EVENT"}
+	msgRsrcs   = []string{"GPIB::INSTR", "GPIB::INTFC", "GPIB::SERVANT", "VXI::INSTR",
+		"VXI::SERVANT", "GPIB-VXI::INSTR", "ASRL::INSTR", "TCPIP::INSTR",
+		"TCPIP::SOCKET", "TCPIP::SERVANT", "USB::INSTR", "USB::RAW"}
+	regRsrcs = []string{"VXI::INSTR", "VXI::MEMACC", "GPIB-VXI::INSTR",
+		"GPIB-VXI::MEMACC", "PXI::INSTR", "PXI::MEMACC"}
+	devRsrcs = []string{"VXI::INSTR", "GPIB-VXI::INSTR", "PXI::INSTR", "USB::INSTR",
+		"USB::RAW"}
+	ieeeRsrcs = []string{"GPIB::INSTR", "VXI::INSTR", "GPIB-VXI::INSTR",
+		"TCPIP::INSTR", "USB::INSTR"}
+	gpibInstr    = []string{"GPIB::INSTR"}
+	gpibIntfc    = []string{"GPIB::INTFC"}
+	gpibRsrcs    = []string{"GPIB::INSTR", "GPIB::INTFC"}
+	servants     = []string{"GPIB::INTFC", "GPIB::SERVANT", "VXI::SERVANT", "TCPIP::SERVANT"}
+	vxiInstr     = []string{"VXI::INSTR", "GPIB-VXI::INSTR"}
+	vxiBackplane = []string{"VXI::BACKPLANE", "GPIB-VXI::BACKPLANE"}
+	vxiRsrcs     = []string{"VXI::INSTR", "GPIB-VXI::INSTR", "VXI::BACKPLANE",
+		"GPIB-VXI::BACKPLANE"}
+	vxiTrig = []string{"VXI::INSTR", "GPIB-VXI::INSTR", "VXI::BACKPLANE",
+		"GPIB-VXI::BACKPLANE", "VXI::SERVANT", "PXI::INSTR", "PXI::BACKPLANE"}
+	gpibVXI      = []string{"GPIB-VXI::*"}
+	slotRsrcs    = []string{"VXI::INSTR", "GPIB-VXI::INSTR", "PXI::INSTR"}
+	asrlInstr    = []string{"ASRL::INSTR"}
+	tcpipRsrcs   = []string{"TCPIP::INSTR", "TCPIP::SOCKET"}
+	tcpipInstr   = []string{"TCPIP::INSTR"}
+	usbRsrcs     = []string{"USB::INSTR", "USB::RAW"}
+	pxiInstr     = []string{"PXI::INSTR"}
+	pxiTrig      = []string{"PXI::INSTR", "PXI::BACKPLANE"}
+	pxiBackplane = []string{"PXI::BACKPLANE"}
+)
+
+// attrTable describes every attribute in defs.go. The pure-Go backend
+// supports the ones its objects store a state for.
+var attrTable = map[uint32]AttrInfo{
+	ATTR_RSRC_CLASS:        {"VI_ATTR_RSRC_CLASS", AttrString, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_NAME:         {"VI_ATTR_RSRC_NAME", AttrString, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_IMPL_VERSION: {"VI_ATTR_RSRC_IMPL_VERSION", AttrUint32, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_LOCK_STATE:   {"VI_ATTR_RSRC_LOCK_STATE", AttrUint32, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_SPEC_VERSION: {"VI_ATTR_RSRC_SPEC_VERSION", AttrUint32, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_MANF_NAME:    {"VI_ATTR_RSRC_MANF_NAME", AttrString, attrRO, attrGlobal, anySession},
+	ATTR_RSRC_MANF_ID:      {"VI_ATTR_RSRC_MANF_ID", AttrUint16, attrRO, attrGlobal, anySession},
+	ATTR_MAX_QUEUE_LENGTH:  {"VI_ATTR_MAX_QUEUE_LENGTH", AttrUint32, attrRW, attrLocal, anySession},
+	ATTR_USER_DATA_32:      {"VI_ATTR_USER_DATA_32", AttrUint32, attrRW, attrLocal, anySession},
+	ATTR_USER_DATA_64:      {"VI_ATTR_USER_DATA_64", AttrUint64, attrRW, attrLocal, anySession},
+	ATTR_TMO_VALUE:         {"VI_ATTR_TMO_VALUE", AttrUint32, attrRW, attrLocal, anySession},
+	ATTR_RM_SESSION:        {"VI_ATTR_RM_SESSION", AttrUint32, attrRO, attrLocal, anyRsrc},
+	ATTR_INTF_TYPE:         {"VI_ATTR_INTF_TYPE", AttrUint16, attrRO, attrGlobal, anyRsrc},
+	ATTR_INTF_NUM:          {"VI_ATTR_INTF_NUM", AttrUint16, attrRO, attrGlobal, anyRsrc},
+	ATTR_INTF_INST_NAME:    {"VI_ATTR_INTF_INST_NAME", AttrString, attrRO, attrGlobal, anyRsrc},
+	ATTR_INTF_PARENT_NUM:   {"VI_ATTR_INTF_PARENT_NUM", AttrUint16, attrRO, attrGlobal, gpibVXI},
+
+	ATTR_TERMCHAR:         {"VI_ATTR_TERMCHAR", AttrUint8, attrRW, attrLocal, msgRsrcs},
+	ATTR_TERMCHAR_EN:      {"VI_ATTR_TERMCHAR_EN", AttrBool, attrRW, attrLocal, msgRsrcs},
+	ATTR_SEND_END_EN:      {"VI_ATTR_SEND_END_EN", AttrBool, attrRW, attrLocal, msgRsrcs},
+	ATTR_SUPPRESS_END_EN:  {"VI_ATTR_SUPPRESS_END_EN", AttrBool, attrRW, attrLocal, msgRsrcs},
+	ATTR_IO_PROT:          {"VI_ATTR_IO_PROT", AttrUint16, attrRW, attrLocal, msgRsrcs},
+	ATTR_DMA_ALLOW_EN:     {"VI_ATTR_DMA_ALLOW_EN", AttrBool, attrRW, attrLocal, append(msgRsrcs, regRsrcs...)},
+	ATTR_FILE_APPEND_EN:   {"VI_ATTR_FILE_APPEND_EN", AttrBool, attrRW, attrLocal, msgRsrcs},
+	ATTR_RD_BUF_OPER_MODE: {"VI_ATTR_RD_BUF_OPER_MODE", AttrUint16, attrRW, attrLocal, msgRsrcs},
+	ATTR_RD_BUF_SIZE:      {"VI_ATTR_RD_BUF_SIZE", AttrUint32, attrRO, attrLocal, msgRsrcs},
+	ATTR_WR_BUF_OPER_MODE: {"VI_ATTR_WR_BUF_OPER_MODE", AttrUint16, attrRW, attrLocal, msgRsrcs},
+	ATTR_WR_BUF_SIZE:      {"VI_ATTR_WR_BUF_SIZE", AttrUint32, attrRO, attrLocal, msgRsrcs},
+	ATTR_4882_COMPLIANT:   {"VI_ATTR_4882_COMPLIANT", AttrBool, attrRO, attrGlobal, ieeeRsrcs},
+
+	ATTR_FDC_CHNL:          {"VI_ATTR_FDC_CHNL", AttrUint16, attrRW, attrLocal, vxiInstr},
+	ATTR_FDC_MODE:          {"VI_ATTR_FDC_MODE", AttrUint16, attrRW, attrLocal, vxiInstr},
+	ATTR_FDC_GEN_SIGNAL_EN: {"VI_ATTR_FDC_GEN_SIGNAL_EN", AttrBool, attrRW, attrLocal, vxiInstr},
+	ATTR_FDC_USE_PAIR:      {"VI_ATTR_FDC_USE_PAIR", AttrBool, attrRW, attrLocal, vxiInstr},
+
+	ATTR_SRC_ACCESS_PRIV:  {"VI_ATTR_SRC_ACCESS_PRIV", AttrUint16, attrRW, attrLocal, regRsrcs},
+	ATTR_SRC_BYTE_ORDER:   {"VI_ATTR_SRC_BYTE_ORDER", AttrUint16, attrRW, attrLocal, regRsrcs},
+	ATTR_SRC_INCREMENT:    {"VI_ATTR_SRC_INCREMENT", AttrInt32, attrRW, attrLocal, regRsrcs},
+	ATTR_DEST_ACCESS_PRIV: {"VI_ATTR_DEST_ACCESS_PRIV", AttrUint16, attrRW, attrLocal, regRsrcs},
+	ATTR_DEST_BYTE_ORDER:  {"VI_ATTR_DEST_BYTE_ORDER", AttrUint16, attrRW, attrLocal, regRsrcs},
+	ATTR_DEST_INCREMENT:   {"VI_ATTR_DEST_INCREMENT", AttrInt32, attrRW, attrLocal, regRsrcs},
+	ATTR_WIN_ACCESS:       {"VI_ATTR_WIN_ACCESS", AttrUint16, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_ACCESS_PRIV:  {"VI_ATTR_WIN_ACCESS_PRIV", AttrUint16, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_BYTE_ORDER:   {"VI_ATTR_WIN_BYTE_ORDER", AttrUint16, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_BASE_ADDR_32: {"VI_ATTR_WIN_BASE_ADDR_32", AttrUint32, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_BASE_ADDR_64: {"VI_ATTR_WIN_BASE_ADDR_64", AttrUint64, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_SIZE_32:      {"VI_ATTR_WIN_SIZE_32", AttrUint32, attrRO, attrLocal, regRsrcs},
+	ATTR_WIN_SIZE_64:      {"VI_ATTR_WIN_SIZE_64", AttrUint64, attrRO, attrLocal, regRsrcs},
+
+	ATTR_MANF_NAME:  {"VI_ATTR_MANF_NAME", AttrString, attrRO, attrGlobal, devRsrcs},
+	ATTR_MODEL_NAME: {"VI_ATTR_MODEL_NAME", AttrString, attrRO, attrGlobal, devRsrcs},
+	ATTR_MANF_ID:    {"VI_ATTR_MANF_ID", AttrUint16, attrRO, attrGlobal, devRsrcs},
+	ATTR_MODEL_CODE: {"VI_ATTR_MODEL_CODE", AttrUint16, attrRO, attrGlobal, devRsrcs},
+	ATTR_SLOT:       {"VI_ATTR_SLOT", AttrInt16, attrRO, attrGlobal, slotRsrcs},
+	ATTR_TRIG_ID:    {"VI_ATTR_TRIG_ID", AttrInt16, attrRW, attrLocal, vxiTrig},
+
+	ATTR_GPIB_PRIMARY_ADDR:    {"VI_ATTR_GPIB_PRIMARY_ADDR", AttrUint16, attrRO, attrGlobal, gpibRsrcs},
+	ATTR_GPIB_SECONDARY_ADDR:  {"VI_ATTR_GPIB_SECONDARY_ADDR", AttrUint16, attrRO, attrGlobal, gpibRsrcs},
+	ATTR_GPIB_READDR_EN:       {"VI_ATTR_GPIB_READDR_EN", AttrBool, attrRW, attrLocal, gpibInstr},
+	ATTR_GPIB_UNADDR_EN:       {"VI_ATTR_GPIB_UNADDR_EN", AttrBool, attrRW, attrLocal, gpibInstr},
+	ATTR_GPIB_REN_STATE:       {"VI_ATTR_GPIB_REN_STATE", AttrInt16, attrRO, attrGlobal, gpibRsrcs},
+	ATTR_GPIB_ATN_STATE:       {"VI_ATTR_GPIB_ATN_STATE", AttrInt16, attrRO, attrGlobal, gpibIntfc},
+	ATTR_GPIB_ADDR_STATE:      {"VI_ATTR_GPIB_ADDR_STATE", AttrInt16, attrRO, attrGlobal, gpibIntfc},
+	ATTR_GPIB_CIC_STATE:       {"VI_ATTR_GPIB_CIC_STATE", AttrBool, attrRO, attrGlobal, gpibIntfc},
+	ATTR_GPIB_NDAC_STATE:      {"VI_ATTR_GPIB_NDAC_STATE", AttrInt16, attrRO, attrGlobal, gpibIntfc},
+	ATTR_GPIB_SRQ_STATE:       {"VI_ATTR_GPIB_SRQ_STATE", AttrInt16, attrRO, attrGlobal, gpibIntfc},
+	ATTR_GPIB_SYS_CNTRL_STATE: {"VI_ATTR_GPIB_SYS_CNTRL_STATE", AttrBool, attrRW, attrGlobal, gpibIntfc},
+	ATTR_GPIB_HS488_CBL_LEN:   {"VI_ATTR_GPIB_HS488_CBL_LEN", AttrInt16, attrRW, attrGlobal, gpibIntfc},
+	ATTR_DEV_STATUS_BYTE:      {"VI_ATTR_DEV_STATUS_BYTE", AttrUint8, attrRW, attrGlobal, servants},
+
+	ATTR_VXI_LA:                {"VI_ATTR_VXI_LA", AttrInt16, attrRO, attrGlobal, vxiRsrcs},
+	ATTR_CMDR_LA:               {"VI_ATTR_CMDR_LA", AttrInt16, attrRO, attrGlobal, vxiInstr},
+	ATTR_MAINFRAME_LA:          {"VI_ATTR_MAINFRAME_LA", AttrInt16, attrRO, attrGlobal, vxiRsrcs},
+	ATTR_VXI_DEV_CLASS:         {"VI_ATTR_VXI_DEV_CLASS", AttrUint16, attrRO, attrGlobal, vxiInstr},
+	ATTR_IMMEDIATE_SERV:        {"VI_ATTR_IMMEDIATE_SERV", AttrBool, attrRO, attrGlobal, vxiInstr},
+	ATTR_MEM_SPACE:             {"VI_ATTR_MEM_SPACE", AttrUint16, attrRO, attrGlobal, vxiInstr},
+	ATTR_MEM_BASE_32:           {"VI_ATTR_MEM_BASE_32", AttrUint32, attrRO, attrGlobal, vxiInstr},
+	ATTR_MEM_BASE_64:           {"VI_ATTR_MEM_BASE_64", AttrUint64, attrRO, attrGlobal, vxiInstr},
+	ATTR_MEM_SIZE_32:           {"VI_ATTR_MEM_SIZE_32", AttrUint32, attrRO, attrGlobal, vxiInstr},
+	ATTR_MEM_SIZE_64:           {"VI_ATTR_MEM_SIZE_64", AttrUint64, attrRO, attrGlobal, vxiInstr},
+	ATTR_VXI_TRIG_SUPPORT:      {"VI_ATTR_VXI_TRIG_SUPPORT", AttrUint32, attrRO, attrGlobal, vxiRsrcs},
+	ATTR_VXI_VME_INTR_STATUS:   {"VI_ATTR_VXI_VME_INTR_STATUS", AttrUint16, attrRO, attrGlobal, vxiBackplane},
+	ATTR_VXI_TRIG_STATUS:       {"VI_ATTR_VXI_TRIG_STATUS", AttrUint32, attrRO, attrGlobal, vxiBackplane},
+	ATTR_VXI_VME_SYSFAIL_STATE: {"VI_ATTR_VXI_VME_SYSFAIL_STATE", AttrInt16, attrRO, attrGlobal, vxiBackplane},
+	ATTR_VXI_TRIG_LINES_EN:     {"VI_ATTR_VXI_TRIG_LINES_EN", AttrUint16, attrRW, attrLocal, vxiRsrcs},
+	ATTR_VXI_TRIG_DIR:          {"VI_ATTR_VXI_TRIG_DIR", AttrUint16, attrRW, attrLocal, vxiRsrcs},
+	ATTR_SYNC_MXI_ALLOW_EN:     {"VI_ATTR_SYNC_MXI_ALLOW_EN", AttrBool, attrRW, attrGlobal, vxiBackplane},
+
+	ATTR_TCPIP_ADDR:        {"VI_ATTR_TCPIP_ADDR", AttrString, attrRO, attrGlobal, tcpipRsrcs},
+	ATTR_TCPIP_HOSTNAME:    {"VI_ATTR_TCPIP_HOSTNAME", AttrString, attrRO, attrGlobal, tcpipRsrcs},
+	ATTR_TCPIP_PORT:        {"VI_ATTR_TCPIP_PORT", AttrUint16, attrRO, attrGlobal, tcpipRsrcs},
+	ATTR_TCPIP_DEVICE_NAME: {"VI_ATTR_TCPIP_DEVICE_NAME", AttrString, attrRO, attrGlobal, tcpipInstr},
+	ATTR_TCPIP_NODELAY:     {"VI_ATTR_TCPIP_NODELAY", AttrBool, attrRW, attrLocal, tcpipRsrcs},
+	ATTR_TCPIP_KEEPALIVE:   {"VI_ATTR_TCPIP_KEEPALIVE", AttrBool, attrRW, attrLocal, tcpipRsrcs},
+
+	ATTR_TCPIP_IS_HISLIP:             {"VI_ATTR_TCPIP_IS_HISLIP", AttrBool, attrRO, attrGlobal, tcpipInstr},
+	ATTR_TCPIP_HISLIP_VERSION:        {"VI_ATTR_TCPIP_HISLIP_VERSION", AttrUint32, attrRO, attrGlobal, tcpipInstr},
+	ATTR_TCPIP_HISLIP_OVERLAP_EN:     {"VI_ATTR_TCPIP_HISLIP_OVERLAP_EN", AttrBool, attrRW, attrGlobal, tcpipInstr},
+	ATTR_TCPIP_HISLIP_MAX_MESSAGE_KB: {"VI_ATTR_TCPIP_HISLIP_MAX_MESSAGE_KB", AttrUint32, attrRW, attrLocal, tcpipInstr},
+
+	ATTR_USB_SERIAL_NUM:    {"VI_ATTR_USB_SERIAL_NUM", AttrString, attrRO, attrGlobal, usbRsrcs},
+	ATTR_USB_INTFC_NUM:     {"VI_ATTR_USB_INTFC_NUM", AttrInt16, attrRO, attrGlobal, usbRsrcs},
+	ATTR_USB_PROTOCOL:      {"VI_ATTR_USB_PROTOCOL", AttrInt16, attrRO, attrGlobal, usbRsrcs},
+	ATTR_USB_MAX_INTR_SIZE: {"VI_ATTR_USB_MAX_INTR_SIZE", AttrUint16, attrRW, attrLocal, usbRsrcs},
+
+	ATTR_PXI_DEV_NUM:             {"VI_ATTR_PXI_DEV_NUM", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_FUNC_NUM:            {"VI_ATTR_PXI_FUNC_NUM", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_BUS_NUM:             {"VI_ATTR_PXI_BUS_NUM", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_CHASSIS:             {"VI_ATTR_PXI_CHASSIS", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_SLOTPATH:            {"VI_ATTR_PXI_SLOTPATH", AttrString, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_SLOT_LBUS_LEFT:      {"VI_ATTR_PXI_SLOT_LBUS_LEFT", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_SLOT_LBUS_RIGHT:     {"VI_ATTR_PXI_SLOT_LBUS_RIGHT", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_TRIG_BUS:            {"VI_ATTR_PXI_TRIG_BUS", AttrInt16, attrRW, attrLocal, pxiTrig},
+	ATTR_PXI_STAR_TRIG_BUS:       {"VI_ATTR_PXI_STAR_TRIG_BUS", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_STAR_TRIG_LINE:      {"VI_ATTR_PXI_STAR_TRIG_LINE", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_SRC_TRIG_BUS:        {"VI_ATTR_PXI_SRC_TRIG_BUS", AttrInt16, attrRW, attrLocal, pxiBackplane},
+	ATTR_PXI_DEST_TRIG_BUS:       {"VI_ATTR_PXI_DEST_TRIG_BUS", AttrInt16, attrRW, attrLocal, pxiBackplane},
+	ATTR_PXI_MEM_TYPE_BAR0:       {"VI_ATTR_PXI_MEM_TYPE_BAR0", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_TYPE_BAR1:       {"VI_ATTR_PXI_MEM_TYPE_BAR1", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_TYPE_BAR2:       {"VI_ATTR_PXI_MEM_TYPE_BAR2", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_TYPE_BAR3:       {"VI_ATTR_PXI_MEM_TYPE_BAR3", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_TYPE_BAR4:       {"VI_ATTR_PXI_MEM_TYPE_BAR4", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_TYPE_BAR5:       {"VI_ATTR_PXI_MEM_TYPE_BAR5", AttrUint16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR0_32:    {"VI_ATTR_PXI_MEM_BASE_BAR0_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR1_32:    {"VI_ATTR_PXI_MEM_BASE_BAR1_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR2_32:    {"VI_ATTR_PXI_MEM_BASE_BAR2_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR3_32:    {"VI_ATTR_PXI_MEM_BASE_BAR3_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR4_32:    {"VI_ATTR_PXI_MEM_BASE_BAR4_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR5_32:    {"VI_ATTR_PXI_MEM_BASE_BAR5_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR0_64:    {"VI_ATTR_PXI_MEM_BASE_BAR0_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR1_64:    {"VI_ATTR_PXI_MEM_BASE_BAR1_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR2_64:    {"VI_ATTR_PXI_MEM_BASE_BAR2_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR3_64:    {"VI_ATTR_PXI_MEM_BASE_BAR3_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR4_64:    {"VI_ATTR_PXI_MEM_BASE_BAR4_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_BASE_BAR5_64:    {"VI_ATTR_PXI_MEM_BASE_BAR5_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR0_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR0_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR1_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR1_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR2_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR2_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR3_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR3_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR4_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR4_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR5_32:    {"VI_ATTR_PXI_MEM_SIZE_BAR5_32", AttrUint32, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR0_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR0_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR1_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR1_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR2_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR2_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR3_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR3_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR4_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR4_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MEM_SIZE_BAR5_64:    {"VI_ATTR_PXI_MEM_SIZE_BAR5_64", AttrUint64, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_IS_EXPRESS:          {"VI_ATTR_PXI_IS_EXPRESS", AttrBool, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_SLOT_LWIDTH:         {"VI_ATTR_PXI_SLOT_LWIDTH", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_MAX_LWIDTH:          {"VI_ATTR_PXI_MAX_LWIDTH", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_ACTUAL_LWIDTH:       {"VI_ATTR_PXI_ACTUAL_LWIDTH", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_DSTAR_BUS:           {"VI_ATTR_PXI_DSTAR_BUS", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_DSTAR_SET:           {"VI_ATTR_PXI_DSTAR_SET", AttrInt16, attrRO, attrGlobal, pxiInstr},
+	ATTR_PXI_ALLOW_WRITE_COMBINE: {"VI_ATTR_PXI_ALLOW_WRITE_COMBINE", AttrBool, attrRW, attrLocal, pxiInstr},
+
+	ATTR_ASRL_BAUD:          {"VI_ATTR_ASRL_BAUD", AttrUint32, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_DATA_BITS:     {"VI_ATTR_ASRL_DATA_BITS", AttrUint16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_PARITY:        {"VI_ATTR_ASRL_PARITY", AttrUint16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_STOP_BITS:     {"VI_ATTR_ASRL_STOP_BITS", AttrUint16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_FLOW_CNTRL:    {"VI_ATTR_ASRL_FLOW_CNTRL", AttrUint16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_END_IN:        {"VI_ATTR_ASRL_END_IN", AttrUint16, attrRW, attrLocal, asrlInstr},
+	ATTR_ASRL_END_OUT:       {"VI_ATTR_ASRL_END_OUT", AttrUint16, attrRW, attrLocal, asrlInstr},
+	ATTR_ASRL_REPLACE_CHAR:  {"VI_ATTR_ASRL_REPLACE_CHAR", AttrUint8, attrRW, attrLocal, asrlInstr},
+	ATTR_ASRL_DISCARD_NULL:  {"VI_ATTR_ASRL_DISCARD_NULL", AttrBool, attrRW, attrLocal, asrlInstr},
+	ATTR_ASRL_XON_CHAR:      {"VI_ATTR_ASRL_XON_CHAR", AttrUint8, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_XOFF_CHAR:     {"VI_ATTR_ASRL_XOFF_CHAR", AttrUint8, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_DTR_STATE:     {"VI_ATTR_ASRL_DTR_STATE", AttrInt16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_RTS_STATE:     {"VI_ATTR_ASRL_RTS_STATE", AttrInt16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_CTS_STATE:     {"VI_ATTR_ASRL_CTS_STATE", AttrInt16, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_DSR_STATE:     {"VI_ATTR_ASRL_DSR_STATE", AttrInt16, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_DCD_STATE:     {"VI_ATTR_ASRL_DCD_STATE", AttrInt16, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_RI_STATE:      {"VI_ATTR_ASRL_RI_STATE", AttrInt16, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_BREAK_STATE:   {"VI_ATTR_ASRL_BREAK_STATE", AttrInt16, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_BREAK_LEN:     {"VI_ATTR_ASRL_BREAK_LEN", AttrInt16, attrRW, attrLocal, asrlInstr},
+	ATTR_ASRL_AVAIL_NUM:     {"VI_ATTR_ASRL_AVAIL_NUM", AttrUint32, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_CONNECTED:     {"VI_ATTR_ASRL_CONNECTED", AttrBool, attrRO, attrGlobal, asrlInstr},
+	ATTR_ASRL_ALLOW_TRANSMI: {"VI_ATTR_ASRL_ALLOW_TRANSMIT", AttrBool, attrRW, attrGlobal, asrlInstr},
+	ATTR_ASRL_WIRE_MODE:     {"VI_ATTR_ASRL_WIRE_MODE", AttrInt16, attrRW, attrGlobal, asrlInstr},
+
+	ATTR_EVENT_TYPE:          {"VI_ATTR_EVENT_TYPE", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_STATUS:              {"VI_ATTR_STATUS", AttrInt32, attrRO, attrLocal, eventAttr},
+	ATTR_JOB_ID:              {"VI_ATTR_JOB_ID", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_RET_COUNT_32:        {"VI_ATTR_RET_COUNT_32", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_RET_COUNT_64:        {"VI_ATTR_RET_COUNT_64", AttrUint64, attrRO, attrLocal, eventAttr},
+	ATTR_BUFFER:              {"VI_ATTR_BUFFER", AttrAddr, attrRO, attrLocal, eventAttr},
+	ATTR_OPER_NAME:           {"VI_ATTR_OPER_NAME", AttrString, attrRO, attrLocal, eventAttr},
+	ATTR_RECV_TRIG_ID:        {"VI_ATTR_RECV_TRIG_ID", AttrInt16, attrRO, attrLocal, eventAttr},
+	ATTR_SIGP_STATUS_ID:      {"VI_ATTR_SIGP_STATUS_ID", AttrUint16, attrRO, attrLocal, eventAttr},
+	ATTR_INTR_STATUS_ID:      {"VI_ATTR_INTR_STATUS_ID", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_RECV_INTR_LEVEL:     {"VI_ATTR_RECV_INTR_LEVEL", AttrInt16, attrRO, attrLocal, eventAttr},
+	ATTR_GPIB_RECV_CIC_STATE: {"VI_ATTR_GPIB_RECV_CIC_STATE", AttrBool, attrRO, attrLocal, eventAttr},
+	ATTR_RECV_TCPIP_ADDR:     {"VI_ATTR_RECV_TCPIP_ADDR", AttrString, attrRO, attrLocal, eventAttr},
+	ATTR_USB_RECV_INTR_SIZE:  {"VI_ATTR_USB_RECV_INTR_SIZE", AttrUint16, attrRO, attrLocal, eventAttr},
+	ATTR_USB_RECV_INTR_DATA:  {"VI_ATTR_USB_RECV_INTR_DATA", AttrBytes, attrRO, attrLocal, eventAttr},
+	ATTR_PXI_RECV_INTR_SEQ:   {"VI_ATTR_PXI_RECV_INTR_SEQ", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_PXI_RECV_INTR_DATA:  {"VI_ATTR_PXI_RECV_INTR_DATA", AttrUint32, attrRO, attrLocal, eventAttr},
+	ATTR_VXI_DEV_CMD_TYPE:    {"VI_ATTR_VXI_DEV_CMD_TYPE", AttrInt16, attrRO, attrLocal, eventAttr},
+	ATTR_VXI_DEV_CMD_VALUE:   {"VI_ATTR_VXI_DEV_CMD_VALUE", AttrUint32, attrRO, attrLocal, eventAttr},
+}
+
+// attrStore holds the attribute states of one pure-Go backend object.
+// Integer states are kept as uint64, string states as string.
+type attrStore struct {
+	mu   sync.Mutex
+	vals map[uint32]interface{}
+}
+
+func newAttrStore(vals map[uint32]interface{}) *attrStore {
+	return &attrStore{vals: vals}
+}
+
+// get copies the state of attr to addr, which must point to a variable of
+// the attribute's type or, for strings, to at least FIND_BUFLEN bytes.
+// Byte arrays are copied whole, addr must hold as many bytes as the state
+// of their length attribute.
+func (a *attrStore) get(attr uint32, addr unsafe.Pointer) Status {
+	info, ok := attrTable[attr]
+	a.mu.Lock()
+	v, present := a.vals[attr]
+	a.mu.Unlock()
+	if !ok || !present {
+		return ERROR_NSUP_ATTR
+	}
+	if addr == nil {
+		return ERROR_USER_BUF
+	}
+	if info.Type == AttrString {
+		s, _ := v.(string)
+		if len(s) > FIND_BUFLEN-1 {
+			s = s[:FIND_BUFLEN-1]
+		}
+		b := (*[FIND_BUFLEN]byte)(addr)
+		copy(b[:], s)
+		b[len(s)] = 0
+		return SUCCESS
+	}
+	if info.Type == AttrBytes {
+		b, _ := v.([]byte)
+		copy(unsafe.Slice((*byte)(addr), len(b)), b)
+		return SUCCESS
+	}
+	u, _ := v.(uint64)
+	switch info.Type {
+	case AttrUint8:
+		*(*uint8)(addr) = uint8(u)
+	case AttrUint16, AttrBool:
+		*(*uint16)(addr) = uint16(u)
+	case AttrInt16:
+		*(*int16)(addr) = int16(u)
+	case AttrUint32:
+		*(*uint32)(addr) = uint32(u)
+	case AttrInt32:
+		*(*int32)(addr) = int32(u)
+	case AttrUint64:
+		*(*uint64)(addr) = u
+	case AttrAddr:
+		*(*uintptr)(addr) = uintptr(u)
+	}
+	return SUCCESS
+}
+
+// num returns the integer state of attr, zero if it isn't set.
+func (a *attrStore) num(attr uint32) uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, _ := a.vals[attr].(uint64)
+	return u
+}
+
+// str returns the string state of attr, empty if it isn't set.
+func (a *attrStore) str(attr uint32) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, _ := a.vals[attr].(string)
+	return s
+}
+
+// check validates that attr is present and writable with the given state.
+func (a *attrStore) check(attr uint32, state uint64) Status {
+	info, ok := attrTable[attr]
+	a.mu.Lock()
+	_, present := a.vals[attr]
+	a.mu.Unlock()
+	switch {
+	case !ok || !present:
+		return ERROR_NSUP_ATTR
+	case info.ReadOnly:
+		return ERROR_ATTR_READONLY
+	case info.Type == AttrString:
+		return ERROR_NSUP_ATTR_STATE
+	case info.Type == AttrBool && state != TRUE && state != FALSE:
+		return ERROR_NSUP_ATTR_STATE
+	}
+	return SUCCESS
+}
+
+// put stores an integer state without checking access.
+func (a *attrStore) put(attr uint32, state uint64) {
+	a.mu.Lock()
+	a.vals[attr] = state
+	a.mu.Unlock()
+}
+
+// putString stores a string state without checking access.
+func (a *attrStore) putString(attr uint32, state string) {
+	a.mu.Lock()
+	a.vals[attr] = state
+	a.mu.Unlock()
+}
+
+// boolState converts a Go bool to a ViBoolean attribute state.
+func boolState(b bool) uint64 {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
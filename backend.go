@@ -0,0 +1,145 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import "unsafe"
+
+// Backend services the VISA operations behind Session and Object. The
+// NI-VISA binding is used when the package is built with cgo, the pure-Go
+// backend when it's built with the novisa tag or with cgo disabled.
+//
+// Object handles are only meaningful to the backend that issued them.
+type Backend interface {
+	// Resource Manager Functions and Operations
+	OpenDefaultRM() (Session, Status)
+	FindRsrc(rm Session, expr string) (findList, retCnt uint32, desc string, status Status)
+	FindNext(findList uint32) (string, Status)
+	ParseRsrc(rm Session, rsrcName string) (intfType, intfNum uint16, status Status)
+	ParseRsrcEx(rm Session, rsrcName string) (intfType, intfNum uint16, rsrcClass,
+		expandedUnaliasedName, aliasIfExists string, status Status)
+	Open(rm Session, name string, mode, timeout uint32) (Object, Status)
+
+	// Resource Template Operations
+	Close(vi uint32) Status
+	SetAttribute(vi, attribute uint32, attrState uint64) Status
+	GetAttribute(vi, attribute uint32, addr unsafe.Pointer) Status
+	StatusDesc(vi uint32, status Status) (string, Status)
+	Terminate(vi uint32, degree, jobId uint16) Status
+	Lock(instr Object, lockType, timeout uint32, requestedKey string) (string, Status)
+	Unlock(instr Object) Status
+	EnableEvent(instr Object, eventType uint32, mechanism uint16, context uint32) Status
+	DisableEvent(instr Object, eventType uint32, mechanism uint16) Status
+	DiscardEvents(instr Object, eventType uint32, mechanism uint16) Status
+	WaitOnEvent(instr Object, inEventType, timeout uint32) (outEventType, outContext uint32, status Status)
+	InstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status
+	UninstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status
+
+	// Basic I/O Operations
+	Read(instr Object, buf []byte) (retCnt uint32, status Status)
+	ReadAsync(instr Object, buf []byte) (jobId uint32, status Status)
+	Write(instr Object, buf []byte) (retCnt uint32, status Status)
+	WriteAsync(instr Object, buf []byte) (jobId uint32, status Status)
+	AssertTrigger(instr Object, protocol uint16) Status
+	ReadSTB(instr Object) (uint16, Status)
+	Clear(instr Object) Status
+}
+
+// FileBackend is implemented by backends that transfer data directly
+// between a device and a file. The package falls back to Read and Write
+// otherwise.
+type FileBackend interface {
+	ReadToFile(instr Object, filename string, cnt uint32) (retCnt uint32, status Status)
+	WriteFromFile(instr Object, filename string, cnt uint32) (retCnt uint32, status Status)
+}
+
+// BufferedBackend is implemented by backends that provide the formatted
+// and buffered I/O operations.
+type BufferedBackend interface {
+	SetBuf(instr Object, mask uint16, size uint32) Status
+	Flush(instr Object, mask uint16) Status
+	BufWrite(instr Object, buf []byte) (retCnt uint32, status Status)
+	BufRead(instr Object, buf []byte) (retCnt uint32, status Status)
+	Printf(instr Object, s string) Status
+	SPrintf(instr Object, buf *uint8, s string) Status
+}
+
+// MemoryBackend is implemented by backends that provide the memory I/O
+// and shared memory operations.
+type MemoryBackend interface {
+	In8(instr Object, space uint16, offset BusAddress) (uint8, Status)
+	Out8(instr Object, space uint16, offset BusAddress, val uint8) Status
+	In16(instr Object, space uint16, offset BusAddress) (uint16, Status)
+	Out16(instr Object, space uint16, offset BusAddress, val uint16) Status
+	In32(instr Object, space uint16, offset BusAddress) (uint32, Status)
+	Out32(instr Object, space uint16, offset BusAddress, val uint32) Status
+	MoveIn8(instr Object, space uint16, offset BusAddress, buf []uint8) Status
+	MoveOut8(instr Object, space uint16, offset BusAddress, buf []uint8) Status
+	MoveIn16(instr Object, space uint16, offset BusAddress, buf []uint16) Status
+	MoveOut16(instr Object, space uint16, offset BusAddress, buf []uint16) Status
+	MoveIn32(instr Object, space uint16, offset BusAddress, buf []uint32) Status
+	MoveOut32(instr Object, space uint16, offset BusAddress, buf []uint32) Status
+	Move(instr Object, srcSpace uint16, srcOffset BusAddress, srcWidth uint16,
+		destSpace uint16, destOffset BusAddress, destWidth uint16, srcLength BusSize) Status
+	MoveAsync(instr Object, srcSpace uint16, srcOffset BusAddress, srcWidth uint16,
+		destSpace uint16, destOffset BusAddress, destWidth uint16, srcLength BusSize) (uint32, Status)
+	MapAddress(instr Object, mapSpace uint16, mapOffset BusAddress, mapSize BusSize,
+		access uint16, suggested *byte) (*byte, Status)
+	UnmapAddress(instr Object) Status
+	Peek8(instr Object, address unsafe.Pointer) uint8
+	Poke8(instr Object, address unsafe.Pointer, val uint8)
+	Peek16(instr Object, address unsafe.Pointer) uint16
+	Poke16(instr Object, address unsafe.Pointer, val uint16)
+	Peek32(instr Object, address unsafe.Pointer) uint32
+	Poke32(instr Object, address unsafe.Pointer, val uint32)
+	MemAlloc(instr Object, size BusSize) (BusAddress, Status)
+	MemFree(instr Object, offset BusAddress) Status
+}
+
+// GPIBBackend is implemented by backends that provide the GPIB interface
+// specific operations.
+type GPIBBackend interface {
+	GpibControlREN(instr Object, mode uint16) Status
+	GpibControlATN(instr Object, mode uint16) Status
+	GpibSendIFC(instr Object) Status
+	GpibCommand(instr Object, cmd []byte) (retCnt uint32, status Status)
+	GpibPassControl(instr Object, primAddr, secAddr uint16) Status
+}
+
+// BackplaneBackend is implemented by backends that provide the VXI and PXI
+// interface specific operations.
+type BackplaneBackend interface {
+	VxiCommandQuery(instr Object, mode uint16, cmd uint32) (uint32, Status)
+	AssertUtilSignal(instr Object, line uint16) Status
+	AssertIntrSignal(instr Object, mode int16, statusID uint16) Status
+	MapTrigger(instr Object, trigSrc, trigDest int16, mode uint16) Status
+	UnmapTrigger(instr Object, trigSrc, trigDest int16) Status
+	PxiReserveTriggers(instr Object, cnt int16, trigBuses, trigLines *int16) (int16, Status)
+}
+
+// USBBackend is implemented by backends that provide USB control pipe
+// transfers.
+type USBBackend interface {
+	UsbControlOut(instr Object, bmRequestType, bRequest int16, wValue, wIndex uint16, buf []byte) Status
+	UsbControlIn(instr Object, bmRequestType, bRequest int16, wValue, wIndex uint16, buf []byte) (uint16, Status)
+}
+
+// backend services every operation in the package.
+var backend Backend = newDefaultBackend()
+
+// SetBackend replaces the backend that services VISA operations, e.g. with
+// a fake for unit testing instrument drivers. It must be called before any
+// session is opened, sessions opened through the previous backend are
+// invalid afterwards.
+func SetBackend(b Backend) {
+	backend = b
+	resetHandlers()
+	resetJobs()
+	resetPools()
+}
+
+// CurrentBackend returns the backend that services VISA operations.
+func CurrentBackend() Backend {
+	return backend
+}
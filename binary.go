@@ -0,0 +1,213 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// DataFormat is the format of the elements of a binary numeric array, as
+// selected with FORM:DATA.
+type DataFormat int
+
+const (
+	Real32 DataFormat = iota // REAL,32, IEEE 754 single precision
+	Real64                   // REAL,64, IEEE 754 double precision
+	Int16                    // INT,16
+	Int32                    // INT,32
+)
+
+var dataFormatNames = []string{"REAL,32", "REAL,64", "INT,16", "INT,32"}
+
+// String returns the FORM:DATA parameters of f, e.g. "REAL,32".
+func (f DataFormat) String() string {
+	if f >= 0 && int(f) < len(dataFormatNames) {
+		return dataFormatNames[f]
+	}
+	return "DataFormat(" + strconv.Itoa(int(f)) + ")"
+}
+
+// Size returns the size of an element in bytes.
+func (f DataFormat) Size() int {
+	switch f {
+	case Real32, Int32:
+		return 4
+	case Real64:
+		return 8
+	case Int16:
+		return 2
+	}
+	return 0
+}
+
+// The byte orders of FORM:BORD, to pass to the binary array functions.
+var (
+	BordNormal  binary.ByteOrder = binary.BigEndian    // FORM:BORD NORM
+	BordSwapped binary.ByteOrder = binary.LittleEndian // FORM:BORD SWAP
+)
+
+// The values SCPI uses to mark an overload or missing value, NaN, and the
+// positive and negative infinities.
+const (
+	scpiNaN = 9.91e37
+	scpiInf = 9.9e37
+)
+
+// scpiFloat returns f with the SCPI markers replaced by NaN and the
+// infinities.
+func scpiFloat(f float64) float64 {
+	switch f {
+	case scpiNaN:
+		return math.NaN()
+	case scpiInf:
+		return math.Inf(1)
+	case -scpiInf:
+		return math.Inf(-1)
+	}
+	return f
+}
+
+// DecodeFloats decodes the elements of binary block data in format and
+// byte order. Integer elements are converted, REAL elements equal to the
+// SCPI markers 9.91E37 and +/-9.9E37 become NaN and +/-Inf.
+func DecodeFloats(b []byte, format DataFormat, order binary.ByteOrder) ([]float64, error) {
+	n, err := elements(b, format)
+	if err != nil {
+		return nil, err
+	}
+	fs := make([]float64, n)
+	for i := range fs {
+		switch format {
+		case Real32:
+			v := math.Float32frombits(order.Uint32(b[i*4:]))
+			switch v {
+			case float32(scpiNaN):
+				fs[i] = math.NaN()
+			case float32(scpiInf):
+				fs[i] = math.Inf(1)
+			case float32(-scpiInf):
+				fs[i] = math.Inf(-1)
+			default:
+				fs[i] = float64(v)
+			}
+		case Real64:
+			fs[i] = scpiFloat(math.Float64frombits(order.Uint64(b[i*8:])))
+		case Int16:
+			fs[i] = float64(int16(order.Uint16(b[i*2:])))
+		case Int32:
+			fs[i] = float64(int32(order.Uint32(b[i*4:])))
+		}
+	}
+	return fs, nil
+}
+
+// DecodeInts decodes the elements of binary block data in an integer
+// format and byte order.
+func DecodeInts(b []byte, format DataFormat, order binary.ByteOrder) ([]int32, error) {
+	if format != Int16 && format != Int32 {
+		return nil, fmt.Errorf("visa: %v isn't an integer format", format)
+	}
+	n, err := elements(b, format)
+	if err != nil {
+		return nil, err
+	}
+	is := make([]int32, n)
+	for i := range is {
+		if format == Int16 {
+			is[i] = int32(int16(order.Uint16(b[i*2:])))
+		} else {
+			is[i] = int32(order.Uint32(b[i*4:]))
+		}
+	}
+	return is, nil
+}
+
+// elements returns the number of elements of format in b.
+func elements(b []byte, format DataFormat) (int, error) {
+	size := format.Size()
+	if size == 0 {
+		return 0, fmt.Errorf("visa: invalid data format %v", format)
+	}
+	if len(b)%size != 0 {
+		return 0, fmt.Errorf("visa: %d bytes of block data aren't a whole number of %v elements",
+			len(b), format)
+	}
+	return len(b) / size, nil
+}
+
+// ParseFloats parses a comma separated ASCII array, e.g. the response to
+// TRAC:DATA? with FORM:DATA ASC. The SCPI markers 9.91E37 and +/-9.9E37
+// become NaN and +/-Inf.
+func ParseFloats(resp string) ([]float64, error) {
+	elems := splitResponse(trimResponse(resp))
+	fs := make([]float64, len(elems))
+	for i, e := range elems {
+		f, err := strconv.ParseFloat(e, 64)
+		if err != nil {
+			return nil, err
+		}
+		fs[i] = scpiFloat(f)
+	}
+	return fs, nil
+}
+
+// QueryBinaryFloats writes cmd to d and decodes the definite length block
+// of the response, whose elements are in format and byte order, which
+// have to match the FORM:DATA and FORM:BORD settings of the device.
+func QueryBinaryFloats(d Driver, cmd string, format DataFormat, order binary.ByteOrder) ([]float64, error) {
+	b, err := queryBlock(d, cmd)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := DecodeFloats(b, format, order)
+	if err != nil {
+		return nil, queryError(cmd, err)
+	}
+	return fs, nil
+}
+
+// QueryBinaryInts writes cmd to d and decodes the definite length block
+// of the response, whose elements are in the integer format and byte
+// order, which have to match the FORM:DATA and FORM:BORD settings of the
+// device.
+func QueryBinaryInts(d Driver, cmd string, format DataFormat, order binary.ByteOrder) ([]int32, error) {
+	b, err := queryBlock(d, cmd)
+	if err != nil {
+		return nil, err
+	}
+	is, err := DecodeInts(b, format, order)
+	if err != nil {
+		return nil, queryError(cmd, err)
+	}
+	return is, nil
+}
+
+// QueryBinaryFloats writes cmd and decodes the binary block of the
+// response.
+func (instr Object) QueryBinaryFloats(cmd string, format DataFormat, order binary.ByteOrder) ([]float64, error) {
+	return QueryBinaryFloats(instr, cmd, format, order)
+}
+
+// QueryBinaryInts writes cmd and decodes the binary block of the response.
+func (instr Object) QueryBinaryInts(cmd string, format DataFormat, order binary.ByteOrder) ([]int32, error) {
+	return QueryBinaryInts(instr, cmd, format, order)
+}
+
+// queryBlock writes cmd to d and returns the data of the block of the
+// response.
+func queryBlock(d Driver, cmd string) ([]byte, error) {
+	if _, err := NewStream(d).WriteString(cmd); err != nil {
+		return nil, queryError(cmd, err)
+	}
+	var buf bytes.Buffer
+	if _, err := ReadBlock(d, &buf, nil); err != nil {
+		return nil, queryError(cmd, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// encode returns the elements of v in order.
+func encode(t *testing.T, order binary.ByteOrder, v interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, order, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sameFloats reports whether a and b are equal, with NaN equal to NaN.
+func sameFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && !(math.IsNaN(a[i]) && math.IsNaN(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	nan    = math.NaN()
+	posInf = math.Inf(1)
+	negInf = math.Inf(-1)
+)
+
+func TestDecodeFloats(t *testing.T) {
+	markers := []float64{1.5, -2, 9.91e37, 9.9e37, -9.9e37}
+	decoded := []float64{1.5, -2, nan, posInf, negInf}
+	tests := []struct {
+		b      []byte
+		format DataFormat
+		order  binary.ByteOrder
+		want   []float64
+	}{
+		{encode(t, BordSwapped, []float32{1.5, -2, 9.91e37, 9.9e37, -9.9e37}), Real32, BordSwapped, decoded},
+		{encode(t, BordNormal, []float32{1.5, -2, 9.91e37, 9.9e37, -9.9e37}), Real32, BordNormal, decoded},
+		{encode(t, BordNormal, markers), Real64, BordNormal, decoded},
+		{encode(t, BordSwapped, markers), Real64, BordSwapped, decoded},
+		{encode(t, BordNormal, []int16{1, -2, 300}), Int16, BordNormal, []float64{1, -2, 300}},
+		{encode(t, BordSwapped, []int32{-70000, 9.91e6}), Int32, BordSwapped, []float64{-70000, 9.91e6}},
+		{nil, Real32, BordNormal, []float64{}},
+	}
+	for _, tt := range tests {
+		got, err := DecodeFloats(tt.b, tt.format, tt.order)
+		if err != nil || !sameFloats(got, tt.want) {
+			t.Errorf("DecodeFloats(% x, %v) = %v, %v, want %v", tt.b, tt.format, got, err, tt.want)
+		}
+	}
+
+	// Only the exact markers are replaced.
+	near := encode(t, BordNormal, []float64{9.91e37 * (1 + 1e-15), 9.9e36})
+	if got, _ := DecodeFloats(near, Real64, BordNormal); math.IsNaN(got[0]) || math.IsInf(got[1], 0) {
+		t.Errorf("DecodeFloats replaced values next to the markers: %v", got)
+	}
+
+	for _, tt := range []struct {
+		n      int
+		format DataFormat
+	}{{6, Real32}, {12, Real64}, {3, Int16}, {2, Int32}, {4, DataFormat(9)}} {
+		if _, err := DecodeFloats(make([]byte, tt.n), tt.format, BordNormal); err == nil {
+			t.Errorf("DecodeFloats of %d bytes of %v succeeded", tt.n, tt.format)
+		}
+	}
+}
+
+func TestDecodeInts(t *testing.T) {
+	got, err := DecodeInts(encode(t, BordSwapped, []int16{1, -2, math.MaxInt16}), Int16, BordSwapped)
+	if err != nil || !reflect.DeepEqual(got, []int32{1, -2, math.MaxInt16}) {
+		t.Errorf("DecodeInts of INT,16 = %v, %v", got, err)
+	}
+	got, err = DecodeInts(encode(t, BordNormal, []int32{math.MinInt32, 70000}), Int32, BordNormal)
+	if err != nil || !reflect.DeepEqual(got, []int32{math.MinInt32, 70000}) {
+		t.Errorf("DecodeInts of INT,32 = %v, %v", got, err)
+	}
+	if _, err := DecodeInts(make([]byte, 8), Real64, BordNormal); err == nil {
+		t.Error("DecodeInts accepted REAL,64")
+	}
+	if _, err := DecodeInts(make([]byte, 3), Int16, BordNormal); err == nil {
+		t.Error("DecodeInts accepted 3 bytes of INT,16")
+	}
+}
+
+func TestParseFloats(t *testing.T) {
+	tests := []struct {
+		resp string
+		want []float64
+	}{
+		{" 1.0, +9.91E+37,9.9E37,-9.90000E+037 ,2\r\n", []float64{1, nan, posInf, negInf, 2}},
+		{"-1.5E-3", []float64{-1.5e-3}},
+		{"\n", []float64{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFloats(tt.resp)
+		if err != nil || !sameFloats(got, tt.want) {
+			t.Errorf("ParseFloats(%q) = %v, %v, want %v", tt.resp, got, err, tt.want)
+		}
+	}
+	if _, err := ParseFloats("1,NAN?,3"); err == nil {
+		t.Error("ParseFloats accepted a bad element")
+	}
+}
+
+func TestQueryBinary(t *testing.T) {
+	real32 := encode(t, BordSwapped, []float32{-50.25, 9.91e37})
+	int16s := encode(t, BordNormal, []int16{-1, 2})
+	d := &fakeDriver{resp: map[string]string{
+		"TRAC:DATA? TRACE1": "#18" + string(real32) + "\n",
+		"CURV?":             "#14" + string(int16s) + "\n",
+		"ODD?":              "#13abc\n",
+	}, chunk: 3}
+	fs, err := QueryBinaryFloats(d, "TRAC:DATA? TRACE1", Real32, BordSwapped)
+	if err != nil || !sameFloats(fs, []float64{-50.25, nan}) {
+		t.Errorf("QueryBinaryFloats = %v, %v", fs, err)
+	}
+	is, err := QueryBinaryInts(d, "CURV?", Int16, BordNormal)
+	if err != nil || !reflect.DeepEqual(is, []int32{-1, 2}) {
+		t.Errorf("QueryBinaryInts = %v, %v", is, err)
+	}
+
+	var qe *QueryError
+	if _, err := QueryBinaryFloats(d, "ODD?", Real32, BordNormal); !errors.As(err, &qe) || qe.Cmd != "ODD?" {
+		t.Errorf("QueryBinaryFloats of 3 bytes of REAL,32: %v, want a *QueryError", err)
+	}
+	if _, err := QueryBinaryInts(d, "NONE?", Int16, BordNormal); StatusOf(err) != ERROR_TMO {
+		t.Errorf("QueryBinaryInts without a response: %v, want ERROR_TMO", err)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// blockChunk is the size of each transfer of block data.
+const blockChunk = 1 << 16
+
+// maxBlockSize is the largest definite length block, whose length has at
+// most 9 digits.
+const maxBlockSize = 999999999
+
+// ErrBlockHeader is returned by ReadBlock when the response doesn't start
+// with an arbitrary block header.
+var ErrBlockHeader = errors.New("visa: invalid block header")
+
+// Progress is called as block data is transferred with the number of
+// bytes transferred so far and the size of the block, -1 while reading an
+// indefinite length block.
+type Progress func(done, size int64)
+
+// ReadBlock reads an IEEE 488.2 arbitrary block from d, either definite
+// length, #<n><length><data>, or indefinite length, #0<data> ended by NL
+// with END, and copies its data to w as it arrives. The terminator after a
+// definite length block is read as well. It returns the number of bytes
+// written to w. progress may be nil.
+//
+// White space before the header is skipped. The termination character is
+// disabled while the block is read, reading an indefinite length block
+// therefore needs an interface signalling END, which SOCKET resources
+// don't.
+func ReadBlock(d Driver, w io.Writer, progress Progress) (int64, error) {
+	restore, err := setBoolAttr(d, ATTR_TERMCHAR_EN, false)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+
+	r := blockReader{d: d}
+	size, err := r.header()
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return r.indefinite(w, progress)
+	}
+	return r.definite(w, size, progress)
+}
+
+// WriteBlock writes header, e.g. "MMEM:DATA 'wave.bin',", followed by the
+// first size bytes of data as a definite length block and NL with END. END
+// is held back until the terminator, ATTR_SEND_END_EN is restored after
+// the write. progress may be nil.
+//
+// If data has fewer than size bytes the block is left incomplete and
+// io.ErrUnexpectedEOF is returned, Clear resets the device then.
+func WriteBlock(d Driver, header string, data io.Reader, size int64, progress Progress) error {
+	if size < 0 || size > maxBlockSize {
+		return fmt.Errorf("visa: block size %d out of range", size)
+	}
+	restore, err := setBoolAttr(d, ATTR_SEND_END_EN, false)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	s := NewStream(d)
+	n := strconv.FormatInt(size, 10)
+	if _, err := s.WriteString(header + "#" + strconv.Itoa(len(n)) + n); err != nil {
+		return err
+	}
+	buf := make([]byte, blockChunk)
+	var done int64
+	for done < size {
+		chunk := buf
+		if rest := size - done; rest < int64(len(chunk)) {
+			chunk = chunk[:rest]
+		}
+		m, err := io.ReadFull(data, chunk)
+		if m > 0 {
+			if _, err := s.Write(chunk[:m]); err != nil {
+				return err
+			}
+			done += int64(m)
+			if progress != nil {
+				progress(done, size)
+			}
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := setBoolAttr(d, ATTR_SEND_END_EN, true); err != nil {
+		return err
+	}
+	_, err = s.WriteString("\n")
+	return err
+}
+
+// ReadBlock reads an arbitrary block and copies its data to w.
+func (instr Object) ReadBlock(w io.Writer, progress Progress) (int64, error) {
+	return ReadBlock(instr, w, progress)
+}
+
+// WriteBlock writes header followed by size bytes of data as a definite
+// length arbitrary block.
+func (instr Object) WriteBlock(header string, data io.Reader, size int64, progress Progress) error {
+	return WriteBlock(instr, header, data, size, progress)
+}
+
+// setBoolAttr sets the boolean attribute attr if d is a session and
+// returns the function restoring its previous value.
+func setBoolAttr(d Driver, attr uint32, on bool) (func(), error) {
+	instr, ok := d.(Object)
+	if !ok {
+		return func() {}, nil
+	}
+	old, err := instr.AttrBool(attr)
+	if err != nil {
+		return nil, err
+	}
+	if err := instr.SetAttrBool(attr, on); err != nil {
+		return nil, err
+	}
+	return func() { instr.SetAttrBool(attr, old) }, nil
+}
+
+// blockReader reads an arbitrary block. Reads within the block ask for no
+// more than what's left of it, so that drivers that can't disable the
+// termination character only end reads early.
+type blockReader struct {
+	d   Driver
+	eom bool // the last read ended with END
+}
+
+// read reads up to cnt bytes.
+func (r *blockReader) read(cnt int) ([]byte, error) {
+	b, n, status := r.d.Read(uint32(cnt))
+	if int(n) < len(b) {
+		b = b[:n]
+	}
+	if status < SUCCESS {
+		return b, wrapStatus(r.d, "Read", status)
+	}
+	r.eom = status == SUCCESS
+	return b, nil
+}
+
+// next reads one byte of the header.
+func (r *blockReader) next() (byte, error) {
+	if r.eom {
+		return 0, ErrBlockHeader
+	}
+	b, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, ErrBlockHeader
+	}
+	return b[0], nil
+}
+
+// header reads the block header and returns the length of the block, -1
+// for an indefinite length block.
+func (r *blockReader) header() (int64, error) {
+	for {
+		c, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		if c == '#' {
+			break
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return 0, ErrBlockHeader
+		}
+	}
+	c, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	if c < '0' || c > '9' {
+		return 0, ErrBlockHeader
+	}
+	if c == '0' {
+		return -1, nil
+	}
+	digits := make([]byte, c-'0')
+	for i := range digits {
+		if digits[i], err = r.next(); err != nil {
+			return 0, err
+		}
+	}
+	size, err := strconv.ParseUint(string(digits), 10, 63)
+	if err != nil {
+		return 0, ErrBlockHeader
+	}
+	return int64(size), nil
+}
+
+// definite copies size bytes of block data to w and reads the terminator.
+func (r *blockReader) definite(w io.Writer, size int64, progress Progress) (int64, error) {
+	var done int64
+	for done < size {
+		if r.eom {
+			return done, io.ErrUnexpectedEOF
+		}
+		cnt := size - done
+		if cnt > blockChunk {
+			cnt = blockChunk
+		}
+		b, err := r.read(int(cnt))
+		n, werr := writeBlockData(w, b)
+		done += int64(n)
+		if werr != nil {
+			return done, werr
+		}
+		if progress != nil && n > 0 {
+			progress(done, size)
+		}
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, r.terminator()
+}
+
+// indefinite copies the block data to w up to END, without the NL sent
+// with it.
+func (r *blockReader) indefinite(w io.Writer, progress Progress) (int64, error) {
+	var done int64
+	for !r.eom {
+		b, err := r.read(blockChunk)
+		if r.eom && len(b) > 0 && b[len(b)-1] == '\n' {
+			b = b[:len(b)-1]
+		}
+		n, werr := writeBlockData(w, b)
+		done += int64(n)
+		if werr != nil {
+			return done, werr
+		}
+		if progress != nil && n > 0 {
+			progress(done, -1)
+		}
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// terminator reads the rest of the message after a definite length block,
+// up to END or NL.
+func (r *blockReader) terminator() error {
+	for !r.eom {
+		b, err := r.read(1)
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 || b[0] == '\n' {
+			return nil
+		}
+	}
+	return nil
+}
+
+// writeBlockData writes b to w, failing with io.ErrShortWrite if w takes
+// less than all of it.
+func writeBlockData(w io.Writer, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := w.Write(b)
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadBlock(t *testing.T) {
+	data10 := "0123\n56789"
+	tests := []struct {
+		resp  string
+		chunk int
+		data  string
+		err   error
+	}{
+		{"#15hello\n", 0, "hello", nil},
+		{" \r\n#15hello\r\n", 0, "hello", nil},
+		{"#210" + data10 + "\n", 3, data10, nil},
+		{"#210" + data10, 0, data10, nil},
+		{"#0" + data10 + "\n", 4, data10, nil},
+		{"#0\n", 0, "", nil},
+		{"hello\n", 0, "", ErrBlockHeader},
+		{"#x\n", 0, "", ErrBlockHeader},
+		{"#2a1\n", 0, "", ErrBlockHeader},
+		{"#", 0, "", ErrBlockHeader},
+		{"\n", 0, "", ErrBlockHeader},
+		{"#15he", 0, "he", io.ErrUnexpectedEOF},
+		{"#210012", 2, "012", io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		d := &fakeDriver{pending: []byte(tt.resp), chunk: tt.chunk}
+		var buf bytes.Buffer
+		var last int64
+		n, err := ReadBlock(d, &buf, func(done, size int64) { last = done })
+		if !errors.Is(err, tt.err) || buf.String() != tt.data || n != int64(len(tt.data)) {
+			t.Errorf("ReadBlock of %q = %d %q, %v, want %q, %v", tt.resp, n, buf.String(), err, tt.data, tt.err)
+		}
+		if last != n {
+			t.Errorf("ReadBlock of %q reported progress up to %d of %d bytes", tt.resp, last, n)
+		}
+		if tt.err == nil && len(d.pending) != 0 {
+			t.Errorf("ReadBlock of %q left %q unread", tt.resp, d.pending)
+		}
+	}
+}
+
+// openBlockDevice opens a loopback session whose reads serve in, with
+// the knobs set by setup.
+func openBlockDevice(t *testing.T, in string, setup func(l *loopback)) (Object, *loopback) {
+	t.Helper()
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	l.buf = []byte(in)
+	if setup != nil {
+		setup(l)
+	}
+	return instr, l
+}
+
+func TestReadBlockAttrs(t *testing.T) {
+	tests := []struct {
+		in      string
+		chunk   int
+		readErr Status
+		err     error
+	}{
+		{"#15he\nlo\n", 2, SUCCESS, nil},
+		{"#0he\nlo\n", 0, SUCCESS, nil},
+		{"hello\n", 0, SUCCESS, ErrBlockHeader},
+		{"#15he", 0, SUCCESS, io.ErrUnexpectedEOF},
+		{"", 0, ERROR_TMO, Status(ERROR_TMO)},
+	}
+	for _, tt := range tests {
+		for _, en := range []bool{true, false} {
+			instr, l := openBlockDevice(t, tt.in, func(l *loopback) {
+				l.chunk, l.readErr = tt.chunk, tt.readErr
+			})
+			instr.SetAttrBool(ATTR_TERMCHAR_EN, en)
+			if _, err := instr.ReadBlock(io.Discard, nil); !errors.Is(err, tt.err) {
+				t.Errorf("ReadBlock of %q: %v, want %v", tt.in, err, tt.err)
+			}
+			for i, on := range l.termEn {
+				if on {
+					t.Errorf("ReadBlock of %q: read %d with the termination character enabled", tt.in, i)
+				}
+			}
+			if got, err := instr.AttrBool(ATTR_TERMCHAR_EN); err != nil || got != en {
+				t.Errorf("ReadBlock of %q: ATTR_TERMCHAR_EN = %v, %v afterwards, want %v", tt.in, got, err, en)
+			}
+			instr.Close()
+		}
+	}
+}
+
+func TestWriteBlock(t *testing.T) {
+	instr, dev := openBlockDevice(t, "", nil)
+	var last int64
+	err := instr.WriteBlock("MMEM:DATA 'a.bin',", strings.NewReader("he\nllo, world"), 5,
+		func(done, size int64) { last = done })
+	if err != nil {
+		t.Fatalf("WriteBlock: %v", err)
+	}
+	if string(dev.out) != "MMEM:DATA 'a.bin',#15he\nll\n" {
+		t.Errorf("the device received %q", dev.out)
+	}
+	if last != 5 {
+		t.Errorf("WriteBlock reported progress up to %d bytes, want 5", last)
+	}
+	for i, end := range dev.sendEnd {
+		if end != (i == len(dev.sendEnd)-1) {
+			t.Errorf("write %d of %d sent END: %v", i, len(dev.sendEnd), end)
+		}
+	}
+
+	// A large block is written in blockChunk pieces.
+	dev.out, dev.sendEnd = nil, nil
+	data := bytes.Repeat([]byte{0xAA}, 2*blockChunk+1)
+	if err := WriteBlock(instr, "", bytes.NewReader(data), int64(len(data)), nil); err != nil {
+		t.Fatalf("WriteBlock of %d bytes: %v", len(data), err)
+	}
+	if len(dev.sendEnd) != 5 || !bytes.Equal(dev.out[len("#6131073"):len(dev.out)-1], data) {
+		t.Errorf("WriteBlock of %d bytes took %d writes", len(data), len(dev.sendEnd))
+	}
+
+	if err := instr.WriteBlock("", strings.NewReader(""), maxBlockSize+1, nil); err == nil {
+		t.Error("WriteBlock accepted a block larger than maxBlockSize")
+	}
+}
+
+func TestWriteBlockAttrs(t *testing.T) {
+	tests := []struct {
+		writeErr Status
+		data     string
+		err      error
+	}{
+		{SUCCESS, "hello", nil},
+		{SUCCESS, "he", io.ErrUnexpectedEOF},
+		{ERROR_TMO, "hello", Status(ERROR_TMO)},
+	}
+	for _, tt := range tests {
+		for _, en := range []bool{true, false} {
+			instr, _ := openBlockDevice(t, "", func(l *loopback) { l.writeErr = tt.writeErr })
+			instr.SetAttrBool(ATTR_SEND_END_EN, en)
+			if err := instr.WriteBlock("DATA ", strings.NewReader(tt.data), 5, nil); !errors.Is(err, tt.err) {
+				t.Errorf("WriteBlock of %q: %v, want %v", tt.data, err, tt.err)
+			}
+			if got, err := instr.AttrBool(ATTR_SEND_END_EN); err != nil || got != en {
+				t.Errorf("WriteBlock of %q: ATTR_SEND_END_EN = %v, %v afterwards, want %v", tt.data, got, err, en)
+			}
+			instr.Close()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Event is an event received through Subscribe. The event context is
+// closed before the event is sent, its attributes are kept in Attrs.
+type Event struct {
+	Type uint32
+
+	// Attrs holds the event context attributes the implementation
+	// reported, with values of the Go type matching their AttrInfo.
+	Attrs map[uint32]interface{}
+
+	// Data is the event decoded from Attrs.
+	Data EventData
+
+	// Overflow is set when events of this type were lost before this
+	// one because the queue was full.
+	Overflow bool
+
+	// Err is set on the last event of a type when the session was closed.
+	// Type is valid but Attrs and Data are nil.
+	Err error
+}
+
+// Subscribe installs a handler for eventTypes, enables the handler
+// mechanism and sends the events that occur on the returned channel until
+// ctx is done. The events are then disabled and discarded, the handlers
+// uninstalled and the channel is closed. Each event context is closed
+// once its attributes are read.
+//
+// The channel buffers as many events as ATTR_MAX_QUEUE_LENGTH. Events that
+// don't fit while the receiver is behind are lost, and the next event of
+// their type is marked with Overflow. If the session is closed, an event
+// with Err is sent for each type and the channel is closed.
+func (instr Object) Subscribe(ctx context.Context, eventTypes ...uint32) (<-chan Event, error) {
+	if len(eventTypes) == 0 {
+		return nil, instr.Wrap("Subscribe", ERROR_INV_EVENT)
+	}
+	size, err := instr.AttrUint32(ATTR_MAX_QUEUE_LENGTH)
+	if err != nil {
+		size = 50
+	}
+	sub := &subscription{
+		instr: instr,
+		ch:    make(chan Event, size),
+		ended: make(chan uint32, len(eventTypes)),
+		lost:  make(map[uint32]bool),
+	}
+	for _, etype := range eventTypes {
+		if err := sub.enable(etype); err != nil {
+			sub.disable()
+			return nil, err
+		}
+	}
+	go sub.run(ctx, len(eventTypes))
+	return sub.ch, nil
+}
+
+// subscription delivers the events of a Subscribe call.
+type subscription struct {
+	instr Object
+	ch    chan Event
+	ended chan uint32 // types whose handler was dropped with the session
+
+	mu       sync.Mutex
+	handlers map[uint32]HandlerID
+	lost     map[uint32]bool // types with events lost since the last sent
+	closed   bool
+}
+
+// enable installs the handler for etype and enables it.
+func (sub *subscription) enable(etype uint32) error {
+	id, status := sub.instr.installHandler(etype, sub.handle,
+		func() { sub.ended <- etype })
+	if status < SUCCESS {
+		return sub.instr.Wrap("InstallHandler "+eventName(etype), status)
+	}
+	sub.mu.Lock()
+	if sub.handlers == nil {
+		sub.handlers = make(map[uint32]HandlerID)
+	}
+	sub.handlers[etype] = id
+	sub.mu.Unlock()
+	if status := sub.instr.EnableEvent(etype, HNDLR, NULL); status < SUCCESS {
+		return sub.instr.Wrap("EnableEvent "+eventName(etype), status)
+	}
+	return nil
+}
+
+// handle sends an event to the channel unless the receiver is behind.
+func (sub *subscription) handle(instr Object, etype, ectx uint32) {
+	attrs := eventAttrs(Object(ectx))
+	e := Event{Type: etype, Attrs: attrs, Data: decodeEvent(etype, attrs)}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	e.Overflow = sub.lost[etype]
+	select {
+	case sub.ch <- e:
+		sub.lost[etype] = false
+	default:
+		sub.lost[etype] = true
+	}
+}
+
+// run waits for ctx to be done or the session to be closed and ends the
+// subscription.
+func (sub *subscription) run(ctx context.Context, types int) {
+	for ended := 0; ended < types && ctx.Err() == nil; {
+		select {
+		case etype := <-sub.ended:
+			ended++
+			e := Event{Type: etype, Err: sub.instr.Wrap("Subscribe "+eventName(etype), ERROR_INV_OBJECT)}
+			select {
+			case sub.ch <- e:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+	}
+	sub.disable()
+	sub.mu.Lock()
+	sub.closed = true
+	close(sub.ch)
+	sub.mu.Unlock()
+}
+
+// disable disables, discards and uninstalls the handlers installed.
+func (sub *subscription) disable() {
+	sub.mu.Lock()
+	handlers := sub.handlers
+	sub.handlers = nil
+	sub.mu.Unlock()
+	for etype, id := range handlers {
+		sub.instr.DisableEvent(etype, HNDLR)
+		sub.instr.DiscardEvents(etype, SUSPEND_HNDLR)
+		sub.instr.UninstallHandler(etype, id)
+	}
+}
+
+// eventAttrs reads the attributes of an event context.
+func eventAttrs(ectx Object) map[uint32]interface{} {
+	attrs := make(map[uint32]interface{})
+	for attr, info := range attrTable {
+		if len(info.Classes) != 1 || info.Classes[0] != "EVENT" {
+			continue
+		}
+		if v, status := ectx.attrState(attr); status >= SUCCESS {
+			attrs[attr] = v
+		}
+	}
+	return attrs
+}
+
+// eventNames are the VISA names of the event types.
+var eventNames = map[uint32]string{
+	EVENT_IO_COMPLETION:    "VI_EVENT_IO_COMPLETION",
+	EVENT_TRIG:             "VI_EVENT_TRIG",
+	EVENT_SERVICE_REQ:      "VI_EVENT_SERVICE_REQ",
+	EVENT_CLEAR:            "VI_EVENT_CLEAR",
+	EVENT_EXCEPTION:        "VI_EVENT_EXCEPTION",
+	EVENT_GPIB_CIC:         "VI_EVENT_GPIB_CIC",
+	EVENT_GPIB_TALK:        "VI_EVENT_GPIB_TALK",
+	EVENT_GPIB_LISTEN:      "VI_EVENT_GPIB_LISTEN",
+	EVENT_VXI_VME_SYSFAIL:  "VI_EVENT_VXI_VME_SYSFAIL",
+	EVENT_VXI_VME_SYSRESET: "VI_EVENT_VXI_VME_SYSRESET",
+	EVENT_VXI_SIGP:         "VI_EVENT_VXI_SIGP",
+	EVENT_VXI_VME_INTR:     "VI_EVENT_VXI_VME_INTR",
+	EVENT_VXI_DEV_CMD:      "VI_EVENT_VXI_DEV_CMD",
+	EVENT_PXI_INTR:         "VI_EVENT_PXI_INTR",
+	EVENT_TCPIP_CONNECT:    "VI_EVENT_TCPIP_CONNECT",
+	EVENT_USB_INTR:         "VI_EVENT_USB_INTR",
+	EVENT_ASRL_BREAK:       "VI_EVENT_ASRL_BREAK",
+	EVENT_ASRL_CTS:         "VI_EVENT_ASRL_CTS",
+	EVENT_ASRL_DSR:         "VI_EVENT_ASRL_DSR",
+	EVENT_ASRL_DCD:         "VI_EVENT_ASRL_DCD",
+	EVENT_ASRL_RI:          "VI_EVENT_ASRL_RI",
+	EVENT_ASRL_CHAR:        "VI_EVENT_ASRL_CHAR",
+	EVENT_ASRL_TERMCHAR:    "VI_EVENT_ASRL_TERMCHAR",
+}
+
+// eventName returns the VISA name of etype, or its value in hex if it's
+// unknown.
+func eventName(etype uint32) string {
+	if name, ok := eventNames[etype]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08X", etype)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// nextEvent receives an event from ch, failing if none arrives.
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("the subscription ended")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return Event{}
+}
+
+// subscribe subscribes instr to eventTypes until the end of the test.
+func subscribe(t *testing.T, instr Object, eventTypes ...uint32) <-chan Event {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := instr.Subscribe(ctx, eventTypes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		for range events {
+		}
+	})
+	return events
+}
+
+func TestSubscribe(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+
+	if _, err := instr.Subscribe(context.Background()); !errors.Is(err, ErrInvEvent) {
+		t.Errorf("Subscribe to no events: %v, want ErrInvEvent", err)
+	}
+	if _, err := instr.Subscribe(context.Background(), EVENT_TRIG, 0x1234); !errors.Is(err, ErrInvEvent) {
+		t.Errorf("Subscribe to a bad event: %v, want ErrInvEvent", err)
+	}
+	if n := registered(instr); n != 0 {
+		t.Errorf("%d handlers left installed by the failed Subscribe", n)
+	}
+
+	events := subscribe(t, instr, EVENT_TRIG, EVENT_SERVICE_REQ)
+	s.postEvent(EVENT_TRIG, map[uint32]interface{}{ATTR_RECV_TRIG_ID: uint64(TRIG_TTL3)})
+	e := nextEvent(t, events)
+	if e.Type != EVENT_TRIG || e.Data != (TriggerEvent{TriggerID: TRIG_TTL3}) || e.Overflow || e.Err != nil {
+		t.Errorf("event = %+v, want a trigger on TTL3", e)
+	}
+	if id, _ := e.Attrs[ATTR_RECV_TRIG_ID].(int16); id != TRIG_TTL3 {
+		t.Errorf("ATTR_RECV_TRIG_ID = %v", e.Attrs[ATTR_RECV_TRIG_ID])
+	}
+	s.postEvent(EVENT_SERVICE_REQ, nil)
+	if e := nextEvent(t, events); e.Data != (ServiceRequestEvent{}) {
+		t.Errorf("event = %+v, want a service request", e)
+	}
+	// The event contexts are closed.
+	b := backend.(*goBackend)
+	for i := 0; ; i++ {
+		b.mu.Lock()
+		n := len(b.objs)
+		b.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("%d objects open, want the resource manager and the session", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+	instr.SetAttribute(ATTR_MAX_QUEUE_LENGTH, 2)
+
+	events := subscribe(t, instr, EVENT_TRIG)
+	for id := TRIG_TTL0; id <= TRIG_TTL3; id++ {
+		s.postEvent(EVENT_TRIG, map[uint32]interface{}{ATTR_RECV_TRIG_ID: uint64(id)})
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		if e := nextEvent(t, events); e.Overflow {
+			t.Errorf("event %d marked with Overflow", i)
+		}
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("received %+v beyond ATTR_MAX_QUEUE_LENGTH", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.postEvent(EVENT_TRIG, nil)
+	if e := nextEvent(t, events); !e.Overflow {
+		t.Error("the event after the lost ones isn't marked with Overflow")
+	}
+	s.postEvent(EVENT_TRIG, nil)
+	if e := nextEvent(t, events); e.Overflow {
+		t.Error("the next event is marked with Overflow too")
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := instr.Subscribe(ctx, EVENT_TRIG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range events {
+	}
+	s.mu.Lock()
+	enabled := s.ev.enabled[EVENT_TRIG]
+	s.mu.Unlock()
+	if enabled != 0 || registered(instr) != 0 {
+		t.Errorf("after cancelling, mechanisms %#x are enabled and %d handlers installed", enabled, registered(instr))
+	}
+	s.postEvent(EVENT_TRIG, nil)
+
+	// Closing the session ends the subscription with an error.
+	events, err = instr.Subscribe(context.Background(), EVENT_TRIG, EVENT_SERVICE_REQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instr.Close()
+	got := make(map[uint32]bool)
+	for e := range events {
+		if !errors.Is(e.Err, ErrInvObject) || e.Attrs != nil {
+			t.Errorf("event after closing the session = %+v, want ErrInvObject", e)
+		}
+		got[e.Type] = true
+	}
+	if len(got) != 2 {
+		t.Errorf("errors received for %d event types, want 2", len(got))
+	}
+}
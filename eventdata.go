@@ -0,0 +1,170 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+// EventData is an event decoded from its event context: one of
+// IOCompletionEvent, ServiceRequestEvent, TriggerEvent, ExceptionEvent,
+// ASRLEvent, GPIBEvent, VXIInterruptEvent, USBInterruptEvent or
+// OtherEvent.
+type EventData interface {
+	EventType() uint32
+}
+
+// IOCompletionEvent reports the completion of an asynchronous operation.
+// The data a read transferred is in the buffer it was started with. The
+// event doesn't copy it, since that memory may be released as soon as the
+// operation completes.
+type IOCompletionEvent struct {
+	JobID     uint32
+	Status    Status // of the operation
+	RetCount  uint64
+	Operation string // e.g. viReadAsync
+}
+
+// ServiceRequestEvent reports a service request from the device. The
+// status byte should be read with ReadSTB.
+type ServiceRequestEvent struct{}
+
+// TriggerEvent reports a trigger, on the line in TriggerID.
+type TriggerEvent struct {
+	TriggerID int16 // e.g. TRIG_TTL0
+}
+
+// ExceptionEvent reports an error condition in an operation.
+type ExceptionEvent struct {
+	Status    Status
+	Operation string
+}
+
+// ASRLEvent reports a serial line change or character, its Type is one of
+// the EVENT_ASRL_ types.
+type ASRLEvent struct {
+	Type uint32
+}
+
+// GPIBEvent reports a change of the controller in charge, talker or
+// listener state, its Type is EVENT_GPIB_CIC, EVENT_GPIB_TALK or
+// EVENT_GPIB_LISTEN.
+type GPIBEvent struct {
+	Type     uint32
+	CICState bool // set for EVENT_GPIB_CIC when the board became CIC
+}
+
+// VXIInterruptEvent reports a VXIbus signal or VME interrupt, its Type is
+// EVENT_VXI_SIGP or EVENT_VXI_VME_INTR.
+type VXIInterruptEvent struct {
+	Type     uint32
+	StatusID uint32
+	Level    int16 // interrupt level of EVENT_VXI_VME_INTR
+}
+
+// USBInterruptEvent reports data received on the USB interrupt-IN pipe.
+type USBInterruptEvent struct {
+	Status Status
+	Data   []byte
+}
+
+// OtherEvent is an event without a decoder, e.g. EVENT_CLEAR.
+type OtherEvent struct {
+	Type uint32
+}
+
+func (IOCompletionEvent) EventType() uint32   { return EVENT_IO_COMPLETION }
+func (ServiceRequestEvent) EventType() uint32 { return EVENT_SERVICE_REQ }
+func (TriggerEvent) EventType() uint32        { return EVENT_TRIG }
+func (ExceptionEvent) EventType() uint32      { return EVENT_EXCEPTION }
+func (e ASRLEvent) EventType() uint32         { return e.Type }
+func (e GPIBEvent) EventType() uint32         { return e.Type }
+func (e VXIInterruptEvent) EventType() uint32 { return e.Type }
+func (USBInterruptEvent) EventType() uint32   { return EVENT_USB_INTR }
+func (e OtherEvent) EventType() uint32        { return e.Type }
+
+// DecodeEvent reads the attributes of an event context and returns the
+// event they describe. It doesn't close the event context, so it can be
+// used in handlers, whose contexts VISA closes.
+func DecodeEvent(eventContext uint32) (EventData, error) {
+	ectx := Object(eventContext)
+	etype, err := ectx.AttrUint32(ATTR_EVENT_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEvent(etype, eventAttrs(ectx)), nil
+}
+
+// WaitEvent waits for an event of eventType, or of any enabled type if
+// eventType is ALL_ENABLED_EVENTS, with the queue mechanism, and returns
+// it decoded. The event context is closed.
+func (instr Object) WaitEvent(eventType, timeout uint32) (EventData, error) {
+	etype, ectx, status := instr.WaitOnEvent(eventType, timeout)
+	if status < SUCCESS {
+		return nil, instr.Wrap("WaitOnEvent "+eventName(eventType), status)
+	}
+	defer Close(ectx)
+	return decodeEvent(etype, eventAttrs(Object(ectx))), nil
+}
+
+// decodeEvent builds the event of type etype from the attributes of its
+// event context.
+func decodeEvent(etype uint32, attrs map[uint32]interface{}) EventData {
+	u32 := func(attr uint32) uint32 {
+		switch v := attrs[attr].(type) {
+		case uint16:
+			return uint32(v)
+		case uint32:
+			return v
+		}
+		return 0
+	}
+	i16 := func(attr uint32) int16 {
+		v, _ := attrs[attr].(int16)
+		return v
+	}
+	str := func(attr uint32) string {
+		v, _ := attrs[attr].(string)
+		return v
+	}
+	status := func() Status {
+		v, _ := attrs[ATTR_STATUS].(int32)
+		return Status(v)
+	}
+
+	switch etype {
+	case EVENT_IO_COMPLETION:
+		e := IOCompletionEvent{
+			JobID:     u32(ATTR_JOB_ID),
+			Status:    status(),
+			RetCount:  uint64(u32(ATTR_RET_COUNT_32)),
+			Operation: str(ATTR_OPER_NAME),
+		}
+		if n, ok := attrs[ATTR_RET_COUNT_64].(uint64); ok {
+			e.RetCount = n
+		}
+		return e
+	case EVENT_SERVICE_REQ:
+		return ServiceRequestEvent{}
+	case EVENT_TRIG:
+		return TriggerEvent{TriggerID: i16(ATTR_RECV_TRIG_ID)}
+	case EVENT_EXCEPTION:
+		return ExceptionEvent{Status: status(), Operation: str(ATTR_OPER_NAME)}
+	case EVENT_ASRL_BREAK, EVENT_ASRL_CTS, EVENT_ASRL_DSR, EVENT_ASRL_DCD,
+		EVENT_ASRL_RI, EVENT_ASRL_CHAR, EVENT_ASRL_TERMCHAR:
+		return ASRLEvent{Type: etype}
+	case EVENT_GPIB_CIC, EVENT_GPIB_TALK, EVENT_GPIB_LISTEN:
+		cic, _ := attrs[ATTR_GPIB_RECV_CIC_STATE].(bool)
+		return GPIBEvent{Type: etype, CICState: cic}
+	case EVENT_VXI_SIGP:
+		return VXIInterruptEvent{Type: etype, StatusID: u32(ATTR_SIGP_STATUS_ID)}
+	case EVENT_VXI_VME_INTR:
+		return VXIInterruptEvent{
+			Type:     etype,
+			StatusID: u32(ATTR_INTR_STATUS_ID),
+			Level:    i16(ATTR_RECV_INTR_LEVEL),
+		}
+	case EVENT_USB_INTR:
+		data, _ := attrs[ATTR_USB_RECV_INTR_DATA].([]byte)
+		return USBInterruptEvent{Status: status(), Data: data}
+	}
+	return OtherEvent{Type: etype}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWaitEvent(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+	// state returns the stored state of a signed attribute.
+	state := func(v int32) uint64 { return uint64(uint32(v)) }
+
+	tests := []struct {
+		etype uint32
+		attrs map[uint32]interface{}
+		want  EventData
+	}{
+		{EVENT_IO_COMPLETION, map[uint32]interface{}{
+			ATTR_STATUS:       state(ERROR_TMO),
+			ATTR_JOB_ID:       uint64(7),
+			ATTR_RET_COUNT_32: uint64(3),
+			ATTR_RET_COUNT_64: uint64(1 << 33),
+			ATTR_BUFFER:       uint64(0x1000),
+			ATTR_OPER_NAME:    "viReadAsync",
+		}, IOCompletionEvent{JobID: 7, Status: ERROR_TMO, RetCount: 1 << 33, Operation: "viReadAsync"}},
+		{EVENT_IO_COMPLETION, map[uint32]interface{}{
+			ATTR_STATUS:       uint64(SUCCESS),
+			ATTR_RET_COUNT_32: uint64(3),
+		}, IOCompletionEvent{RetCount: 3}},
+		{EVENT_SERVICE_REQ, nil, ServiceRequestEvent{}},
+		{EVENT_TRIG, map[uint32]interface{}{ATTR_RECV_TRIG_ID: state(TRIG_SW)}, TriggerEvent{TriggerID: TRIG_SW}},
+		{EVENT_EXCEPTION, map[uint32]interface{}{
+			ATTR_STATUS:    state(ERROR_CONN_LOST),
+			ATTR_OPER_NAME: "viWrite",
+		}, ExceptionEvent{Status: ERROR_CONN_LOST, Operation: "viWrite"}},
+		{EVENT_ASRL_CHAR, nil, ASRLEvent{Type: EVENT_ASRL_CHAR}},
+		{EVENT_GPIB_CIC, map[uint32]interface{}{ATTR_GPIB_RECV_CIC_STATE: uint64(TRUE)}, GPIBEvent{Type: EVENT_GPIB_CIC, CICState: true}},
+		{EVENT_GPIB_TALK, nil, GPIBEvent{Type: EVENT_GPIB_TALK}},
+		{EVENT_VXI_SIGP, map[uint32]interface{}{ATTR_SIGP_STATUS_ID: uint64(0xFD01)}, VXIInterruptEvent{Type: EVENT_VXI_SIGP, StatusID: 0xFD01}},
+		{EVENT_VXI_VME_INTR, map[uint32]interface{}{
+			ATTR_INTR_STATUS_ID:  uint64(0x12345678),
+			ATTR_RECV_INTR_LEVEL: uint64(3),
+		}, VXIInterruptEvent{Type: EVENT_VXI_VME_INTR, StatusID: 0x12345678, Level: 3}},
+		{EVENT_USB_INTR, map[uint32]interface{}{
+			ATTR_STATUS:             uint64(SUCCESS),
+			ATTR_USB_RECV_INTR_SIZE: uint64(3),
+			ATTR_USB_RECV_INTR_DATA: []byte{0x81, 1, 2},
+		}, USBInterruptEvent{Data: []byte{0x81, 1, 2}}},
+		{EVENT_CLEAR, nil, OtherEvent{Type: EVENT_CLEAR}},
+	}
+	for _, tt := range tests {
+		instr.EnableEvent(tt.etype, QUEUE, NULL)
+		s.postEvent(tt.etype, tt.attrs)
+		e, err := instr.WaitEvent(tt.etype, 0)
+		if err != nil || !reflect.DeepEqual(e, tt.want) || e.EventType() != tt.etype {
+			t.Errorf("WaitEvent(%s) = %#v, %v, want %#v", eventName(tt.etype), e, err, tt.want)
+		}
+	}
+
+	if _, err := instr.WaitEvent(EVENT_TRIG, 0); !errors.Is(err, ErrTimeout) {
+		t.Errorf("WaitEvent without an event: %v, want ErrTimeout", err)
+	}
+	// WaitEvent closes the event contexts.
+	b := backend.(*goBackend)
+	b.mu.Lock()
+	n := len(b.objs)
+	b.mu.Unlock()
+	if n != 2 {
+		t.Errorf("%d objects open, want the resource manager and the session", n)
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+
+	instr.EnableEvent(EVENT_TRIG, QUEUE, NULL)
+	s.postEvent(EVENT_TRIG, map[uint32]interface{}{ATTR_RECV_TRIG_ID: uint64(TRIG_TTL5)})
+	etype, ectx, status := instr.WaitOnEvent(EVENT_TRIG, 0)
+	if status != SUCCESS || etype != EVENT_TRIG {
+		t.Fatalf("WaitOnEvent = 0x%08X, %v", etype, status)
+	}
+	// DecodeEvent leaves the event context open.
+	for i := 0; i < 2; i++ {
+		if e, err := DecodeEvent(ectx); err != nil || e != (TriggerEvent{TriggerID: TRIG_TTL5}) {
+			t.Errorf("DecodeEvent = %#v, %v", e, err)
+		}
+	}
+	Close(ectx)
+	if _, err := DecodeEvent(ectx); !errors.Is(err, ErrInvObject) {
+		t.Errorf("DecodeEvent of a closed event context: %v, want ErrInvObject", err)
+	}
+}
@@ -6,7 +6,7 @@
 //
 //  Open A Session To The VISA Resource Manager
 //  Open A Session To A GPIB Device
-//  Enable SRQ Events
+//  Subscribe To SRQ Events
 //  Write A Command To The Instrument
 //  Wait to receive an SRQ event
 //  Read the Data
@@ -17,7 +17,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	vi "github.com/jpoirier/visa"
 )
@@ -43,16 +45,16 @@ func main() {
 	}
 	defer instr.Close()
 
-	// Now we must enable the service request event so that VISA
-	// will receive the events.  Note: one of the parameters is
-	// VI_QUEUE indicating that we want the events to be handled by
-	// a synchronous event queue.  The alternate mechanism for handling
-	// events is to set up an asynchronous event handling function using
-	// the VI_HNDLR option.  The events go into a queue which by default
-	// can hold 50 events.  This maximum queue size can be changed with
-	// an attribute but it must be called before the events are enabled.
-	status = instr.EnableEvent(vi.EVENT_SERVICE_REQ, vi.QUEUE, vi.NULL)
-	if status < vi.SUCCESS {
+	// Now we subscribe to service request events.  Subscribe installs a
+	// handler, enables the events with the VI_HNDLR mechanism and delivers
+	// them on a channel until the context is done, when they are disabled
+	// again.  The channel by default can hold 50 events.  This size is
+	// taken from the VI_ATTR_MAX_QUEUE_LENGTH attribute when Subscribe is
+	// called.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	events, err := instr.Subscribe(ctx, vi.EVENT_SERVICE_REQ)
+	if err != nil {
 		fmt.Println("The SRQ event could not be enabled")
 		return
 	}
@@ -68,22 +70,19 @@ func main() {
 		return
 	}
 
-	// Now we wait for an SRQ event to be received by the event queue.
-	// The timeout is in milliseconds and is set to 30000 or 30 seconds.
-	// Notice that a handle to the event is returned by the viWaitOnEvent
-	// call.  This event handle can be used to obtain various
-	// attributes of the event.  Finally, the event handle should be closed
-	// to free system resources.
+	// Now we wait for an SRQ event to be received, for up to 30 seconds.
+	// The event context is closed by the subscription, its attributes
+	// are copied into the event.  The channel is closed when the timeout
+	// expires.
 	fmt.Println("\nWaiting for an SRQ Event")
-	_, ehandle, status := instr.WaitOnEvent(vi.EVENT_SERVICE_REQ, 30000)
+	e, ok := <-events
 
 	// If an SRQ event was received we first read the status byte with
 	// the viReadSTB function.  This should always be called after
 	// receiving a GPIB SRQ event, or subsequent events will not be
-	// received properly.  Then the data is read and the event is closed
-	// and the data is displayed.  Otherwise sessions are closed and the
-	// program terminates.
-	if status >= vi.SUCCESS {
+	// received properly.  Then the data is read and displayed.  Otherwise
+	// sessions are closed and the program terminates.
+	if ok && e.Err == nil {
 		_, status := instr.ReadSTB()
 		if status < vi.SUCCESS {
 			fmt.Println("There was an error reading the status byte")
@@ -103,7 +102,5 @@ func main() {
 			return
 		}
 		fmt.Println("Count: %d, Data: %s\n", rcount, data)
-
-		vi.Close(ehandle)
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	vi "github.com/jpoirier/visa"
+	"github.com/jpoirier/visa/ieee4882"
 	mxa "github.com/jpoirier/visa/mxa"
 )
 
@@ -30,10 +31,19 @@ func main() {
 	}
 	defer instr.Close()
 
+	// Wait for mode changes with a service request rather than blocking
+	// on *OPC?, so the analyzer stays responsive meanwhile.
+	instr.OPCStrategy = ieee4882.OPCBySRQ
+	instr.SettleTimeout = 20 * time.Second
+
 	instr.SetScreenTitle("MXA Example")
-	instr.ShowSpectrumAnalyzer()
-	time.Sleep(10 * time.Second)
-	instr.ShowLTEACP()
-	time.Sleep(10 * time.Second)
+	if status := instr.ShowSpectrumAnalyzer(); status < vi.SUCCESS {
+		fmt.Println("Spectrum analyzer mode didn't settle:", status)
+		return
+	}
+	if status := instr.ShowLTEACP(); status < vi.SUCCESS {
+		fmt.Println("LTE ACP measurement didn't settle:", status)
+		return
+	}
 	fmt.Println("Closing Sessions...")
 }
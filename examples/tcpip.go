@@ -13,7 +13,6 @@ package main
 
 import (
 	"fmt"
-	"unsafe"
 
 	vi "github.com/jpoirier/visa"
 )
@@ -40,24 +39,36 @@ func main() {
 	}
 	b, _, status := instr.Read(25)
 	if status < vi.SUCCESS {
-		fmt.Printf("Read failed with error code %x \n", status)
+		fmt.Printf("Read failed: %v\n", status)
 		return
 	}
 	fmt.Printf("The server response is:\n %s\n\n", string(b))
 
-	buf := make([]byte, 100)
-	instr.GetAttribute(vi.ATTR_TCPIP_ADDR, unsafe.Pointer(&buf[0]))
-	fmt.Printf(" Address:  %s\n", string(buf))
+	addr, err := instr.AttrString(vi.ATTR_TCPIP_ADDR)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf(" Address:  %s\n", addr)
 
-	buf = nil
-	instr.GetAttribute(vi.ATTR_TCPIP_HOSTNAME, unsafe.Pointer(&buf[0]))
-	fmt.Printf(" Host Name:  %s\n", string(buf))
+	host, err := instr.AttrString(vi.ATTR_TCPIP_HOSTNAME)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf(" Host Name:  %s\n", host)
 
-	buf = nil
-	instr.GetAttribute(vi.ATTR_TCPIP_PORT, unsafe.Pointer(&buf[0]))
-	fmt.Printf(" Port:  %s\n", string(buf))
+	port, err := instr.AttrUint16(vi.ATTR_TCPIP_PORT)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf(" Port:  %d\n", port)
 
-	buf = nil
-	instr.GetAttribute(vi.ATTR_RSRC_CLASS, unsafe.Pointer(&buf[0]))
-	fmt.Printf(" Resource Class:  %s\n", string(buf))
+	class, err := instr.AttrString(vi.ATTR_RSRC_CLASS)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf(" Resource Class:  %s\n", class)
 }
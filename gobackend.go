@@ -0,0 +1,617 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// goObject is a resource manager, session, find list or event context
+// owned by the pure-Go backend.
+type goObject interface {
+	attrs() *attrStore
+	getAttribute(attr uint32, addr unsafe.Pointer) Status
+	setAttribute(attr uint32, state uint64) Status
+	close() Status
+}
+
+// goBackend is a Backend implemented in Go. Resources are served by the
+// transports registered with registerTransport.
+type goBackend struct {
+	mu   sync.Mutex
+	next uint32
+	objs map[uint32]goObject
+
+	lockMu sync.Mutex
+	locks  map[string]*rsrcLock
+}
+
+// NewGoBackend returns a backend that doesn't depend on a VISA library.
+// It's the default when the package is built without cgo or with the
+// novisa tag, and can be installed with SetBackend otherwise.
+func NewGoBackend() Backend {
+	return &goBackend{
+		objs:  make(map[uint32]goObject),
+		locks: make(map[string]*rsrcLock),
+	}
+}
+
+// register assigns a handle to obj.
+func (b *goBackend) register(obj func(vi uint32) goObject) (uint32, goObject) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		b.next++
+		if b.next == NULL {
+			continue
+		}
+		if _, ok := b.objs[b.next]; !ok {
+			break
+		}
+	}
+	o := obj(b.next)
+	b.objs[b.next] = o
+	return b.next, o
+}
+
+// unregister releases the handle vi.
+func (b *goBackend) unregister(vi uint32) {
+	b.mu.Lock()
+	delete(b.objs, vi)
+	b.mu.Unlock()
+}
+
+// object returns the object with handle vi.
+func (b *goBackend) object(vi uint32) (goObject, Status) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, ok := b.objs[vi]
+	if !ok {
+		return nil, ERROR_INV_OBJECT
+	}
+	return o, SUCCESS
+}
+
+// session returns the instrument session with handle vi.
+func (b *goBackend) session(vi uint32) (*goSession, Status) {
+	o, status := b.object(vi)
+	if status != SUCCESS {
+		return nil, status
+	}
+	s, ok := o.(*goSession)
+	if !ok {
+		return nil, ERROR_NSUP_OPER
+	}
+	return s, SUCCESS
+}
+
+// rm returns the resource manager session with handle vi.
+func (b *goBackend) rm(vi uint32) (*goRM, Status) {
+	o, status := b.object(vi)
+	if status != SUCCESS {
+		return nil, status
+	}
+	rm, ok := o.(*goRM)
+	if !ok {
+		return nil, ERROR_INV_OBJECT
+	}
+	return rm, SUCCESS
+}
+
+// ----------------------------------------------------------------------------
+// Resource Manager Functions and Operations
+//
+
+// goRM is a resource manager session. Closing it closes every session and
+// find list opened through it.
+type goRM struct {
+	b     *goBackend
+	vi    uint32
+	a     *attrStore
+	mu    sync.Mutex
+	owned map[uint32]bool
+}
+
+func (rm *goRM) attrs() *attrStore { return rm.a }
+
+func (rm *goRM) getAttribute(attr uint32, addr unsafe.Pointer) Status {
+	return rm.a.get(attr, addr)
+}
+
+func (rm *goRM) setAttribute(attr uint32, state uint64) Status {
+	if status := rm.a.check(attr, state); status != SUCCESS {
+		return status
+	}
+	rm.a.put(attr, state)
+	return SUCCESS
+}
+
+func (rm *goRM) own(vi uint32) {
+	rm.mu.Lock()
+	rm.owned[vi] = true
+	rm.mu.Unlock()
+}
+
+func (rm *goRM) disown(vi uint32) {
+	rm.mu.Lock()
+	delete(rm.owned, vi)
+	rm.mu.Unlock()
+}
+
+func (rm *goRM) close() Status {
+	rm.mu.Lock()
+	owned := make([]uint32, 0, len(rm.owned))
+	for vi := range rm.owned {
+		owned = append(owned, vi)
+	}
+	rm.mu.Unlock()
+	for _, vi := range owned {
+		if o, status := rm.b.object(vi); status == SUCCESS {
+			o.close()
+		}
+	}
+	rm.b.unregister(rm.vi)
+	return SUCCESS
+}
+
+func (b *goBackend) OpenDefaultRM() (Session, Status) {
+	vi, _ := b.register(func(vi uint32) goObject {
+		return &goRM{
+			b:  b,
+			vi: vi,
+			a: newAttrStore(map[uint32]interface{}{
+				ATTR_RSRC_CLASS:        "",
+				ATTR_RSRC_NAME:         "",
+				ATTR_RSRC_IMPL_VERSION: uint64(SPEC_VERSION),
+				ATTR_RSRC_SPEC_VERSION: uint64(SPEC_VERSION),
+				ATTR_RSRC_MANF_NAME:    goManfName,
+				ATTR_RSRC_MANF_ID:      uint64(0),
+				ATTR_RSRC_LOCK_STATE:   uint64(NO_LOCK),
+				ATTR_RM_SESSION:        uint64(vi),
+				ATTR_MAX_QUEUE_LENGTH:  uint64(50),
+				ATTR_USER_DATA_32:      uint64(0),
+				ATTR_USER_DATA_64:      uint64(0),
+			}),
+			owned: make(map[uint32]bool),
+		}
+	})
+	return Session(vi), SUCCESS
+}
+
+// goManfName is reported as the manufacturer of the pure-Go resources.
+const goManfName = "visa (Go)"
+
+// goFindList is the list of resources matched by FindRsrc.
+type goFindList struct {
+	b     *goBackend
+	rm    *goRM
+	vi    uint32
+	a     *attrStore
+	mu    sync.Mutex
+	names []string
+}
+
+func (l *goFindList) attrs() *attrStore { return l.a }
+
+func (l *goFindList) getAttribute(attr uint32, addr unsafe.Pointer) Status {
+	return l.a.get(attr, addr)
+}
+
+func (l *goFindList) setAttribute(attr uint32, state uint64) Status {
+	if status := l.a.check(attr, state); status != SUCCESS {
+		return status
+	}
+	l.a.put(attr, state)
+	return SUCCESS
+}
+
+func (l *goFindList) close() Status {
+	l.rm.disown(l.vi)
+	l.b.unregister(l.vi)
+	return SUCCESS
+}
+
+func (b *goBackend) FindRsrc(rm Session, expr string) (findList, retCnt uint32,
+	desc string, status Status) {
+
+	r, status := b.rm(uint32(rm))
+	if status != SUCCESS {
+		return 0, 0, "", status
+	}
+	e, status := compileRsrcExpr(expr)
+	if status != SUCCESS {
+		return 0, 0, "", status
+	}
+	var names []string
+	for _, f := range findResources() {
+		if e.re.MatchString(f.name) && (e.cond == nil || b.rsrcMatches(rm, f, e.cond)) {
+			names = append(names, f.name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, 0, "", ERROR_RSRC_NFOUND
+	}
+	vi, _ := b.register(func(vi uint32) goObject {
+		return &goFindList{
+			b:     b,
+			rm:    r,
+			vi:    vi,
+			a:     newAttrStore(map[uint32]interface{}{ATTR_USER_DATA_32: uint64(0), ATTR_USER_DATA_64: uint64(0)}),
+			names: names[1:],
+		}
+	})
+	r.own(vi)
+	return vi, uint32(len(names)), names[0], SUCCESS
+}
+
+// rsrcMatches evaluates an attribute expression for a found resource.
+// The attributes its name and the finder give are answered without
+// opening it, since opening resets serial ports and creates network links.
+// Only other attributes open a session, and a resource that can't be
+// opened then doesn't match.
+func (b *goBackend) rsrcMatches(rm Session, f foundRsrc, cond attrCond) bool {
+	var s *goSession
+	opened := false
+	defer func() {
+		if s != nil {
+			b.Close(s.vi)
+		}
+	}()
+	return cond(func(attr uint32) (interface{}, bool) {
+		if v, ok := f.attrs[attr]; ok {
+			return v, true
+		}
+		if v, ok := nameAttr(f.name, attr); ok {
+			return v, true
+		}
+		if !opened {
+			opened = true
+			if instr, status := b.Open(rm, f.name, NO_LOCK, 0); status == SUCCESS {
+				s, _ = b.session(uint32(instr))
+			}
+		}
+		if s == nil {
+			return nil, false
+		}
+		var v [FIND_BUFLEN]byte
+		if s.getAttribute(attr, unsafe.Pointer(&v[0])) != SUCCESS {
+			return nil, false
+		}
+		p := unsafe.Pointer(&v[0])
+		switch attrTable[attr].Type {
+		case AttrString:
+			return cString(v[:]), true
+		case AttrUint8:
+			return int64(*(*uint8)(p)), true
+		case AttrUint16, AttrBool:
+			return int64(*(*uint16)(p)), true
+		case AttrInt16:
+			return int64(*(*int16)(p)), true
+		case AttrUint32:
+			return int64(*(*uint32)(p)), true
+		case AttrInt32:
+			return int64(*(*int32)(p)), true
+		case AttrUint64:
+			return int64(*(*uint64)(p)), true
+		}
+		return nil, false
+	})
+}
+
+// nameAttr returns the state of the attributes a session to the resource
+// would take from its name.
+func nameAttr(name string, attr uint32) (interface{}, bool) {
+	p, status := parseRsrcName(name)
+	if status != SUCCESS {
+		return nil, false
+	}
+	switch attr {
+	case ATTR_RSRC_NAME:
+		return p.String(), true
+	case ATTR_RSRC_CLASS:
+		return p.class, true
+	case ATTR_RSRC_MANF_NAME:
+		return goManfName, true
+	case ATTR_RSRC_MANF_ID:
+		return int64(0), true
+	case ATTR_INTF_TYPE:
+		return int64(intfTypes[p.intf]), true
+	case ATTR_INTF_NUM:
+		return int64(p.board), true
+	case ATTR_INTF_INST_NAME:
+		return p.intf + strconv.Itoa(int(p.board)), true
+	}
+	return nil, false
+}
+
+func (b *goBackend) FindNext(findList uint32) (string, Status) {
+	o, status := b.object(findList)
+	if status != SUCCESS {
+		return "", status
+	}
+	l, ok := o.(*goFindList)
+	if !ok {
+		return "", ERROR_INV_OBJECT
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.names) == 0 {
+		return "", ERROR_RSRC_NFOUND
+	}
+	name := l.names[0]
+	l.names = l.names[1:]
+	return name, SUCCESS
+}
+
+// foundRsrc is a resource reported by a finder, along with the states of
+// the attributes the finder knows without opening it: int64 for numbers,
+// string for strings.
+type foundRsrc struct {
+	name  string
+	attrs map[uint32]interface{}
+}
+
+// foundNames returns the resources with names and no attributes.
+func foundNames(names []string) []foundRsrc {
+	found := make([]foundRsrc, len(names))
+	for i, name := range names {
+		found[i].name = name
+	}
+	return found
+}
+
+// finders list the resources present for FindRsrc.
+var (
+	findersMu sync.Mutex
+	finders   []func() []foundRsrc
+)
+
+// registerFinder adds a source of resources to FindRsrc.
+func registerFinder(f func() []foundRsrc) {
+	findersMu.Lock()
+	finders = append(finders, f)
+	findersMu.Unlock()
+}
+
+// findResources returns the resources reported by all finders, sorted by
+// name.
+func findResources() []foundRsrc {
+	findersMu.Lock()
+	fs := append([]func() []foundRsrc(nil), finders...)
+	findersMu.Unlock()
+	seen := make(map[string]bool)
+	var found []foundRsrc
+	for _, f := range fs {
+		for _, r := range f() {
+			if !seen[r.name] {
+				seen[r.name] = true
+				found = append(found, r)
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].name < found[j].name })
+	return found
+}
+
+// rsrcParts is a resource name split into its interface, board number,
+// address fields and resource class.
+type rsrcParts struct {
+	intf   string
+	board  uint16
+	path   string // device path of ASRL/dev/ttyUSB0 style names
+	fields []string
+	class  string
+}
+
+// intfTypes maps the interface prefix of resource names to INTF_*.
+var intfTypes = map[string]uint16{
+	"GPIB":     INTF_GPIB,
+	"VXI":      INTF_VXI,
+	"GPIB-VXI": INTF_GPIB_VXI,
+	"ASRL":     INTF_ASRL,
+	"PXI":      INTF_PXI,
+	"TCPIP":    INTF_TCPIP,
+	"USB":      INTF_USB,
+}
+
+// rsrcClasses are the resource classes that may end a resource name.
+var rsrcClasses = map[string]bool{
+	"INSTR":     true,
+	"SOCKET":    true,
+	"RAW":       true,
+	"INTFC":     true,
+	"BACKPLANE": true,
+	"MEMACC":    true,
+	"SERVANT":   true,
+}
+
+// parseRsrcName splits a resource name. The class defaults to INSTR.
+func parseRsrcName(name string) (rsrcParts, Status) {
+	f := splitRsrcName(strings.TrimSpace(name))
+	head := strings.ToUpper(f[0])
+	i := 0
+	for i < len(head) && (head[i] >= 'A' && head[i] <= 'Z' || head[i] == '-') {
+		i++
+	}
+	p := rsrcParts{intf: head[:i], class: "INSTR"}
+	if _, ok := intfTypes[p.intf]; !ok {
+		return p, ERROR_INV_RSRC_NAME
+	}
+	switch {
+	case p.intf == "ASRL" && strings.HasPrefix(head[i:], "/"):
+		p.path = f[0][i:]
+	case i < len(head):
+		n, err := strconv.ParseUint(head[i:], 10, 16)
+		if err != nil {
+			return p, ERROR_INV_RSRC_NAME
+		}
+		p.board = uint16(n)
+	}
+	f = f[1:]
+	if len(f) > 0 && rsrcClasses[strings.ToUpper(f[len(f)-1])] {
+		p.class = strings.ToUpper(f[len(f)-1])
+		f = f[:len(f)-1]
+	}
+	for i, s := range f {
+		if s == "" {
+			return p, ERROR_INV_RSRC_NAME
+		}
+		if len(s) > 2 && s[0] == '[' && s[len(s)-1] == ']' {
+			f[i] = s[1 : len(s)-1]
+		}
+	}
+	p.fields = f
+	return p, SUCCESS
+}
+
+// splitRsrcName splits name at the :: separators outside of brackets, so
+// IPv6 addresses can be written as [fe80::1].
+func splitRsrcName(name string) []string {
+	var f []string
+	depth, start := 0, 0
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '[':
+			depth++
+		case name[i] == ']' && depth > 0:
+			depth--
+		case depth == 0 && strings.HasPrefix(name[i:], "::"):
+			f = append(f, name[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	return append(f, name[start:])
+}
+
+// String returns the canonical form of the resource name.
+func (p rsrcParts) String() string {
+	if r, status := p.resourceName(); status == SUCCESS {
+		return r.String()
+	}
+	s := []string{p.intf + strconv.Itoa(int(p.board))}
+	if p.path != "" {
+		s[0] = p.intf + p.path
+	}
+	s = append(s, p.fields...)
+	return strings.Join(append(s, p.class), "::")
+}
+
+func (b *goBackend) ParseRsrc(rm Session, rsrcName string) (intfType, intfNum uint16,
+	status Status) {
+
+	if _, status = b.rm(uint32(rm)); status != SUCCESS {
+		return 0, 0, status
+	}
+	p, status := parseRsrcName(rsrcName)
+	if status != SUCCESS {
+		return 0, 0, status
+	}
+	return intfTypes[p.intf], p.board, SUCCESS
+}
+
+func (b *goBackend) ParseRsrcEx(rm Session, rsrcName string) (intfType, intfNum uint16,
+	rsrcClass, expandedUnaliasedName, aliasIfExists string, status Status) {
+
+	if _, status = b.rm(uint32(rm)); status != SUCCESS {
+		return 0, 0, "", "", "", status
+	}
+	p, status := parseRsrcName(rsrcName)
+	if status != SUCCESS {
+		return 0, 0, "", "", "", status
+	}
+	return intfTypes[p.intf], p.board, p.class, p.String(), "", SUCCESS
+}
+
+func (b *goBackend) Open(rm Session, name string, mode, timeout uint32) (Object, Status) {
+	r, status := b.rm(uint32(rm))
+	if status != SUCCESS {
+		return 0, status
+	}
+	if mode&^(EXCLUSIVE_LOCK|SHARED_LOCK|LOAD_CONFIG) != 0 ||
+		mode&EXCLUSIVE_LOCK != 0 && mode&SHARED_LOCK != 0 {
+		return 0, ERROR_INV_ACC_MODE
+	}
+	p, status := parseRsrcName(name)
+	if status != SUCCESS {
+		return 0, status
+	}
+	open, ok := lookupTransport(p.intf + "::" + p.class)
+	if !ok {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	vi, o := b.register(func(vi uint32) goObject {
+		return newGoSession(b, r, vi, p)
+	})
+	s := o.(*goSession)
+	t, status := open(s, p, timeout)
+	if status != SUCCESS {
+		b.unregister(vi)
+		return 0, status
+	}
+	s.t = t
+	r.own(vi)
+	if mode&(EXCLUSIVE_LOCK|SHARED_LOCK) != 0 {
+		lockType := uint32(EXCLUSIVE_LOCK)
+		if mode&SHARED_LOCK != 0 {
+			lockType = SHARED_LOCK
+		}
+		if _, status = b.Lock(Object(vi), lockType, timeout, ""); status.IsError() {
+			s.close()
+			return 0, status
+		}
+	}
+	return Object(vi), SUCCESS
+}
+
+// ----------------------------------------------------------------------------
+// Resource Template Operations
+//
+
+func (b *goBackend) Close(vi uint32) Status {
+	if vi == NULL {
+		return WARN_NULL_OBJECT
+	}
+	o, status := b.object(vi)
+	if status != SUCCESS {
+		return status
+	}
+	return o.close()
+}
+
+func (b *goBackend) SetAttribute(vi, attribute uint32, attrState uint64) Status {
+	o, status := b.object(vi)
+	if status != SUCCESS {
+		return status
+	}
+	return o.setAttribute(attribute, attrState)
+}
+
+func (b *goBackend) GetAttribute(vi, attribute uint32, addr unsafe.Pointer) Status {
+	o, status := b.object(vi)
+	if status != SUCCESS {
+		return status
+	}
+	return o.getAttribute(attribute, addr)
+}
+
+func (b *goBackend) StatusDesc(vi uint32, status Status) (string, Status) {
+	if _, ok := statusTable[status]; !ok {
+		return status.Error(), WARN_UNKNOWN_STATUS
+	}
+	return status.Error(), SUCCESS
+}
+
+func (b *goBackend) Terminate(vi uint32, degree, jobId uint16) Status {
+	s, status := b.session(vi)
+	if status != SUCCESS {
+		return status
+	}
+	return s.terminate(uint32(jobId))
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// openTestRM installs a fresh pure-Go backend and returns a resource
+// manager that's closed at the end of the test.
+func openTestRM(t *testing.T) Session {
+	t.Helper()
+	SetBackend(NewGoBackend())
+	rm, status := OpenDefaultRM()
+	if status != SUCCESS {
+		t.Fatalf("OpenDefaultRM: %v", status)
+	}
+	t.Cleanup(func() { rm.Close() })
+	return rm
+}
+
+// withTransport registers open for key for the duration of the test.
+func withTransport(t *testing.T, key string, open transportOpener) {
+	t.Helper()
+	old, ok := lookupTransport(key)
+	registerTransport(key, open)
+	t.Cleanup(func() {
+		transportsMu.Lock()
+		defer transportsMu.Unlock()
+		if ok {
+			transports[key] = old
+		} else {
+			delete(transports, key)
+		}
+	})
+}
+
+// withFinder adds names to the resources found for the duration of the
+// test.
+func withFinder(t *testing.T, names ...string) {
+	t.Helper()
+	withFound(t, foundNames(names)...)
+}
+
+// withFound adds resources to those found for the duration of the test.
+func withFound(t *testing.T, found ...foundRsrc) {
+	t.Helper()
+	registerFinder(func() []foundRsrc { return found })
+	findersMu.Lock()
+	n := len(finders)
+	findersMu.Unlock()
+	t.Cleanup(func() {
+		findersMu.Lock()
+		finders = append(finders[:n-1], finders[n:]...)
+		findersMu.Unlock()
+	})
+}
+
+// loopback is a transport that reads back what was last written. Its
+// knobs make reads serve at most chunk bytes, fail with readErr or block
+// until the session aborts or clears them if stall is set, and make writes
+// fail with writeErr. It records the data written and whether each read
+// had the termination character enabled and each write sent END.
+type loopback struct {
+	buf    []byte
+	closed bool
+
+	chunk    int
+	readErr  Status
+	writeErr Status
+	stall    bool
+	onClose  func() // called when the session closes the transport
+
+	out     []byte // every byte written
+	termEn  []bool
+	sendEnd []bool
+
+	reading chan struct{} // receives when a stalled read blocks
+	release chan struct{} // closed to release stalled reads
+	once    sync.Once
+}
+
+func newLoopback() *loopback {
+	return &loopback{reading: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (l *loopback) read(s *goSession, buf []byte) (int, Status) {
+	_, en := s.termChar()
+	l.termEn = append(l.termEn, en)
+	if l.stall {
+		l.reading <- struct{}{}
+		<-l.release
+		return 0, ERROR_ABORT
+	}
+	if l.readErr != SUCCESS {
+		return 0, l.readErr
+	}
+	if l.chunk > 0 && len(buf) > l.chunk {
+		buf = buf[:l.chunk]
+	}
+	n := copy(buf, l.buf)
+	l.buf = l.buf[n:]
+	if len(l.buf) > 0 {
+		return n, SUCCESS_MAX_CNT
+	}
+	return n, SUCCESS
+}
+
+func (l *loopback) write(s *goSession, buf []byte) (int, Status) {
+	l.sendEnd = append(l.sendEnd, s.sendEnd())
+	if l.writeErr != SUCCESS {
+		return 0, l.writeErr
+	}
+	l.out = append(l.out, buf...)
+	l.buf = append(l.buf[:0], buf...)
+	return len(buf), SUCCESS
+}
+
+func (l *loopback) abort(s *goSession) {
+	l.once.Do(func() { close(l.release) })
+}
+
+func (l *loopback) clear(s *goSession) Status {
+	l.abort(s)
+	return SUCCESS
+}
+
+func (l *loopback) close() Status {
+	l.closed = true
+	if l.onClose != nil {
+		l.onClose()
+	}
+	return SUCCESS
+}
+
+// serveLoopback serves the VXI resources with a new loopback for each
+// session for the duration of the test, passing it to setup first.
+func serveLoopback(t *testing.T, setup func(l *loopback)) {
+	t.Helper()
+	withTransport(t, "VXI::INSTR", func(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+		l := newLoopback()
+		setup(l)
+		return l, SUCCESS
+	})
+}
+
+// openLoopback opens a loopback session to the VXI resource name.
+func openLoopback(t *testing.T, rm Session, name string) (Object, *loopback) {
+	t.Helper()
+	var l *loopback
+	serveLoopback(t, func(nl *loopback) { l = nl })
+	instr, status := rm.Open(name, NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open(%q): %v", name, status)
+	}
+	return instr, l
+}
+
+// waitStalled waits for a read of l to block.
+func waitStalled(t *testing.T, l *loopback) {
+	t.Helper()
+	select {
+	case <-l.reading:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the read never started")
+	}
+}
+
+// goSessionOf returns the Go backend's session behind instr.
+func goSessionOf(t *testing.T, instr Object) *goSession {
+	t.Helper()
+	s, status := backend.(*goBackend).session(uint32(instr))
+	if status != SUCCESS {
+		t.Fatalf("session %d: %v", instr, status)
+	}
+	return s
+}
+
+func TestGoBackendReadWrite(t *testing.T) {
+	rm := openTestRM(t)
+	instr, l := openLoopback(t, rm, "vxi0::5::instr")
+
+	if _, status := instr.Write([]byte("hello"), 5); status != SUCCESS {
+		t.Fatalf("Write: %v", status)
+	}
+	buf, n, status := instr.Read(3)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "hel" {
+		t.Errorf("Read(3) = %q, %v, want \"hel\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+	buf, n, status = instr.Read(10)
+	if status != SUCCESS || string(buf[:n]) != "lo" {
+		t.Errorf("Read(10) = %q, %v, want \"lo\", SUCCESS", buf[:n], status)
+	}
+
+	var name [FIND_BUFLEN]byte
+	instr.GetAttribute(ATTR_RSRC_NAME, unsafe.Pointer(&name[0]))
+	if got := cString(name[:]); got != "VXI0::5::INSTR" {
+		t.Errorf("ATTR_RSRC_NAME = %q, want canonical VXI0::5::INSTR", got)
+	}
+
+	if status := instr.Close(); status != SUCCESS {
+		t.Errorf("Close: %v", status)
+	}
+	if !l.closed {
+		t.Error("Close didn't close the transport")
+	}
+	if _, _, status := instr.Read(1); status != ERROR_INV_OBJECT {
+		t.Errorf("Read after Close: %v, want ERROR_INV_OBJECT", status)
+	}
+}
+
+func TestGoBackendStrings(t *testing.T) {
+	rm := openTestRM(t)
+	withFinder(t, "VXI0::1::INSTR", "VXI0::2::INSTR")
+
+	list, cnt, desc, status := rm.FindRsrc("VXI?*INSTR")
+	if status != SUCCESS || cnt != 2 || desc != "VXI0::1::INSTR" {
+		t.Fatalf("FindRsrc = %d, %q, %v", cnt, desc, status)
+	}
+	if desc, status := FindNext(list); status != SUCCESS || desc != "VXI0::2::INSTR" {
+		t.Errorf("FindNext = %q, %v", desc, status)
+	}
+	if _, status := FindNext(list); status != ERROR_RSRC_NFOUND {
+		t.Errorf("FindNext past the end: %v, want ERROR_RSRC_NFOUND", status)
+	}
+
+	intf, num, class, expanded, alias, status := rm.ParseRsrcEx("vxi3::7")
+	if status != SUCCESS || intf != INTF_VXI || num != 3 || class != "INSTR" ||
+		expanded != "VXI3::7::INSTR" || alias != "" {
+		t.Errorf("ParseRsrcEx = %d, %d, %q, %q, %q, %v", intf, num, class, expanded, alias, status)
+	}
+
+	withFinder(t, "FOO0::1::INSTR")
+	names, err := rm.Resources("[FV]?*")
+	var ne *NamesError
+	if len(names) != 2 || !errors.As(err, &ne) || !reflect.DeepEqual(ne.Names, []string{"FOO0::1::INSTR"}) ||
+		!errors.Is(err, ErrInvRsrcName) {
+		t.Errorf("Resources with an unsupported name = %v, %v", names, err)
+	}
+
+	desc, status = Object(rm).StatusDesc(ERROR_TMO)
+	if status != SUCCESS || strings.ContainsRune(desc, 0) || !strings.Contains(desc, "VI_ERROR_TMO") {
+		t.Errorf("StatusDesc(ERROR_TMO) = %q, %v", desc, status)
+	}
+}
+
+func TestGoBackendLocks(t *testing.T) {
+	rm := openTestRM(t)
+	a, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	b, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+
+	key, status := a.Lock(SHARED_LOCK, TMO_IMMEDIATE, "")
+	if status != SUCCESS || key == "" {
+		t.Fatalf("shared Lock = %q, %v", key, status)
+	}
+	if _, status := b.Write([]byte("x"), 1); status != ERROR_RSRC_LOCKED {
+		t.Errorf("Write without the key: %v, want ERROR_RSRC_LOCKED", status)
+	}
+	if _, status := b.Lock(SHARED_LOCK, TMO_IMMEDIATE, "other"); status != ERROR_INV_ACCESS_KEY {
+		t.Errorf("shared Lock with a wrong key: %v, want ERROR_INV_ACCESS_KEY", status)
+	}
+	if got, status := b.Lock(SHARED_LOCK, TMO_IMMEDIATE, key); status != SUCCESS || got != key {
+		t.Errorf("shared Lock with the key = %q, %v", got, status)
+	}
+	if _, status := b.Write([]byte("x"), 1); status != SUCCESS {
+		t.Errorf("Write with the key: %v", status)
+	}
+	b.Unlock()
+
+	if status := b.LockExclusive(EXCLUSIVE_LOCK, 50); status != ERROR_TMO {
+		t.Errorf("exclusive Lock of a shared resource: %v, want ERROR_TMO", status)
+	}
+	if status := a.Unlock(); status != SUCCESS {
+		t.Errorf("Unlock: %v", status)
+	}
+	if status := b.LockExclusive(EXCLUSIVE_LOCK, 50); status != SUCCESS {
+		t.Errorf("exclusive Lock: %v", status)
+	}
+	if _, _, status := a.Read(1); status != ERROR_RSRC_LOCKED {
+		t.Errorf("Read of an exclusively locked resource: %v, want ERROR_RSRC_LOCKED", status)
+	}
+	b.Close()
+	if _, status := a.Write([]byte("x"), 1); status != SUCCESS {
+		t.Errorf("Write after the lock holder closed: %v", status)
+	}
+}
+
+func TestGoBackendCloseRM(t *testing.T) {
+	rm := openTestRM(t)
+	instr, l := openLoopback(t, rm, "VXI0::1::INSTR")
+	if status := rm.Close(); status != SUCCESS {
+		t.Fatalf("Close: %v", status)
+	}
+	if !l.closed {
+		t.Error("closing the resource manager didn't close its session")
+	}
+	if _, status := instr.Write([]byte("x"), 1); status != ERROR_INV_OBJECT {
+		t.Errorf("Write after closing the resource manager: %v, want ERROR_INV_OBJECT", status)
+	}
+	if _, status := rm.Open("VXI0::1::INSTR", NO_LOCK, 0); status != ERROR_INV_OBJECT {
+		t.Errorf("Open after Close: %v, want ERROR_INV_OBJECT", status)
+	}
+}
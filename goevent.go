@@ -0,0 +1,376 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"time"
+	"unsafe"
+)
+
+// eventState is the event bookkeeping of a pure-Go session, guarded by the
+// session's mu.
+type eventState struct {
+	enabled   map[uint32]uint16
+	handlers  map[uint32][]HandlerID
+	queue     []*goEvent
+	suspended []*goEvent
+	overflow  bool
+	posted    chan struct{}
+}
+
+// goEvent is an event context.
+type goEvent struct {
+	b     *goBackend
+	vi    uint32
+	etype uint32
+	a     *attrStore
+}
+
+func (e *goEvent) attrs() *attrStore { return e.a }
+
+func (e *goEvent) getAttribute(attr uint32, addr unsafe.Pointer) Status {
+	return e.a.get(attr, addr)
+}
+
+func (e *goEvent) setAttribute(attr uint32, state uint64) Status {
+	return e.a.check(attr, state)
+}
+
+func (e *goEvent) close() Status {
+	e.b.unregister(e.vi)
+	return SUCCESS
+}
+
+// newEvent creates an event context of type etype holding vals.
+func (s *goSession) newEvent(etype uint32, vals map[uint32]interface{}) *goEvent {
+	a := make(map[uint32]interface{}, len(vals)+1)
+	for attr, v := range vals {
+		a[attr] = v
+	}
+	a[ATTR_EVENT_TYPE] = uint64(etype)
+	_, o := s.b.register(func(vi uint32) goObject {
+		return &goEvent{b: s.b, vi: vi, etype: etype, a: newAttrStore(a)}
+	})
+	return o.(*goEvent)
+}
+
+// postEvent delivers an occurrence of etype through the mechanisms enabled
+// for it. Occurrences beyond ATTR_MAX_QUEUE_LENGTH are lost and reported
+// by the next WaitOnEvent.
+func (s *goSession) postEvent(etype uint32, vals map[uint32]interface{}) {
+	s.mu.Lock()
+	mech := s.ev.enabled[etype]
+	s.mu.Unlock()
+	if mech == 0 {
+		return
+	}
+	max := int(s.a.num(ATTR_MAX_QUEUE_LENGTH))
+
+	if mech&QUEUE != 0 {
+		e := s.newEvent(etype, vals)
+		s.mu.Lock()
+		if len(s.ev.queue) < max {
+			s.ev.queue = append(s.ev.queue, e)
+			if s.ev.posted != nil {
+				close(s.ev.posted)
+				s.ev.posted = nil
+			}
+			e = nil
+		} else {
+			s.ev.overflow = true
+		}
+		s.mu.Unlock()
+		if e != nil {
+			e.close()
+		}
+	}
+
+	switch {
+	case mech&HNDLR != 0:
+		go s.dispatch(s.newEvent(etype, vals))
+	case mech&SUSPEND_HNDLR != 0:
+		e := s.newEvent(etype, vals)
+		s.mu.Lock()
+		if len(s.ev.suspended) < max {
+			s.ev.suspended = append(s.ev.suspended, e)
+			e = nil
+		}
+		s.mu.Unlock()
+		if e != nil {
+			e.close()
+		}
+	}
+}
+
+// dispatch calls the handlers installed for e's type and closes e.
+func (s *goSession) dispatch(e *goEvent) {
+	s.mu.Lock()
+	hs := append([]HandlerID(nil), s.ev.handlers[e.etype]...)
+	s.mu.Unlock()
+	for _, id := range hs {
+		CallHandler(id, Object(s.vi), e.etype, e.vi)
+	}
+	e.close()
+}
+
+// discardAll drops every pending event, used when the session closes.
+func (s *goSession) discardAll() {
+	s.mu.Lock()
+	pending := append(s.ev.queue, s.ev.suspended...)
+	s.ev.queue, s.ev.suspended = nil, nil
+	s.ev.enabled = nil
+	s.mu.Unlock()
+	for _, e := range pending {
+		e.close()
+	}
+}
+
+// validEvent reports whether etype names a single event type.
+func validEvent(etype uint32) bool {
+	return etype != ALL_ENABLED_EVENTS && etype&0x3FFFF000 == 0x3FFF2000
+}
+
+// matchEvent reports whether etype is selected by the argument sel.
+func matchEvent(sel, etype uint32) bool {
+	return sel == ALL_ENABLED_EVENTS || sel == etype
+}
+
+func (b *goBackend) EnableEvent(instr Object, eventType uint32, mechanism uint16,
+	context uint32) Status {
+
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	if !validEvent(eventType) {
+		return ERROR_INV_EVENT
+	}
+	if mechanism == 0 || mechanism&^(QUEUE|HNDLR|SUSPEND_HNDLR) != 0 ||
+		mechanism&HNDLR != 0 && mechanism&SUSPEND_HNDLR != 0 {
+		return ERROR_INV_MECH
+	}
+
+	s.mu.Lock()
+	if mechanism&(HNDLR|SUSPEND_HNDLR) != 0 && len(s.ev.handlers[eventType]) == 0 {
+		s.mu.Unlock()
+		return ERROR_HNDLR_NINSTALLED
+	}
+	armed := s.ev.enabled[eventType] != 0
+	s.mu.Unlock()
+	if en, ok := s.t.(eventEnabler); ok && !armed {
+		if status := en.enableEvent(s, eventType, true); status != SUCCESS {
+			return status
+		}
+	}
+
+	s.mu.Lock()
+	if s.ev.enabled == nil {
+		s.ev.enabled = make(map[uint32]uint16)
+	}
+	old := s.ev.enabled[eventType]
+	mech := old | mechanism
+	if mechanism&(HNDLR|SUSPEND_HNDLR) != 0 {
+		mech = old&^(HNDLR|SUSPEND_HNDLR) | mechanism
+	}
+	s.ev.enabled[eventType] = mech
+	var resumed []*goEvent
+	if mech&HNDLR != 0 {
+		resumed, s.ev.suspended = s.ev.suspended, nil
+	}
+	s.mu.Unlock()
+
+	for _, e := range resumed {
+		go s.dispatch(e)
+	}
+	if old == mech {
+		return SUCCESS_EVENT_EN
+	}
+	return SUCCESS
+}
+
+func (b *goBackend) DisableEvent(instr Object, eventType uint32, mechanism uint16) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	if eventType != ALL_ENABLED_EVENTS && !validEvent(eventType) {
+		return ERROR_INV_EVENT
+	}
+	if mechanism == 0 || mechanism&^(QUEUE|HNDLR|SUSPEND_HNDLR) != 0 && mechanism != ALL_MECH {
+		return ERROR_INV_MECH
+	}
+	var disarmed []uint32
+	s.mu.Lock()
+	status = SUCCESS_EVENT_DIS
+	for etype, mech := range s.ev.enabled {
+		if !matchEvent(eventType, etype) || mech&mechanism == 0 {
+			continue
+		}
+		if mech &^= mechanism; mech == 0 {
+			delete(s.ev.enabled, etype)
+			disarmed = append(disarmed, etype)
+		} else {
+			s.ev.enabled[etype] = mech
+		}
+		status = SUCCESS
+	}
+	s.mu.Unlock()
+	if en, ok := s.t.(eventEnabler); ok {
+		for _, etype := range disarmed {
+			en.enableEvent(s, etype, false)
+		}
+	}
+	return status
+}
+
+func (b *goBackend) DiscardEvents(instr Object, eventType uint32, mechanism uint16) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	if eventType != ALL_ENABLED_EVENTS && !validEvent(eventType) {
+		return ERROR_INV_EVENT
+	}
+	if mechanism == 0 || mechanism&^(QUEUE|SUSPEND_HNDLR) != 0 && mechanism != ALL_MECH {
+		return ERROR_INV_MECH
+	}
+	var dropped []*goEvent
+	keep := func(events []*goEvent) []*goEvent {
+		var kept []*goEvent
+		for _, e := range events {
+			if matchEvent(eventType, e.etype) {
+				dropped = append(dropped, e)
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+	s.mu.Lock()
+	if mechanism&QUEUE != 0 {
+		s.ev.queue = keep(s.ev.queue)
+		s.ev.overflow = false
+	}
+	if mechanism&SUSPEND_HNDLR != 0 {
+		s.ev.suspended = keep(s.ev.suspended)
+	}
+	s.mu.Unlock()
+	for _, e := range dropped {
+		e.close()
+	}
+	if len(dropped) == 0 {
+		return SUCCESS_QUEUE_EMPTY
+	}
+	return SUCCESS
+}
+
+func (b *goBackend) WaitOnEvent(instr Object, inEventType, timeout uint32) (outEventType,
+	outContext uint32, status Status) {
+
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, 0, status
+	}
+	if inEventType != ALL_ENABLED_EVENTS && !validEvent(inEventType) {
+		return 0, 0, ERROR_INV_EVENT
+	}
+	var expired <-chan time.Time
+	if timeout != TMO_INFINITE {
+		t := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		s.mu.Lock()
+		enabled := false
+		for etype, mech := range s.ev.enabled {
+			if matchEvent(inEventType, etype) && mech&QUEUE != 0 {
+				enabled = true
+			}
+		}
+		if !enabled {
+			s.mu.Unlock()
+			return 0, 0, ERROR_NENABLED
+		}
+		for i, e := range s.ev.queue {
+			if !matchEvent(inEventType, e.etype) {
+				continue
+			}
+			s.ev.queue = append(s.ev.queue[:i:i], s.ev.queue[i+1:]...)
+			status = SUCCESS
+			for _, r := range s.ev.queue {
+				if matchEvent(inEventType, r.etype) {
+					status = SUCCESS_QUEUE_NEMPTY
+					break
+				}
+			}
+			if s.ev.overflow {
+				s.ev.overflow = false
+				status = WARN_QUEUE_OVERFLOW
+			}
+			s.mu.Unlock()
+			return e.etype, e.vi, status
+		}
+		if s.ev.posted == nil {
+			s.ev.posted = make(chan struct{})
+		}
+		posted := s.ev.posted
+		s.mu.Unlock()
+
+		if timeout == TMO_IMMEDIATE {
+			return 0, 0, ERROR_TMO
+		}
+		select {
+		case <-posted:
+		case <-expired:
+			return 0, 0, ERROR_TMO
+		}
+	}
+}
+
+func (b *goBackend) InstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	if !validEvent(eventType) {
+		return ERROR_INV_EVENT
+	}
+	if userHandle == ANY_HNDLR {
+		return ERROR_INV_HNDLR_REF
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ev.handlers == nil {
+		s.ev.handlers = make(map[uint32][]HandlerID)
+	}
+	s.ev.handlers[eventType] = append(s.ev.handlers[eventType], userHandle)
+	return SUCCESS
+}
+
+// UninstallHandler removes the handler userHandle for eventType, or all of
+// them when userHandle is ANY_HNDLR.
+func (b *goBackend) UninstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hs := s.ev.handlers[eventType]
+	var kept []HandlerID
+	if userHandle != ANY_HNDLR {
+		for _, id := range hs {
+			if id != userHandle {
+				kept = append(kept, id)
+			}
+		}
+	}
+	if len(kept) == len(hs) {
+		return ERROR_INV_HNDLR_REF
+	}
+	s.ev.handlers[eventType] = kept
+	return SUCCESS
+}
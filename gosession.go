@@ -0,0 +1,681 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// transport moves the bytes of one pure-Go session. Reads honor the
+// session's termination attributes and deadline, and report SUCCESS when
+// END was received, SUCCESS_TERM_CHAR or SUCCESS_MAX_CNT otherwise.
+type transport interface {
+	read(s *goSession, buf []byte) (int, Status)
+	write(s *goSession, buf []byte) (int, Status)
+	close() Status
+}
+
+// The optional transport interfaces below back the corresponding session
+// operations, which fail with ERROR_NSUP_OPER when they're missing.
+type (
+	// attrSetter applies a session attribute before it's stored.
+	attrSetter interface {
+		setAttribute(s *goSession, attr uint32, state uint64) Status
+	}
+
+	// attrRefresher updates attributes that track the hardware, such as
+	// modem lines, before they're read.
+	attrRefresher interface {
+		refreshAttribute(s *goSession, attr uint32)
+	}
+
+	stbReader interface {
+		readSTB(s *goSession) (uint16, Status)
+	}
+
+	clearer interface {
+		clear(s *goSession) Status
+	}
+
+	triggerer interface {
+		assertTrigger(s *goSession, protocol uint16) Status
+	}
+
+	// renController drives the remote enable line for GpibControlREN.
+	renController interface {
+		controlREN(s *goSession, mode uint16) Status
+	}
+
+	// aborter interrupts the transfer in progress on behalf of Terminate.
+	aborter interface {
+		abort(s *goSession)
+	}
+
+	// eventEnabler arms or disarms the source of an event type when its
+	// first mechanism is enabled or its last one is disabled.
+	eventEnabler interface {
+		enableEvent(s *goSession, etype uint32, enable bool) Status
+	}
+
+	// remoteLocker extends locks to the device, so they hold against other
+	// hosts too. unlock releases the exclusive lock if s holds one, else
+	// the shared one.
+	remoteLocker interface {
+		lock(s *goSession, lockType, timeout uint32, key string) Status
+		unlock(s *goSession) Status
+	}
+)
+
+// transportOpener connects a new session to the resource p. The opener
+// adds the attributes specific to its resource class to s.
+type transportOpener func(s *goSession, p rsrcParts, timeout uint32) (transport, Status)
+
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[string]transportOpener)
+)
+
+// registerTransport makes the pure-Go backend open resources whose
+// interface and class match key, e.g. "TCPIP::SOCKET".
+func registerTransport(key string, open transportOpener) {
+	transportsMu.Lock()
+	transports[key] = open
+	transportsMu.Unlock()
+}
+
+func lookupTransport(key string) (transportOpener, bool) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	open, ok := transports[key]
+	return open, ok
+}
+
+// goSession is an instrument session of the pure-Go backend.
+type goSession struct {
+	b    *goBackend
+	rm   *goRM
+	vi   uint32
+	name string
+	rsrc rsrcParts
+	a    *attrStore
+	t    transport
+
+	// ioMu serializes transfers, synchronous and asynchronous alike.
+	ioMu sync.Mutex
+
+	mu     sync.Mutex
+	closed bool
+	jobSeq uint32
+	jobs   map[uint32]*goJob
+	ev     eventState
+}
+
+func newGoSession(b *goBackend, rm *goRM, vi uint32, p rsrcParts) *goSession {
+	name := p.String()
+	return &goSession{
+		b:    b,
+		rm:   rm,
+		vi:   vi,
+		name: name,
+		rsrc: p,
+		a: newAttrStore(map[uint32]interface{}{
+			ATTR_RSRC_CLASS:        p.class,
+			ATTR_RSRC_NAME:         name,
+			ATTR_RSRC_IMPL_VERSION: uint64(SPEC_VERSION),
+			ATTR_RSRC_SPEC_VERSION: uint64(SPEC_VERSION),
+			ATTR_RSRC_MANF_NAME:    goManfName,
+			ATTR_RSRC_MANF_ID:      uint64(0),
+			ATTR_RSRC_LOCK_STATE:   uint64(NO_LOCK),
+			ATTR_RM_SESSION:        uint64(rm.vi),
+			ATTR_MAX_QUEUE_LENGTH:  rm.a.num(ATTR_MAX_QUEUE_LENGTH),
+			ATTR_USER_DATA_32:      uint64(0),
+			ATTR_USER_DATA_64:      uint64(0),
+			ATTR_INTF_TYPE:         uint64(intfTypes[p.intf]),
+			ATTR_INTF_NUM:          uint64(p.board),
+			ATTR_INTF_INST_NAME:    p.intf + strconv.Itoa(int(p.board)),
+			ATTR_TMO_VALUE:         uint64(2000),
+			ATTR_TERMCHAR:          uint64('\n'),
+			ATTR_TERMCHAR_EN:       uint64(FALSE),
+			ATTR_SEND_END_EN:       uint64(TRUE),
+			ATTR_SUPPRESS_END_EN:   uint64(FALSE),
+			ATTR_FILE_APPEND_EN:    uint64(FALSE),
+		}),
+		jobs: make(map[uint32]*goJob),
+	}
+}
+
+func (s *goSession) attrs() *attrStore { return s.a }
+
+func (s *goSession) getAttribute(attr uint32, addr unsafe.Pointer) Status {
+	if attr == ATTR_RSRC_LOCK_STATE {
+		s.a.put(attr, uint64(s.b.lockState(s.name)))
+	}
+	if r, ok := s.t.(attrRefresher); ok {
+		r.refreshAttribute(s, attr)
+	}
+	return s.a.get(attr, addr)
+}
+
+func (s *goSession) setAttribute(attr uint32, state uint64) Status {
+	if status := s.a.check(attr, state); status != SUCCESS {
+		return status
+	}
+	if as, ok := s.t.(attrSetter); ok {
+		if status := as.setAttribute(s, attr, state); status != SUCCESS {
+			return status
+		}
+	}
+	s.a.put(attr, state)
+	return SUCCESS
+}
+
+func (s *goSession) close() Status {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ERROR_INV_OBJECT
+	}
+	s.closed = true
+	for _, j := range s.jobs {
+		j.aborted = true
+	}
+	busy := len(s.jobs) > 0
+	s.mu.Unlock()
+
+	if a, ok := s.t.(aborter); ok && busy {
+		a.abort(s)
+	}
+	s.discardAll()
+	excl, shared := s.b.releaseLocks(s.name, s.vi)
+	if rl, ok := s.t.(remoteLocker); ok {
+		if excl {
+			rl.unlock(s)
+		}
+		if shared {
+			rl.unlock(s)
+		}
+	}
+	status := s.t.close()
+	s.rm.disown(s.vi)
+	s.b.unregister(s.vi)
+	if status.IsError() {
+		return ERROR_CLOSING_FAILED
+	}
+	return SUCCESS
+}
+
+// deadline returns the time at which a transfer started now times out,
+// the zero Time if the session's timeout is infinite.
+func (s *goSession) deadline() time.Time {
+	tmo := s.a.num(ATTR_TMO_VALUE)
+	if tmo == TMO_INFINITE {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(tmo) * time.Millisecond)
+}
+
+// termChar returns the termination character and whether reads stop on it.
+func (s *goSession) termChar() (byte, bool) {
+	return byte(s.a.num(ATTR_TERMCHAR)), s.a.num(ATTR_TERMCHAR_EN) == TRUE
+}
+
+// sendEnd reports whether writes assert END with the last byte.
+func (s *goSession) sendEnd() bool {
+	return s.a.num(ATTR_SEND_END_EN) == TRUE
+}
+
+// access fails if another session holds a lock on the resource.
+func (s *goSession) access() Status {
+	if s.b.lockedOut(s.name, s.vi) {
+		return ERROR_RSRC_LOCKED
+	}
+	return SUCCESS
+}
+
+// ----------------------------------------------------------------------------
+// Basic I/O Operations
+//
+
+func (s *goSession) read(buf []byte) (int, Status) {
+	if status := s.access(); status != SUCCESS {
+		return 0, status
+	}
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	return s.t.read(s, buf)
+}
+
+func (s *goSession) write(buf []byte) (int, Status) {
+	if status := s.access(); status != SUCCESS {
+		return 0, status
+	}
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	return s.t.write(s, buf)
+}
+
+func (b *goBackend) Read(instr Object, buf []byte) (retCnt uint32, status Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	n, status := s.read(buf)
+	return uint32(n), status
+}
+
+func (b *goBackend) Write(instr Object, buf []byte) (retCnt uint32, status Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	n, status := s.write(buf)
+	return uint32(n), status
+}
+
+// goJob is an asynchronous transfer in progress.
+type goJob struct {
+	id      uint32
+	aborted bool
+}
+
+// startJob runs xfer in the background and posts EVENT_IO_COMPLETION
+// when it's done.
+func (s *goSession) startJob(oper string, buf []byte, xfer func([]byte) (int, Status)) (uint32, Status) {
+	if status := s.access(); status != SUCCESS {
+		return 0, status
+	}
+	s.mu.Lock()
+	s.jobSeq++
+	if s.jobSeq == NULL {
+		s.jobSeq++
+	}
+	j := &goJob{id: s.jobSeq}
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	go func() {
+		s.ioMu.Lock()
+		s.mu.Lock()
+		aborted := j.aborted
+		s.mu.Unlock()
+		var n int
+		status := Status(ERROR_ABORT)
+		if !aborted {
+			n, status = xfer(buf)
+		}
+		s.ioMu.Unlock()
+
+		s.mu.Lock()
+		if j.aborted {
+			status = ERROR_ABORT
+		}
+		delete(s.jobs, j.id)
+		s.mu.Unlock()
+
+		var p uint64
+		if len(buf) > 0 {
+			p = uint64(uintptr(unsafe.Pointer(&buf[0])))
+		}
+		s.postEvent(EVENT_IO_COMPLETION, map[uint32]interface{}{
+			ATTR_STATUS:       uint64(uint32(status)),
+			ATTR_JOB_ID:       uint64(j.id),
+			ATTR_RET_COUNT_32: uint64(n),
+			ATTR_RET_COUNT_64: uint64(n),
+			ATTR_BUFFER:       p,
+			ATTR_OPER_NAME:    oper,
+		})
+	}()
+	return j.id, SUCCESS
+}
+
+// terminate aborts the job jobId, or every job when it's NULL.
+func (s *goSession) terminate(jobId uint32) Status {
+	s.mu.Lock()
+	found := false
+	for id, j := range s.jobs {
+		if jobId == NULL || id == jobId {
+			j.aborted = true
+			found = true
+		}
+	}
+	s.mu.Unlock()
+	if !found {
+		return ERROR_INV_JOB_ID
+	}
+	if a, ok := s.t.(aborter); ok {
+		a.abort(s)
+	}
+	return SUCCESS
+}
+
+func (b *goBackend) ReadAsync(instr Object, buf []byte) (jobId uint32, status Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	return s.startJob("viReadAsync", buf, func(buf []byte) (int, Status) {
+		return s.t.read(s, buf)
+	})
+}
+
+func (b *goBackend) WriteAsync(instr Object, buf []byte) (jobId uint32, status Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	return s.startJob("viWriteAsync", buf, func(buf []byte) (int, Status) {
+		return s.t.write(s, buf)
+	})
+}
+
+func (b *goBackend) AssertTrigger(instr Object, protocol uint16) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	t, ok := s.t.(triggerer)
+	if !ok {
+		return ERROR_NSUP_OPER
+	}
+	if status := s.access(); status != SUCCESS {
+		return status
+	}
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	return t.assertTrigger(s, protocol)
+}
+
+func (b *goBackend) ReadSTB(instr Object) (uint16, Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	r, ok := s.t.(stbReader)
+	if !ok {
+		return 0, ERROR_NSUP_OPER
+	}
+	if status := s.access(); status != SUCCESS {
+		return 0, status
+	}
+	return r.readSTB(s)
+}
+
+func (b *goBackend) Clear(instr Object) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	c, ok := s.t.(clearer)
+	if !ok {
+		return ERROR_NSUP_OPER
+	}
+	if status := s.access(); status != SUCCESS {
+		return status
+	}
+	return c.clear(s)
+}
+
+func (b *goBackend) GpibControlREN(instr Object, mode uint16) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	c, ok := s.t.(renController)
+	if !ok {
+		return ERROR_NSUP_OPER
+	}
+	if mode > GPIB_REN_ADDRESS_GTL {
+		return ERROR_INV_MODE
+	}
+	if status := s.access(); status != SUCCESS {
+		return status
+	}
+	return c.controlREN(s, mode)
+}
+
+func (b *goBackend) GpibControlATN(instr Object, mode uint16) Status {
+	_, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	return ERROR_NSUP_OPER
+}
+
+func (b *goBackend) GpibSendIFC(instr Object) Status {
+	_, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	return ERROR_NSUP_OPER
+}
+
+func (b *goBackend) GpibCommand(instr Object, cmd []byte) (uint32, Status) {
+	_, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return 0, status
+	}
+	return 0, ERROR_NSUP_OPER
+}
+
+func (b *goBackend) GpibPassControl(instr Object, primAddr, secAddr uint16) Status {
+	_, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	return ERROR_NSUP_OPER
+}
+
+// ----------------------------------------------------------------------------
+// Locking
+//
+
+// rsrcLock is the lock state of one resource, shared by every session of
+// the backend that opened it.
+type rsrcLock struct {
+	excl      uint32
+	exclCount int
+	key       string
+	shared    map[uint32]int
+	changed   chan struct{}
+}
+
+var lockKeySeq uint32
+
+// lockFor returns the lock of the resource name. b.lockMu must be held.
+func (b *goBackend) lockFor(name string) *rsrcLock {
+	l, ok := b.locks[name]
+	if !ok {
+		l = &rsrcLock{shared: make(map[uint32]int), changed: make(chan struct{})}
+		b.locks[name] = l
+	}
+	return l
+}
+
+// acquire takes the lock for vi if possible, ok is false if vi must wait.
+func (l *rsrcLock) acquire(vi, lockType uint32, requestedKey string) (key string, status Status, ok bool) {
+	if l.excl != NULL && l.excl != vi {
+		return "", SUCCESS, false
+	}
+	if lockType == EXCLUSIVE_LOCK {
+		if l.excl == vi {
+			l.exclCount++
+			return "", SUCCESS_NESTED_EXCLUSI, true
+		}
+		for other := range l.shared {
+			if other != vi {
+				return "", SUCCESS, false
+			}
+		}
+		l.excl, l.exclCount = vi, 1
+		return "", SUCCESS, true
+	}
+	if l.shared[vi] > 0 {
+		l.shared[vi]++
+		return l.key, SUCCESS_NESTED_SHARED, true
+	}
+	if len(l.shared) > 0 {
+		if requestedKey == "" {
+			return "", SUCCESS, false
+		}
+		if requestedKey != l.key {
+			return "", ERROR_INV_ACCESS_KEY, true
+		}
+	} else {
+		if requestedKey == "" {
+			lockKeySeq++
+			requestedKey = "GoVisaKey" + strconv.FormatUint(uint64(lockKeySeq), 10)
+		}
+		l.key = requestedKey
+	}
+	l.shared[vi] = 1
+	return l.key, SUCCESS, true
+}
+
+// release drops one level of vi's lock.
+func (l *rsrcLock) release(vi uint32) Status {
+	status := Status(SUCCESS)
+	switch {
+	case l.excl == vi:
+		if l.exclCount--; l.exclCount == 0 {
+			l.excl = NULL
+		} else {
+			status = SUCCESS_NESTED_EXCLUSI
+		}
+	case l.shared[vi] > 0:
+		if l.shared[vi]--; l.shared[vi] == 0 {
+			delete(l.shared, vi)
+		} else {
+			status = SUCCESS_NESTED_SHARED
+		}
+		if len(l.shared) == 0 {
+			l.key = ""
+		}
+	default:
+		return ERROR_SESN_NLOCKED
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return status
+}
+
+func (b *goBackend) Lock(instr Object, lockType, timeout uint32, requestedKey string) (string, Status) {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return "", status
+	}
+	if lockType != EXCLUSIVE_LOCK && lockType != SHARED_LOCK {
+		return "", ERROR_INV_LOCK_TYPE
+	}
+	var expired <-chan time.Time
+	if timeout != TMO_INFINITE {
+		t := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		b.lockMu.Lock()
+		l := b.lockFor(s.name)
+		key, status, ok := l.acquire(s.vi, lockType, requestedKey)
+		changed := l.changed
+		b.lockMu.Unlock()
+		if ok && status == SUCCESS {
+			status = b.lockRemote(s, lockType, timeout, key)
+		}
+		if ok {
+			return key, status
+		}
+		if timeout == TMO_IMMEDIATE {
+			return "", ERROR_RSRC_LOCKED
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			return "", ERROR_TMO
+		}
+	}
+}
+
+// lockRemote extends a newly acquired lock to the device, the local lock
+// is dropped again if that fails.
+func (b *goBackend) lockRemote(s *goSession, lockType, timeout uint32, key string) Status {
+	rl, ok := s.t.(remoteLocker)
+	if !ok {
+		return SUCCESS
+	}
+	status := rl.lock(s, lockType, timeout, key)
+	if status != SUCCESS {
+		b.lockMu.Lock()
+		b.lockFor(s.name).release(s.vi)
+		b.lockMu.Unlock()
+	}
+	return status
+}
+
+func (b *goBackend) Unlock(instr Object) Status {
+	s, status := b.session(uint32(instr))
+	if status != SUCCESS {
+		return status
+	}
+	b.lockMu.Lock()
+	l := b.lockFor(s.name)
+	wasExcl, wasShared := l.excl == s.vi, l.shared[s.vi] > 0
+	status = l.release(s.vi)
+	released := wasExcl && l.excl != s.vi || !wasExcl && wasShared && l.shared[s.vi] == 0
+	b.lockMu.Unlock()
+	if rl, ok := s.t.(remoteLocker); ok && released {
+		if rs := rl.unlock(s); rs != SUCCESS {
+			return rs
+		}
+	}
+	return status
+}
+
+// releaseLocks drops every lock vi holds on the resource name and reports
+// which kinds it held.
+func (b *goBackend) releaseLocks(name string, vi uint32) (excl, shared bool) {
+	b.lockMu.Lock()
+	defer b.lockMu.Unlock()
+	l := b.lockFor(name)
+	excl, shared = l.excl == vi, l.shared[vi] > 0
+	for l.release(vi) != ERROR_SESN_NLOCKED {
+	}
+	if l.excl == NULL && len(l.shared) == 0 {
+		delete(b.locks, name)
+	}
+	return excl, shared
+}
+
+// lockedOut reports whether a session other than vi locks the resource.
+func (b *goBackend) lockedOut(name string, vi uint32) bool {
+	b.lockMu.Lock()
+	defer b.lockMu.Unlock()
+	l, ok := b.locks[name]
+	if !ok {
+		return false
+	}
+	if l.excl != NULL {
+		return l.excl != vi
+	}
+	return len(l.shared) > 0 && l.shared[vi] == 0
+}
+
+// lockState returns the ATTR_RSRC_LOCK_STATE of the resource name.
+func (b *goBackend) lockState(name string) uint32 {
+	b.lockMu.Lock()
+	defer b.lockMu.Unlock()
+	l, ok := b.locks[name]
+	switch {
+	case !ok:
+		return NO_LOCK
+	case l.excl != NULL:
+		return EXCLUSIVE_LOCK
+	case len(l.shared) > 0:
+		return SHARED_LOCK
+	}
+	return NO_LOCK
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+func init() {
+	registerTransport("GPIB::INSTR", openGPIB)
+}
+
+// gpibLib is the part of the linux-gpib API the GPIB transport uses. Each
+// call returns ibsta and, where the C API sets them, ibcntl and iberr
+// sampled right after the call. Board level calls take the board index.
+type gpibLib interface {
+	ibdev(board, pad, sad, tmo, eot, eos int) (ud, sta, iberr int)
+	ibonl(ud, online int) (sta, iberr int)
+	ibwrt(ud int, buf []byte) (cnt, sta, iberr int)
+	ibrd(ud int, buf []byte) (cnt, sta, iberr int)
+	ibrsp(ud int) (stb byte, sta, iberr int)
+	ibclr(ud int) (sta, iberr int)
+	ibtrg(ud int) (sta, iberr int)
+	ibwait(ud, mask int) (sta, iberr int)
+	ibstop(ud int) (sta, iberr int)
+	ibtmo(ud, tmo int) (sta, iberr int)
+	ibeot(ud, eot int) (sta, iberr int)
+	ibeos(ud, eos int) (sta, iberr int)
+	ibloc(ud int) (sta, iberr int)
+	ibsre(board, v int) (sta, iberr int)
+	ibcmd(board int, cmd []byte) (cnt, sta, iberr int)
+}
+
+// gpibLibrary serves GPIB INSTR resources, it's set when the package is
+// built with the linuxgpib tag.
+var gpibLibrary gpibLib
+
+// linux-gpib ibsta bits, error codes and settings, see gpib/ib.h.
+const (
+	gpibRQS  = 0x800
+	gpibEND  = 0x2000
+	gpibTIMO = 0x4000
+	gpibERR  = 0x8000
+
+	gpibEDVR = 0
+	gpibECIC = 1
+	gpibENOL = 2
+	gpibEARG = 4
+	gpibESAC = 5
+	gpibEABO = 6
+	gpibENEB = 7
+	gpibEOIP = 10
+	gpibECAP = 11
+
+	gpibREOS = 0x400
+	gpibBIN  = 0x1000
+
+	gpibLLO = 0x11
+)
+
+// gpibTimeouts are the limits in milliseconds of the ibtmo codes T10us
+// through T1000s, TNONE is 0.
+var gpibTimeouts = []float64{0.01, 0.03, 0.1, 0.3, 1, 3, 10, 30, 100, 300,
+	1000, 3000, 10000, 30000, 100000, 300000, 1000000}
+
+// gpibTimeout returns the smallest ibtmo code not shorter than tmo.
+func gpibTimeout(tmo uint32) int {
+	if tmo == TMO_INFINITE {
+		return 0
+	}
+	for i, limit := range gpibTimeouts {
+		if float64(tmo) <= limit {
+			return i + 1
+		}
+	}
+	return len(gpibTimeouts)
+}
+
+// gpibStatus maps an iberr code to a VISA status. EABO is reported both
+// for timeouts, which also set TIMO, and for ibstop.
+func gpibStatus(sta, iberr int) Status {
+	if sta&gpibERR == 0 {
+		return SUCCESS
+	}
+	switch {
+	case sta&gpibTIMO != 0:
+		return ERROR_TMO
+	case iberr == gpibEABO:
+		return ERROR_ABORT
+	case iberr == gpibEDVR:
+		return ERROR_SYSTEM_ERROR
+	case iberr == gpibECIC:
+		return ERROR_NCIC
+	case iberr == gpibENOL:
+		return ERROR_NLISTENERS
+	case iberr == gpibEARG:
+		return ERROR_INV_PARAMETER
+	case iberr == gpibESAC:
+		return ERROR_NSYS_CNTLR
+	case iberr == gpibENEB:
+		return ERROR_RSRC_NFOUND
+	case iberr == gpibEOIP:
+		return ERROR_IN_PROGRESS
+	case iberr == gpibECAP:
+		return ERROR_NSUP_OPER
+	}
+	return ERROR_IO
+}
+
+// gpibSRQPoll is how often an armed session checks for service requests.
+const gpibSRQPoll = 20 * time.Millisecond
+
+// gpibTransport serves GPIBn::pad[::sad]::INSTR through a linux-gpib
+// device descriptor.
+type gpibTransport struct {
+	lib   gpibLib
+	ud    int
+	board int
+
+	// The settings last applied, guarded by the session's ioMu.
+	applied bool
+	tmo     int
+	eot     bool
+	eos     int
+
+	mu         sync.Mutex
+	srqStop    chan struct{}
+	srqDone    chan struct{}
+	srqPending bool // reported, the status byte wasn't read yet
+}
+
+func openGPIB(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if gpibLibrary == nil {
+		return nil, ERROR_RSRC_NFOUND
+	}
+	if len(p.fields) < 1 || len(p.fields) > 2 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	pad, err := strconv.ParseUint(p.fields[0], 10, 8)
+	if err != nil || pad > 30 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	sad := uint64(NO_SEC_ADDR)
+	if len(p.fields) == 2 {
+		if sad, err = strconv.ParseUint(p.fields[1], 10, 8); err != nil || sad > 30 {
+			return nil, ERROR_INV_RSRC_NAME
+		}
+	}
+	sadCode := 0
+	if sad != NO_SEC_ADDR {
+		sadCode = 0x60 + int(sad)
+	}
+
+	t := &gpibTransport{lib: gpibLibrary, board: int(p.board)}
+	ud, sta, iberr := t.lib.ibdev(t.board, int(pad), sadCode,
+		gpibTimeout(uint32(s.a.num(ATTR_TMO_VALUE))), 1, 0)
+	if sta&gpibERR != 0 || ud < 0 {
+		if iberr == gpibEDVR {
+			return nil, ERROR_SYSTEM_ERROR
+		}
+		return nil, ERROR_RSRC_NFOUND
+	}
+	t.ud = ud
+
+	s.a.put(ATTR_GPIB_PRIMARY_ADDR, pad)
+	s.a.put(ATTR_GPIB_SECONDARY_ADDR, sad)
+	s.a.put(ATTR_GPIB_READDR_EN, TRUE)
+	s.a.put(ATTR_GPIB_UNADDR_EN, FALSE)
+	s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+	return t, SUCCESS
+}
+
+// configure maps the session's timeout to ibtmo, ATTR_SEND_END_EN to
+// ibeot and the termination character to ibeos when they changed.
+func (t *gpibTransport) configure(s *goSession) Status {
+	tmo := gpibTimeout(uint32(s.a.num(ATTR_TMO_VALUE)))
+	eot := s.sendEnd()
+	term, termEn := s.termChar()
+	eos := 0
+	if termEn {
+		eos = int(term) | gpibREOS | gpibBIN
+	}
+	if t.applied && tmo == t.tmo && eot == t.eot && eos == t.eos {
+		return SUCCESS
+	}
+	eotArg := 0
+	if eot {
+		eotArg = 1
+	}
+	for _, set := range []func() (int, int){
+		func() (int, int) { return t.lib.ibtmo(t.ud, tmo) },
+		func() (int, int) { return t.lib.ibeot(t.ud, eotArg) },
+		func() (int, int) { return t.lib.ibeos(t.ud, eos) },
+	} {
+		if status := gpibStatus(set()); status != SUCCESS {
+			return status
+		}
+	}
+	t.applied, t.tmo, t.eot, t.eos = true, tmo, eot, eos
+	return SUCCESS
+}
+
+// read returns what one ibrd delivers, it stops at EOI, the termination
+// character or the count.
+func (t *gpibTransport) read(s *goSession, buf []byte) (int, Status) {
+	if status := t.configure(s); status != SUCCESS {
+		return 0, status
+	}
+	n, sta, iberr := t.lib.ibrd(t.ud, buf)
+	if status := gpibStatus(sta, iberr); status != SUCCESS {
+		return n, status
+	}
+	term, termEn := s.termChar()
+	switch {
+	case termEn && n > 0 && buf[n-1] == term:
+		return n, SUCCESS_TERM_CHAR
+	case sta&gpibEND != 0:
+		return n, SUCCESS
+	}
+	return n, SUCCESS_MAX_CNT
+}
+
+func (t *gpibTransport) write(s *goSession, buf []byte) (int, Status) {
+	if status := t.configure(s); status != SUCCESS {
+		return 0, status
+	}
+	n, sta, iberr := t.lib.ibwrt(t.ud, buf)
+	return n, gpibStatus(sta, iberr)
+}
+
+func (t *gpibTransport) close() Status {
+	t.stopSRQ()
+	if status := gpibStatus(t.lib.ibonl(t.ud, 0)); status != SUCCESS {
+		return ERROR_CLOSING_FAILED
+	}
+	return SUCCESS
+}
+
+// abort stops the ibrd or ibwrt in progress, it fails with ERROR_ABORT.
+func (t *gpibTransport) abort(s *goSession) {
+	t.lib.ibstop(t.ud)
+}
+
+func (t *gpibTransport) readSTB(s *goSession) (uint16, Status) {
+	stb, sta, iberr := t.lib.ibrsp(t.ud)
+	status := gpibStatus(sta, iberr)
+	if status == SUCCESS {
+		// The serial poll cleared RQS, the next one is a new request even
+		// if the watcher didn't see it clear.
+		t.mu.Lock()
+		t.srqPending = false
+		t.mu.Unlock()
+	}
+	return uint16(stb), status
+}
+
+func (t *gpibTransport) clear(s *goSession) Status {
+	return gpibStatus(t.lib.ibclr(t.ud))
+}
+
+func (t *gpibTransport) assertTrigger(s *goSession, protocol uint16) Status {
+	if protocol != TRIG_PROT_DEFAULT {
+		return ERROR_INV_PROT
+	}
+	return gpibStatus(t.lib.ibtrg(t.ud))
+}
+
+// controlREN drives REN through the board, local lockout is the LLO
+// command and go to local is ibloc.
+func (t *gpibTransport) controlREN(s *goSession, mode uint16) Status {
+	ren := func(v int) Status { return gpibStatus(t.lib.ibsre(t.board, v)) }
+	status := Status(SUCCESS)
+	switch mode {
+	case GPIB_REN_DEASSERT:
+		status = ren(0)
+	case GPIB_REN_ASSERT, GPIB_REN_ASSERT_ADDRESS:
+		status = ren(1)
+	case GPIB_REN_DEASSERT_GTL:
+		if status = gpibStatus(t.lib.ibloc(t.ud)); status == SUCCESS {
+			status = ren(0)
+		}
+	case GPIB_REN_ASSERT_LLO, GPIB_REN_ASSERT_ADDRESS_:
+		if status = ren(1); status == SUCCESS {
+			_, sta, iberr := t.lib.ibcmd(t.board, []byte{gpibLLO})
+			status = gpibStatus(sta, iberr)
+		}
+	case GPIB_REN_ADDRESS_GTL:
+		status = gpibStatus(t.lib.ibloc(t.ud))
+	}
+	return status
+}
+
+// enableEvent starts watching the device's RQS status for service
+// requests when EVENT_SERVICE_REQ is armed. linux-gpib sets RQS when it
+// autopolls a device asserting SRQ, a request is reported once until the
+// status byte has been read.
+func (t *gpibTransport) enableEvent(s *goSession, etype uint32, enable bool) Status {
+	if etype != EVENT_SERVICE_REQ {
+		return SUCCESS
+	}
+	if !enable {
+		t.stopSRQ()
+		return SUCCESS
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.srqStop == nil {
+		t.srqStop, t.srqDone = make(chan struct{}), make(chan struct{})
+		go t.watchSRQ(s, t.srqStop, t.srqDone)
+	}
+	return SUCCESS
+}
+
+func (t *gpibTransport) watchSRQ(s *goSession, stop, done chan struct{}) {
+	defer close(done)
+	tick := time.NewTicker(gpibSRQPoll)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+		}
+		sta, _ := t.lib.ibwait(t.ud, 0)
+		rqs := sta&gpibRQS != 0
+		t.mu.Lock()
+		post := rqs && !t.srqPending
+		t.srqPending = rqs
+		t.mu.Unlock()
+		if post {
+			s.postEvent(EVENT_SERVICE_REQ, nil)
+		}
+	}
+}
+
+func (t *gpibTransport) stopSRQ() {
+	t.mu.Lock()
+	stop, done := t.srqStop, t.srqDone
+	t.srqStop, t.srqDone = nil, nil
+	t.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
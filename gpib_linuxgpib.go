@@ -0,0 +1,161 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+//go:build cgo && linuxgpib
+// +build cgo,linuxgpib
+
+package visa
+
+/*
+#cgo LDFLAGS: -lgpib
+#include <gpib/ib.h>
+
+// Every wrapper samples the thread local iberr and ibcntl before returning
+// to Go, which may move the goroutine to another thread.
+static int go_ibsta(int sta, int *err) {
+	*err = ThreadIberr();
+	return sta;
+}
+
+static int go_ibdev(int board, int pad, int sad, int tmo, int eot, int eos, int *ud, int *err) {
+	*ud = ibdev(board, pad, sad, tmo, eot, eos);
+	return go_ibsta(ThreadIbsta(), err);
+}
+
+static int go_ibio(int wr, int ud, void *buf, long cnt, long *n, int *err) {
+	int sta = wr ? ibwrt(ud, buf, cnt) : ibrd(ud, buf, cnt);
+	*n = ThreadIbcntl();
+	return go_ibsta(sta, err);
+}
+
+static int go_ibcmd(int ud, void *buf, long cnt, long *n, int *err) {
+	int sta = ibcmd(ud, buf, cnt);
+	*n = ThreadIbcntl();
+	return go_ibsta(sta, err);
+}
+
+static int go_ibrsp(int ud, char *stb, int *err) { return go_ibsta(ibrsp(ud, stb), err); }
+static int go_ibonl(int ud, int v, int *err)     { return go_ibsta(ibonl(ud, v), err); }
+static int go_ibclr(int ud, int *err)            { return go_ibsta(ibclr(ud), err); }
+static int go_ibtrg(int ud, int *err)            { return go_ibsta(ibtrg(ud), err); }
+static int go_ibwait(int ud, int mask, int *err) { return go_ibsta(ibwait(ud, mask), err); }
+static int go_ibstop(int ud, int *err)           { return go_ibsta(ibstop(ud), err); }
+static int go_ibtmo(int ud, int v, int *err)     { return go_ibsta(ibtmo(ud, v), err); }
+static int go_ibeot(int ud, int v, int *err)     { return go_ibsta(ibeot(ud, v), err); }
+static int go_ibeos(int ud, int v, int *err)     { return go_ibsta(ibeos(ud, v), err); }
+static int go_ibloc(int ud, int *err)            { return go_ibsta(ibloc(ud), err); }
+static int go_ibsre(int ud, int v, int *err)     { return go_ibsta(ibsre(ud, v), err); }
+*/
+import "C"
+
+import "unsafe"
+
+func init() {
+	gpibLibrary = linuxGPIB{}
+}
+
+// linuxGPIB calls libgpib.
+type linuxGPIB struct{}
+
+func gpibBuf(buf []byte) unsafe.Pointer {
+	if len(buf) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&buf[0])
+}
+
+func (linuxGPIB) ibdev(board, pad, sad, tmo, eot, eos int) (ud, sta, iberr int) {
+	var cud, cerr C.int
+	sta = int(C.go_ibdev(C.int(board), C.int(pad), C.int(sad), C.int(tmo), C.int(eot),
+		C.int(eos), &cud, &cerr))
+	return int(cud), sta, int(cerr)
+}
+
+func (linuxGPIB) ibonl(ud, online int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibonl(C.int(ud), C.int(online), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibwrt(ud int, buf []byte) (cnt, sta, iberr int) {
+	var n C.long
+	var cerr C.int
+	sta = int(C.go_ibio(1, C.int(ud), gpibBuf(buf), C.long(len(buf)), &n, &cerr))
+	return int(n), sta, int(cerr)
+}
+
+func (linuxGPIB) ibrd(ud int, buf []byte) (cnt, sta, iberr int) {
+	var n C.long
+	var cerr C.int
+	sta = int(C.go_ibio(0, C.int(ud), gpibBuf(buf), C.long(len(buf)), &n, &cerr))
+	return int(n), sta, int(cerr)
+}
+
+func (linuxGPIB) ibrsp(ud int) (stb byte, sta, iberr int) {
+	var cstb C.char
+	var cerr C.int
+	sta = int(C.go_ibrsp(C.int(ud), &cstb, &cerr))
+	return byte(cstb), sta, int(cerr)
+}
+
+func (linuxGPIB) ibclr(ud int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibclr(C.int(ud), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibtrg(ud int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibtrg(C.int(ud), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibwait(ud, mask int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibwait(C.int(ud), C.int(mask), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibstop(ud int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibstop(C.int(ud), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibtmo(ud, tmo int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibtmo(C.int(ud), C.int(tmo), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibeot(ud, eot int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibeot(C.int(ud), C.int(eot), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibeos(ud, eos int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibeos(C.int(ud), C.int(eos), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibloc(ud int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibloc(C.int(ud), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibsre(board, v int) (sta, iberr int) {
+	var cerr C.int
+	sta = int(C.go_ibsre(C.int(board), C.int(v), &cerr))
+	return sta, int(cerr)
+}
+
+func (linuxGPIB) ibcmd(board int, cmd []byte) (cnt, sta, iberr int) {
+	var n C.long
+	var cerr C.int
+	sta = int(C.go_ibcmd(C.int(board), gpibBuf(cmd), C.long(len(cmd)), &n, &cerr))
+	return int(n), sta, int(cerr)
+}
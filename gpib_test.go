@@ -0,0 +1,256 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGPIB is a gpibLib with one device. ibrd hands out in, stopping at
+// the ibeos character, and sets END with the last byte unless noEOI is
+// set. A non-zero rdSta fails the next ibrd with it and rdErr.
+type fakeGPIB struct {
+	mu    sync.Mutex
+	pad   int
+	eos   int
+	in    []byte
+	out   []byte
+	noEOI bool
+	rdSta int
+	rdErr int
+	rqs   bool
+	polls int
+	calls []string
+}
+
+func (f *fakeGPIB) log(call string) {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+}
+
+func (f *fakeGPIB) ibdev(board, pad, sad, tmo, eot, eos int) (int, int, int) {
+	if pad != f.pad {
+		return -1, gpibERR, gpibENEB
+	}
+	return 3, 0, 0
+}
+
+func (f *fakeGPIB) ibonl(ud, online int) (int, int) { f.log("ibonl"); return 0, 0 }
+
+func (f *fakeGPIB) ibwrt(ud int, buf []byte) (int, int, int) {
+	f.out = append(f.out, buf...)
+	return len(buf), 0, 0
+}
+
+func (f *fakeGPIB) ibrd(ud int, buf []byte) (int, int, int) {
+	if f.rdSta != 0 {
+		sta, iberr := f.rdSta, f.rdErr
+		f.rdSta = 0
+		return 0, sta, iberr
+	}
+	n := 0
+	for n < len(buf) && n < len(f.in) {
+		buf[n] = f.in[n]
+		n++
+		if f.eos&gpibREOS != 0 && buf[n-1] == byte(f.eos) {
+			f.in = f.in[n:]
+			return n, gpibEND, 0
+		}
+	}
+	f.in = f.in[n:]
+	if len(f.in) == 0 && !f.noEOI {
+		return n, gpibEND, 0
+	}
+	return n, 0, 0
+}
+
+func (f *fakeGPIB) ibrsp(ud int) (byte, int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stb := byte(0x01)
+	if f.rqs {
+		stb |= 0x40
+	}
+	f.rqs = false
+	return stb, 0, 0
+}
+
+func (f *fakeGPIB) ibwait(ud, mask int) (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	if f.rqs {
+		return gpibRQS, 0
+	}
+	return 0, 0
+}
+
+func (f *fakeGPIB) ibclr(ud int) (int, int)       { f.log("ibclr"); return 0, 0 }
+func (f *fakeGPIB) ibtrg(ud int) (int, int)       { f.log("ibtrg"); return 0, 0 }
+func (f *fakeGPIB) ibstop(ud int) (int, int)      { f.log("ibstop"); return 0, 0 }
+func (f *fakeGPIB) ibtmo(ud, tmo int) (int, int)  { return 0, 0 }
+func (f *fakeGPIB) ibeot(ud, eot int) (int, int)  { return 0, 0 }
+func (f *fakeGPIB) ibeos(ud, eos int) (int, int)  { f.eos = eos; return 0, 0 }
+func (f *fakeGPIB) ibloc(ud int) (int, int)       { f.log("ibloc"); return 0, 0 }
+func (f *fakeGPIB) ibsre(board, v int) (int, int) { f.log("ibsre"); return 0, 0 }
+func (f *fakeGPIB) ibcmd(board int, cmd []byte) (int, int, int) {
+	f.log("ibcmd")
+	return len(cmd), 0, 0
+}
+
+// withGPIB installs f as the GPIB library for the duration of the test.
+func withGPIB(t *testing.T, f *fakeGPIB) {
+	old := gpibLibrary
+	gpibLibrary = f
+	t.Cleanup(func() { gpibLibrary = old })
+}
+
+// openGPIBTest opens GPIB0::5::INSTR on f.
+func openGPIBTest(t *testing.T, f *fakeGPIB) Object {
+	t.Helper()
+	withGPIB(t, f)
+	rm := openTestRM(t)
+	instr, status := rm.Open("GPIB0::5::INSTR", NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open: %v", status)
+	}
+	return instr
+}
+
+func TestGPIBStatus(t *testing.T) {
+	tests := []struct {
+		sta, iberr int
+		want       Status
+	}{
+		{gpibEND, gpibEABO, SUCCESS},
+		{gpibERR | gpibTIMO, gpibEABO, ERROR_TMO},
+		{gpibERR, gpibEABO, ERROR_ABORT},
+		{gpibERR, gpibENOL, ERROR_NLISTENERS},
+		{gpibERR, gpibECIC, ERROR_NCIC},
+		{gpibERR, gpibENEB, ERROR_RSRC_NFOUND},
+		{gpibERR, gpibEDVR, ERROR_SYSTEM_ERROR},
+		{gpibERR, 99, ERROR_IO},
+	}
+	for _, tt := range tests {
+		if got := gpibStatus(tt.sta, tt.iberr); got != tt.want {
+			t.Errorf("gpibStatus(%#x, %d) = %v, want %v", tt.sta, tt.iberr, got, tt.want)
+		}
+	}
+}
+
+func TestGPIBOpen(t *testing.T) {
+	withGPIB(t, &fakeGPIB{pad: 5})
+	rm := openTestRM(t)
+	instr, status := rm.Open("GPIB0::5::2::INSTR", NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open: %v", status)
+	}
+	if pad, err := instr.AttrUint16(ATTR_GPIB_PRIMARY_ADDR); err != nil || pad != 5 {
+		t.Errorf("ATTR_GPIB_PRIMARY_ADDR = %d, %v", pad, err)
+	}
+	if sad, err := instr.AttrUint16(ATTR_GPIB_SECONDARY_ADDR); err != nil || sad != 2 {
+		t.Errorf("ATTR_GPIB_SECONDARY_ADDR = %d, %v", sad, err)
+	}
+	for name, want := range map[string]Status{
+		"GPIB0::6::INSTR":     ERROR_RSRC_NFOUND,
+		"GPIB0::31::INSTR":    ERROR_INV_RSRC_NAME,
+		"GPIB0::5::31::INSTR": ERROR_INV_RSRC_NAME,
+	} {
+		if _, status := rm.Open(name, NO_LOCK, 0); status != want {
+			t.Errorf("Open(%q): %v, want %v", name, status, want)
+		}
+	}
+}
+
+func TestGPIBReadWrite(t *testing.T) {
+	f := &fakeGPIB{pad: 5}
+	instr := openGPIBTest(t, f)
+
+	if n, status := instr.Write([]byte("*IDN?\n"), 6); status != SUCCESS || n != 6 {
+		t.Fatalf("Write = %d, %v", n, status)
+	}
+	if !bytes.Equal(f.out, []byte("*IDN?\n")) {
+		t.Errorf("device received %q", f.out)
+	}
+
+	f.in = []byte("ACME,1234")
+	buf, n, status := instr.Read(4)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "ACME" {
+		t.Errorf("Read(4) = %q, %v, want \"ACME\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+	buf, n, status = instr.Read(64)
+	if status != SUCCESS || string(buf[:n]) != ",1234" {
+		t.Errorf("Read up to EOI = %q, %v, want \",1234\", SUCCESS", buf[:n], status)
+	}
+
+	instr.SetAttrBool(ATTR_TERMCHAR_EN, true)
+	f.in = []byte("1\n2")
+	buf, n, status = instr.Read(64)
+	if status != SUCCESS_TERM_CHAR || string(buf[:n]) != "1\n" {
+		t.Errorf("Read up to the termination character = %q, %v", buf[:n], status)
+	}
+	if f.eos != '\n'|gpibREOS|gpibBIN {
+		t.Errorf("ibeos = %#x, want REOS|BIN|'\\n'", f.eos)
+	}
+
+	f.in, f.noEOI = []byte("2"), true
+	buf, n, status = instr.Read(64)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "2" {
+		t.Errorf("Read without EOI = %q, %v, want \"2\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+
+	f.rdSta, f.rdErr = gpibERR|gpibTIMO, gpibEABO
+	if _, _, status := instr.Read(64); status != ERROR_TMO {
+		t.Errorf("Read timing out: %v, want ERROR_TMO", status)
+	}
+	f.rdSta, f.rdErr = gpibERR, gpibEABO
+	if _, _, status := instr.Read(64); status != ERROR_ABORT {
+		t.Errorf("Read stopped by ibstop: %v, want ERROR_ABORT", status)
+	}
+}
+
+func TestGPIBServiceRequest(t *testing.T) {
+	f := &fakeGPIB{pad: 5}
+	instr := openGPIBTest(t, f)
+	if status := instr.EnableEvent(EVENT_SERVICE_REQ, QUEUE, NULL); status != SUCCESS {
+		t.Fatalf("EnableEvent: %v", status)
+	}
+
+	f.mu.Lock()
+	f.rqs = true
+	f.mu.Unlock()
+	if _, err := instr.WaitEvent(EVENT_SERVICE_REQ, 2000); err != nil {
+		t.Fatalf("WaitEvent: %v", err)
+	}
+	// RQS stays set until the status byte is read, that's one request.
+	if _, err := instr.WaitEvent(EVENT_SERVICE_REQ, 5*uint32(gpibSRQPoll.Milliseconds())); err == nil {
+		t.Error("a pending request was reported twice")
+	}
+	if stb, status := instr.ReadSTB(); status != SUCCESS || stb != 0x41 {
+		t.Errorf("ReadSTB = %#x, %v, want 0x41", stb, status)
+	}
+
+	f.mu.Lock()
+	f.rqs = true
+	f.mu.Unlock()
+	if _, err := instr.WaitEvent(EVENT_SERVICE_REQ, 2000); err != nil {
+		t.Errorf("WaitEvent for the second request: %v", err)
+	}
+
+	instr.DisableEvent(EVENT_SERVICE_REQ, QUEUE)
+	f.mu.Lock()
+	polls := f.polls
+	f.mu.Unlock()
+	time.Sleep(3 * gpibSRQPoll)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.polls != polls {
+		t.Error("the device is still polled after DisableEvent")
+	}
+}
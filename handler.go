@@ -0,0 +1,124 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"log"
+	"sync"
+)
+
+// HandlerID identifies an installed event handler. It's passed to the
+// backend as the VISA user handle, so no Go pointer crosses into C.
+type HandlerID uint32
+
+// handlerEntry is an installed handler and what it was installed for.
+type handlerEntry struct {
+	instr   Object
+	rm      Session // that opened instr
+	etype   uint32
+	fn      UserCallback
+	dropped func() // called when the session closes, may be nil
+}
+
+// handlers is the registry of installed handlers, keyed by ID.
+var handlers = struct {
+	sync.Mutex
+	next HandlerID
+	m    map[HandlerID]handlerEntry
+}{m: make(map[HandlerID]handlerEntry)}
+
+// installHandler installs fn like InstallHandler, dropped is called if
+// the handler is removed because the session closes.
+func (instr Object) installHandler(eventType uint32, fn UserCallback, dropped func()) (HandlerID, Status) {
+	if fn == nil {
+		return 0, ERROR_INV_HNDLR_REF
+	}
+	id := registerHandler(instr, eventType, fn, dropped)
+	status := backend.InstallHandler(instr, eventType, id)
+	if status < SUCCESS {
+		unregisterHandler(instr, eventType, id)
+		return 0, status
+	}
+	return id, status
+}
+
+// registerHandler adds fn to the registry and returns its ID, which is
+// never ANY_HNDLR.
+func registerHandler(instr Object, etype uint32, fn UserCallback, dropped func()) HandlerID {
+	rm, _ := instr.AttrUint32(ATTR_RM_SESSION)
+	handlers.Lock()
+	defer handlers.Unlock()
+	for {
+		handlers.next++
+		id := handlers.next
+		if _, used := handlers.m[id]; id != ANY_HNDLR && !used {
+			handlers.m[id] = handlerEntry{instr, Session(rm), etype, fn, dropped}
+			return id
+		}
+	}
+}
+
+// unregisterHandler removes the handler with id, or every handler of
+// instr for etype when id is ANY_HNDLR.
+func unregisterHandler(instr Object, etype uint32, id HandlerID) {
+	handlers.Lock()
+	defer handlers.Unlock()
+	if id != ANY_HNDLR {
+		delete(handlers.m, id)
+		return
+	}
+	for id, h := range handlers.m {
+		if h.instr == instr && h.etype == etype {
+			delete(handlers.m, id)
+		}
+	}
+}
+
+// dropHandlers removes every handler of instr, and of the sessions it
+// opened if it's a resource manager, used when it's closed. The backend
+// closes those sessions along with it and may reuse their numbers.
+func dropHandlers(instr Object) {
+	var dropped []func()
+	handlers.Lock()
+	for id, h := range handlers.m {
+		if h.instr == instr || h.rm == Session(instr) {
+			delete(handlers.m, id)
+			if h.dropped != nil {
+				dropped = append(dropped, h.dropped)
+			}
+		}
+	}
+	handlers.Unlock()
+	for _, f := range dropped {
+		f()
+	}
+}
+
+// resetHandlers forgets every handler, used when the backend is replaced.
+func resetHandlers() {
+	handlers.Lock()
+	handlers.m = make(map[HandlerID]handlerEntry)
+	handlers.Unlock()
+}
+
+// CallHandler calls the handler with id for an event. Backends call it
+// for each handler installed through InstallHandler. Unknown IDs are
+// ignored, since an event can race with the handler's uninstallation. A
+// panicking handler is logged and treated as having returned, it must not
+// unwind into the thread that delivered the event.
+func CallHandler(id HandlerID, instr Object, etype, eventContext uint32) {
+	handlers.Lock()
+	h, ok := handlers.m[id]
+	handlers.Unlock()
+	if !ok {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("visa: handler %d for event 0x%08X panicked: %v", id, etype, r)
+		}
+	}()
+	h.fn(instr, etype, eventContext)
+}
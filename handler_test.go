@@ -0,0 +1,139 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// registered returns the number of handlers registered for instr.
+func registered(instr Object) int {
+	handlers.Lock()
+	defer handlers.Unlock()
+	n := 0
+	for _, h := range handlers.m {
+		if h.instr == instr {
+			n++
+		}
+	}
+	return n
+}
+
+func TestHandlers(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	s := goSessionOf(t, instr)
+
+	calls := make(chan string, 10)
+	handler := func(name string) UserCallback {
+		return func(i Object, etype, ctx uint32) {
+			if i != instr || etype != EVENT_SERVICE_REQ {
+				t.Errorf("handler %s called for %d and event 0x%08X", name, i, etype)
+			}
+			calls <- name
+		}
+	}
+	if _, status := instr.InstallHandler(EVENT_SERVICE_REQ, nil); status != ERROR_INV_HNDLR_REF {
+		t.Errorf("InstallHandler(nil): %v, want ERROR_INV_HNDLR_REF", status)
+	}
+	first, status := instr.InstallHandler(EVENT_SERVICE_REQ, handler("first"))
+	if status != SUCCESS {
+		t.Fatalf("InstallHandler: %v", status)
+	}
+	second, status := instr.InstallHandler(EVENT_SERVICE_REQ, handler("second"))
+	if status != SUCCESS || second == first {
+		t.Fatalf("InstallHandler = %d, %v, want a new ID", second, status)
+	}
+	if _, status := instr.InstallHandler(0x1234, handler("bad")); status != ERROR_INV_EVENT || registered(instr) != 2 {
+		t.Errorf("InstallHandler of a bad event: %v, %d handlers registered", status, registered(instr))
+	}
+	instr.EnableEvent(EVENT_SERVICE_REQ, HNDLR, NULL)
+
+	// Handlers run in the order they were installed.
+	expect := func(names ...string) {
+		t.Helper()
+		s.postEvent(EVENT_SERVICE_REQ, nil)
+		for _, want := range names {
+			select {
+			case got := <-calls:
+				if got != want {
+					t.Errorf("handler %s called, want %s", got, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("handler %s not called", want)
+			}
+		}
+	}
+	expect("first", "second")
+
+	if status := instr.UninstallHandler(EVENT_SERVICE_REQ, first); status != SUCCESS {
+		t.Fatalf("UninstallHandler(%d): %v", first, status)
+	}
+	if status := instr.UninstallHandler(EVENT_SERVICE_REQ, first); status != ERROR_INV_HNDLR_REF {
+		t.Errorf("UninstallHandler of an uninstalled handler: %v, want ERROR_INV_HNDLR_REF", status)
+	}
+	third, _ := instr.InstallHandler(EVENT_SERVICE_REQ, handler("third"))
+	expect("second", "third")
+	if n := registered(instr); n != 2 {
+		t.Errorf("%d handlers registered, want 2", n)
+	}
+
+	if status := instr.UninstallHandler(EVENT_SERVICE_REQ, ANY_HNDLR); status != SUCCESS {
+		t.Fatalf("UninstallHandler(ANY_HNDLR): %v", status)
+	}
+	s.postEvent(EVENT_SERVICE_REQ, nil)
+	time.Sleep(50 * time.Millisecond)
+	if len(calls) != 0 || registered(instr) != 0 {
+		t.Errorf("%d calls after uninstalling every handler, %d still registered", len(calls), registered(instr))
+	}
+	CallHandler(third, instr, EVENT_SERVICE_REQ, 0)
+	if len(calls) != 0 {
+		t.Error("CallHandler called an uninstalled handler")
+	}
+}
+
+func TestCallHandlerPanic(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	called := false
+	id := registerHandler(Object(1), EVENT_TRIG, func(Object, uint32, uint32) {
+		called = true
+		panic("boom")
+	}, nil)
+	defer unregisterHandler(Object(1), EVENT_TRIG, id)
+	CallHandler(id, Object(1), EVENT_TRIG, 0)
+	if !called || !strings.Contains(logged.String(), "panicked: boom") {
+		t.Errorf("handler called %v, logged %q", called, logged.String())
+	}
+}
+
+func TestHandlersClose(t *testing.T) {
+	rm := openTestRM(t)
+	a, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+	b, _ := openLoopback(t, rm, "VXI0::2::INSTR")
+	nop := func(Object, uint32, uint32) {}
+	for _, instr := range []Object{a, b, a} {
+		if _, status := instr.InstallHandler(EVENT_SERVICE_REQ, nop); status != SUCCESS {
+			t.Fatalf("InstallHandler: %v", status)
+		}
+	}
+
+	a.Close()
+	if n := registered(a); n != 0 {
+		t.Errorf("%d handlers registered after closing the session", n)
+	}
+	// Closing the resource manager closes the sessions it opened.
+	rm.Close()
+	if n := registered(b); n != 0 {
+		t.Errorf("%d handlers registered after closing the resource manager", n)
+	}
+}
@@ -0,0 +1,574 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HiSLIP message types, see IVI-6.1 rev 1.1, section 6.
+const (
+	hsInitialize                      = 0
+	hsInitializeResponse              = 1
+	hsFatalError                      = 2
+	hsError                           = 3
+	hsAsyncLock                       = 4
+	hsAsyncLockResponse               = 5
+	hsData                            = 6
+	hsDataEnd                         = 7
+	hsDeviceClearComplete             = 8
+	hsDeviceClearAcknowledge          = 9
+	hsTrigger                         = 12
+	hsInterrupted                     = 13
+	hsAsyncInterrupted                = 14
+	hsAsyncMaximumMessageSize         = 15
+	hsAsyncMaximumMessageSizeResponse = 16
+	hsAsyncInitialize                 = 17
+	hsAsyncInitializeResponse         = 18
+	hsAsyncDeviceClear                = 19
+	hsAsyncServiceRequest             = 20
+	hsAsyncStatusQuery                = 21
+	hsAsyncStatusResponse             = 22
+	hsAsyncDeviceClearAcknowledge     = 23
+)
+
+const (
+	hsPort           = 4880
+	hsVersion        = 0x0100 // 1.0
+	hsVendorID       = 'G'<<8 | 'O'
+	hsFirstMessageID = 0xFFFFFF00
+	hsMaxMessageKB   = 1024
+	hsHeaderLen      = 16
+
+	// AsyncLockResponse control codes.
+	hsLockFailed         = 0
+	hsLockSuccess        = 1
+	hsLockSharedReleased = 2
+)
+
+var errHiSLIPHeader = errors.New("visa: malformed HiSLIP message")
+
+// hsMessage is a HiSLIP message.
+type hsMessage struct {
+	typ     byte
+	ctrl    byte
+	param   uint32
+	payload []byte
+}
+
+func writeHS(w io.Writer, m hsMessage) error {
+	b := make([]byte, hsHeaderLen, hsHeaderLen+len(m.payload))
+	b[0], b[1], b[2], b[3] = 'H', 'S', m.typ, m.ctrl
+	binary.BigEndian.PutUint32(b[4:], m.param)
+	binary.BigEndian.PutUint64(b[8:], uint64(len(m.payload)))
+	_, err := w.Write(append(b, m.payload...))
+	return err
+}
+
+// readHS reads a message whose payload is at most max bytes.
+func readHS(r io.Reader, max uint64) (hsMessage, error) {
+	h := hsReader{r: r}
+	return h.next(max)
+}
+
+// hsReader reads messages from a stream that reads can time out on. The
+// part of a message received before a timeout, or an abort, is kept for
+// the next call, so the stream stays in step with the message framing.
+type hsReader struct {
+	r   io.Reader
+	hdr [hsHeaderLen]byte
+	nh  int // header bytes received
+	m   hsMessage
+	np  int // payload bytes received
+}
+
+// next reads a message whose payload is at most max bytes, or the rest of
+// the one an earlier call was interrupted in.
+func (h *hsReader) next(max uint64) (hsMessage, error) {
+	if h.nh < hsHeaderLen {
+		n, err := io.ReadFull(h.r, h.hdr[h.nh:])
+		h.nh += n
+		if err != nil {
+			return hsMessage{}, err
+		}
+		size := binary.BigEndian.Uint64(h.hdr[8:])
+		if h.hdr[0] != 'H' || h.hdr[1] != 'S' || size > max {
+			h.nh = 0
+			return hsMessage{}, errHiSLIPHeader
+		}
+		h.m = hsMessage{typ: h.hdr[2], ctrl: h.hdr[3], param: binary.BigEndian.Uint32(h.hdr[4:])}
+		if size > 0 {
+			h.m.payload = make([]byte, size)
+		}
+		h.np = 0
+	}
+	n, err := io.ReadFull(h.r, h.m.payload[h.np:])
+	h.np += n
+	if err != nil {
+		return hsMessage{}, err
+	}
+	m := h.m
+	h.nh, h.m = 0, hsMessage{}
+	return m, nil
+}
+
+// hsSlack is added to lock timeouts for the round trip.
+const hsSlack = 2 * time.Second
+
+// hislipTransport serves TCPIP::host::hislipN[,port]::INSTR resources.
+// Messages go over the synchronous channel, status queries, device clear,
+// locks and service requests over the asynchronous one.
+type hislipTransport struct {
+	syncConn  net.Conn
+	asyncConn net.Conn
+	rd        hsReader // of the synchronous channel
+	maxMsg    uint64   // largest message the server accepts
+	maxRecv   uint64   // largest message the client accepts
+
+	// The receive state and overlap are guarded by the session's ioMu.
+	overlap bool
+	pend    []byte
+	pendEnd bool
+	loaded  bool
+
+	idMu     sync.Mutex
+	msgID    uint32
+	lastID   uint32
+	rmt      bool
+	clearing bool // a device clear is interrupting the transfers
+
+	asyncMu sync.Mutex
+	resp    chan hsMessage
+	done    chan struct{}
+}
+
+func openHiSLIP(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	host, sub, port := p.fields[0], p.fields[1], strconv.Itoa(hsPort)
+	if i := strings.IndexByte(sub, ','); i >= 0 {
+		sub, port = sub[:i], sub[i+1:]
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return nil, ERROR_INV_RSRC_NAME
+		}
+	}
+	addr := net.JoinHostPort(host, port)
+	deadline := s.deadline()
+	d := net.Dialer{Deadline: deadline}
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		return nil, ERROR_RSRC_NFOUND
+	}
+	t := &hislipTransport{
+		syncConn: conn,
+		rd:       hsReader{r: bufio.NewReader(conn)},
+		maxRecv:  hsMaxMessageKB << 10,
+		msgID:    hsFirstMessageID,
+		lastID:   hsFirstMessageID - 2,
+		resp:     make(chan hsMessage, 1),
+		done:     make(chan struct{}),
+	}
+	version, status := t.initialize(addr, sub, deadline)
+	if status != SUCCESS {
+		t.syncConn.Close()
+		if t.asyncConn != nil {
+			t.asyncConn.Close()
+		}
+		return nil, status
+	}
+	go t.serveAsync(s)
+	if status := t.maxMessage(hsMaxMessageKB, deadline); status != SUCCESS {
+		t.close()
+		return nil, status
+	}
+
+	ip := host
+	if ra, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		ip = ra.IP.String()
+	}
+	s.a.putString(ATTR_TCPIP_ADDR, ip)
+	s.a.putString(ATTR_TCPIP_HOSTNAME, host)
+	s.a.putString(ATTR_TCPIP_DEVICE_NAME, p.fields[1])
+	s.a.put(ATTR_TCPIP_IS_HISLIP, TRUE)
+	s.a.put(ATTR_TCPIP_HISLIP_VERSION, uint64(version))
+	s.a.put(ATTR_TCPIP_HISLIP_OVERLAP_EN, boolState(t.overlap))
+	s.a.put(ATTR_TCPIP_HISLIP_MAX_MESSAGE_KB, hsMaxMessageKB)
+	s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+	return t, SUCCESS
+}
+
+// initialize opens the session on the synchronous channel and attaches
+// the asynchronous one to it. It returns the negotiated protocol version.
+func (t *hislipTransport) initialize(addr, sub string, deadline time.Time) (uint32, Status) {
+	t.syncConn.SetDeadline(deadline)
+	defer t.syncConn.SetDeadline(time.Time{})
+	err := writeHS(t.syncConn, hsMessage{typ: hsInitialize, param: hsVersion<<16 | hsVendorID, payload: []byte(sub)})
+	if err != nil {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	m, err := t.rd.next(t.maxRecv)
+	if err != nil || m.typ != hsInitializeResponse {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	t.overlap = m.ctrl&1 != 0
+	version, session := m.param>>16, m.param&0xFFFF
+
+	d := net.Dialer{Deadline: deadline}
+	if t.asyncConn, err = d.Dial("tcp", addr); err != nil {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	t.asyncConn.SetDeadline(deadline)
+	defer t.asyncConn.SetDeadline(time.Time{})
+	if err := writeHS(t.asyncConn, hsMessage{typ: hsAsyncInitialize, param: session}); err != nil {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	if m, err = readHS(t.asyncConn, hsMaxMessageKB<<10); err != nil || m.typ != hsAsyncInitializeResponse {
+		return 0, ERROR_RSRC_NFOUND
+	}
+	return version, SUCCESS
+}
+
+// serveAsync receives on the asynchronous channel, service requests are
+// posted as EVENT_SERVICE_REQ and responses handed to asyncCall.
+func (t *hislipTransport) serveAsync(s *goSession) {
+	defer close(t.done)
+	for {
+		m, err := readHS(t.asyncConn, hsMaxMessageKB<<10)
+		if err != nil {
+			return
+		}
+		switch m.typ {
+		case hsAsyncServiceRequest:
+			s.postEvent(EVENT_SERVICE_REQ, nil)
+		case hsAsyncInterrupted:
+		default:
+			select {
+			case t.resp <- m:
+			default:
+			}
+		}
+	}
+}
+
+// asyncCall sends req on the asynchronous channel and waits for the
+// response of type want.
+func (t *hislipTransport) asyncCall(req hsMessage, want byte, deadline time.Time) (hsMessage, Status) {
+	t.asyncMu.Lock()
+	defer t.asyncMu.Unlock()
+	select {
+	case <-t.resp: // late response of a timed out call
+	default:
+	}
+	t.asyncConn.SetWriteDeadline(deadline)
+	if err := writeHS(t.asyncConn, req); err != nil {
+		return hsMessage{}, netStatus(err)
+	}
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		tm := time.NewTimer(time.Until(deadline))
+		defer tm.Stop()
+		expired = tm.C
+	}
+	select {
+	case m := <-t.resp:
+		switch m.typ {
+		case want:
+			return m, SUCCESS
+		case hsFatalError:
+			return m, ERROR_CONN_LOST
+		}
+		return m, ERROR_IO
+	case <-t.done:
+		return hsMessage{}, ERROR_CONN_LOST
+	case <-expired:
+		return hsMessage{}, ERROR_TMO
+	}
+}
+
+// maxMessage announces the largest message the client accepts and learns
+// the server's.
+func (t *hislipTransport) maxMessage(kb uint32, deadline time.Time) Status {
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(kb)*1024)
+	m, status := t.asyncCall(hsMessage{typ: hsAsyncMaximumMessageSize, payload: size},
+		hsAsyncMaximumMessageSizeResponse, deadline)
+	if status != SUCCESS {
+		return status
+	}
+	if len(m.payload) != 8 {
+		return ERROR_IO
+	}
+	t.maxMsg, t.maxRecv = binary.BigEndian.Uint64(m.payload), uint64(kb)<<10
+	return SUCCESS
+}
+
+// send writes a message that takes a MessageID on the synchronous channel.
+func (t *hislipTransport) send(s *goSession, typ byte, payload []byte) error {
+	t.idMu.Lock()
+	m := hsMessage{typ: typ, param: t.msgID, payload: payload}
+	if t.rmt {
+		m.ctrl, t.rmt = 1, false
+	}
+	t.lastID = t.msgID
+	t.msgID += 2
+	t.idMu.Unlock()
+	t.syncConn.SetWriteDeadline(s.deadline())
+	return writeHS(t.syncConn, m)
+}
+
+func (t *hislipTransport) setAttribute(s *goSession, attr uint32, state uint64) Status {
+	switch attr {
+	case ATTR_TCPIP_HISLIP_OVERLAP_EN:
+		return t.deviceClear(s, state == TRUE)
+	case ATTR_TCPIP_HISLIP_MAX_MESSAGE_KB:
+		if state == 0 {
+			return ERROR_NSUP_ATTR_STATE
+		}
+		s.ioMu.Lock()
+		defer s.ioMu.Unlock()
+		return t.maxMessage(uint32(state), s.deadline())
+	}
+	return SUCCESS
+}
+
+// read fills buf from Data and DataEnd messages, a read ends with the
+// DataEnd message. In synchronized mode only the response to the most
+// recent message counts. A device clear makes it fail with ERROR_ABORT.
+func (t *hislipTransport) read(s *goSession, buf []byte) (int, Status) {
+	t.syncConn.SetReadDeadline(s.deadline())
+	if t.isClearing() {
+		return 0, ERROR_ABORT
+	}
+	term, termEn := s.termChar()
+	n := 0
+	for n < len(buf) {
+		if !t.loaded {
+			if status := t.receive(); status != SUCCESS {
+				if status == ERROR_TMO && t.isClearing() {
+					status = ERROR_ABORT
+				}
+				return n, status
+			}
+		}
+		chunk, found := t.pend, false
+		if len(chunk) > len(buf)-n {
+			chunk = chunk[:len(buf)-n]
+		}
+		if termEn {
+			if i := bytes.IndexByte(chunk, term); i >= 0 {
+				chunk, found = chunk[:i+1], true
+			}
+		}
+		n += copy(buf[n:], chunk)
+		t.pend = t.pend[len(chunk):]
+		if len(t.pend) == 0 {
+			t.loaded = false
+			if t.pendEnd {
+				t.idMu.Lock()
+				t.rmt = true
+				t.idMu.Unlock()
+				return n, SUCCESS
+			}
+		}
+		if found {
+			return n, SUCCESS_TERM_CHAR
+		}
+	}
+	return n, SUCCESS_MAX_CNT
+}
+
+// receive loads the next data message of the synchronous channel. If it
+// times out partway through a message, the next call picks up the rest.
+func (t *hislipTransport) receive() Status {
+	for {
+		m, err := t.rd.next(t.maxRecv)
+		if err != nil {
+			return netStatus(err)
+		}
+		switch m.typ {
+		case hsData, hsDataEnd:
+			t.idMu.Lock()
+			stale := !t.overlap && m.param != t.lastID
+			t.idMu.Unlock()
+			if stale {
+				continue
+			}
+			t.pend, t.pendEnd, t.loaded = m.payload, m.typ == hsDataEnd, true
+			return SUCCESS
+		case hsFatalError:
+			return ERROR_CONN_LOST
+		case hsError:
+			return ERROR_IO
+		}
+	}
+}
+
+// write sends buf in messages no larger than the server accepts, the
+// last one is DataEnd if ATTR_SEND_END_EN is set.
+func (t *hislipTransport) write(s *goSession, buf []byte) (int, Status) {
+	if !t.overlap {
+		t.pend, t.loaded = nil, false
+	}
+	n := 0
+	for {
+		chunk, last := buf[n:], true
+		if t.maxMsg > 0 && uint64(len(chunk)) > t.maxMsg {
+			chunk, last = chunk[:int(t.maxMsg)], false
+		}
+		typ := byte(hsData)
+		if last && s.sendEnd() {
+			typ = hsDataEnd
+		}
+		if err := t.send(s, typ, chunk); err != nil {
+			return n, netStatus(err)
+		}
+		n += len(chunk)
+		if last {
+			return n, SUCCESS
+		}
+	}
+}
+
+func (t *hislipTransport) assertTrigger(s *goSession, protocol uint16) Status {
+	if protocol != TRIG_PROT_DEFAULT {
+		return ERROR_INV_PROT
+	}
+	return netStatus(t.send(s, hsTrigger, nil))
+}
+
+// readSTB uses AsyncStatusQuery, so it doesn't wait for pending I/O.
+func (t *hislipTransport) readSTB(s *goSession) (uint16, Status) {
+	t.idMu.Lock()
+	req := hsMessage{typ: hsAsyncStatusQuery, param: t.lastID}
+	if t.rmt {
+		req.ctrl, t.rmt = 1, false
+	}
+	t.idMu.Unlock()
+	m, status := t.asyncCall(req, hsAsyncStatusResponse, s.deadline())
+	if status != SUCCESS {
+		return 0, status
+	}
+	return uint16(m.ctrl), SUCCESS
+}
+
+func (t *hislipTransport) clear(s *goSession) Status {
+	return t.deviceClear(s, s.a.num(ATTR_TCPIP_HISLIP_OVERLAP_EN) == TRUE)
+}
+
+// isClearing reports whether a device clear is in progress.
+func (t *hislipTransport) isClearing() bool {
+	t.idMu.Lock()
+	defer t.idMu.Unlock()
+	return t.clearing
+}
+
+// deviceClear runs the device clear handshake, requesting overlapped or
+// synchronized mode. A read waiting on the synchronous channel is
+// interrupted, and everything the channel delivers before the
+// acknowledgement is discarded.
+func (t *hislipTransport) deviceClear(s *goSession, overlap bool) Status {
+	deadline := s.deadline()
+	if _, status := t.asyncCall(hsMessage{typ: hsAsyncDeviceClear},
+		hsAsyncDeviceClearAcknowledge, deadline); status != SUCCESS {
+		return status
+	}
+
+	t.idMu.Lock()
+	t.clearing = true
+	t.idMu.Unlock()
+	t.syncConn.SetReadDeadline(time.Now())
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	t.idMu.Lock()
+	t.clearing = false
+	t.idMu.Unlock()
+
+	var req byte
+	if overlap {
+		req = 1
+	}
+	t.syncConn.SetDeadline(deadline)
+	defer t.syncConn.SetDeadline(time.Time{})
+	if err := writeHS(t.syncConn, hsMessage{typ: hsDeviceClearComplete, ctrl: req}); err != nil {
+		return netStatus(err)
+	}
+	for {
+		m, err := t.rd.next(t.maxRecv)
+		if err != nil {
+			return netStatus(err)
+		}
+		if m.typ == hsDeviceClearAcknowledge {
+			t.overlap = m.ctrl&1 != 0
+			break
+		}
+	}
+	t.pend, t.pendEnd, t.loaded = nil, false, false
+	t.idMu.Lock()
+	t.msgID, t.lastID, t.rmt = hsFirstMessageID, hsFirstMessageID-2, false
+	t.idMu.Unlock()
+	s.a.put(ATTR_TCPIP_HISLIP_OVERLAP_EN, boolState(t.overlap))
+	if t.overlap != overlap {
+		return ERROR_NSUP_ATTR_STATE
+	}
+	return SUCCESS
+}
+
+// lock requests an exclusive lock, or a shared lock under key.
+func (t *hislipTransport) lock(s *goSession, lockType, timeout uint32, key string) Status {
+	req := hsMessage{typ: hsAsyncLock, ctrl: 1, param: timeout}
+	if lockType == SHARED_LOCK {
+		req.payload = []byte(key)
+	}
+	var deadline time.Time
+	if timeout != TMO_INFINITE {
+		deadline = time.Now().Add(time.Duration(timeout)*time.Millisecond + hsSlack)
+	}
+	m, status := t.asyncCall(req, hsAsyncLockResponse, deadline)
+	switch {
+	case status != SUCCESS:
+		return status
+	case m.ctrl == hsLockSuccess:
+		return SUCCESS
+	case m.ctrl == hsLockFailed && timeout != TMO_IMMEDIATE:
+		return ERROR_TMO
+	}
+	return ERROR_RSRC_LOCKED
+}
+
+// unlock releases the exclusive lock if held, else the shared one.
+func (t *hislipTransport) unlock(s *goSession) Status {
+	t.idMu.Lock()
+	req := hsMessage{typ: hsAsyncLock, param: t.lastID}
+	t.idMu.Unlock()
+	m, status := t.asyncCall(req, hsAsyncLockResponse, time.Now().Add(hsSlack))
+	switch {
+	case status != SUCCESS:
+		return status
+	case m.ctrl == hsLockSuccess, m.ctrl == hsLockSharedReleased:
+		return SUCCESS
+	}
+	return ERROR_SESN_NLOCKED
+}
+
+// abort unblocks a transfer in progress, it fails with ERROR_TMO.
+func (t *hislipTransport) abort(s *goSession) {
+	t.syncConn.SetDeadline(time.Now())
+}
+
+func (t *hislipTransport) close() Status {
+	t.asyncConn.Close()
+	if err := t.syncConn.Close(); err != nil {
+		return ERROR_CLOSING_FAILED
+	}
+	<-t.done
+	return SUCCESS
+}
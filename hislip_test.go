@@ -0,0 +1,397 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHiSLIP is an in-process HiSLIP server in synchronized mode. Queries
+// are answered from replies, in messages of at most chunk bytes.
+type fakeHiSLIP struct {
+	t       *testing.T
+	ln      net.Listener
+	maxMsg  uint64 // reported by AsyncMaximumMessageSize
+	chunk   int
+	replies map[string]string
+
+	mu       sync.Mutex
+	sub      string // sub-address sent with Initialize
+	session  uint32 // sent with AsyncInitialize
+	msgs     []hsMessage
+	locked   bool // locked by another client
+	keys     []string
+	clears   int
+	syncConn net.Conn
+	async    net.Conn
+}
+
+func startHiSLIP(t *testing.T) *fakeHiSLIP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeHiSLIP{t: t, ln: ln, maxMsg: 1 << 20, chunk: 1 << 20, replies: make(map[string]string)}
+	go f.serve()
+	return f
+}
+
+// name returns the resource name of the server.
+func (f *fakeHiSLIP) name() string {
+	return "TCPIP0::127.0.0.1::hislip0," + strconv.Itoa(f.ln.Addr().(*net.TCPAddr).Port) + "::INSTR"
+}
+
+func (f *fakeHiSLIP) serve() {
+	syncConn, err := f.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer syncConn.Close()
+	rd := bufio.NewReader(syncConn)
+	m, err := readHS(rd, 1<<20)
+	if err != nil || m.typ != hsInitialize {
+		f.t.Errorf("first message = %d, %v, want Initialize", m.typ, err)
+		return
+	}
+	f.mu.Lock()
+	f.sub, f.syncConn = string(m.payload), syncConn
+	f.mu.Unlock()
+	writeHS(syncConn, hsMessage{typ: hsInitializeResponse, param: hsVersion<<16 | 7})
+
+	async, err := f.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer async.Close()
+	m, err = readHS(async, 1<<20)
+	if err != nil || m.typ != hsAsyncInitialize {
+		f.t.Errorf("first async message = %d, %v, want AsyncInitialize", m.typ, err)
+		return
+	}
+	f.mu.Lock()
+	f.session, f.async = m.param, async
+	f.mu.Unlock()
+	writeHS(async, hsMessage{typ: hsAsyncInitializeResponse})
+	go f.serveAsync(async)
+
+	var msg []byte
+	for {
+		m, err := readHS(rd, 1<<20)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.msgs = append(f.msgs, m)
+		f.mu.Unlock()
+		switch m.typ {
+		case hsData, hsDataEnd:
+			msg = append(msg, m.payload...)
+			if m.typ == hsDataEnd {
+				if reply, ok := f.replies[string(msg)]; ok {
+					f.reply(syncConn, m.param, reply)
+				}
+				msg = nil
+			}
+		case hsDeviceClearComplete:
+			msg = nil
+			writeHS(syncConn, hsMessage{typ: hsDeviceClearAcknowledge})
+		}
+	}
+}
+
+// reply sends data in Data messages and a final DataEnd one.
+func (f *fakeHiSLIP) reply(c net.Conn, id uint32, data string) {
+	for len(data) > f.chunk {
+		writeHS(c, hsMessage{typ: hsData, param: id, payload: []byte(data[:f.chunk])})
+		data = data[f.chunk:]
+	}
+	writeHS(c, hsMessage{typ: hsDataEnd, param: id, payload: []byte(data)})
+}
+
+func (f *fakeHiSLIP) serveAsync(c net.Conn) {
+	for {
+		m, err := readHS(c, 1<<20)
+		if err != nil {
+			return
+		}
+		switch m.typ {
+		case hsAsyncMaximumMessageSize:
+			size := binary.BigEndian.AppendUint64(nil, f.maxMsg)
+			writeHS(c, hsMessage{typ: hsAsyncMaximumMessageSizeResponse, payload: size})
+		case hsAsyncStatusQuery:
+			writeHS(c, hsMessage{typ: hsAsyncStatusResponse, ctrl: 0x50})
+		case hsAsyncDeviceClear:
+			f.mu.Lock()
+			f.clears++
+			f.mu.Unlock()
+			writeHS(c, hsMessage{typ: hsAsyncDeviceClearAcknowledge})
+		case hsAsyncLock:
+			f.mu.Lock()
+			ctrl := byte(hsLockSuccess)
+			if m.ctrl == 1 {
+				f.keys = append(f.keys, string(m.payload))
+				if f.locked {
+					ctrl = hsLockFailed
+				}
+			}
+			f.mu.Unlock()
+			writeHS(c, hsMessage{typ: hsAsyncLockResponse, ctrl: ctrl})
+		}
+	}
+}
+
+// sent returns the messages received on the synchronous channel.
+func (f *fakeHiSLIP) sent() []hsMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]hsMessage(nil), f.msgs...)
+}
+
+func openHiSLIPTest(t *testing.T, f *fakeHiSLIP) Object {
+	t.Helper()
+	rm := openTestRM(t)
+	instr, status := rm.Open(f.name(), NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open: %v", status)
+	}
+	return instr
+}
+
+func TestHiSLIPInitialize(t *testing.T) {
+	f := startHiSLIP(t)
+	instr := openHiSLIPTest(t, f)
+
+	if f.sub != "hislip0" || f.session != 7 {
+		t.Errorf("Initialize sub-address %q, AsyncInitialize session %d, want hislip0 and 7", f.sub, f.session)
+	}
+	if on, err := instr.AttrBool(ATTR_TCPIP_IS_HISLIP); err != nil || !on {
+		t.Errorf("ATTR_TCPIP_IS_HISLIP = %v, %v", on, err)
+	}
+	if v, err := instr.AttrUint32(ATTR_TCPIP_HISLIP_VERSION); err != nil || v != hsVersion {
+		t.Errorf("ATTR_TCPIP_HISLIP_VERSION = %#x, %v", v, err)
+	}
+	if on, err := instr.AttrBool(ATTR_TCPIP_HISLIP_OVERLAP_EN); err != nil || on {
+		t.Errorf("ATTR_TCPIP_HISLIP_OVERLAP_EN = %v, %v, want synchronized mode", on, err)
+	}
+	if stb, status := instr.ReadSTB(); status != SUCCESS || stb != 0x50 {
+		t.Errorf("ReadSTB = %#x, %v", stb, status)
+	}
+}
+
+func TestHiSLIPDataEnd(t *testing.T) {
+	f := startHiSLIP(t)
+	f.maxMsg, f.chunk = 4, 3
+	f.replies["*IDN?\n"] = "ACME,X,1\nrest"
+	instr := openHiSLIPTest(t, f)
+
+	if n, status := instr.Write([]byte("*IDN?\n"), 6); status != SUCCESS || n != 6 {
+		t.Fatalf("Write = %d, %v", n, status)
+	}
+	instr.SetAttribute(ATTR_TERMCHAR_EN, TRUE)
+	buf, n, status := instr.Read(100)
+	if status != SUCCESS_TERM_CHAR || string(buf[:n]) != "ACME,X,1\n" {
+		t.Errorf("Read = %q, %v, want \"ACME,X,1\\n\", SUCCESS_TERM_CHAR", buf[:n], status)
+	}
+	buf, n, status = instr.Read(100)
+	if status != SUCCESS || string(buf[:n]) != "rest" {
+		t.Errorf("Read = %q, %v, want \"rest\", SUCCESS", buf[:n], status)
+	}
+
+	msgs := f.sent()
+	if len(msgs) != 2 || msgs[0].typ != hsData || msgs[1].typ != hsDataEnd ||
+		string(msgs[0].payload) != "*IDN" || msgs[0].param != hsFirstMessageID || msgs[1].param != hsFirstMessageID+2 {
+		t.Errorf("messages sent = %+v, want Data and DataEnd of at most 4 bytes", msgs)
+	}
+
+	// Without END the message goes out as Data only, and the device
+	// doesn't answer.
+	instr.SetAttribute(ATTR_SEND_END_EN, FALSE)
+	instr.Write([]byte("*IDN?\n"), 6)
+	instr.SetAttribute(ATTR_TMO_VALUE, 100)
+	if _, _, status := instr.Read(100); status != ERROR_TMO {
+		t.Errorf("Read without END sent: %v, want ERROR_TMO", status)
+	}
+	if msgs := f.sent(); msgs[len(msgs)-1].typ != hsData {
+		t.Errorf("last message type %d, want Data", msgs[len(msgs)-1].typ)
+	}
+}
+
+func TestHiSLIPStaleResponse(t *testing.T) {
+	f := startHiSLIP(t)
+	f.replies["A?\n"] = "a\n"
+	instr := openHiSLIPTest(t, f)
+
+	// Responses to other messages than the last one are discarded in
+	// synchronized mode.
+	f.mu.Lock()
+	writeHS(f.syncConn, hsMessage{typ: hsDataEnd, param: 5, payload: []byte("stale\n")})
+	f.mu.Unlock()
+	instr.Write([]byte("A?\n"), 3)
+	buf, n, status := instr.Read(100)
+	if status != SUCCESS || string(buf[:n]) != "a\n" {
+		t.Errorf("Read = %q, %v, want \"a\\n\", SUCCESS", buf[:n], status)
+	}
+}
+
+func TestHiSLIPPartialMessage(t *testing.T) {
+	f := startHiSLIP(t)
+	f.replies["Q2?\n"] = "second\n"
+	instr := openHiSLIPTest(t, f)
+	instr.SetAttribute(ATTR_TMO_VALUE, 100)
+	send := func(b []byte) {
+		f.mu.Lock()
+		f.syncConn.Write(b)
+		f.mu.Unlock()
+	}
+	message := func(id uint32, data string) []byte {
+		var b bytes.Buffer
+		writeHS(&b, hsMessage{typ: hsDataEnd, param: id, payload: []byte(data)})
+		return b.Bytes()
+	}
+
+	// A read timing out in the middle of the header or the payload is
+	// picked up by the next one.
+	instr.Write([]byte("Q1?\n"), 4)
+	first := message(hsFirstMessageID, "first\n")
+	for _, part := range [][]byte{first[:10], first[10:19]} {
+		send(part)
+		if _, _, status := instr.Read(100); status != ERROR_TMO {
+			t.Fatalf("Read of part of a message: %v, want ERROR_TMO", status)
+		}
+	}
+	send(first[19:])
+	if buf, n, status := instr.Read(100); status != SUCCESS || string(buf[:n]) != "first\n" {
+		t.Errorf("Read of the rest of the message = %q, %v, want \"first\\n\"", buf[:n], status)
+	}
+
+	// The rest of an abandoned response is discarded with it.
+	stale := message(hsFirstMessageID, "stale\n")
+	send(stale[:18])
+	if _, _, status := instr.Read(100); status != ERROR_TMO {
+		t.Fatalf("Read of part of a message: %v, want ERROR_TMO", status)
+	}
+	send(stale[18:])
+	instr.Write([]byte("Q2?\n"), 4)
+	if buf, n, status := instr.Read(100); status != SUCCESS || string(buf[:n]) != "second\n" {
+		t.Errorf("Read after an interrupted message = %q, %v, want \"second\\n\"", buf[:n], status)
+	}
+}
+
+func TestHiSLIPLock(t *testing.T) {
+	f := startHiSLIP(t)
+	instr := openHiSLIPTest(t, f)
+
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, TMO_IMMEDIATE); status != SUCCESS {
+		t.Fatalf("LockExclusive: %v", status)
+	}
+	if status := instr.Unlock(); status != SUCCESS {
+		t.Errorf("Unlock: %v", status)
+	}
+	key, status := instr.Lock(SHARED_LOCK, TMO_IMMEDIATE, "bench")
+	if status != SUCCESS || key != "bench" {
+		t.Errorf("shared Lock = %q, %v", key, status)
+	}
+	instr.Unlock()
+	if len(f.keys) != 2 || f.keys[0] != "" || f.keys[1] != "bench" {
+		t.Errorf("lock keys sent = %q, want \"\" and \"bench\"", f.keys)
+	}
+
+	f.mu.Lock()
+	f.locked = true
+	f.mu.Unlock()
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, TMO_IMMEDIATE); status != ERROR_RSRC_LOCKED {
+		t.Errorf("LockExclusive of a locked device: %v, want ERROR_RSRC_LOCKED", status)
+	}
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, 100); status != ERROR_TMO {
+		t.Errorf("LockExclusive with a timeout: %v, want ERROR_TMO", status)
+	}
+	if state, _ := instr.AttrUint32(ATTR_RSRC_LOCK_STATE); state != NO_LOCK {
+		t.Errorf("ATTR_RSRC_LOCK_STATE after refused locks = %d, want NO_LOCK", state)
+	}
+}
+
+func TestHiSLIPClearPendingRead(t *testing.T) {
+	f := startHiSLIP(t)
+	f.replies["B?\n"] = "b\n"
+	instr := openHiSLIPTest(t, f)
+	instr.SetAttribute(ATTR_TMO_VALUE, 5000)
+
+	instr.Write([]byte("HANG?\n"), 6)
+	done := make(chan Status)
+	go func() {
+		_, _, status := instr.Read(100)
+		done <- status
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if status := instr.Clear(); status != SUCCESS {
+		t.Fatalf("Clear: %v", status)
+	}
+	select {
+	case status := <-done:
+		if status != ERROR_ABORT {
+			t.Errorf("pending Read: %v, want ERROR_ABORT", status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Clear didn't interrupt the pending Read")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Clear took %v, waiting for the Read's timeout", d)
+	}
+	if f.clears != 1 {
+		t.Errorf("AsyncDeviceClear sent %d times", f.clears)
+	}
+
+	// The message IDs start over after the clear.
+	instr.Write([]byte("B?\n"), 3)
+	buf, n, status := instr.Read(100)
+	if status != SUCCESS || string(buf[:n]) != "b\n" {
+		t.Errorf("Read after Clear = %q, %v", buf[:n], status)
+	}
+	msgs := f.sent()
+	if last := msgs[len(msgs)-1]; last.param != hsFirstMessageID {
+		t.Errorf("message ID after Clear = %#x, want %#x", last.param, uint32(hsFirstMessageID))
+	}
+}
+
+func TestHiSLIPServiceRequest(t *testing.T) {
+	f := startHiSLIP(t)
+	instr := openHiSLIPTest(t, f)
+
+	if status := instr.EnableEvent(EVENT_SERVICE_REQ, QUEUE, NULL); status != SUCCESS {
+		t.Fatalf("EnableEvent: %v", status)
+	}
+	f.mu.Lock()
+	writeHS(f.async, hsMessage{typ: hsAsyncServiceRequest, ctrl: 0x40})
+	f.mu.Unlock()
+	e, err := instr.WaitEvent(EVENT_SERVICE_REQ, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(ServiceRequestEvent); !ok {
+		t.Errorf("event = %T, want ServiceRequestEvent", e)
+	}
+}
+
+func TestReadHSLimit(t *testing.T) {
+	var b bytes.Buffer
+	writeHS(&b, hsMessage{typ: hsDataEnd, payload: []byte("12345")})
+	if _, err := readHS(bytes.NewReader(b.Bytes()), 4); err != errHiSLIPHeader {
+		t.Errorf("readHS of an oversized message: %v, want errHiSLIPHeader", err)
+	}
+	m, err := readHS(bytes.NewReader(b.Bytes()), 5)
+	if err != nil || m.typ != hsDataEnd || string(m.payload) != "12345" {
+		t.Errorf("readHS = %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+// Package ieee4882 implements the IEEE 488.2 common commands, which most
+// message based instruments support, on any VISA session. Instrument
+// drivers embed Device to get them:
+//
+//	type Driver struct {
+//		ieee4882.Device
+//	}
+//
+//	d := &Driver{ieee4882.Device{Driver: instr}}
+//	id, err := d.Identify()
+package ieee4882
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// Device sends the common commands to a session, or any other vi.Driver.
+type Device struct {
+	vi.Driver
+
+	// Terminator is appended to every command, e.g. "\n" for SOCKET
+	// resources, which have no END indicator.
+	Terminator string
+
+	// CheckEvery turns error checking on when it's set: the error queue
+	// is drained after every CheckEvery commands, and an error returned
+	// if it wasn't empty. Checking after every command finds the one at
+	// fault, checking less often costs fewer queries.
+	CheckEvery int
+
+	// OPCStrategy is how Settle waits for pending operations.
+	OPCStrategy OPCStrategy
+
+	sent []string // commands since the last check
+	err  error    // found by the last check
+}
+
+// Identity is the response to *IDN?.
+type Identity struct {
+	Manufacturer string
+	Model        string
+	Serial       string // "0" if the device doesn't report one
+	Firmware     string
+}
+
+// String returns the identity in the *IDN? response format.
+func (id Identity) String() string {
+	return strings.Join([]string{id.Manufacturer, id.Model, id.Serial, id.Firmware}, ",")
+}
+
+// ParseIdentity parses a *IDN? response. Fields the device left out are
+// empty, the firmware field keeps any commas following it.
+func ParseIdentity(resp string) (Identity, error) {
+	resp = strings.TrimSpace(resp)
+	if resp == "" {
+		return Identity{}, errors.New("ieee4882: empty identity")
+	}
+	f := strings.SplitN(resp, ",", 4)
+	for len(f) < 4 {
+		f = append(f, "")
+	}
+	for i := range f {
+		f[i] = strings.TrimSpace(f[i])
+	}
+	return Identity{f[0], f[1], f[2], f[3]}, nil
+}
+
+// Identify queries *IDN? and returns the parsed identity.
+func (d *Device) Identify() (Identity, error) {
+	resp, err := d.query("*IDN?")
+	if err != nil {
+		return Identity{}, err
+	}
+	return ParseIdentity(resp)
+}
+
+// Reset sends *RST, returning the device to its default settings.
+func (d *Device) Reset() error {
+	return d.send("*RST")
+}
+
+// ClearStatus sends *CLS, clearing the status data structures and the
+// error queue.
+func (d *Device) ClearStatus() error {
+	return d.send("*CLS")
+}
+
+// OPC sends *OPC, which sets the operation complete bit of the standard
+// event status register once the pending operations are done.
+func (d *Device) OPC() error {
+	return d.send("*OPC")
+}
+
+// OPCQuery sends *OPC?, which the device answers once the pending
+// operations are done. The query fails with ERROR_TMO if that takes longer
+// than the session's timeout.
+func (d *Device) OPCQuery() error {
+	_, err := d.query("*OPC?")
+	return err
+}
+
+// Wait sends *WAI, which makes the device finish the pending operations
+// before it executes further commands.
+func (d *Device) Wait() error {
+	return d.send("*WAI")
+}
+
+// Trigger sends *TRG, the bus trigger command.
+func (d *Device) Trigger() error {
+	return d.send("*TRG")
+}
+
+// SelfTest runs the self test with *TST? and returns its result, 0 if it
+// passed.
+func (d *Device) SelfTest() (int, error) {
+	resp, err := d.query("*TST?")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(resp, "+"))
+	if err != nil {
+		return 0, &vi.QueryError{Cmd: "*TST?", Err: err}
+	}
+	return n, nil
+}
+
+// EventStatus reads and clears the standard event status register with
+// *ESR?.
+func (d *Device) EventStatus() (EventStatus, error) {
+	v, err := d.queryByte("*ESR?")
+	return EventStatus(v), err
+}
+
+// EventStatusEnable returns the standard event status enable register.
+func (d *Device) EventStatusEnable() (EventStatus, error) {
+	v, err := d.queryByte("*ESE?")
+	return EventStatus(v), err
+}
+
+// SetEventStatusEnable sets the standard event status enable register with
+// *ESE.
+func (d *Device) SetEventStatusEnable(mask EventStatus) error {
+	return d.send(fmt.Sprintf("*ESE %d", mask))
+}
+
+// ServiceRequestEnable returns the service request enable register.
+func (d *Device) ServiceRequestEnable() (StatusByte, error) {
+	v, err := d.queryByte("*SRE?")
+	return StatusByte(v), err
+}
+
+// SetServiceRequestEnable sets the service request enable register with
+// *SRE.
+func (d *Device) SetServiceRequestEnable(mask StatusByte) error {
+	return d.send(fmt.Sprintf("*SRE %d", mask))
+}
+
+// StatusByte reads the status byte with *STB?. Unlike a serial poll it
+// doesn't clear RQS, bit 6 is MSS instead.
+func (d *Device) StatusByte() (StatusByte, error) {
+	v, err := d.queryByte("*STB?")
+	return StatusByte(v), err
+}
+
+// Options returns the installed options reported by *OPT?, without the
+// "0" entries of absent ones.
+func (d *Device) Options() ([]string, error) {
+	resp, err := d.query("*OPT?")
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, o := range strings.Split(resp, ",") {
+		o = strings.TrimSpace(o)
+		o = strings.Trim(o, `"`)
+		if o != "" && o != "0" {
+			installed = append(installed, o)
+		}
+	}
+	return installed, nil
+}
+
+// Recall restores the settings saved in register n with *RCL.
+func (d *Device) Recall(n int) error {
+	return d.send(fmt.Sprintf("*RCL %d", n))
+}
+
+// Save saves the current settings to register n with *SAV.
+func (d *Device) Save(n int) error {
+	return d.send(fmt.Sprintf("*SAV %d", n))
+}
+
+// cmd returns cmd with the terminator.
+func (d *Device) cmd(cmd string) string {
+	return cmd + d.Terminator
+}
+
+// send writes cmd and checks the error queue if it's due.
+func (d *Device) send(cmd string) error {
+	if _, err := vi.NewStream(d.Driver).WriteString(d.cmd(cmd)); err != nil {
+		return err
+	}
+	return d.check(cmd)
+}
+
+// query writes cmd, returns the response and checks the error queue if
+// it's due.
+func (d *Device) query(cmd string) (string, error) {
+	resp, err := vi.Query(d.Driver, d.cmd(cmd))
+	if err != nil {
+		return "", err
+	}
+	return resp, d.check(cmd)
+}
+
+// queryByte writes cmd and returns the response as a register value.
+func (d *Device) queryByte(cmd string) (uint8, error) {
+	resp, err := d.query(cmd)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(resp, "+"), 10, 8)
+	if err != nil {
+		return 0, &vi.QueryError{Cmd: cmd, Err: err}
+	}
+	return uint8(n), nil
+}
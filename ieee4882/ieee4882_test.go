@@ -0,0 +1,270 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// fakeDevice is a vi.Driver emulating an IEEE 488.2 instrument. It keeps
+// the status registers and the SCPI error queue and answers the common
+// queries from them, other queries with their entry in resp. The commands
+// in bad add their entry to the error queue. Reading with nothing to
+// answer times out.
+type fakeDevice struct {
+	resp     map[string]string
+	bad      map[string]string
+	opcDelay time.Duration // until *OPC sets OPC, forever if negative
+	changed  func()        // called when the device sets status bits itself
+
+	mu      sync.Mutex
+	cmds    []string // received, with their terminators
+	pending []byte
+	errs    []string
+	esr     EventStatus
+	ese     EventStatus
+	sre     StatusByte
+	stb     StatusByte  // bits set other than the summaries ESB, EAV and MSS
+	polls   []time.Time // of the status byte reads
+}
+
+func (f *fakeDevice) Close() vi.Status { return vi.SUCCESS }
+
+func (f *fakeDevice) Write(buf []byte, cnt uint32) (uint32, vi.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cmds = append(f.cmds, string(buf[:cnt]))
+	cmd := strings.TrimRight(string(buf[:cnt]), "\r\n")
+	f.pending = nil
+	switch {
+	case cmd == "*ESR?":
+		f.answer(strconv.Itoa(int(f.esr)))
+		f.esr = 0
+	case cmd == "*ESE?":
+		f.answer(strconv.Itoa(int(f.ese)))
+	case cmd == "*SRE?":
+		f.answer(strconv.Itoa(int(f.sre)))
+	case cmd == "*STB?":
+		f.answer(strconv.Itoa(int(f.readSTB())))
+	case strings.HasPrefix(cmd, "*ESE "):
+		n, _ := strconv.Atoi(cmd[5:])
+		f.ese = EventStatus(n)
+	case strings.HasPrefix(cmd, "*SRE "):
+		n, _ := strconv.Atoi(cmd[5:])
+		f.sre = StatusByte(n)
+	case cmd == "*OPC?":
+		if f.opcDelay >= 0 {
+			f.answer("1")
+		}
+	case cmd == "*OPC":
+		f.startOPC()
+	case cmd == "*CLS":
+		f.esr, f.errs = 0, nil
+	case cmd == "SYST:ERR?":
+		if len(f.errs) == 0 {
+			f.answer(`+0,"No error"`)
+			break
+		}
+		f.answer(f.errs[0])
+		f.errs = f.errs[1:]
+	default:
+		if e, ok := f.bad[cmd]; ok {
+			f.errs = append(f.errs, e)
+		}
+		if r, ok := f.resp[cmd]; ok {
+			f.answer(r)
+		}
+	}
+	return cnt, vi.SUCCESS
+}
+
+func (f *fakeDevice) Read(cnt uint32) ([]byte, uint32, vi.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.pending) == 0 {
+		return nil, 0, vi.ERROR_TMO
+	}
+	n := len(f.pending)
+	if n > int(cnt) {
+		n = int(cnt)
+	}
+	b := f.pending[:n]
+	f.pending = f.pending[n:]
+	if len(f.pending) > 0 {
+		return b, uint32(n), vi.SUCCESS_MAX_CNT
+	}
+	return b, uint32(n), vi.SUCCESS
+}
+
+// answer makes resp the response to read.
+func (f *fakeDevice) answer(resp string) {
+	f.pending = []byte(resp + "\n")
+}
+
+// status returns the status byte.
+func (f *fakeDevice) status() StatusByte {
+	stb := f.stb &^ (ESB | EAV | MSS)
+	if f.esr&f.ese != 0 {
+		stb |= ESB
+	}
+	if len(f.errs) > 0 {
+		stb |= EAV
+	}
+	if stb&f.sre != 0 {
+		stb |= MSS
+	}
+	return stb
+}
+
+// readSTB returns the status byte and records the read.
+func (f *fakeDevice) readSTB() StatusByte {
+	f.polls = append(f.polls, time.Now())
+	return f.status()
+}
+
+// startOPC sets OPC once the pending operations are done, after
+// opcDelay.
+func (f *fakeDevice) startOPC() {
+	switch {
+	case f.opcDelay == 0:
+		f.esr |= OPC
+	case f.opcDelay > 0:
+		time.AfterFunc(f.opcDelay, func() { f.set(OPC, 0) })
+	}
+}
+
+// set sets events in the event status register and bits in the status
+// byte, as the device does on its own.
+func (f *fakeDevice) set(events EventStatus, bits StatusByte) {
+	f.mu.Lock()
+	f.esr |= events
+	f.stb |= bits
+	changed := f.changed
+	f.mu.Unlock()
+	if changed != nil {
+		changed()
+	}
+}
+
+// sent returns the commands received.
+func (f *fakeDevice) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.cmds...)
+}
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		resp string
+		id   Identity
+	}{
+		{"ACME,Model 7,1234,1.2.3\n", Identity{"ACME", "Model 7", "1234", "1.2.3"}},
+		{" ACME , 7 , 0 , A.01 ", Identity{"ACME", "7", "0", "A.01"}},
+		{"ACME,7,0,fw 1.2, build 5,beta", Identity{"ACME", "7", "0", "fw 1.2, build 5,beta"}},
+		{"ACME,7", Identity{"ACME", "7", "", ""}},
+		{"ACME", Identity{Manufacturer: "ACME"}},
+		{"ACME,,,", Identity{Manufacturer: "ACME"}},
+	}
+	for _, tt := range tests {
+		if id, err := ParseIdentity(tt.resp); err != nil || id != tt.id {
+			t.Errorf("ParseIdentity(%q) = %+v, %v, want %+v", tt.resp, id, err, tt.id)
+		}
+	}
+	if _, err := ParseIdentity(" \r\n"); err == nil {
+		t.Error("ParseIdentity of an empty response succeeded")
+	}
+	id := Identity{"ACME", "7", "0", "1.2, build 5"}
+	if got, _ := ParseIdentity(id.String()); got != id {
+		t.Errorf("ParseIdentity(%q) = %+v, want %+v", id.String(), got, id)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	f := &fakeDevice{resp: map[string]string{"*IDN?": "ACME,7,1234,1.0"}}
+	d := &Device{Driver: f, Terminator: "\n"}
+	if id, err := d.Identify(); err != nil || id != (Identity{"ACME", "7", "1234", "1.0"}) {
+		t.Errorf("Identify = %+v, %v", id, err)
+	}
+	d.Reset()
+	d.Save(3)
+	if want := []string{"*IDN?\n", "*RST\n", "*SAV 3\n"}; !reflect.DeepEqual(f.sent(), want) {
+		t.Errorf("the device received %q, want %q", f.sent(), want)
+	}
+
+	// A device that doesn't answer.
+	d = &Device{Driver: &fakeDevice{}}
+	if _, err := d.Identify(); !errors.Is(err, vi.Status(vi.ERROR_TMO)) {
+		t.Errorf("Identify without a response: %v, want ERROR_TMO", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		resp string
+		opts []string
+	}{
+		{`"B25,P03,EA3"`, []string{"B25", "P03", "EA3"}},
+		{"B25, P03 ,EA3", []string{"B25", "P03", "EA3"}},
+		{`"B25","0",0,"EA3"`, []string{"B25", "EA3"}},
+		{"0", nil},
+		{`""`, nil},
+	}
+	for _, tt := range tests {
+		d := &Device{Driver: &fakeDevice{resp: map[string]string{"*OPT?": tt.resp}}}
+		if opts, err := d.Options(); err != nil || !reflect.DeepEqual(opts, tt.opts) {
+			t.Errorf("Options of %s = %q, %v, want %q", tt.resp, opts, err, tt.opts)
+		}
+	}
+}
+
+func TestQueryByte(t *testing.T) {
+	tests := []struct {
+		resp string
+		v    uint8
+		ok   bool
+	}{
+		{"32", 32, true},
+		{"+32", 32, true},
+		{" +0\r", 0, true},
+		{"255", 255, true},
+		{"256", 0, false},
+		{"-1", 0, false},
+		{"+", 0, false},
+		{"1.5", 0, false},
+		{"0x10", 0, false},
+	}
+	for _, tt := range tests {
+		d := &Device{Driver: &fakeDevice{resp: map[string]string{"REG?": tt.resp}}}
+		v, err := d.queryByte("REG?")
+		var qe *vi.QueryError
+		if tt.ok && (err != nil || v != tt.v) {
+			t.Errorf("queryByte of %q = %d, %v, want %d", tt.resp, v, err, tt.v)
+		}
+		if !tt.ok && (!errors.As(err, &qe) || qe.Cmd != "REG?") {
+			t.Errorf("queryByte of %q = %d, %v, want a *vi.QueryError for REG?", tt.resp, v, err)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	f := &fakeDevice{resp: map[string]string{"*TST?": "+1"}}
+	d := &Device{Driver: f}
+	if n, err := d.SelfTest(); err != nil || n != 1 {
+		t.Errorf("SelfTest = %d, %v, want 1", n, err)
+	}
+	f.resp["*TST?"] = "failed"
+	var qe *vi.QueryError
+	if _, err := d.SelfTest(); !errors.As(err, &qe) || qe.Cmd != "*TST?" {
+		t.Errorf("SelfTest of a response that isn't a number: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// OPCStrategy is how WaitOPC waits for the pending operations of a device
+// to complete.
+type OPCStrategy int
+
+const (
+	// OPCByQuery sends *OPC? and blocks until the device answers. The
+	// session's timeout is extended to the context's deadline, or made
+	// infinite if there is none, for the query. It's the simplest
+	// strategy but the device can't be talked to while it's waiting.
+	OPCByQuery OPCStrategy = iota
+
+	// OPCByPolling sends *OPC and polls *ESR? until the OPC bit is set.
+	OPCByPolling
+
+	// OPCBySRQ sends *OPC with OPC enabled in *ESE and ESB in *SRE, and
+	// waits for the service request, see WaitForStatus. The enable
+	// registers are restored afterwards.
+	OPCBySRQ
+)
+
+var opcStrategyNames = []string{"OPCByQuery", "OPCByPolling", "OPCBySRQ"}
+
+func (s OPCStrategy) String() string {
+	if s >= 0 && int(s) < len(opcStrategyNames) {
+		return opcStrategyNames[s]
+	}
+	return "OPCStrategy(" + strconv.Itoa(int(s)) + ")"
+}
+
+// WaitOPC waits until the operations the device has pending are complete,
+// or returns ctx.Err() if ctx is done first. A *OPC? that was cancelled is
+// aborted with a device clear.
+func (d *Device) WaitOPC(ctx context.Context, strategy OPCStrategy) error {
+	switch strategy {
+	case OPCByQuery:
+		return d.opcByQuery(ctx)
+	case OPCByPolling:
+		return d.opcByPolling(ctx)
+	case OPCBySRQ:
+		return d.opcBySRQ(ctx)
+	}
+	return fmt.Errorf("ieee4882: invalid OPC strategy %v", strategy)
+}
+
+// Settle waits for the pending operations with the device's OPCStrategy.
+func (d *Device) Settle(ctx context.Context) error {
+	return d.WaitOPC(ctx, d.OPCStrategy)
+}
+
+func (d *Device) opcByQuery(ctx context.Context) error {
+	instr, ok := d.Driver.(vi.Object)
+	if !ok {
+		return d.OPCQuery()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		old, err := instr.AttrUint32(vi.ATTR_TMO_VALUE)
+		if err != nil {
+			return err
+		}
+		if err := instr.SetAttrUint32(vi.ATTR_TMO_VALUE, vi.TMO_INFINITE); err != nil {
+			return err
+		}
+		defer instr.SetAttrUint32(vi.ATTR_TMO_VALUE, old)
+	}
+	if _, err := instr.QueryContext(ctx, d.cmd("*OPC?")); err != nil {
+		if ctx.Err() != nil {
+			instr.Clear()
+		}
+		return err
+	}
+	return d.check("*OPC?")
+}
+
+func (d *Device) opcByPolling(ctx context.Context) error {
+	if _, err := d.EventStatus(); err != nil {
+		return err
+	}
+	if err := d.OPC(); err != nil {
+		return err
+	}
+	interval := pollMin
+	for {
+		esr, err := d.EventStatus()
+		if err != nil || esr&OPC != 0 {
+			return err
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if interval *= 2; interval > pollMax {
+			interval = pollMax
+		}
+	}
+}
+
+func (d *Device) opcBySRQ(ctx context.Context) error {
+	ese, err := d.EventStatusEnable()
+	if err != nil {
+		return err
+	}
+	sre, err := d.ServiceRequestEnable()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		d.SetEventStatusEnable(ese)
+		d.SetServiceRequestEnable(sre)
+	}()
+	if err := d.SetEventStatusEnable(ese | OPC); err != nil {
+		return err
+	}
+	if err := d.SetServiceRequestEnable(sre | ESB); err != nil {
+		return err
+	}
+	if _, err := d.EventStatus(); err != nil {
+		return err
+	}
+	if err := d.OPC(); err != nil {
+		return err
+	}
+	for {
+		if _, err := d.WaitForStatus(ctx, ESB); err != nil {
+			return err
+		}
+		// Reading the register clears ESB, which other enabled events
+		// may have set.
+		esr, err := d.EventStatus()
+		if err != nil || esr&OPC != 0 {
+			return err
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	vi "github.com/jpoirier/visa"
+)
+
+func TestWaitOPCByQuery(t *testing.T) {
+	ctx := context.Background()
+
+	// Other drivers just query *OPC?.
+	f := &fakeDevice{}
+	if err := (&Device{Driver: f}).WaitOPC(ctx, OPCByQuery); err != nil || !reflect.DeepEqual(f.sent(), []string{"*OPC?"}) {
+		t.Errorf("WaitOPC sent %q, %v", f.sent(), err)
+	}
+
+	// Without a deadline the query waits forever.
+	f = &fakeDevice{}
+	fb := useFakeBackend(t, f)
+	d := &Device{Driver: fakeInstr}
+	if err := d.WaitOPC(ctx, OPCByQuery); err != nil {
+		t.Fatalf("WaitOPC: %v", err)
+	}
+	if want := []uint32{vi.TMO_INFINITE, 2000}; !reflect.DeepEqual(fb.tmos, want) {
+		t.Errorf("ATTR_TMO_VALUE was set to %v, want %v", fb.tmos, want)
+	}
+
+	// With one it waits until the deadline.
+	fb.tmos = nil
+	dctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := d.WaitOPC(dctx, OPCByQuery); err != nil {
+		t.Fatalf("WaitOPC: %v", err)
+	}
+	// It's set for the write and the read.
+	if len(fb.tmos) != 4 || fb.tmos[0] > 1000 || fb.tmos[2] > 1000 || fb.tmos[1] != 2000 || fb.tmos[3] != 2000 {
+		t.Errorf("ATTR_TMO_VALUE was set to %v, want the time left and 2000 twice", fb.tmos)
+	}
+
+	// A query that's cancelled is aborted with a device clear, and the
+	// timeout restored.
+	f.opcDelay, fb.stall, fb.tmos = -1, true, nil
+	cctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := d.WaitOPC(cctx, OPCByQuery); err != context.Canceled {
+		t.Errorf("cancelled WaitOPC = %v, want context.Canceled", err)
+	}
+	if fb.mu.Lock(); fb.clears == 0 || fb.tmo != 2000 {
+		t.Errorf("after cancelling: %d clears, ATTR_TMO_VALUE %d, want 2000", fb.clears, fb.tmo)
+	}
+	fb.mu.Unlock()
+}
+
+func TestWaitOPCByPolling(t *testing.T) {
+	f := &fakeDevice{opcDelay: 30 * time.Millisecond, esr: OPC}
+	d := &Device{Driver: f}
+	if err := d.WaitOPC(context.Background(), OPCByPolling); err != nil {
+		t.Fatalf("WaitOPC: %v", err)
+	}
+	// An OPC set before is cleared first.
+	cmds := f.sent()
+	if len(cmds) < 4 || !reflect.DeepEqual(cmds[:3], []string{"*ESR?", "*OPC", "*ESR?"}) {
+		t.Fatalf("the device received %q", cmds)
+	}
+	for _, cmd := range cmds[3:] {
+		if cmd != "*ESR?" {
+			t.Errorf("the device received %q while polling", cmd)
+		}
+	}
+
+	f = &fakeDevice{opcDelay: -1}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := (&Device{Driver: f}).WaitOPC(ctx, OPCByPolling); err != context.DeadlineExceeded {
+		t.Errorf("WaitOPC of a busy device = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitOPCBySRQ(t *testing.T) {
+	f := &fakeDevice{opcDelay: 100 * time.Millisecond, ese: QYE | EXE, sre: MAV}
+	fb := useFakeBackend(t, f)
+	d := &Device{Driver: fakeInstr, OPCStrategy: OPCBySRQ}
+
+	// Other events summarized in ESB don't end the wait.
+	time.AfterFunc(30*time.Millisecond, func() { f.set(EXE, 0) })
+	start := time.Now()
+	if err := d.Settle(context.Background()); err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond || d >= pollMax/2 {
+		t.Errorf("Settle took %v, want about 100ms", d)
+	}
+	if srqs, _ := fb.state(); srqs != 2 {
+		t.Errorf("%d service requests, want 2", srqs)
+	}
+	want := []string{"*ESE?", "*SRE?", "*ESE 21", "*SRE 48", "*ESR?", "*OPC"}
+	if cmds := f.sent(); len(cmds) < len(want) || !reflect.DeepEqual(cmds[:len(want)], want) {
+		t.Errorf("the device received %q, want %q first", cmds, want)
+	}
+	if f.mu.Lock(); f.ese != QYE|EXE || f.sre != MAV {
+		t.Errorf("*ESE %v and *SRE %v afterwards, want them restored", f.ese, f.sre)
+	}
+	f.mu.Unlock()
+
+	// They're restored when the wait is cancelled too.
+	f.opcDelay = -1
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Settle(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Settle of a busy device = %v, want context.DeadlineExceeded", err)
+	}
+	if f.mu.Lock(); f.ese != QYE|EXE || f.sre != MAV {
+		t.Errorf("*ESE %v and *SRE %v after the timeout, want them restored", f.ese, f.sre)
+	}
+	f.mu.Unlock()
+}
+
+func TestOPCStrategy(t *testing.T) {
+	if err := (&Device{Driver: &fakeDevice{}}).WaitOPC(context.Background(), OPCStrategy(3)); err == nil {
+		t.Error("WaitOPC accepted an invalid strategy")
+	}
+	if s := OPCBySRQ.String(); s != "OPCBySRQ" {
+		t.Errorf("OPCBySRQ.String() = %q", s)
+	}
+	if s := OPCStrategy(-1).String(); s != "OPCStrategy(-1)" {
+		t.Errorf("OPCStrategy(-1).String() = %q", s)
+	}
+}
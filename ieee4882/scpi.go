@@ -0,0 +1,198 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// maxErrors bounds the number of entries Errors reads, in case a device
+// never reports an empty queue.
+const maxErrors = 100
+
+// ErrorClass is the class of a SCPI error, given by its code.
+type ErrorClass int
+
+const (
+	ClassNone              ErrorClass = iota // 0, no error
+	ClassCommand                             // -100 to -199, e.g. a syntax error
+	ClassExecution                           // -200 to -299, e.g. a parameter out of range
+	ClassDeviceSpecific                      // -300 to -399
+	ClassQuery                               // -400 to -499, e.g. a query interrupted
+	ClassPowerOn                             // -500 to -599
+	ClassUserRequest                         // -600 to -699
+	ClassRequestControl                      // -700 to -799
+	ClassOperationComplete                   // -800 to -899
+	ClassDeviceDefined                       // positive codes
+	ClassUnknown                             // other negative codes
+)
+
+var errorClassNames = []string{
+	"no error",
+	"command error",
+	"execution error",
+	"device-specific error",
+	"query error",
+	"power on",
+	"user request",
+	"request control",
+	"operation complete",
+	"device-defined error",
+	"unknown error",
+}
+
+func (c ErrorClass) String() string {
+	if c >= 0 && int(c) < len(errorClassNames) {
+		return errorClassNames[c]
+	}
+	return "ErrorClass(" + strconv.Itoa(int(c)) + ")"
+}
+
+// SCPIError is an entry of the SCPI error queue, as returned by SYST:ERR?.
+type SCPIError struct {
+	Code    int
+	Message string
+}
+
+func (e SCPIError) Error() string {
+	return fmt.Sprintf("scpi: %d, %s", e.Code, e.Message)
+}
+
+// Class returns the class of the error.
+func (e SCPIError) Class() ErrorClass {
+	switch {
+	case e.Code == 0:
+		return ClassNone
+	case e.Code > 0:
+		return ClassDeviceDefined
+	case e.Code <= -100 && e.Code >= -899:
+		return ErrorClass(-e.Code / 100)
+	}
+	return ClassUnknown
+}
+
+// ParseSCPIError parses a SYST:ERR? response, e.g.
+// -113,"Undefined header".
+func ParseSCPIError(resp string) (SCPIError, error) {
+	resp = strings.TrimSpace(resp)
+	i := strings.IndexByte(resp, ',')
+	if i < 0 {
+		i = len(resp)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(resp[:i]))
+	if err != nil {
+		return SCPIError{}, fmt.Errorf("ieee4882: invalid error queue entry %q", resp)
+	}
+	var msg string
+	if i < len(resp) {
+		msg = strings.Trim(strings.TrimSpace(resp[i+1:]), `"`)
+	}
+	return SCPIError{code, msg}, nil
+}
+
+// CommandError is an instrument error found in the error queue after
+// commands were sent with error checking on.
+type CommandError struct {
+	// Cmds are the commands sent since the previous check, without their
+	// terminators, one of which caused the errors.
+	Cmds   []string
+	Errors []SCPIError // the error queue, oldest first
+}
+
+func (e *CommandError) Error() string {
+	cmds := make([]string, len(e.Cmds))
+	for i, cmd := range e.Cmds {
+		cmds[i] = strconv.Quote(cmd)
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, se := range e.Errors {
+		msgs[i] = strings.TrimPrefix(se.Error(), "scpi: ")
+	}
+	s := "scpi: command "
+	if len(cmds) > 1 {
+		s = "scpi: commands "
+	}
+	return s + strings.Join(cmds, ", ") + ": " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the oldest error, the one most likely caused by the
+// command.
+func (e *CommandError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// Errors drains the error queue with SYST:ERR? and returns its entries,
+// oldest first, nil if it was empty.
+func (d *Device) Errors() ([]SCPIError, error) {
+	var errs []SCPIError
+	for i := 0; i < maxErrors; i++ {
+		resp, err := vi.Query(d.Driver, d.cmd("SYST:ERR?"))
+		if err != nil {
+			return errs, err
+		}
+		se, err := ParseSCPIError(resp)
+		if err != nil {
+			return errs, err
+		}
+		if se.Code == 0 {
+			return errs, nil
+		}
+		errs = append(errs, se)
+	}
+	return errs, errors.New("ieee4882: error queue doesn't empty")
+}
+
+// Err returns the instrument errors found by the last check of the error
+// queue, a *CommandError, or the failure to read it. It's nil if the queue
+// was empty. See CheckEvery.
+func (d *Device) Err() error {
+	return d.err
+}
+
+// Write writes b, like the session's Write. With error checking on it then
+// drains the error queue and fails with ERROR_SYSTEM_ERROR if it wasn't
+// empty. A status can't tell which errors the instrument reported, callers
+// get them from Err before the next check. Commands containing a query
+// aren't checked as their response has to be read first.
+func (d *Device) Write(b []byte, cnt uint32) (uint32, vi.Status) {
+	n, status := d.Driver.Write(b, cnt)
+	if status < vi.SUCCESS {
+		return n, status
+	}
+	if int(cnt) > len(b) {
+		cnt = uint32(len(b))
+	}
+	if cmd := string(b[:cnt]); !strings.Contains(cmd, "?") {
+		if err := d.check(cmd); err != nil {
+			return n, vi.StatusOf(err)
+		}
+	}
+	return n, status
+}
+
+// check counts a command and, with error checking on, drains the error
+// queue when it's due.
+func (d *Device) check(cmd string) error {
+	if d.CheckEvery <= 0 {
+		return nil
+	}
+	d.sent = append(d.sent, strings.TrimRight(cmd, "\r\n"))
+	if len(d.sent) < d.CheckEvery {
+		return nil
+	}
+	cmds := d.sent
+	d.sent = nil
+	errs, err := d.Errors()
+	if err == nil && len(errs) > 0 {
+		err = &CommandError{Cmds: cmds, Errors: errs}
+	}
+	d.err = err
+	return err
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	vi "github.com/jpoirier/visa"
+)
+
+func TestParseSCPIError(t *testing.T) {
+	tests := []struct {
+		resp string
+		se   SCPIError
+	}{
+		{`-113,"Undefined header"` + "\n", SCPIError{-113, "Undefined header"}},
+		{`+0,"No error"`, SCPIError{0, "No error"}},
+		{` -222 , "Data out of range;FREQ 1E12" `, SCPIError{-222, "Data out of range;FREQ 1E12"}},
+		{`-350,"Queue overflow, entries lost"`, SCPIError{-350, "Queue overflow, entries lost"}},
+		{"0", SCPIError{0, ""}},
+		{`201,"Invalid while in local"`, SCPIError{201, "Invalid while in local"}},
+	}
+	for _, tt := range tests {
+		if se, err := ParseSCPIError(tt.resp); err != nil || se != tt.se {
+			t.Errorf("ParseSCPIError(%q) = %+v, %v, want %+v", tt.resp, se, err, tt.se)
+		}
+	}
+	for _, resp := range []string{"", `"No error"`, `x,"No error"`, "1.5,x"} {
+		if se, err := ParseSCPIError(resp); err == nil {
+			t.Errorf("ParseSCPIError(%q) = %+v, want an error", resp, se)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		code  int
+		class ErrorClass
+	}{
+		{0, ClassNone},
+		{1, ClassDeviceDefined},
+		{100, ClassDeviceDefined},
+		{-1, ClassUnknown},
+		{-99, ClassUnknown},
+		{-100, ClassCommand},
+		{-199, ClassCommand},
+		{-200, ClassExecution},
+		{-350, ClassDeviceSpecific},
+		{-400, ClassQuery},
+		{-500, ClassPowerOn},
+		{-600, ClassUserRequest},
+		{-700, ClassRequestControl},
+		{-800, ClassOperationComplete},
+		{-899, ClassOperationComplete},
+		{-900, ClassUnknown},
+		{-1000, ClassUnknown},
+	}
+	for _, tt := range tests {
+		if c := (SCPIError{Code: tt.code}).Class(); c != tt.class {
+			t.Errorf("class of %d = %v, want %v", tt.code, c, tt.class)
+		}
+	}
+	if s := ClassExecution.String(); s != "execution error" {
+		t.Errorf("ClassExecution.String() = %q", s)
+	}
+	if s := ErrorClass(42).String(); s != "ErrorClass(42)" {
+		t.Errorf("ErrorClass(42).String() = %q", s)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	f := &fakeDevice{}
+	f.errs = []string{`-113,"Undefined header"`, `-222,"Data out of range"`}
+	d := &Device{Driver: f}
+	want := []SCPIError{{-113, "Undefined header"}, {-222, "Data out of range"}}
+	if errs, err := d.Errors(); err != nil || !reflect.DeepEqual(errs, want) {
+		t.Errorf("Errors = %v, %v, want %v", errs, err, want)
+	}
+	if errs, err := d.Errors(); err != nil || errs != nil {
+		t.Errorf("Errors of an empty queue = %v, %v", errs, err)
+	}
+
+	// A queue that doesn't empty is read up to maxErrors entries.
+	f.errs = make([]string, maxErrors+1)
+	for i := range f.errs {
+		f.errs[i] = strconv.Itoa(i+1) + `,"Device error"`
+	}
+	if errs, err := d.Errors(); err == nil || len(errs) != maxErrors {
+		t.Errorf("Errors of an endless queue = %d entries, %v", len(errs), err)
+	}
+
+	// An unreadable entry ends the read with the entries before it.
+	f.errs = []string{`-113,"Undefined header"`, "garbage"}
+	if errs, err := d.Errors(); err == nil || len(errs) != 1 {
+		t.Errorf("Errors with an unreadable entry = %v, %v", errs, err)
+	}
+}
+
+func TestCheckEvery(t *testing.T) {
+	f := &fakeDevice{bad: map[string]string{"BAD": `-113,"Undefined header"`}}
+	d := &Device{Driver: f, Terminator: "\n", CheckEvery: 2}
+
+	// The queue is read after every second command.
+	if err := d.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.sent()); n != 1 {
+		t.Errorf("%d commands sent for the first command, want no check", n)
+	}
+	if err := d.send("BAD"); err == nil {
+		t.Fatal("the error wasn't reported")
+	}
+	var ce *CommandError
+	if !errors.As(d.Err(), &ce) || !reflect.DeepEqual(ce.Cmds, []string{"*RST", "BAD"}) {
+		t.Fatalf("Err = %v, want a *CommandError for *RST and BAD", d.Err())
+	}
+	var se SCPIError
+	if !errors.As(d.Err(), &se) || se.Code != -113 {
+		t.Errorf("Err unwraps to %v, want the -113 error", se)
+	}
+	if want := `scpi: commands "*RST", "BAD": -113, Undefined header`; ce.Error() != want {
+		t.Errorf("Error() = %q, want %q", ce.Error(), want)
+	}
+
+	// Write reports the errors with ERROR_SYSTEM_ERROR, their detail is
+	// left to Err. Queries aren't counted.
+	for _, cmd := range []string{"*IDN?\n", "BAD\n", "OUTP ON\n"} {
+		b := []byte(cmd)
+		n, status := d.Write(b, uint32(len(b)))
+		want := vi.Status(vi.SUCCESS)
+		if cmd == "OUTP ON\n" {
+			want = vi.ERROR_SYSTEM_ERROR
+		}
+		if n != uint32(len(b)) || status != want {
+			t.Errorf("Write(%q) = %d, %v, want %v", cmd, n, status, want)
+		}
+	}
+	if !errors.As(d.Err(), &ce) || !reflect.DeepEqual(ce.Cmds, []string{"BAD", "OUTP ON"}) {
+		t.Errorf("Err = %v, want a *CommandError for BAD and OUTP ON", d.Err())
+	}
+
+	// A check of an empty queue clears Err.
+	d.CheckEvery = 1
+	if err := d.ClearStatus(); err != nil || d.Err() != nil {
+		t.Errorf("ClearStatus = %v, Err = %v", err, d.Err())
+	}
+	want := []string{"*RST\n", "BAD\n", "SYST:ERR?\n", "SYST:ERR?\n", "*IDN?\n", "BAD\n",
+		"OUTP ON\n", "SYST:ERR?\n", "SYST:ERR?\n", "*CLS\n", "SYST:ERR?\n"}
+	if !reflect.DeepEqual(f.sent(), want) {
+		t.Errorf("the device received %q, want %q", f.sent(), want)
+	}
+}
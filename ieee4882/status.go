@@ -0,0 +1,179 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// StatusByte is the status byte register, read with a serial poll or
+// *STB?, and the service request enable register.
+type StatusByte uint8
+
+const (
+	EAV  StatusByte = 1 << 2 // error/event queue not empty (SCPI)
+	QUES StatusByte = 1 << 3 // questionable status summary (SCPI)
+	MAV  StatusByte = 1 << 4 // message available
+	ESB  StatusByte = 1 << 5 // event status summary
+	RQS  StatusByte = 1 << 6 // request service, in a serial poll
+	MSS  StatusByte = 1 << 6 // master summary status, with *STB?
+	OPER StatusByte = 1 << 7 // operation status summary (SCPI)
+)
+
+var statusByteNames = []string{"0x01", "0x02", "EAV", "QUES", "MAV", "ESB", "RQS", "OPER"}
+
+// String returns the names of the bits set, e.g. "MAV|RQS".
+func (s StatusByte) String() string {
+	return bitNames(uint8(s), statusByteNames)
+}
+
+// EventStatus is the standard event status register, read with *ESR?, and
+// the standard event status enable register.
+type EventStatus uint8
+
+const (
+	OPC EventStatus = 1 << 0 // operation complete
+	RQC EventStatus = 1 << 1 // request control
+	QYE EventStatus = 1 << 2 // query error
+	DDE EventStatus = 1 << 3 // device dependent error
+	EXE EventStatus = 1 << 4 // execution error
+	CME EventStatus = 1 << 5 // command error
+	URQ EventStatus = 1 << 6 // user request
+	PON EventStatus = 1 << 7 // power on
+)
+
+var eventStatusNames = []string{"OPC", "RQC", "QYE", "DDE", "EXE", "CME", "URQ", "PON"}
+
+// String returns the names of the bits set, e.g. "OPC|EXE".
+func (e EventStatus) String() string {
+	return bitNames(uint8(e), eventStatusNames)
+}
+
+// bitNames joins the names of the bits set in v, "0" if there are none.
+func bitNames(v uint8, names []string) string {
+	if v == 0 {
+		return "0"
+	}
+	var set []string
+	for i, name := range names {
+		if v&(1<<uint(i)) != 0 {
+			set = append(set, name)
+		}
+	}
+	return strings.Join(set, "|")
+}
+
+// The serial poll interval of WaitForStatus starts at pollMin and doubles
+// up to pollMax.
+const (
+	pollMin = 10 * time.Millisecond
+	pollMax = time.Second
+)
+
+// ServiceRequestOn programs the device to request service when a bit of
+// mask is set in the status byte, or an event of events occurs: *ESE is set
+// to events and *SRE to mask, with ESB added if events isn't 0.
+func (d *Device) ServiceRequestOn(mask StatusByte, events EventStatus) error {
+	if err := d.SetEventStatusEnable(events); err != nil {
+		return err
+	}
+	if events != 0 {
+		mask |= ESB
+	}
+	return d.SetServiceRequestEnable(mask &^ RQS)
+}
+
+// SerialPoll reads the status byte with a serial poll, ReadSTB, which
+// clears RQS, or with *STB? if the driver isn't a session or the session
+// can't serial poll.
+func (d *Device) SerialPoll() (StatusByte, error) {
+	instr, ok := d.Driver.(vi.Object)
+	if !ok {
+		return d.StatusByte()
+	}
+	stb, status := instr.ReadSTB()
+	if status == vi.ERROR_NSUP_OPER {
+		return d.StatusByte()
+	}
+	if status < vi.SUCCESS {
+		return 0, instr.Wrap("ReadSTB", status)
+	}
+	return StatusByte(stb), nil
+}
+
+// WaitForStatus waits until a bit of mask is set in the status byte and
+// returns the status byte, or returns ctx.Err() if ctx is done first.
+//
+// On sessions that support EVENT_SERVICE_REQ the status byte is read when
+// the device requests service, which it has to be programmed to do, with
+// ServiceRequestOn, for the bits of mask, and every second as a fallback.
+// Otherwise it's polled, at intervals growing from 10ms to 1s.
+func (d *Device) WaitForStatus(ctx context.Context, mask StatusByte) (StatusByte, error) {
+	if instr, ok := d.Driver.(vi.Object); ok {
+		sctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if events, err := instr.Subscribe(sctx, vi.EVENT_SERVICE_REQ); err == nil {
+			stb, err := d.waitSRQ(ctx, mask, events)
+			cancel()
+			for range events {
+				// Wait for the events to be disabled.
+			}
+			return stb, err
+		}
+	}
+	return d.pollStatus(ctx, mask)
+}
+
+// waitSRQ reads the status byte when a service request arrives on events,
+// at the start in case the bits were set before, and every pollMax in case
+// the requests don't arrive, e.g. on interfaces without an SRQ line.
+func (d *Device) waitSRQ(ctx context.Context, mask StatusByte, events <-chan vi.Event) (StatusByte, error) {
+	t := time.NewTicker(pollMax)
+	defer t.Stop()
+	for {
+		stb, err := d.SerialPoll()
+		if err != nil || stb&mask != 0 {
+			return stb, err
+		}
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return 0, ctx.Err()
+			}
+			if e.Err != nil {
+				return 0, e.Err
+			}
+		case <-t.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// pollStatus polls the status byte with backoff.
+func (d *Device) pollStatus(ctx context.Context, mask StatusByte) (StatusByte, error) {
+	interval := pollMin
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		stb, err := d.SerialPoll()
+		if err != nil || stb&mask != 0 {
+			return stb, err
+		}
+		t.Reset(interval)
+		if interval *= 2; interval > pollMax {
+			interval = pollMax
+		}
+	}
+}
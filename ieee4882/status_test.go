@@ -0,0 +1,362 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package ieee4882
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	vi "github.com/jpoirier/visa"
+)
+
+// fakeInstr is the session a fakeBackend serves.
+const fakeInstr = vi.Object(1)
+
+// fakeBackend is a vi.Backend serving a fakeDevice as fakeInstr. It
+// requests service when MSS gets set while EVENT_SERVICE_REQ is enabled,
+// and has no jobs, so the context aware operations run synchronously and
+// are aborted with Clear. Operations it doesn't implement panic.
+type fakeBackend struct {
+	vi.Backend
+	dev *fakeDevice
+
+	noSTB bool // ReadSTB isn't supported
+	noSRQ bool // EVENT_SERVICE_REQ isn't supported
+	stall bool // reads with nothing to answer wait for Clear
+
+	mu        sync.Mutex
+	tmo       uint32
+	tmos      []uint32 // the ATTR_TMO_VALUE states set
+	handlers  map[vi.HandlerID]bool
+	srq       bool // EVENT_SERVICE_REQ is enabled
+	requested bool // MSS was set at the last check
+	srqs      int  // service requests delivered
+	clears    int
+	cleared   chan struct{} // closed by the first Clear
+	once      sync.Once
+}
+
+// useFakeBackend installs a fakeBackend serving dev for the duration of
+// the test.
+func useFakeBackend(t *testing.T, dev *fakeDevice) *fakeBackend {
+	t.Helper()
+	fb := &fakeBackend{
+		dev:      dev,
+		tmo:      2000,
+		handlers: make(map[vi.HandlerID]bool),
+		cleared:  make(chan struct{}),
+	}
+	dev.changed = fb.check
+	old := vi.CurrentBackend()
+	vi.SetBackend(fb)
+	t.Cleanup(func() { vi.SetBackend(old) })
+	return fb
+}
+
+func (fb *fakeBackend) Close(v uint32) vi.Status { return vi.SUCCESS }
+
+func (fb *fakeBackend) GetAttribute(v, attr uint32, addr unsafe.Pointer) vi.Status {
+	if vi.Object(v) != fakeInstr {
+		return vi.ERROR_NSUP_ATTR
+	}
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	switch attr {
+	case vi.ATTR_TMO_VALUE:
+		*(*uint32)(addr) = fb.tmo
+	case vi.ATTR_MAX_QUEUE_LENGTH:
+		*(*uint32)(addr) = 50
+	default:
+		return vi.ERROR_NSUP_ATTR
+	}
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) SetAttribute(v, attr uint32, state uint64) vi.Status {
+	if attr != vi.ATTR_TMO_VALUE {
+		return vi.ERROR_NSUP_ATTR
+	}
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.tmo = uint32(state)
+	fb.tmos = append(fb.tmos, fb.tmo)
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) InstallHandler(instr vi.Object, etype uint32, id vi.HandlerID) vi.Status {
+	if etype == vi.EVENT_SERVICE_REQ {
+		fb.mu.Lock()
+		fb.handlers[id] = true
+		fb.mu.Unlock()
+	}
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) UninstallHandler(instr vi.Object, etype uint32, id vi.HandlerID) vi.Status {
+	fb.mu.Lock()
+	delete(fb.handlers, id)
+	fb.mu.Unlock()
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) EnableEvent(instr vi.Object, etype uint32, mechanism uint16, context uint32) vi.Status {
+	if etype != vi.EVENT_SERVICE_REQ || fb.noSRQ {
+		return vi.ERROR_INV_EVENT
+	}
+	fb.mu.Lock()
+	fb.srq = true
+	fb.mu.Unlock()
+	fb.check()
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) DisableEvent(instr vi.Object, etype uint32, mechanism uint16) vi.Status {
+	fb.mu.Lock()
+	fb.srq = false
+	fb.mu.Unlock()
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) DiscardEvents(instr vi.Object, etype uint32, mechanism uint16) vi.Status {
+	return vi.SUCCESS
+}
+
+func (fb *fakeBackend) Read(instr vi.Object, buf []byte) (uint32, vi.Status) {
+	b, n, status := fb.dev.Read(uint32(len(buf)))
+	if status == vi.ERROR_TMO && fb.stall {
+		<-fb.cleared
+		return 0, vi.ERROR_ABORT
+	}
+	copy(buf, b[:n])
+	fb.check()
+	return n, status
+}
+
+func (fb *fakeBackend) Write(instr vi.Object, buf []byte) (uint32, vi.Status) {
+	n, status := fb.dev.Write(buf, uint32(len(buf)))
+	fb.check()
+	return n, status
+}
+
+func (fb *fakeBackend) ReadSTB(instr vi.Object) (uint16, vi.Status) {
+	if fb.noSTB {
+		return 0, vi.ERROR_NSUP_OPER
+	}
+	fb.dev.mu.Lock()
+	defer fb.dev.mu.Unlock()
+	return uint16(fb.dev.readSTB()), vi.SUCCESS
+}
+
+func (fb *fakeBackend) Clear(instr vi.Object) vi.Status {
+	fb.mu.Lock()
+	fb.clears++
+	fb.mu.Unlock()
+	fb.once.Do(func() { close(fb.cleared) })
+	return vi.SUCCESS
+}
+
+// check requests service if MSS was set since the last check.
+func (fb *fakeBackend) check() {
+	fb.dev.mu.Lock()
+	mss := fb.dev.status()&MSS != 0
+	fb.dev.mu.Unlock()
+	fb.mu.Lock()
+	var ids []vi.HandlerID
+	if mss && !fb.requested && fb.srq {
+		for id := range fb.handlers {
+			ids = append(ids, id)
+		}
+		fb.srqs++
+	}
+	fb.requested = mss
+	fb.mu.Unlock()
+	for _, id := range ids {
+		go vi.CallHandler(id, fakeInstr, vi.EVENT_SERVICE_REQ, 2)
+	}
+}
+
+// state returns the SRQ requests delivered and whether handlers are left
+// installed or EVENT_SERVICE_REQ enabled.
+func (fb *fakeBackend) state() (srqs int, enabled bool) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.srqs, fb.srq || len(fb.handlers) > 0
+}
+
+func TestStatusStrings(t *testing.T) {
+	tests := []struct {
+		s    interface{ String() string }
+		want string
+	}{
+		{StatusByte(0), "0"},
+		{MAV | RQS, "MAV|RQS"},
+		{MSS, "RQS"},
+		{StatusByte(0xFF), "0x01|0x02|EAV|QUES|MAV|ESB|RQS|OPER"},
+		{EventStatus(0), "0"},
+		{OPC | EXE, "OPC|EXE"},
+		{EventStatus(0xFF), "OPC|RQC|QYE|DDE|EXE|CME|URQ|PON"},
+	}
+	for _, tt := range tests {
+		if s := tt.s.String(); s != tt.want {
+			t.Errorf("String() of %#x = %q, want %q", tt.s, s, tt.want)
+		}
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	f := &fakeDevice{esr: OPC | EXE, stb: MAV}
+	d := &Device{Driver: f}
+	if err := d.SetEventStatusEnable(EXE); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetServiceRequestEnable(MAV); err != nil {
+		t.Fatal(err)
+	}
+	if ese, err := d.EventStatusEnable(); err != nil || ese != EXE {
+		t.Errorf("EventStatusEnable = %v, %v, want EXE", ese, err)
+	}
+	if sre, err := d.ServiceRequestEnable(); err != nil || sre != MAV {
+		t.Errorf("ServiceRequestEnable = %v, %v, want MAV", sre, err)
+	}
+	if stb, err := d.StatusByte(); err != nil || stb != MAV|ESB|MSS {
+		t.Errorf("StatusByte = %v, %v, want MAV|ESB|MSS", stb, err)
+	}
+	// Reading the event status register clears it.
+	for _, want := range []EventStatus{OPC | EXE, 0} {
+		if esr, err := d.EventStatus(); err != nil || esr != want {
+			t.Errorf("EventStatus = %v, %v, want %v", esr, err, want)
+		}
+	}
+}
+
+func TestServiceRequestOn(t *testing.T) {
+	tests := []struct {
+		mask   StatusByte
+		events EventStatus
+		cmds   []string
+	}{
+		{MAV, 0, []string{"*ESE 0", "*SRE 16"}},
+		{MAV, OPC | EXE, []string{"*ESE 17", "*SRE 48"}},
+		{MAV | RQS, OPC, []string{"*ESE 1", "*SRE 48"}},
+		{0, CME, []string{"*ESE 32", "*SRE 32"}},
+	}
+	for _, tt := range tests {
+		f := &fakeDevice{}
+		d := &Device{Driver: f}
+		if err := d.ServiceRequestOn(tt.mask, tt.events); err != nil || !reflect.DeepEqual(f.sent(), tt.cmds) {
+			t.Errorf("ServiceRequestOn(%v, %v) sent %q, %v, want %q", tt.mask, tt.events, f.sent(), err, tt.cmds)
+		}
+	}
+}
+
+func TestSerialPoll(t *testing.T) {
+	f := &fakeDevice{stb: MAV}
+	fb := useFakeBackend(t, f)
+	d := &Device{Driver: fakeInstr}
+	if stb, err := d.SerialPoll(); err != nil || stb != MAV || len(f.sent()) != 0 {
+		t.Errorf("SerialPoll = %v, %v, sent %q, want MAV with ReadSTB", stb, err, f.sent())
+	}
+	fb.noSTB = true
+	if stb, err := d.SerialPoll(); err != nil || stb != MAV || !reflect.DeepEqual(f.sent(), []string{"*STB?"}) {
+		t.Errorf("SerialPoll = %v, %v, sent %q, want MAV with *STB?", stb, err, f.sent())
+	}
+}
+
+func TestWaitForStatusSRQ(t *testing.T) {
+	f := &fakeDevice{}
+	fb := useFakeBackend(t, f)
+	d := &Device{Driver: fakeInstr}
+	if err := d.ServiceRequestOn(MAV, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { f.set(0, MAV) })
+	start := time.Now()
+	stb, err := d.WaitForStatus(context.Background(), MAV)
+	if err != nil || stb&MAV == 0 {
+		t.Fatalf("WaitForStatus = %v, %v, want MAV", stb, err)
+	}
+	// The status byte is read at the start and on the request, rather
+	// than on the fallback poll every pollMax.
+	if d := time.Since(start); d >= pollMax/2 {
+		t.Errorf("WaitForStatus took %v", d)
+	}
+	srqs, enabled := fb.state()
+	if f.mu.Lock(); srqs != 1 || len(f.polls) != 2 {
+		t.Errorf("%d service requests and %d polls, want 1 and 2", srqs, len(f.polls))
+	}
+	f.mu.Unlock()
+	if enabled {
+		t.Error("WaitForStatus left the service request enabled or its handler installed")
+	}
+
+	// Bits already set are found without a request.
+	if stb, err := d.WaitForStatus(context.Background(), MAV); err != nil || stb&MAV == 0 {
+		t.Errorf("WaitForStatus of a set bit = %v, %v", stb, err)
+	}
+
+	// Without requests it ends with ctx.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := d.WaitForStatus(ctx, OPER); err != context.DeadlineExceeded {
+		t.Errorf("WaitForStatus without a request = %v, want context.DeadlineExceeded", err)
+	}
+	if _, enabled := fb.state(); enabled {
+		t.Error("the cancelled WaitForStatus left the service request enabled or its handler installed")
+	}
+}
+
+func TestWaitForStatusPoll(t *testing.T) {
+	// Sessions without service requests and other drivers are polled,
+	// with *STB? if they can't serial poll.
+	for _, session := range []bool{true, false} {
+		f := &fakeDevice{}
+		d := &Device{Driver: f}
+		if session {
+			fb := useFakeBackend(t, f)
+			fb.noSRQ, fb.noSTB = true, true
+			d.Driver = fakeInstr
+		}
+		const polls = 5
+		go func() {
+			for {
+				f.mu.Lock()
+				n := len(f.polls)
+				f.mu.Unlock()
+				if n == polls {
+					f.set(0, OPER)
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		if stb, err := d.WaitForStatus(context.Background(), OPER|MAV); err != nil || stb != OPER {
+			t.Fatalf("WaitForStatus = %v, %v, want OPER", stb, err)
+		}
+
+		// The interval doubles from pollMin.
+		f.mu.Lock()
+		if len(f.polls) < polls+1 {
+			t.Fatalf("%d polls, want %d", len(f.polls), polls+1)
+		}
+		for i := 1; i < len(f.polls); i++ {
+			if gap := f.polls[i].Sub(f.polls[i-1]); gap < pollMin<<uint(i-1) {
+				t.Errorf("poll %d came %v after the previous one, want at least %v", i, gap, pollMin<<uint(i-1))
+			}
+		}
+		f.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&Device{Driver: &fakeDevice{}}).WaitForStatus(ctx, MAV); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForStatus with a cancelled context = %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"time"
+)
+
+// The context aware operations run the transfer as a Job and terminate
+// it if ctx is done first. If Terminate fails they return its error and
+// leave the job to complete on its own. Sessions that can't run jobs fall
+// back to the synchronous operation, aborted with Clear.
+//
+// While a call is in progress the session's ATTR_TMO_VALUE is set to the
+// time left until the context's deadline, the session's own timeout
+// applies if there is none. Concurrent calls on a session therefore
+// shouldn't mix deadlines.
+
+// ReadContext reads up to cnt bytes, like Read, until ctx is done. It
+// returns ctx.Err() with the data received so far when ctx ends the read.
+func (instr Object) ReadContext(ctx context.Context, cnt uint32) ([]byte, error) {
+	b, _, err := instr.readContext(ctx, cnt)
+	return b, err
+}
+
+// WriteContext writes b, like Write, until ctx is done. It returns
+// ctx.Err() with the count sent so far when ctx ends the write.
+func (instr Object) WriteContext(ctx context.Context, b []byte) (uint32, error) {
+	_, n, _, err := instr.transfer(ctx, "Write",
+		func() (*Job, error) { return instr.StartWrite(b) },
+		func() (uint32, Status) { return backend.Write(instr, b) })
+	return n, err
+}
+
+// QueryContext writes cmd and reads the whole response, in as many reads
+// as it takes to reach the end of the message, until ctx is done.
+func (instr Object) QueryContext(ctx context.Context, cmd string) ([]byte, error) {
+	if _, err := instr.WriteContext(ctx, []byte(cmd)); err != nil {
+		return nil, err
+	}
+	var resp []byte
+	for {
+		b, status, err := instr.readContext(ctx, queryChunk)
+		resp = append(resp, b...)
+		if err != nil || status != SUCCESS_MAX_CNT {
+			return resp, err
+		}
+	}
+}
+
+// queryChunk is the size of each read of a query response.
+const queryChunk = 4096
+
+// readContext is ReadContext returning the status of the read as well.
+func (instr Object) readContext(ctx context.Context, cnt uint32) ([]byte, Status, error) {
+	var buf []byte
+	j, n, status, err := instr.transfer(ctx, "Read",
+		func() (*Job, error) { return instr.StartRead(cnt) },
+		func() (uint32, Status) {
+			buf = make([]byte, cnt)
+			return backend.Read(instr, buf)
+		})
+	if j != nil {
+		return j.data, status, err
+	}
+	return buf[:n], status, err
+}
+
+// transfer runs an I/O operation under ctx as the job start returns, or
+// with sync if the session doesn't support jobs. The job is returned if
+// one ran.
+func (instr Object) transfer(ctx context.Context, op string, start func() (*Job, error),
+	sync func() (uint32, Status)) (*Job, uint32, Status, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	restore, err := instr.deadlineTimeout(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer restore()
+
+	if ctx.Done() == nil {
+		n, status := sync()
+		return nil, n, status, instr.ioError(ctx, op, status)
+	}
+
+	j, err := start()
+	if err == nil {
+		select {
+		case <-j.done:
+			return j, j.n, j.status, instr.ioError(ctx, op, j.status)
+		case <-ctx.Done():
+			if err := j.Cancel(); err != nil {
+				return nil, 0, 0, err
+			}
+			return j, j.n, j.status, ctx.Err()
+		}
+	}
+	if !jobsUnsupported(err) {
+		return nil, 0, 0, err
+	}
+
+	type result struct {
+		n      uint32
+		status Status
+	}
+	ch := make(chan result, 1)
+	go func() {
+		n, status := sync()
+		ch <- result{n, status}
+	}()
+	select {
+	case r := <-ch:
+		return nil, r.n, r.status, instr.ioError(ctx, op, r.status)
+	case <-ctx.Done():
+		instr.Clear()
+		r := <-ch
+		return nil, r.n, r.status, ctx.Err()
+	}
+}
+
+// ioError returns ctx.Err() if ctx ended the operation, e.g. with a
+// timeout at its deadline, and the wrapped status otherwise.
+func (instr Object) ioError(ctx context.Context, op string, status Status) error {
+	if status >= SUCCESS {
+		return nil
+	}
+	if err := ctx.Err(); err != nil && (status == ERROR_TMO || status == ERROR_ABORT) {
+		return err
+	}
+	return instr.Wrap(op, status)
+}
+
+// deadlineTimeout sets ATTR_TMO_VALUE to the time left until ctx's
+// deadline and returns the function restoring the previous value.
+func (instr Object) deadlineTimeout(ctx context.Context) (func(), error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return func() {}, nil
+	}
+	left := time.Until(deadline)
+	if left <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	old, err := instr.AttrUint32(ATTR_TMO_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	ms := uint32((left + time.Millisecond - 1) / time.Millisecond)
+	if err := instr.SetAttrUint32(ATTR_TMO_VALUE, ms); err != nil {
+		return nil, err
+	}
+	return func() { instr.SetAttrUint32(ATTR_TMO_VALUE, old) }, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadContextCancel(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	instr.SetAttrUint32(ATTR_TMO_VALUE, 1234)
+	l.stall = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-l.reading
+		cancel()
+	}()
+	if b, err := instr.ReadContext(ctx, 16); err != context.Canceled || len(b) != 0 {
+		t.Errorf("ReadContext = %q, %v, want context.Canceled", b, err)
+	}
+	if tmo, err := instr.AttrUint32(ATTR_TMO_VALUE); err != nil || tmo != 1234 {
+		t.Errorf("ATTR_TMO_VALUE = %d, %v, want 1234 unchanged", tmo, err)
+	}
+}
+
+func TestReadContextDeadline(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	instr.SetAttrUint32(ATTR_TMO_VALUE, 5000)
+	l.stall = true
+
+	during := make(chan uint32, 1)
+	go func() {
+		<-l.reading
+		tmo, _ := instr.AttrUint32(ATTR_TMO_VALUE)
+		during <- tmo
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := instr.ReadContext(ctx, 16); err != context.DeadlineExceeded {
+		t.Errorf("ReadContext = %v, want context.DeadlineExceeded", err)
+	}
+	if tmo := <-during; tmo == 0 || tmo > 100 {
+		t.Errorf("ATTR_TMO_VALUE = %d during the read, want the time left", tmo)
+	}
+	if tmo, err := instr.AttrUint32(ATTR_TMO_VALUE); err != nil || tmo != 5000 {
+		t.Errorf("ATTR_TMO_VALUE = %d, %v afterwards, want 5000 restored", tmo, err)
+	}
+
+	// A context past its deadline doesn't start the read.
+	if _, err := instr.ReadContext(ctx, 16); err != context.DeadlineExceeded {
+		t.Errorf("ReadContext past the deadline = %v", err)
+	}
+}
+
+func TestWriteContextDone(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := instr.WriteContext(ctx, []byte("hello")); err != context.Canceled || n != 0 {
+		t.Errorf("WriteContext = %d, %v, want context.Canceled", n, err)
+	}
+	if len(l.out) != 0 {
+		t.Errorf("the device received %q", l.out)
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, ctx := range []context.Context{context.Background(), ctx} {
+		instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+		l.chunk = 3
+		if resp, err := instr.QueryContext(ctx, "*IDN?\n"); err != nil || string(resp) != "*IDN?\n" {
+			t.Errorf("QueryContext = %q, %v", resp, err)
+		}
+		if len(l.termEn) != 2 {
+			t.Errorf("QueryContext took %d reads, want 2", len(l.termEn))
+		}
+	}
+}
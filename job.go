@@ -0,0 +1,356 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// Job is an asynchronous operation started by StartRead, StartWrite,
+// StartMove, StartMoveIn or StartMoveOut. The job owns the memory VISA
+// transfers to or from, allocated outside of the Go heap when the library
+// needs it, and frees it when the operation completes.
+//
+// Completion is tracked through EVENT_IO_COMPLETION, which the package
+// enables with the handler mechanism on the session the first time a job
+// is started. The job's memory stays allocated until the completion event
+// arrives or the session is closed.
+type Job struct {
+	instr Object
+	id    uint32
+	op    string
+
+	done   chan struct{} // closed on completion
+	status Status
+	n      uint32
+	data   []byte
+}
+
+// StartRead starts reading up to cnt bytes, like ReadAsync.
+func (instr Object) StartRead(cnt uint32) (*Job, error) {
+	mem, free, status := jobMemory(int(cnt))
+	if status != SUCCESS {
+		return nil, instr.Wrap("ReadAsync", status)
+	}
+	return instr.startJob("ReadAsync", mem, free,
+		func() (uint32, Status) { return backend.ReadAsync(instr, mem) },
+		func(mem []byte, c completion) []byte {
+			n := int(c.retCnt)
+			if n > len(mem) {
+				n = len(mem)
+			}
+			return append([]byte(nil), mem[:n]...)
+		})
+}
+
+// StartWrite starts writing b, like WriteAsync. b may be reused as soon as
+// StartWrite returns.
+func (instr Object) StartWrite(b []byte) (*Job, error) {
+	mem, free, status := jobMemory(len(b))
+	if status != SUCCESS {
+		return nil, instr.Wrap("WriteAsync", status)
+	}
+	copy(mem, b)
+	return instr.startJob("WriteAsync", mem, free,
+		func() (uint32, Status) { return backend.WriteAsync(instr, mem) }, nil)
+}
+
+// StartMove starts moving srcLength elements between two bus address
+// spaces, like MoveAsync.
+func (instr Object) StartMove(srcSpace uint16, srcOffset BusAddress, srcWidth,
+	destSpace uint16, destOffset BusAddress, destWidth uint16,
+	srcLength BusSize) (*Job, error) {
+
+	return instr.startJob("MoveAsync", nil, func() {},
+		func() (uint32, Status) {
+			return instr.MoveAsync(srcSpace, srcOffset, srcWidth, destSpace,
+				destOffset, destWidth, srcLength)
+		}, nil)
+}
+
+// StartMoveIn starts moving length elements of width, e.g. WIDTH_16, from
+// the bus address space into the job's memory. Result returns them in the
+// byte order of the host.
+func (instr Object) StartMoveIn(space uint16, offset BusAddress, width uint16,
+	length BusSize) (*Job, error) {
+
+	mem, free, status := jobMemory(int(length) * int(width))
+	if status != SUCCESS {
+		return nil, instr.Wrap("MoveAsync", status)
+	}
+	return instr.startJob("MoveAsync", mem, free,
+		func() (uint32, Status) {
+			return instr.MoveAsync(space, offset, width, LOCAL_SPACE,
+				memAddress(mem), width, length)
+		},
+		func(mem []byte, c completion) []byte {
+			if c.status < SUCCESS {
+				return nil
+			}
+			return append([]byte(nil), mem...)
+		})
+}
+
+// StartMoveOut starts moving data, elements of width in the byte order of
+// the host, to the bus address space.
+func (instr Object) StartMoveOut(space uint16, offset BusAddress, width uint16,
+	data []byte) (*Job, error) {
+
+	if width == 0 || len(data)%int(width) != 0 {
+		return nil, instr.Wrap("MoveAsync", ERROR_INV_LENGTH)
+	}
+	mem, free, status := jobMemory(len(data))
+	if status != SUCCESS {
+		return nil, instr.Wrap("MoveAsync", status)
+	}
+	copy(mem, data)
+	length := BusSize(len(data) / int(width))
+	return instr.startJob("MoveAsync", mem, free,
+		func() (uint32, Status) {
+			return instr.MoveAsync(LOCAL_SPACE, memAddress(mem), width, space,
+				offset, width, length)
+		}, nil)
+}
+
+// memAddress returns the address of job memory as a local bus address.
+func memAddress(mem []byte) BusAddress {
+	if len(mem) == 0 {
+		return 0
+	}
+	return BusAddress(uintptr(unsafe.Pointer(&mem[0])))
+}
+
+// startJob starts a job with start, which transfers to or from mem. free
+// releases mem once the job has completed, after collect, nil for jobs
+// that only send data, has copied the data read out of it.
+func (instr Object) startJob(op string, mem []byte, free func(),
+	start func() (uint32, Status), collect func([]byte, completion) []byte) (*Job, error) {
+
+	w, err := instr.jobs()
+	if err != nil {
+		free()
+		return nil, err
+	}
+	id, ch, status := w.start(start)
+	if status < SUCCESS {
+		free()
+		return nil, instr.Wrap(op, status)
+	}
+	j := &Job{instr: instr, id: id, op: op, done: make(chan struct{})}
+	go func() {
+		c := <-ch
+		j.status, j.n = c.status, c.retCnt
+		if collect != nil {
+			j.data = collect(mem, c)
+		}
+		free()
+		close(j.done)
+	}()
+	return j, nil
+}
+
+// ID returns the VISA job ID.
+func (j *Job) ID() uint32 {
+	return j.id
+}
+
+// Done returns a channel that's closed when the job completes.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to complete and returns its error, or returns
+// ctx.Err() if ctx is done first. The job isn't cancelled then.
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel terminates the job with Terminate and waits for it to complete.
+// Cancelling a completed job does nothing.
+func (j *Job) Cancel() error {
+	select {
+	case <-j.done:
+		return nil
+	default:
+	}
+	status := j.instr.Terminate(NULL, uint16(j.id))
+	if status < SUCCESS && status != ERROR_INV_JOB_ID {
+		return j.instr.Wrap("Terminate", status)
+	}
+	<-j.done
+	return nil
+}
+
+// Result waits for the job to complete and returns the data it read, nil
+// for jobs that only send data, and its error. A read that failed, e.g.
+// timed out or was cancelled, returns the data received before.
+func (j *Job) Result() ([]byte, error) {
+	<-j.done
+	return j.data, j.err()
+}
+
+// RetCount waits for the job to complete and returns the number of bytes
+// it transferred.
+func (j *Job) RetCount() uint32 {
+	<-j.done
+	return j.n
+}
+
+func (j *Job) err() error {
+	return j.instr.Wrap(j.op, j.status)
+}
+
+// jobsUnsupported reports whether err is the failure to start a job on a
+// session that can't run them.
+func jobsUnsupported(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Status {
+	case ERROR_NSUP_OPER, ERROR_NSUP_MECH, ERROR_INV_EVENT:
+		return true
+	}
+	return false
+}
+
+// completion is the outcome of an asynchronous job.
+type completion struct {
+	status Status
+	retCnt uint32
+}
+
+// jobWaiter hands the EVENT_IO_COMPLETION events of a session to the jobs
+// waiting for them.
+type jobWaiter struct {
+	handler HandlerID
+	rm      Session // that opened the session
+
+	mu       sync.Mutex
+	waiting  map[uint32]chan completion
+	starting int                   // jobs being started
+	early    map[uint32]completion // completed before start returned
+}
+
+// jobWaiters are the job waiters of the sessions, guarded by their mutex.
+var jobWaiters = struct {
+	sync.Mutex
+	m map[Object]*jobWaiter
+}{m: make(map[Object]*jobWaiter)}
+
+// jobs returns the session's job waiter, installing its handler and
+// enabling EVENT_IO_COMPLETION the first time.
+func (instr Object) jobs() (*jobWaiter, error) {
+	jobWaiters.Lock()
+	defer jobWaiters.Unlock()
+	if w, ok := jobWaiters.m[instr]; ok {
+		return w, nil
+	}
+	rm, _ := instr.AttrUint32(ATTR_RM_SESSION)
+	w := &jobWaiter{
+		rm:      Session(rm),
+		waiting: make(map[uint32]chan completion),
+		early:   make(map[uint32]completion),
+	}
+	id, status := instr.InstallHandler(EVENT_IO_COMPLETION, w.handle)
+	if status < SUCCESS {
+		return nil, instr.Wrap("InstallHandler", status)
+	}
+	if status := instr.EnableEvent(EVENT_IO_COMPLETION, HNDLR, NULL); status < SUCCESS {
+		instr.UninstallHandler(EVENT_IO_COMPLETION, id)
+		return nil, instr.Wrap("EnableEvent", status)
+	}
+	w.handler = id
+	jobWaiters.m[instr] = w
+	return w, nil
+}
+
+// dropJobs forgets the job waiter of instr, and those of the sessions it
+// opened if it's a resource manager, used when it's closed. Closing the
+// sessions ended their jobs, those still waiting complete with
+// ERROR_ABORT.
+func dropJobs(instr Object) {
+	var dropped []*jobWaiter
+	jobWaiters.Lock()
+	for o, w := range jobWaiters.m {
+		if o == instr || w.rm == Session(instr) {
+			dropped = append(dropped, w)
+			delete(jobWaiters.m, o)
+		}
+	}
+	jobWaiters.Unlock()
+	for _, w := range dropped {
+		w.mu.Lock()
+		for id, ch := range w.waiting {
+			ch <- completion{status: ERROR_ABORT}
+			delete(w.waiting, id)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// resetJobs forgets every job waiter, used when the backend is replaced.
+func resetJobs() {
+	jobWaiters.Lock()
+	jobWaiters.m = make(map[Object]*jobWaiter)
+	jobWaiters.Unlock()
+}
+
+// start starts a job with f and returns its ID and the channel receiving
+// its completion.
+func (w *jobWaiter) start(f func() (uint32, Status)) (uint32, <-chan completion, Status) {
+	w.mu.Lock()
+	w.starting++
+	w.mu.Unlock()
+
+	job, status := f()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.starting--
+	var ch chan completion
+	if status >= SUCCESS {
+		ch = make(chan completion, 1)
+		if c, ok := w.early[job]; ok {
+			ch <- c
+			delete(w.early, job)
+		} else {
+			w.waiting[job] = ch
+		}
+	}
+	if w.starting == 0 {
+		w.early = make(map[uint32]completion)
+	}
+	return job, ch, status
+}
+
+// handle is the EVENT_IO_COMPLETION handler.
+func (w *jobWaiter) handle(instr Object, etype, eventContext uint32) {
+	e, err := DecodeEvent(eventContext)
+	if err != nil {
+		return
+	}
+	io, ok := e.(IOCompletionEvent)
+	if !ok {
+		return
+	}
+	c := completion{io.Status, uint32(io.RetCount)}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if ch, ok := w.waiting[io.JobID]; ok {
+		ch <- c
+		delete(w.waiting, io.JobID)
+	} else if w.starting > 0 {
+		w.early[io.JobID] = c
+	}
+}
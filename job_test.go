@@ -0,0 +1,176 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startStalledRead stalls the reads of l, starts a read job and waits for
+// it to block.
+func startStalledRead(t *testing.T, instr Object, l *loopback) *Job {
+	t.Helper()
+	l.stall = true
+	j, err := instr.StartRead(16)
+	if err != nil {
+		t.Fatalf("StartRead: %v", err)
+	}
+	waitStalled(t, l)
+	return j
+}
+
+func TestJobResult(t *testing.T) {
+	rm := openTestRM(t)
+	instr, _ := openLoopback(t, rm, "VXI0::1::INSTR")
+
+	w, err := instr.StartWrite([]byte("hello"))
+	if err != nil {
+		t.Fatalf("StartWrite: %v", err)
+	}
+	if err := w.Wait(context.Background()); err != nil || w.RetCount() != 5 {
+		t.Errorf("write job = %d, %v", w.RetCount(), err)
+	}
+	r, err := instr.StartRead(16)
+	if err != nil {
+		t.Fatalf("StartRead: %v", err)
+	}
+	if data, err := r.Result(); err != nil || string(data) != "hello" {
+		t.Errorf("read job Result = %q, %v", data, err)
+	}
+	if r.ID() == w.ID() {
+		t.Errorf("both jobs have ID %d", r.ID())
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	j := startStalledRead(t, instr, l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := j.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait = %v, want context.DeadlineExceeded", err)
+	}
+	if err := j.Cancel(); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if _, err := j.Result(); !errors.Is(err, ErrAbort) {
+		t.Errorf("cancelled job Result error %v, want ErrAbort", err)
+	}
+	if err := j.Cancel(); err != nil {
+		t.Errorf("cancelling a completed job: %v", err)
+	}
+}
+
+func TestJobTerminate(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	j := startStalledRead(t, instr, l)
+
+	if status := instr.Terminate(NULL, uint16(j.ID())); status != SUCCESS {
+		t.Fatalf("Terminate: %v", status)
+	}
+	select {
+	case <-j.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("the job didn't complete after Terminate")
+	}
+	if _, err := j.Result(); !errors.Is(err, ErrAbort) {
+		t.Errorf("terminated job Result error %v, want ErrAbort", err)
+	}
+	if status := instr.Terminate(NULL, uint16(j.ID())); status != ERROR_INV_JOB_ID {
+		t.Errorf("Terminate of a completed job: %v, want ERROR_INV_JOB_ID", status)
+	}
+}
+
+func TestJobClose(t *testing.T) {
+	instr, l := openLoopback(t, openTestRM(t), "VXI0::1::INSTR")
+	j := startStalledRead(t, instr, l)
+	instr.Close()
+	select {
+	case <-j.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("the job didn't complete after Close")
+	}
+	if _, err := j.Result(); !errors.Is(err, ErrAbort) {
+		t.Errorf("Result error %v after Close, want ErrAbort", err)
+	}
+}
+
+func TestJobCloseRM(t *testing.T) {
+	rm := openTestRM(t)
+	instr, l := openLoopback(t, rm, "VXI0::1::INSTR")
+	j := startStalledRead(t, instr, l)
+	rm.Close()
+	select {
+	case <-j.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("the job didn't complete after closing the resource manager")
+	}
+	if _, err := j.Result(); !errors.Is(err, ErrAbort) {
+		t.Errorf("Result error %v after closing the resource manager, want ErrAbort", err)
+	}
+	jobWaiters.Lock()
+	defer jobWaiters.Unlock()
+	if _, ok := jobWaiters.m[instr]; ok {
+		t.Error("the session's job waiter outlived its resource manager")
+	}
+}
+
+func TestJobMemory(t *testing.T) {
+	rm := openTestRM(t)
+	instr, l := openLoopback(t, rm, "VXI0::1::INSTR")
+	l.buf = []byte("data")
+
+	var mu sync.Mutex
+	freed := 0
+	free := func() {
+		mu.Lock()
+		freed++
+		mu.Unlock()
+	}
+	mem := make([]byte, 8)
+	j, err := instr.startJob("ReadAsync", mem, free,
+		func() (uint32, Status) { return backend.ReadAsync(instr, mem) },
+		func(mem []byte, c completion) []byte {
+			mu.Lock()
+			defer mu.Unlock()
+			if freed != 0 {
+				t.Error("the memory was freed before the data was collected")
+			}
+			return append([]byte(nil), mem[:c.retCnt]...)
+		})
+	if err != nil {
+		t.Fatalf("startJob: %v", err)
+	}
+	if data, err := j.Result(); err != nil || string(data) != "data" {
+		t.Errorf("Result = %q, %v", data, err)
+	}
+	mu.Lock()
+	if freed != 1 {
+		t.Errorf("memory freed %d times after completion, want once", freed)
+	}
+	mu.Unlock()
+
+	jobWaiters.Lock()
+	w := jobWaiters.m[instr]
+	jobWaiters.Unlock()
+	w.mu.Lock()
+	if len(w.waiting) != 0 || len(w.early) != 0 {
+		t.Errorf("the waiter still tracks %d waiting and %d early jobs", len(w.waiting), len(w.early))
+	}
+	w.mu.Unlock()
+
+	// A job that fails to start frees its memory right away.
+	freed = 0
+	_, err = instr.startJob("ReadAsync", mem, free,
+		func() (uint32, Status) { return 0, ERROR_NSUP_OPER }, nil)
+	if !jobsUnsupported(err) || freed != 1 {
+		t.Errorf("failed start: %v, memory freed %d times", err, freed)
+	}
+}
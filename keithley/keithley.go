@@ -7,11 +7,13 @@ import (
 	"os"
 
 	vi "github.com/jpoirier/visa"
+	"github.com/jpoirier/visa/ieee4882"
 )
 
-// Driver holds the VI driver session
+// Driver holds the VI driver session, the embedded Device provides the
+// IEEE 488.2 common commands.
 type Driver struct {
-	vi.Driver
+	ieee4882.Device
 }
 
 // keithley - works with Keithley S46 RF Switch.
@@ -25,15 +27,31 @@ type Driver struct {
 //
 // Caution: Do not close more than one RF path per multiport switch.
 
+// Open Opens a session to the specified resource, the Driver is nil if
+// that fails.
+func Open(rm vi.Session, name vi.ResourceName, mode, timeout uint32) (*Driver, vi.Status) {
+	instr, status := rm.Open(name.String(), mode, timeout)
+	if status < vi.SUCCESS {
+		return nil, status
+	}
+	return &Driver{ieee4882.Device{Driver: instr}}, status
+}
+
 // OpenGpib Opens a session to the specified resource.
 func OpenGpib(rm vi.Session, ctrl, addr, mode, timeout uint32) (*Driver, vi.Status) {
-	name := fmt.Sprintf("GPIB%d::%d", ctrl, addr)
-	instr, status := rm.Open(name, mode, timeout)
+	name := vi.ResourceName{
+		Interface:        vi.InterfaceGPIB,
+		Board:            uint16(ctrl),
+		Class:            "INSTR",
+		PrimaryAddress:   uint16(addr),
+		SecondaryAddress: vi.NO_SEC_ADDR,
+	}
+	instr, status := rm.Open(name.String(), mode, timeout)
 	if status < vi.SUCCESS {
 		fmt.Println("Error, OpenGpib failed with error: ", status)
 		os.Exit(0)
 	}
-	return &Driver{instr}, status
+	return &Driver{ieee4882.Device{Driver: instr}}, status
 }
 
 // OpenTCP Opens a session to the specified resource.
@@ -48,14 +66,12 @@ func OpenTCP(rm vi.Session, ip string, mode, timeout uint32) (*Driver, vi.Status
 		fmt.Println("Error, OpenGpib failed with error: ", status)
 		os.Exit(0)
 	}
-	return &Driver{instr}, status
+	return &Driver{ieee4882.Device{Driver: instr}}, status
 }
 
 // Reset Resets the switch unit.
 func (d *Driver) Reset() (status vi.Status) {
-	b := []byte("*RST")
-	_, status = d.Write(b, uint32(len(b)))
-	return
+	return vi.StatusOf(d.Device.Reset())
 }
 
 // OpenChan Opens the specified channel. Where an open channel does not
@@ -96,16 +112,8 @@ func (d *Driver) ClosedChanList() (list string, status vi.Status) {
 	// RF Switch returns format '(@1,2,3)'.
 	// If no channels closed, switch returns '(@)'.
 
-	b := []byte("CLOSE?")
-	_, status = d.Write(b, uint32(len(b)))
-	if status < vi.SUCCESS {
-		return
-	}
-	buffer, _, status := d.Read(100)
-	if status < vi.SUCCESS {
-		return
-	}
-	return string(buffer), status
+	list, err := vi.Query(d.Driver, "CLOSE?")
+	return list, vi.StatusOf(err)
 }
 
 // if len(strClosedChans) > 3:  # Len always greater than 3 if channel in the list.
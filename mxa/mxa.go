@@ -3,28 +3,73 @@
 package mxa
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
 	vi "github.com/jpoirier/visa"
+	"github.com/jpoirier/visa/ieee4882"
 )
 
-// Driver holds the VI driver session
+// Driver holds the VI driver session, the embedded Device provides the
+// IEEE 488.2 common commands.
 type Driver struct {
-	vi.Driver
+	ieee4882.Device
+
+	// SettleTimeout bounds how long mode changes wait for the analyzer to
+	// settle, using Device.OPCStrategy, before they fail with ERROR_TMO.
+	// Zero means DefaultSettleTimeout.
+	SettleTimeout time.Duration
+}
+
+// DefaultSettleTimeout is the settle timeout of drivers that don't set one.
+const DefaultSettleTimeout = 30 * time.Second
+
+// settle waits for the analyzer to complete the commands sent, failing
+// with ERROR_TMO if that takes longer than the settle timeout.
+func (d *Driver) settle() vi.Status {
+	timeout := d.SettleTimeout
+	if timeout == 0 {
+		timeout = DefaultSettleTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := d.Settle(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return vi.ERROR_TMO
+	}
+	return vi.StatusOf(err)
+}
+
+// Open Opens a session to the specified resource, the Driver is nil if
+// that fails.
+func Open(rm vi.Session, name vi.ResourceName, mode, timeout uint32) (*Driver, vi.Status) {
+	instr, status := rm.Open(name.String(), mode, timeout)
+	if status < vi.SUCCESS {
+		return nil, status
+	}
+	return &Driver{Device: ieee4882.Device{Driver: instr}}, status
 }
 
 // OpenGpib Opens a session to the specified resource.
 func OpenGpib(rm vi.Session, ctrl, addr, mode, timeout uint32) (*Driver, vi.Status) {
-	name := fmt.Sprintf("GPIB%d::%d", ctrl, addr)
-	instr, status := rm.Open(name, mode, timeout)
+	name := vi.ResourceName{
+		Interface:        vi.InterfaceGPIB,
+		Board:            uint16(ctrl),
+		Class:            "INSTR",
+		PrimaryAddress:   uint16(addr),
+		SecondaryAddress: vi.NO_SEC_ADDR,
+	}
+	instr, status := rm.Open(name.String(), mode, timeout)
 	if status < vi.SUCCESS {
 		fmt.Println("Error, OpenGpib failed with error: ", status)
 		os.Exit(0)
 	}
-	return &Driver{instr}, status
+	return &Driver{Device: ieee4882.Device{Driver: instr}}, status
 }
 
 // OpenTCP Opens a session to the specified resource.
@@ -39,7 +84,7 @@ func OpenTCP(rm vi.Session, ip string, mode, timeout uint32) (*Driver, vi.Status
 		fmt.Println("Error, OpenGpib failed with error: ", status)
 		os.Exit(0)
 	}
-	return &Driver{instr}, status
+	return &Driver{Device: ieee4882.Device{Driver: instr}}, status
 }
 
 // SetScreenTitle Sets screen title.
@@ -56,29 +101,24 @@ func (d *Driver) SaveScreenShot(name string) (status vi.Status) {
 	return
 }
 
-// def getFile(self, strSrcFilename, strDstFilename):
-//   """
-//     Get file contents as raw binary data and save to file on local pc.
-//     Usually used to fetch a screenshot or trace.
-//   """
-//   strSrcFilename = strSrcFilename.upper()  # Convert filename to all uppercase per GPIB standard
-
-//   # Agilent Command to read data file
-//   self.write("MMEM:DATA? '%s'" % strSrcFilename)
-//   strData = self.Instrument.read_raw()
-
-//   # data is stored as a packed string in strData. Entire file is stored here, limited only by PC RAM.
-//   if strData[0] == '#':  # must start with the # sign
-//     Length_of_LengthBytes = int(strData[1],10)  # Second byte tells how many bytes it takes to tell us the full file length
-//     dataLen = int(strData[2:2+Length_of_LengthBytes],10)  # Read bytes to tell how long data is
-//     #print 'File Length is: %d' % dataLen
-
-//     outfile = open(strDstFilename,'wb')
+// GetFile Copies the contents of the file name, which must include the
+// full path, to w. Usually used to fetch a screenshot or trace.
+func (d *Driver) GetFile(name string, w io.Writer) (n int64, status vi.Status) {
+	b := fmt.Sprintf("MMEM:DATA? '%s'", name)
+	_, status = d.Write([]byte(b), uint32(len(b)))
+	if status < vi.SUCCESS {
+		return
+	}
+	n, err := vi.ReadBlock(d.Driver, w, nil)
+	return n, vi.StatusOf(err)
+}
 
-//     for i in range(2+Length_of_LengthBytes,len(strData)-1):
-//       outfile.write(strData[i])
-//       outfile.flush()
-//       outfile.close
+// PutFile Writes size bytes of r to the file name, which must include the
+// full path. progress, which may be nil, is called as the data is sent.
+func (d *Driver) PutFile(name string, r io.Reader, size int64, progress vi.Progress) (status vi.Status) {
+	err := vi.WriteBlock(d.Driver, fmt.Sprintf("MMEM:DATA '%s',", name), r, size, progress)
+	return vi.StatusOf(err)
+}
 
 // DeleteFile Deletes file name; name must include the full path.
 func (d *Driver) DeleteFile(name string) (status vi.Status) {
@@ -135,6 +175,26 @@ func (d *Driver) ClearAllTraces() (status vi.Status) {
 	return
 }
 
+// GetTrace Returns the points of trace number, in the Y axis unit, e.g.
+// dBm. The points are transferred as REAL,32 in swapped byte order, the
+// data format and byte order are set back to ASCII and normal afterwards.
+// A failed query is reported before a failure to restore them.
+func (d *Driver) GetTrace(trace uint32) (points []float64, status vi.Status) {
+	b := []byte("FORM:DATA REAL,32;:FORM:BORD SWAP")
+	_, status = d.Write(b, uint32(len(b)))
+	if status < vi.SUCCESS {
+		return
+	}
+	points, err := vi.QueryBinaryFloats(d.Driver, fmt.Sprintf("TRAC:DATA? TRACE%d", trace),
+		vi.Real32, vi.BordSwapped)
+	b = []byte("FORM:DATA ASC;:FORM:BORD NORM")
+	_, status = d.Write(b, uint32(len(b)))
+	if err != nil {
+		return nil, vi.StatusOf(err)
+	}
+	return
+}
+
 // SetCenterFreqKHz Sets the center crequency mhz.
 func (d *Driver) SetCenterFreqKHz(mhz float32) (status vi.Status) {
 	b := fmt.Sprintf("FREQ:CENT %f KHZ", mhz)
@@ -158,21 +218,12 @@ func (d *Driver) SetCenterFreqGHz(ghz float32) (status vi.Status) {
 
 // GetCenterFreqMHz returns the center frequency mhz).
 func (d *Driver) GetCenterFreqMHz() (mhz float32, status vi.Status) {
-	b := []byte("FREQ:CENT?")
-	_, status = d.Write(b, uint32(len(b)))
-	if status < vi.SUCCESS {
-		return
-	}
-	buffer, retCount, status := d.Read(50)
-	if status < vi.SUCCESS && retCount > 0 {
-		return
-	}
-	t, err := strconv.ParseFloat(string(buffer), 32)
+	t, err := vi.QueryFloat(d.Driver, "FREQ:CENT?")
 	if err != nil {
-		return mhz, -1
+		return mhz, vi.StatusOf(err)
 	}
 	mhz = float32(t / 1000.0 / 1000.0)
-	return
+	return mhz, vi.SUCCESS
 }
 
 // TBD - setFreqSpan seems to only work for Spectrum Analyzer.  How do I set for LTE ACP Measurement?
@@ -395,14 +446,14 @@ func (d *Driver) SetMarkerNextPeakL(marker uint32) (status vi.Status) {
 
 // SetMarkerContPeakOn Sets marker continuous peak search on.
 func (d *Driver) SetMarkerContPeakOn(marker uint32) (status vi.Status) {
-	b := fmt.Sprintf("CALC:MARK%d:CPSEARCH: ON", marker)
+	b := fmt.Sprintf("CALC:MARK%d:CPS ON", marker)
 	_, status = d.Write([]byte(b), uint32(len(b)))
 	return
 }
 
 // SetMarkerContPeakOff Sets marker continuous peak search off.
 func (d *Driver) SetMarkerContPeakOff(marker uint32) (status vi.Status) {
-	b := fmt.Sprintf("CALC:MARK%d:CPSEARCH: OFF", marker)
+	b := fmt.Sprintf("CALC:MARK%d:CPS OFF", marker)
 	_, status = d.Write([]byte(b), uint32(len(b)))
 	return
 }
@@ -464,7 +515,8 @@ func (d *Driver) SaveSpectogram(filename string) (status vi.Status) {
 	return
 }
 
-// ShowLTEACP Sets LTE mode and ACP measurement screen on.
+// ShowLTEACP Sets LTE mode and ACP measurement screen on, returning once
+// the analyzer has settled.
 func (d *Driver) ShowLTEACP() (status vi.Status) {
 	b := []byte("INST LTE")
 	_, status = d.Write(b, uint32(len(b)))
@@ -473,17 +525,24 @@ func (d *Driver) ShowLTEACP() (status vi.Status) {
 	}
 	b = []byte("CONF:ACP")
 	_, status = d.Write(b, uint32(len(b)))
-	return
+	if status < vi.SUCCESS {
+		return
+	}
+	return d.settle()
 }
 
 //   # TBD - Resize Marker Table
 //   #       Does not seem possible via remote commands.
 
-// ShowSpectrumAnalyzer Sets spectrum analyzer mode on.
+// ShowSpectrumAnalyzer Sets spectrum analyzer mode on, returning once the
+// analyzer has settled.
 func (d *Driver) ShowSpectrumAnalyzer() (status vi.Status) {
 	b := []byte("INST SA")
 	_, status = d.Write(b, uint32(len(b)))
-	return
+	if status < vi.SUCCESS {
+		return
+	}
+	return d.settle()
 }
 
 // SetRefLevel Sets the reference level to dbm.
@@ -0,0 +1,137 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package mxa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	vi "github.com/jpoirier/visa"
+	"github.com/jpoirier/visa/ieee4882"
+)
+
+// fakeMXA is a vi.Driver recording the commands written to it. It answers
+// TRAC:DATA? with trace, unless it's nil, and fails writes of failCmd.
+// *OPC? and *ESR? report the operations complete unless it's busy.
+type fakeMXA struct {
+	trace   []byte
+	failCmd string
+	busy    bool
+	cmds    []string
+	pending []byte
+}
+
+func (f *fakeMXA) Close() vi.Status { return vi.SUCCESS }
+
+func (f *fakeMXA) Write(buf []byte, cnt uint32) (uint32, vi.Status) {
+	cmd := string(buf[:cnt])
+	f.cmds = append(f.cmds, cmd)
+	if cmd == f.failCmd {
+		return 0, vi.ERROR_CONN_LOST
+	}
+	switch {
+	case cmd == "TRAC:DATA? TRACE1" && f.trace != nil:
+		f.pending = f.trace
+	case cmd == "*OPC?" && !f.busy:
+		f.pending = []byte("1\n")
+	case cmd == "*ESR?" && !f.busy:
+		f.pending = []byte("1\n")
+	case cmd == "*ESR?":
+		f.pending = []byte("0\n")
+	}
+	return cnt, vi.SUCCESS
+}
+
+func (f *fakeMXA) Read(cnt uint32) ([]byte, uint32, vi.Status) {
+	if len(f.pending) == 0 {
+		return nil, 0, vi.ERROR_TMO
+	}
+	n := len(f.pending)
+	if n > int(cnt) {
+		n = int(cnt)
+	}
+	b := f.pending[:n]
+	f.pending = f.pending[n:]
+	if len(f.pending) > 0 {
+		return b, uint32(n), vi.SUCCESS_MAX_CNT
+	}
+	return b, uint32(n), vi.SUCCESS
+}
+
+const restoreFormat = "FORM:DATA ASC;:FORM:BORD NORM"
+
+func TestGetTrace(t *testing.T) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []float32{-50.5, -80})
+	f := &fakeMXA{trace: append(append([]byte("#18"), data.Bytes()...), '\n')}
+	d := &Driver{Device: ieee4882.Device{Driver: f}}
+
+	points, status := d.GetTrace(1)
+	if status != vi.SUCCESS || !reflect.DeepEqual(points, []float64{-50.5, -80}) {
+		t.Errorf("GetTrace = %v, %v", points, status)
+	}
+	want := []string{"FORM:DATA REAL,32;:FORM:BORD SWAP", "TRAC:DATA? TRACE1", restoreFormat}
+	if !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("the analyzer received %q, want %q", f.cmds, want)
+	}
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	// A failed query is reported even if restoring the format fails too,
+	// which is still attempted.
+	f := &fakeMXA{failCmd: restoreFormat}
+	d := &Driver{Device: ieee4882.Device{Driver: f}}
+	if points, status := d.GetTrace(1); status != vi.ERROR_TMO || points != nil {
+		t.Errorf("GetTrace without a response = %v, %v, want ERROR_TMO", points, status)
+	}
+	if last := f.cmds[len(f.cmds)-1]; last != restoreFormat {
+		t.Errorf("the last command was %q, want %q", last, restoreFormat)
+	}
+
+	// Otherwise a failure to restore it is.
+	f = &fakeMXA{trace: []byte("#10\n"), failCmd: restoreFormat}
+	d = &Driver{Device: ieee4882.Device{Driver: f}}
+	if _, status := d.GetTrace(1); status != vi.ERROR_CONN_LOST {
+		t.Errorf("GetTrace failing to restore the format: %v, want ERROR_CONN_LOST", status)
+	}
+}
+
+func TestShowSettles(t *testing.T) {
+	f := &fakeMXA{}
+	d := &Driver{Device: ieee4882.Device{Driver: f}}
+	if status := d.ShowLTEACP(); status != vi.SUCCESS {
+		t.Errorf("ShowLTEACP: %v", status)
+	}
+	if want := []string{"INST LTE", "CONF:ACP", "*OPC?"}; !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("the analyzer received %q, want %q", f.cmds, want)
+	}
+
+	// The strategy is the Device's.
+	f.cmds = nil
+	d.OPCStrategy = ieee4882.OPCByPolling
+	if status := d.ShowSpectrumAnalyzer(); status != vi.SUCCESS {
+		t.Errorf("ShowSpectrumAnalyzer: %v", status)
+	}
+	if want := []string{"INST SA", "*ESR?", "*OPC", "*ESR?"}; !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("the analyzer received %q, want %q", f.cmds, want)
+	}
+
+	// An analyzer that doesn't settle times out.
+	f.busy = true
+	d.SettleTimeout = 50 * time.Millisecond
+	if status := d.ShowSpectrumAnalyzer(); status != vi.ERROR_TMO {
+		t.Errorf("ShowSpectrumAnalyzer of a busy analyzer: %v, want ERROR_TMO", status)
+	}
+
+	// A failed command isn't waited for.
+	f = &fakeMXA{failCmd: "CONF:ACP"}
+	d = &Driver{Device: ieee4882.Device{Driver: f}}
+	if status := d.ShowLTEACP(); status != vi.ERROR_CONN_LOST || len(f.cmds) != 2 {
+		t.Errorf("ShowLTEACP = %v after sending %q, want ERROR_CONN_LOST", status, f.cmds)
+	}
+}
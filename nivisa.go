@@ -0,0 +1,676 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+//go:build cgo && !novisa
+// +build cgo,!novisa
+
+package visa
+
+/*
+#cgo linux LDFLAGS: -lvisa
+#cgo darwin LDFLAGS: -framework VISA
+#cgo windows LDFLAGS: -lvisa64 -L.
+#cgo windows CFLAGS: -IC:/Program\ Files/IVI\ Foundation/VISA/Win64/Include
+#cgo CFLAGS: -I.
+
+#include <stdint.h>
+#include <stdlib.h>
+#include "visa.h"
+
+extern ViStatus go_cb(ViSession, ViEventType, ViEvent, ViAddr);
+
+// The handler ID travels as the user handle, an integer and not a Go
+// pointer.
+ViStatus install_go_cb(ViSession vi, ViEventType etype, uintptr_t id) {
+	return viInstallHandler(vi, etype, (ViHndlr)go_cb, (ViAddr)id);
+}
+
+ViStatus uninstall_go_cb(ViSession vi, ViEventType etype, uintptr_t id) {
+	return viUninstallHandler(vi, etype, (ViHndlr)go_cb, (ViAddr)id);
+}
+
+ViStatus vi_printf(ViSession vi, ViString string) {
+	return viPrintf(vi, "%s", string);
+}
+
+ViStatus vi_sprintf(ViSession vi, ViPBuf buf, ViString string) {
+	return viSPrintf(vi, buf, "%s", string);
+}
+*/
+import "C"
+import "unsafe"
+
+// niBackend calls the NI-VISA library, or any other VISA implementation
+// the package is linked against.
+type niBackend struct{}
+
+func newDefaultBackend() Backend {
+	return niBackend{}
+}
+
+// NewNIBackend returns the backend that calls the linked VISA library.
+func NewNIBackend() Backend {
+	return niBackend{}
+}
+
+// jobMemory allocates the memory of an asynchronous job with malloc, as
+// VISA keeps using it after the call starting the job returns, and returns
+// the function freeing it. It fails with ERROR_ALLOC if malloc does.
+func jobMemory(n int) ([]byte, func(), Status) {
+	if n == 0 {
+		return nil, func() {}, SUCCESS
+	}
+	p := C.malloc(C.size_t(n))
+	if p == nil {
+		return nil, nil, ERROR_ALLOC
+	}
+	return unsafe.Slice((*byte)(p), n), func() { C.free(p) }, SUCCESS
+}
+
+// bufPtr returns a pointer to the first byte of buf, nil if it's empty.
+func bufPtr(buf []byte) *C.ViByte {
+	if len(buf) == 0 {
+		return nil
+	}
+	return (*C.ViByte)(unsafe.Pointer(&buf[0]))
+}
+
+// ----------------------------------------------------------------------------
+// Resource Manager Functions and Operations
+//
+
+func (niBackend) OpenDefaultRM() (rm Session, status Status) {
+	status = Status(C.viOpenDefaultRM((*C.ViSession)(unsafe.Pointer(&rm))))
+	return rm, status
+}
+
+func (niBackend) FindRsrc(rm Session, expr string) (findList, retCnt uint32, desc string,
+	status Status) {
+
+	cexpr := (*C.ViChar)(C.CString(expr))
+	defer C.free(unsafe.Pointer(cexpr))
+	d := make([]byte, 257)
+	status = Status(C.viFindRsrc(C.ViSession(rm),
+		cexpr,
+		(*C.ViFindList)(unsafe.Pointer(&findList)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt)),
+		(*C.ViChar)(unsafe.Pointer(&d[0]))))
+	return findList, retCnt, cString(d), status
+}
+
+func (niBackend) FindNext(findList uint32) (string, Status) {
+	d := make([]byte, 257)
+	status := Status(C.viFindNext((C.ViFindList)(findList),
+		(*C.ViChar)(unsafe.Pointer(&d[0]))))
+	return cString(d), status
+}
+
+func (niBackend) ParseRsrc(rm Session, rsrcName string) (intfType, intfNum uint16, status Status) {
+	crsrcName := (*C.ViChar)(C.CString(rsrcName))
+	defer C.free(unsafe.Pointer(crsrcName))
+	status = Status(C.viParseRsrc(C.ViSession(rm),
+		crsrcName,
+		(*C.ViUInt16)(unsafe.Pointer(&intfType)),
+		(*C.ViUInt16)(unsafe.Pointer(&intfNum))))
+	return intfType, intfNum, status
+}
+
+func (niBackend) ParseRsrcEx(rm Session, rsrcName string) (intfType, intfNum uint16, rsrcClass,
+	expandedUnaliasedName, aliasIfExists string, status Status) {
+
+	crsrcName := (*C.ViChar)(C.CString(rsrcName))
+	defer C.free(unsafe.Pointer(crsrcName))
+	r := make([]byte, 257)
+	e := make([]byte, 257)
+	a := make([]byte, 257)
+	status = Status(C.viParseRsrcEx(C.ViSession(rm),
+		crsrcName,
+		(*C.ViUInt16)(unsafe.Pointer(&intfType)),
+		(*C.ViUInt16)(unsafe.Pointer(&intfNum)),
+		(*C.ViChar)(unsafe.Pointer(&r[0])),
+		(*C.ViChar)(unsafe.Pointer(&e[0])),
+		(*C.ViChar)(unsafe.Pointer(&a[0]))))
+	return intfType, intfNum, cString(r), cString(e), cString(a), status
+}
+
+func (niBackend) Open(rm Session, name string, mode, timeout uint32) (instr Object,
+	status Status) {
+
+	cname := (*C.ViChar)(C.CString(name))
+	defer C.free(unsafe.Pointer(cname))
+	status = Status(C.viOpen(C.ViSession(rm),
+		cname,
+		(C.ViAccessMode)(mode),
+		(C.ViUInt32)(timeout),
+		(*C.ViSession)(unsafe.Pointer(&instr))))
+	return instr, status
+}
+
+// ----------------------------------------------------------------------------
+// Resource Template Operations
+//
+
+func (niBackend) Close(vi uint32) Status {
+	return Status(C.viClose((C.ViObject)(vi)))
+}
+
+func (niBackend) SetAttribute(vi, attribute uint32, attrState uint64) Status {
+	return Status(C.viSetAttribute((C.ViObject)(vi),
+		(C.ViAttr)(attribute),
+		(C.ViAttrState)(attrState)))
+}
+
+func (niBackend) GetAttribute(vi, attribute uint32, addr unsafe.Pointer) Status {
+	return Status(C.viGetAttribute((C.ViObject)(vi), (C.ViAttr)(attribute), addr))
+}
+
+func (niBackend) StatusDesc(vi uint32, status_in Status) (string, Status) {
+	d := make([]byte, 257)
+	status := Status(C.viStatusDesc((C.ViObject)(vi),
+		(C.ViStatus)(status_in),
+		(*C.ViChar)(unsafe.Pointer(&d[0]))))
+	return cString(d), status
+}
+
+func (niBackend) Terminate(vi uint32, degree, jobId uint16) Status {
+	return Status(C.viTerminate((C.ViObject)(vi),
+		(C.ViUInt16)(degree),
+		(C.ViJobId)(jobId)))
+}
+
+func (niBackend) Lock(instr Object, lockType, timeout uint32, requestedKey string) (string, Status) {
+	if lockType == EXCLUSIVE_LOCK {
+		return "", Status(C.viLock((C.ViSession)(instr),
+			(C.ViAccessMode)(lockType),
+			(C.ViUInt32)(timeout),
+			(*C.ViChar)(nil),
+			(*C.ViChar)(nil)))
+	}
+	var rk *C.ViChar
+	if requestedKey != "" {
+		rk = (*C.ViChar)(C.CString(requestedKey))
+		defer C.free(unsafe.Pointer(rk))
+	}
+	a := make([]byte, 257)
+	status := Status(C.viLock((C.ViSession)(instr),
+		(C.ViAccessMode)(lockType),
+		(C.ViUInt32)(timeout),
+		rk,
+		(*C.ViChar)(unsafe.Pointer(&a[0]))))
+	return cString(a), status
+}
+
+func (niBackend) Unlock(instr Object) Status {
+	return Status(C.viUnlock((C.ViSession)(instr)))
+}
+
+func (niBackend) EnableEvent(instr Object, eventType uint32, mechanism uint16,
+	context uint32) Status {
+
+	return Status(C.viEnableEvent((C.ViSession)(instr),
+		(C.ViEventType)(eventType),
+		(C.ViUInt16)(mechanism),
+		(C.ViEventFilter)(context)))
+}
+
+func (niBackend) DisableEvent(instr Object, eventType uint32, mechanism uint16) Status {
+	return Status(C.viDisableEvent((C.ViSession)(instr),
+		(C.ViEventType)(eventType),
+		(C.ViUInt16)(mechanism)))
+}
+
+func (niBackend) DiscardEvents(instr Object, eventType uint32, mechanism uint16) Status {
+	return Status(C.viDiscardEvents((C.ViSession)(instr),
+		(C.ViEventType)(eventType),
+		(C.ViUInt16)(mechanism)))
+}
+
+func (niBackend) WaitOnEvent(instr Object, inEventType, timeout uint32) (outEventType,
+	outContext uint32, status Status) {
+
+	status = Status(C.viWaitOnEvent((C.ViSession)(instr),
+		(C.ViEventType)(inEventType),
+		(C.ViUInt32)(timeout),
+		(*C.ViEventType)(unsafe.Pointer(&outEventType)),
+		(*C.ViEvent)(unsafe.Pointer(&outContext))))
+	return outEventType, outContext, status
+}
+
+func (niBackend) InstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status {
+	return Status(C.install_go_cb((C.ViSession)(instr),
+		(C.ViEventType)(eventType),
+		(C.uintptr_t)(userHandle)))
+}
+
+func (niBackend) UninstallHandler(instr Object, eventType uint32, userHandle HandlerID) Status {
+	// ANY_HNDLR as the handler removes them all, whatever the user handle.
+	if userHandle == ANY_HNDLR {
+		return Status(C.viUninstallHandler((C.ViSession)(instr),
+			(C.ViEventType)(eventType),
+			nil,
+			nil))
+	}
+	return Status(C.uninstall_go_cb((C.ViSession)(instr),
+		(C.ViEventType)(eventType),
+		(C.uintptr_t)(userHandle)))
+}
+
+// ----------------------------------------------------------------------------
+// Basic I/O Operations
+//
+
+func (niBackend) Read(instr Object, buf []byte) (retCnt uint32, status Status) {
+	status = Status(C.viRead((C.ViSession)(instr),
+		bufPtr(buf),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) ReadAsync(instr Object, buf []byte) (jobId uint32, status Status) {
+	status = Status(C.viReadAsync((C.ViSession)(instr),
+		bufPtr(buf),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViJobId)(unsafe.Pointer(&jobId))))
+	return jobId, status
+}
+
+func (niBackend) ReadToFile(instr Object, filename string, cnt uint32) (retCnt uint32,
+	status Status) {
+
+	cfilename := (*C.ViChar)(C.CString(filename))
+	defer C.free(unsafe.Pointer(cfilename))
+	status = Status(C.viReadToFile((C.ViSession)(instr),
+		cfilename,
+		(C.ViUInt32)(cnt),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) Write(instr Object, buf []byte) (retCnt uint32, status Status) {
+	status = Status(C.viWrite((C.ViSession)(instr),
+		(C.ViBuf)(bufPtr(buf)),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) WriteAsync(instr Object, buf []byte) (jobId uint32, status Status) {
+	status = Status(C.viWriteAsync((C.ViSession)(instr),
+		(C.ViBuf)(bufPtr(buf)),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViJobId)(unsafe.Pointer(&jobId))))
+	return jobId, status
+}
+
+func (niBackend) WriteFromFile(instr Object, filename string, cnt uint32) (retCnt uint32,
+	status Status) {
+
+	cfilename := (*C.ViChar)(C.CString(filename))
+	defer C.free(unsafe.Pointer(cfilename))
+	status = Status(C.viWriteFromFile((C.ViSession)(instr),
+		cfilename,
+		(C.ViUInt32)(cnt),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) AssertTrigger(instr Object, protocol uint16) Status {
+	return Status(C.viAssertTrigger((C.ViSession)(instr),
+		(C.ViUInt16)(protocol)))
+}
+
+func (niBackend) ReadSTB(instr Object) (stb_stat uint16, status Status) {
+	status = Status(C.viReadSTB((C.ViSession)(instr),
+		(*C.ViUInt16)(unsafe.Pointer(&stb_stat))))
+	return stb_stat, status
+}
+
+func (niBackend) Clear(instr Object) Status {
+	return Status(C.viClear((C.ViSession)(instr)))
+}
+
+// ----------------------------------------------------------------------------
+// Formatted and Buffered I/O Operations
+//
+
+func (niBackend) SetBuf(instr Object, mask uint16, size uint32) Status {
+	return Status(C.viSetBuf((C.ViSession)(instr),
+		(C.ViUInt16)(mask),
+		(C.ViUInt32)(size)))
+}
+
+func (niBackend) Flush(instr Object, mask uint16) Status {
+	return Status(C.viFlush((C.ViSession)(instr),
+		(C.ViUInt16)(mask)))
+}
+
+func (niBackend) BufWrite(instr Object, buf []byte) (retCnt uint32, status Status) {
+	status = Status(C.viBufWrite((C.ViSession)(instr),
+		(C.ViBuf)(bufPtr(buf)),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) BufRead(instr Object, buf []byte) (retCnt uint32, status Status) {
+	status = Status(C.viBufRead((C.ViSession)(instr),
+		(C.ViBuf)(bufPtr(buf)),
+		(C.ViUInt32)(len(buf)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) Printf(instr Object, s string) Status {
+	cstr := (*C.ViChar)(C.CString(s))
+	defer C.free(unsafe.Pointer(cstr))
+	return Status(C.vi_printf((C.ViSession)(instr), cstr))
+}
+
+func (niBackend) SPrintf(instr Object, buf *uint8, s string) Status {
+	cstr := (*C.ViChar)(C.CString(s))
+	defer C.free(unsafe.Pointer(cstr))
+	return Status(C.vi_sprintf((C.ViSession)(instr),
+		(*C.ViByte)(unsafe.Pointer(buf)), cstr))
+}
+
+// ----------------------------------------------------------------------------
+// Memory I/O Operations
+//
+
+func (niBackend) In8(instr Object, space uint16, offset BusAddress) (val uint8, status Status) {
+	status = Status(C.viIn8((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(*C.ViUInt8)(&val)))
+	return val, status
+}
+
+func (niBackend) Out8(instr Object, space uint16, offset BusAddress, val uint8) Status {
+	return Status(C.viOut8((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViUInt8)(val)))
+}
+
+func (niBackend) In16(instr Object, space uint16, offset BusAddress) (val uint16, status Status) {
+	status = Status(C.viIn16((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(*C.ViUInt16)(&val)))
+	return val, status
+}
+
+func (niBackend) Out16(instr Object, space uint16, offset BusAddress, val uint16) Status {
+	return Status(C.viOut16((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViUInt16)(val)))
+}
+
+func (niBackend) In32(instr Object, space uint16, offset BusAddress) (val uint32, status Status) {
+	status = Status(C.viIn32((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(*C.ViUInt32)(unsafe.Pointer(&val))))
+	return val, status
+}
+
+func (niBackend) Out32(instr Object, space uint16, offset BusAddress, val uint32) Status {
+	return Status(C.viOut32((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViUInt32)(val)))
+}
+
+func (niBackend) MoveIn8(instr Object, space uint16, offset BusAddress, buf []uint8) Status {
+	return Status(C.viMoveIn8((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt8)(unsafe.Pointer(bufPtr(buf)))))
+}
+
+func (niBackend) MoveOut8(instr Object, space uint16, offset BusAddress, buf []uint8) Status {
+	return Status(C.viMoveOut8((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt8)(unsafe.Pointer(bufPtr(buf)))))
+}
+
+func (niBackend) MoveIn16(instr Object, space uint16, offset BusAddress, buf []uint16) Status {
+	var p unsafe.Pointer
+	if len(buf) > 0 {
+		p = unsafe.Pointer(&buf[0])
+	}
+	return Status(C.viMoveIn16((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt16)(p)))
+}
+
+func (niBackend) MoveOut16(instr Object, space uint16, offset BusAddress, buf []uint16) Status {
+	var p unsafe.Pointer
+	if len(buf) > 0 {
+		p = unsafe.Pointer(&buf[0])
+	}
+	return Status(C.viMoveOut16((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt16)(p)))
+}
+
+func (niBackend) MoveIn32(instr Object, space uint16, offset BusAddress, buf []uint32) Status {
+	var p unsafe.Pointer
+	if len(buf) > 0 {
+		p = unsafe.Pointer(&buf[0])
+	}
+	return Status(C.viMoveIn32((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt32)(p)))
+}
+
+func (niBackend) MoveOut32(instr Object, space uint16, offset BusAddress, buf []uint32) Status {
+	var p unsafe.Pointer
+	if len(buf) > 0 {
+		p = unsafe.Pointer(&buf[0])
+	}
+	return Status(C.viMoveOut32((C.ViSession)(instr),
+		(C.ViUInt16)(space),
+		(C.ViBusAddress)(offset),
+		(C.ViBusSize)(len(buf)),
+		(C.ViAUInt32)(p)))
+}
+
+func (niBackend) Move(instr Object, srcSpace uint16, srcOffset BusAddress, srcWidth uint16,
+	destSpace uint16, destOffset BusAddress, destWidth uint16, srcLength BusSize) Status {
+
+	return Status(C.viMove((C.ViSession)(instr),
+		(C.ViUInt16)(srcSpace),
+		(C.ViBusAddress)(srcOffset),
+		(C.ViUInt16)(srcWidth),
+		(C.ViUInt16)(destSpace),
+		(C.ViBusAddress)(destOffset),
+		(C.ViUInt16)(destWidth),
+		(C.ViBusSize)(srcLength)))
+}
+
+func (niBackend) MoveAsync(instr Object, srcSpace uint16, srcOffset BusAddress, srcWidth uint16,
+	destSpace uint16, destOffset BusAddress, destWidth uint16,
+	srcLength BusSize) (jobId uint32, status Status) {
+
+	status = Status(C.viMoveAsync((C.ViSession)(instr),
+		(C.ViUInt16)(srcSpace),
+		(C.ViBusAddress)(srcOffset),
+		(C.ViUInt16)(srcWidth),
+		(C.ViUInt16)(destSpace),
+		(C.ViBusAddress)(destOffset),
+		(C.ViUInt16)(destWidth),
+		(C.ViBusSize)(srcLength),
+		(*C.ViJobId)(unsafe.Pointer(&jobId))))
+	return jobId, status
+}
+
+func (niBackend) MapAddress(instr Object, mapSpace uint16, mapOffset BusAddress, mapSize BusSize,
+	access uint16, suggested *byte) (address *byte, status Status) {
+
+	status = Status(C.viMapAddress((C.ViSession)(instr),
+		(C.ViUInt16)(mapSpace),
+		(C.ViBusAddress)(mapOffset),
+		(C.ViBusSize)(mapSize),
+		(C.ViBoolean)(access),
+		(C.ViAddr)(unsafe.Pointer(suggested)),
+		(*C.ViAddr)(unsafe.Pointer(&address))))
+	return address, status
+}
+
+func (niBackend) UnmapAddress(instr Object) Status {
+	return Status(C.viUnmapAddress((C.ViSession)(instr)))
+}
+
+func (niBackend) Peek8(instr Object, address unsafe.Pointer) (val uint8) {
+	C.viPeek8((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt8)(&val))
+	return val
+}
+
+func (niBackend) Poke8(instr Object, address unsafe.Pointer, val uint8) {
+	C.viPoke8((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt8)(val))
+}
+
+func (niBackend) Peek16(instr Object, address unsafe.Pointer) (val uint16) {
+	C.viPeek16((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt16)(&val))
+	return val
+}
+
+func (niBackend) Poke16(instr Object, address unsafe.Pointer, val uint16) {
+	C.viPoke16((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt16)(val))
+}
+
+func (niBackend) Peek32(instr Object, address unsafe.Pointer) (val uint32) {
+	C.viPeek32((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt32)(unsafe.Pointer(&val)))
+	return val
+}
+
+func (niBackend) Poke32(instr Object, address unsafe.Pointer, val uint32) {
+	C.viPoke32((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt32)(val))
+}
+
+// ----------------------------------------------------------------------------
+// Shared Memory Operations
+//
+
+func (niBackend) MemAlloc(instr Object, size BusSize) (offset BusAddress, status Status) {
+	status = Status(C.viMemAlloc((C.ViSession)(instr),
+		(C.ViBusSize)(size),
+		(*C.ViBusAddress)(unsafe.Pointer(&offset))))
+	return offset, status
+}
+
+func (niBackend) MemFree(instr Object, offset BusAddress) Status {
+	return Status(C.viMemFree((C.ViSession)(instr), (C.ViBusAddress)(offset)))
+}
+
+// ----------------------------------------------------------------------------
+// Interface Specific Operations
+//
+
+func (niBackend) GpibControlREN(instr Object, mode uint16) Status {
+	return Status(C.viGpibControlREN((C.ViSession)(instr),
+		(C.ViUInt16)(mode)))
+}
+
+func (niBackend) GpibControlATN(instr Object, mode uint16) Status {
+	return Status(C.viGpibControlATN((C.ViSession)(instr),
+		(C.ViUInt16)(mode)))
+}
+
+func (niBackend) GpibSendIFC(instr Object) Status {
+	return Status(C.viGpibSendIFC((C.ViSession)(instr)))
+}
+
+func (niBackend) GpibCommand(instr Object, cmd []byte) (retCnt uint32, status Status) {
+	status = Status(C.viGpibCommand((C.ViSession)(instr),
+		(C.ViBuf)(bufPtr(cmd)),
+		(C.ViUInt32)(len(cmd)),
+		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	return retCnt, status
+}
+
+func (niBackend) GpibPassControl(instr Object, primAddr, secAddr uint16) Status {
+	return Status(C.viGpibPassControl((C.ViSession)(instr),
+		(C.ViUInt16)(primAddr),
+		(C.ViUInt16)(secAddr)))
+}
+
+func (niBackend) VxiCommandQuery(instr Object, mode uint16, cmd uint32) (response uint32, status Status) {
+	status = Status(C.viVxiCommandQuery((C.ViSession)(instr),
+		(C.ViUInt16)(mode),
+		(C.ViUInt32)(cmd),
+		(*C.ViUInt32)(unsafe.Pointer(&response))))
+	return response, status
+}
+
+func (niBackend) AssertUtilSignal(instr Object, line uint16) Status {
+	return Status(C.viAssertUtilSignal((C.ViSession)(instr),
+		(C.ViUInt16)(line)))
+}
+
+func (niBackend) AssertIntrSignal(instr Object, mode int16, statusID uint16) Status {
+	return Status(C.viAssertIntrSignal((C.ViSession)(instr),
+		(C.ViInt16)(mode),
+		(C.ViUInt32)(statusID)))
+}
+
+func (niBackend) MapTrigger(instr Object, trigSrc, trigDest int16, mode uint16) Status {
+	return Status(C.viMapTrigger((C.ViSession)(instr),
+		(C.ViInt16)(trigSrc),
+		(C.ViInt16)(trigDest),
+		(C.ViUInt16)(mode)))
+}
+
+func (niBackend) UnmapTrigger(instr Object, trigSrc, trigDest int16) Status {
+	return Status(C.viUnmapTrigger((C.ViSession)(instr),
+		(C.ViInt16)(trigSrc),
+		(C.ViInt16)(trigDest)))
+}
+
+func (niBackend) PxiReserveTriggers(instr Object, cnt int16, trigBuses, trigLines *int16) (failureIndex int16, status Status) {
+	status = Status(C.viPxiReserveTriggers((C.ViSession)(instr),
+		(C.ViInt16)(cnt),
+		(*C.ViInt16)(trigBuses),
+		(*C.ViInt16)(trigLines),
+		(*C.ViInt16)(&failureIndex)))
+	return failureIndex, status
+}
+
+func (niBackend) UsbControlOut(instr Object, bmRequestType, bRequest int16, wValue, wIndex uint16,
+	buf []byte) Status {
+
+	return Status(C.viUsbControlOut((C.ViSession)(instr),
+		(C.ViInt16)(bmRequestType),
+		(C.ViInt16)(bRequest),
+		(C.ViUInt16)(wValue),
+		(C.ViUInt16)(wIndex),
+		(C.ViUInt16)(len(buf)),
+		bufPtr(buf)))
+}
+
+func (niBackend) UsbControlIn(instr Object, bmRequestType, bRequest int16, wValue, wIndex uint16,
+	buf []byte) (retCnt uint16, status Status) {
+
+	status = Status(C.viUsbControlIn((C.ViSession)(instr),
+		(C.ViInt16)(bmRequestType),
+		(C.ViInt16)(bRequest),
+		(C.ViUInt16)(wValue),
+		(C.ViUInt16)(wIndex),
+		(C.ViUInt16)(len(buf)),
+		bufPtr(buf),
+		(*C.ViUInt16)(&retCnt)))
+	return retCnt, status
+}
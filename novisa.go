@@ -0,0 +1,18 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+//go:build !cgo || novisa
+// +build !cgo novisa
+
+package visa
+
+func newDefaultBackend() Backend {
+	return NewGoBackend()
+}
+
+// jobMemory allocates the memory of an asynchronous job. The pure-Go
+// backend keeps the slice referenced until the job completes.
+func jobMemory(n int) ([]byte, func(), Status) {
+	return make([]byte, n), func() {}, SUCCESS
+}
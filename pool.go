@@ -0,0 +1,335 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool shares the sessions of a resource manager between the parts of a
+// program opening the same resources. Open hands out reference counted
+// handles to one session per resource, named in any form that resolves to
+// the same canonical name, and sessions without handles are closed once
+// they have been idle for the pool's TTL.
+//
+// Handles of a resource share its session, sequences of operations that
+// mustn't interleave, e.g. a write and the read of its response, have to
+// be serialized by the callers.
+type Pool struct {
+	rm  Session
+	ttl time.Duration
+
+	// Attrs are set, in increasing attribute order, on a session every
+	// time a handle to it is handed out, e.g. ATTR_TMO_VALUE and
+	// ATTR_TERMCHAR_EN, undoing changes made through earlier handles.
+	Attrs map[uint32]uint64
+
+	// OpenTimeout is the timeout of Session.Open, in milliseconds.
+	OpenTimeout uint32
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	stats   PoolStats
+	closed  bool
+}
+
+// PoolStats are the counters of a pool.
+type PoolStats struct {
+	Opened  int // sessions opened
+	Reused  int // handles served by an open session
+	Closed  int // sessions closed
+	Leaked  int // handles collected or left over by Close without being closed
+	Open    int // sessions open
+	Handles int // handles not closed
+}
+
+// poolEntry is a pooled session.
+type poolEntry struct {
+	name  string        // canonical resource name
+	ready chan struct{} // closed once the session is open, or failed to
+	instr Object        // set when ready
+	err   error         // set when ready
+	refs  int
+	idle  *time.Timer // closes the session once it has been idle for the TTL
+}
+
+// Handle is a reference to a pooled session. Its Close releases the
+// reference, the other methods are those of the session.
+type Handle struct {
+	Object
+	p    *Pool
+	e    *poolEntry
+	once sync.Once
+}
+
+// pools are the pools of the resource managers, guarded by their mutex.
+var pools = struct {
+	sync.Mutex
+	m map[Session][]*Pool
+}{m: make(map[Session][]*Pool)}
+
+// NewPool returns a pool of sessions opened with rm, which closes idle
+// sessions after ttl, or keeps them open until the pool is closed if ttl is
+// 0. Closing rm closes the pool.
+func NewPool(rm Session, ttl time.Duration) *Pool {
+	p := &Pool{rm: rm, ttl: ttl, entries: make(map[string]*poolEntry)}
+	pools.Lock()
+	pools.m[rm] = append(pools.m[rm], p)
+	pools.Unlock()
+	return p
+}
+
+// Open returns a handle to the session of the resource name, opening it if
+// the pool has none, and sets the pool's Attrs on it. Resources are opened
+// concurrently, callers opening one that's being opened wait for it.
+func (p *Pool) Open(name string) (*Handle, error) {
+	e, opener, err := p.acquire(p.canonical(name))
+	if err != nil {
+		return nil, err
+	}
+	if opener {
+		instr, status := p.rm.Open(name, NULL, p.OpenTimeout)
+		p.opened(e, instr, status.Wrap("Open", name))
+	}
+	<-e.ready
+	if err = e.err; err == nil {
+		err = p.apply(e.instr)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil && p.closed {
+		err = errPoolClosed(name)
+	}
+	if err != nil {
+		p.release(e)
+		return nil, err
+	}
+	h := &Handle{Object: e.instr, p: p, e: e}
+	runtime.SetFinalizer(h, func(h *Handle) { h.release(true) })
+	return h, nil
+}
+
+// acquire returns the entry of the resource key with a reference taken,
+// creating it if the pool has none, in which case the caller has to open
+// the session.
+func (p *Pool) acquire(key string) (e *poolEntry, opener bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, false, errPoolClosed(key)
+	}
+	if e, ok := p.entries[key]; ok {
+		if e.idle != nil {
+			e.idle.Stop()
+			e.idle = nil
+		}
+		e.refs++
+		p.stats.Reused++
+		return e, false, nil
+	}
+	e = &poolEntry{name: key, ready: make(chan struct{}), refs: 1}
+	p.entries[key] = e
+	return e, true, nil
+}
+
+// opened records the outcome of opening the session of e, which is closed
+// again if the pool was closed in the meantime.
+func (p *Pool) opened(e *poolEntry, instr Object, err error) {
+	p.mu.Lock()
+	late := err == nil && p.closed
+	if late {
+		err = errPoolClosed(e.name)
+	}
+	e.instr, e.err = instr, err
+	if err == nil {
+		p.stats.Opened++
+	} else if p.entries[e.name] == e {
+		delete(p.entries, e.name)
+	}
+	close(e.ready)
+	p.mu.Unlock()
+	if late {
+		instr.Close()
+	}
+}
+
+// release drops a reference to e and starts its idle timer when it was the
+// last one.
+func (p *Pool) release(e *poolEntry) {
+	if p.closed || p.entries[e.name] != e {
+		return
+	}
+	if e.refs--; e.refs == 0 && p.ttl > 0 {
+		e.idle = time.AfterFunc(p.ttl, func() { p.expire(e) })
+	}
+}
+
+// errPoolClosed is the error opening a resource with a closed pool.
+func errPoolClosed(name string) error {
+	return Status(ERROR_INV_OBJECT).Wrap("Pool.Open", name)
+}
+
+// Close releases the handle. The session stays open for other handles, or
+// until it has been idle for the pool's TTL. Closing a handle more than
+// once does nothing.
+func (h *Handle) Close() Status {
+	runtime.SetFinalizer(h, nil)
+	h.release(false)
+	return SUCCESS
+}
+
+// release drops the handle's reference, counting it as leaked if it's
+// released by the garbage collector.
+func (h *Handle) release(leaked bool) {
+	h.once.Do(func() {
+		h.p.mu.Lock()
+		defer h.p.mu.Unlock()
+		if leaked {
+			h.p.stats.Leaked++
+		}
+		h.p.release(h.e)
+	})
+}
+
+// expire closes the session of e if it's still idle.
+func (p *Pool) expire(e *poolEntry) {
+	p.mu.Lock()
+	if p.closed || e.refs != 0 || p.entries[e.name] != e {
+		p.mu.Unlock()
+		return
+	}
+	p.removeEntry(e)
+	p.mu.Unlock()
+	e.instr.Close()
+}
+
+// removeEntry removes e from the pool, the caller closes its session once
+// it has unlocked p.mu, as closing can take as long as the I/O timeout.
+func (p *Pool) removeEntry(e *poolEntry) {
+	if e.idle != nil {
+		e.idle.Stop()
+	}
+	delete(p.entries, e.name)
+	p.stats.Closed++
+}
+
+// Stats returns the pool's counters.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	for _, e := range p.entries {
+		if e.instr != 0 {
+			s.Open++
+			s.Handles += e.refs
+		}
+	}
+	return s
+}
+
+// Close closes the pooled sessions, including those with handles, which
+// are counted as leaked, and returns the first failure. Open fails
+// afterwards.
+func (p *Pool) Close() error {
+	pools.Lock()
+	ps := pools.m[p.rm]
+	for i, q := range ps {
+		if q == p {
+			ps = append(ps[:i], ps[i+1:]...)
+			break
+		}
+	}
+	if len(ps) == 0 {
+		delete(pools.m, p.rm)
+	} else {
+		pools.m[p.rm] = ps
+	}
+	pools.Unlock()
+	return p.close()
+}
+
+func (p *Pool) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	var instrs []Object
+	for _, e := range p.entries {
+		if e.instr == 0 {
+			// Being opened, opened closes it.
+			delete(p.entries, e.name)
+			continue
+		}
+		p.stats.Leaked += e.refs
+		p.removeEntry(e)
+		instrs = append(instrs, e.instr)
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	var first error
+	for _, instr := range instrs {
+		if err := instr.Wrap("Close", instr.Close()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// closePools closes the pools of rm, used when it's closed.
+func closePools(rm Session) {
+	pools.Lock()
+	ps := pools.m[rm]
+	delete(pools.m, rm)
+	pools.Unlock()
+	for _, p := range ps {
+		p.close()
+	}
+}
+
+// resetPools forgets every pool, used when the backend is replaced.
+func resetPools() {
+	pools.Lock()
+	pools.m = make(map[Session][]*Pool)
+	pools.Unlock()
+}
+
+// apply sets the pool's Attrs on instr.
+func (p *Pool) apply(instr Object) error {
+	attrs := make([]uint32, 0, len(p.Attrs))
+	for attr := range p.Attrs {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i] < attrs[j] })
+	for _, attr := range attrs {
+		status := backend.SetAttribute(uint32(instr), attr, p.Attrs[attr])
+		if status < SUCCESS {
+			return instr.Wrap("SetAttribute "+attrName(attr), status)
+		}
+	}
+	return nil
+}
+
+// canonical returns the canonical form of a resource name, resolving
+// aliases with the resource manager. Names that can't be parsed are used
+// as they are.
+func (p *Pool) canonical(name string) string {
+	if r, err := ParseResourceName(name); err == nil {
+		return r.String()
+	}
+	_, _, _, expanded, _, status := p.rm.ParseRsrcEx(name)
+	if status >= SUCCESS {
+		if r, err := ParseResourceName(expanded); err == nil {
+			return r.String()
+		}
+		return expanded
+	}
+	return name
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// poolDevice counts the sessions opened to it and closed. Closing one
+// checks that the pool's lock isn't held meanwhile.
+type poolDevice struct {
+	mu     sync.Mutex
+	p      *Pool
+	opens  int
+	closes int
+	locked bool // a session was closed with the pool locked
+}
+
+// close counts a session closed, checking that the pool isn't locked.
+func (d *poolDevice) close() {
+	done := make(chan struct{})
+	go func() {
+		d.p.Stats()
+		close(done)
+	}()
+	locked := false
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		locked = true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closes++
+	d.locked = d.locked || locked
+}
+
+func (d *poolDevice) counts(t *testing.T) (opens, closes int) {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.locked {
+		t.Error("a session was closed with the pool locked")
+	}
+	return d.opens, d.closes
+}
+
+// openPool returns a pool of loopback sessions to the VXI resources,
+// counted by a poolDevice.
+func openPool(t *testing.T, ttl time.Duration) (Session, *Pool, *poolDevice) {
+	t.Helper()
+	d := &poolDevice{}
+	serveLoopback(t, func(l *loopback) {
+		d.mu.Lock()
+		d.opens++
+		d.mu.Unlock()
+		l.onClose = d.close
+	})
+	rm := openTestRM(t)
+	d.p = NewPool(rm, ttl)
+	return rm, d.p, d
+}
+
+// waitStats polls the pool's counters until done accepts them.
+func waitStats(t *testing.T, p *Pool, done func(PoolStats) bool) PoolStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s := p.Stats()
+		if done(s) || time.Now().After(deadline) {
+			return s
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolShare(t *testing.T) {
+	_, p, d := openPool(t, 0)
+	p.Attrs = map[uint32]uint64{ATTR_TMO_VALUE: 1234, ATTR_TERMCHAR_EN: TRUE}
+
+	var wg sync.WaitGroup
+	hs := make([]*Handle, 10)
+	for i := range hs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "VXI0::1::INSTR"
+			if i%2 == 1 {
+				name = "vxi::1"
+			}
+			h, err := p.Open(name)
+			if err != nil {
+				t.Errorf("Open(%q): %v", name, err)
+				return
+			}
+			hs[i] = h
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	for _, h := range hs {
+		if h.Object != hs[0].Object {
+			t.Fatal("the handles don't share one session")
+		}
+	}
+	want := PoolStats{Opened: 1, Reused: 9, Open: 1, Handles: 10}
+	if s := p.Stats(); s != want {
+		t.Errorf("Stats = %+v, want %+v", s, want)
+	}
+
+	// Every Open sets the Attrs again.
+	hs[0].SetAttribute(ATTR_TMO_VALUE, 5)
+	h, err := p.Open("VXI0::1::INSTR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmo, err := h.AttrUint32(ATTR_TMO_VALUE); err != nil || tmo != 1234 {
+		t.Errorf("ATTR_TMO_VALUE = %d, %v, want 1234 again", tmo, err)
+	}
+	if en, err := h.AttrBool(ATTR_TERMCHAR_EN); err != nil || !en {
+		t.Errorf("ATTR_TERMCHAR_EN = %v, %v, want true", en, err)
+	}
+
+	for _, h := range hs {
+		h.Close()
+	}
+	h.Close()
+	h.Close()
+	want = PoolStats{Opened: 1, Reused: 10, Open: 1}
+	if s := p.Stats(); s != want {
+		t.Errorf("Stats after closing the handles = %+v, want %+v", s, want)
+	}
+	// Without a TTL the session stays open.
+	if opens, closes := d.counts(t); opens != 1 || closes != 0 {
+		t.Errorf("%d sessions opened, %d closed, want 1 and 0", opens, closes)
+	}
+}
+
+func TestPoolTTL(t *testing.T) {
+	_, p, d := openPool(t, 50*time.Millisecond)
+
+	h, err := p.Open("VXI0::1::INSTR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	// Opening it again within the TTL reuses the session.
+	if h, err = p.Open("VXI0::1::INSTR"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if s := p.Stats(); s.Open != 1 || s.Reused != 1 || s.Closed != 0 {
+		t.Errorf("Stats with a handle past the TTL = %+v", s)
+	}
+	h.Close()
+
+	s := waitStats(t, p, func(s PoolStats) bool { return s.Closed == 1 })
+	if s != (PoolStats{Opened: 1, Reused: 1, Closed: 1}) {
+		t.Errorf("Stats after the TTL = %+v", s)
+	}
+	if opens, closes := d.counts(t); opens != 1 || closes != 1 {
+		t.Errorf("%d sessions opened, %d closed, want 1 and 1", opens, closes)
+	}
+
+	// The next Open opens a new session.
+	if h, err = p.Open("VXI0::1::INSTR"); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if opens, _ := d.counts(t); opens != 2 {
+		t.Errorf("%d sessions opened, want 2", opens)
+	}
+}
+
+func TestPoolLeak(t *testing.T) {
+	_, p, _ := openPool(t, 0)
+	func() {
+		if _, err := p.Open("VXI0::1::INSTR"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	s := waitStats(t, p, func(s PoolStats) bool { return s.Leaked == 1 })
+	if s != (PoolStats{Opened: 1, Leaked: 1, Open: 1}) {
+		t.Errorf("Stats after collecting an unclosed handle = %+v", s)
+	}
+}
+
+func TestPoolCloseRM(t *testing.T) {
+	rm, p, d := openPool(t, 0)
+	h, err := p.Open("VXI0::1::INSTR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm.Close()
+
+	if s := p.Stats(); s != (PoolStats{Opened: 1, Closed: 1, Leaked: 1}) {
+		t.Errorf("Stats after closing the resource manager = %+v", s)
+	}
+	if _, closes := d.counts(t); closes != 1 {
+		t.Errorf("%d sessions closed, want 1", closes)
+	}
+	if _, err := p.Open("VXI0::1::INSTR"); !errors.Is(err, Status(ERROR_INV_OBJECT)) {
+		t.Errorf("Open after closing the resource manager: %v, want ERROR_INV_OBJECT", err)
+	}
+	if status := h.Close(); status != SUCCESS {
+		t.Errorf("closing a handle of a closed pool: %v", status)
+	}
+}
+
+func TestPoolOpenError(t *testing.T) {
+	_, p, _ := openPool(t, 0)
+	if _, err := p.Open("GPIB0::5::INSTR"); err == nil {
+		t.Fatal("Open of a missing resource succeeded")
+	}
+	if s := p.Stats(); s != (PoolStats{}) {
+		t.Errorf("Stats after a failed Open = %+v", s)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The query functions write a command, read the whole response, in as
+// many reads as it takes to reach END or the termination character, and
+// parse it with the leading and trailing white space, line terminators
+// and NUL bytes removed. The command is written as is, it has to end with
+// a terminator if the interface needs one, e.g. for SOCKET resources.
+//
+// They take a Driver so instrument drivers embedding one can use them,
+// the Object methods of the same names call them.
+
+// QueryError is a failed query, it records the command sent.
+type QueryError struct {
+	Cmd string // without its terminator
+	Err error  // e.g. an *Error or a *strconv.NumError
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("visa: query %q: %s", e.Cmd,
+		strings.TrimPrefix(e.Err.Error(), "visa: "))
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// StatusOf returns the status err wraps, SUCCESS if err is nil and
+// ERROR_SYSTEM_ERROR if it doesn't wrap one, e.g. for a parse error.
+func StatusOf(err error) Status {
+	if err == nil {
+		return SUCCESS
+	}
+	var s Status
+	if errors.As(err, &s) {
+		return s
+	}
+	return ERROR_SYSTEM_ERROR
+}
+
+// Query writes cmd to d and returns the response.
+func Query(d Driver, cmd string) (string, error) {
+	s := NewStream(d)
+	if _, err := s.WriteString(cmd); err != nil {
+		return "", queryError(cmd, err)
+	}
+	b, err := s.ReadMessage()
+	if err != nil {
+		return "", queryError(cmd, err)
+	}
+	return trimResponse(string(b)), nil
+}
+
+// QueryFloat writes cmd to d and returns the response as a number.
+func QueryFloat(d Driver, cmd string) (float64, error) {
+	resp, err := Query(d, cmd)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(resp, 64)
+	if err != nil {
+		return 0, queryError(cmd, err)
+	}
+	return f, nil
+}
+
+// QueryInt writes cmd to d and returns the response as an integer. A
+// response in exponent form, e.g. +1.00000000E+001, is accepted if its
+// value is integral.
+func QueryInt(d Driver, cmd string) (int64, error) {
+	resp, err := Query(d, cmd)
+	if err != nil {
+		return 0, err
+	}
+	i, err := parseInt(resp)
+	if err != nil {
+		return 0, queryError(cmd, err)
+	}
+	return i, nil
+}
+
+// QueryBool writes cmd to d and returns the response as a boolean: ON or
+// a non-zero integer is true, OFF or 0 is false.
+func QueryBool(d Driver, cmd string) (bool, error) {
+	resp, err := Query(d, cmd)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToUpper(resp) {
+	case "ON":
+		return true, nil
+	case "OFF":
+		return false, nil
+	}
+	i, err := parseInt(resp)
+	if err != nil {
+		return false, queryError(cmd, err)
+	}
+	return i != 0, nil
+}
+
+// QueryStrings writes cmd to d and returns the comma separated elements of
+// the response, trimmed of white space. An empty response has no elements.
+func QueryStrings(d Driver, cmd string) ([]string, error) {
+	resp, err := Query(d, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return splitResponse(resp), nil
+}
+
+// QueryFloats writes cmd to d and returns the comma separated elements of
+// the response as numbers, parsed with ParseFloats.
+func QueryFloats(d Driver, cmd string) ([]float64, error) {
+	resp, err := Query(d, cmd)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := ParseFloats(resp)
+	if err != nil {
+		return nil, queryError(cmd, err)
+	}
+	return fs, nil
+}
+
+// Query writes cmd and returns the response.
+func (instr Object) Query(cmd string) (string, error) {
+	return Query(instr, cmd)
+}
+
+// QueryFloat writes cmd and returns the response as a number.
+func (instr Object) QueryFloat(cmd string) (float64, error) {
+	return QueryFloat(instr, cmd)
+}
+
+// QueryInt writes cmd and returns the response as an integer.
+func (instr Object) QueryInt(cmd string) (int64, error) {
+	return QueryInt(instr, cmd)
+}
+
+// QueryBool writes cmd and returns the response as a boolean.
+func (instr Object) QueryBool(cmd string) (bool, error) {
+	return QueryBool(instr, cmd)
+}
+
+// QueryStrings writes cmd and returns the comma separated elements of the
+// response.
+func (instr Object) QueryStrings(cmd string) ([]string, error) {
+	return QueryStrings(instr, cmd)
+}
+
+// QueryFloats writes cmd and returns the comma separated elements of the
+// response as numbers.
+func (instr Object) QueryFloats(cmd string) ([]float64, error) {
+	return QueryFloats(instr, cmd)
+}
+
+// queryError returns err as a *QueryError for cmd.
+func queryError(cmd string, err error) error {
+	return &QueryError{Cmd: strings.TrimRight(cmd, "\r\n"), Err: err}
+}
+
+// trimResponse removes the white space, line terminators and NUL bytes
+// around a response.
+func trimResponse(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return r == 0 || unicode.IsSpace(r)
+	})
+}
+
+// splitResponse returns the comma separated elements of a response.
+func splitResponse(s string) []string {
+	if s == "" {
+		return nil
+	}
+	elems := strings.Split(s, ",")
+	for i, e := range elems {
+		elems[i] = strings.TrimSpace(e)
+	}
+	return elems
+}
+
+// parseInt parses an integer, in exponent form if its value is integral.
+func parseInt(s string) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+		return 0, err
+	}
+	return int64(f), nil
+}
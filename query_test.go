@@ -0,0 +1,132 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	d := &fakeDriver{resp: map[string]string{"*IDN?": " ACME,1234\r\n\x00"}}
+	resp, err := Query(d, "*IDN?\n")
+	if err != nil || resp != "ACME,1234" {
+		t.Errorf("Query = %q, %v", resp, err)
+	}
+	if len(d.written) != 1 || d.written[0] != "*IDN?\n" {
+		t.Errorf("the device received %q", d.written)
+	}
+
+	_, err = Query(d, "FOO?\n")
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Cmd != "FOO?" || StatusOf(err) != ERROR_TMO {
+		t.Errorf("unanswered query: %v, want a *QueryError for FOO? with ERROR_TMO", err)
+	}
+}
+
+func TestQueryChunks(t *testing.T) {
+	long := strings.Repeat("0123456789", 1000)
+	d := &fakeDriver{resp: map[string]string{"TRAC?": long + "\n"}, chunk: 1000}
+	resp, err := Query(d, "TRAC?")
+	if err != nil || resp != long {
+		t.Fatalf("Query returned %d bytes, %v, want %d", len(resp), err, len(long))
+	}
+	if d.reads != 11 {
+		t.Errorf("the response took %d reads, want 11", d.reads)
+	}
+
+	// Without a chunk limit the reads are streamChunk bytes.
+	d = &fakeDriver{resp: map[string]string{"TRAC?": long}}
+	if resp, err := Query(d, "TRAC?"); err != nil || resp != long || d.reads != (len(long)+streamChunk-1)/streamChunk {
+		t.Errorf("Query returned %d bytes in %d reads, %v", len(resp), d.reads, err)
+	}
+}
+
+func TestQueryNumbers(t *testing.T) {
+	d := &fakeDriver{resp: map[string]string{
+		"FREQ?":  "+1.50000000E+009\n",
+		"COUN?":  "+1.00000000E+001\n",
+		"POIN?":  "1001\n",
+		"FRAC?":  "2.5\n",
+		"LIST?":  "1.5, -2E3,+4\n",
+		"EMPTY?": "\n",
+		"BAD?":   "1,x,3\n",
+	}}
+	if f, err := QueryFloat(d, "FREQ?"); err != nil || f != 1.5e9 {
+		t.Errorf("QueryFloat = %v, %v", f, err)
+	}
+	if i, err := QueryInt(d, "COUN?"); err != nil || i != 10 {
+		t.Errorf("QueryInt in exponent form = %v, %v", i, err)
+	}
+	if i, err := QueryInt(d, "POIN?"); err != nil || i != 1001 {
+		t.Errorf("QueryInt = %v, %v", i, err)
+	}
+	if _, err := QueryInt(d, "FRAC?"); err == nil {
+		t.Error("QueryInt accepted 2.5")
+	}
+	if fs, err := QueryFloats(d, "LIST?"); err != nil || !reflect.DeepEqual(fs, []float64{1.5, -2000, 4}) {
+		t.Errorf("QueryFloats = %v, %v", fs, err)
+	}
+	if fs, err := QueryFloats(d, "EMPTY?"); err != nil || len(fs) != 0 {
+		t.Errorf("QueryFloats of an empty response = %v, %v", fs, err)
+	}
+
+	_, err := QueryFloats(d, "BAD?")
+	var qe *QueryError
+	var ne *strconv.NumError
+	if !errors.As(err, &qe) || qe.Cmd != "BAD?" || !errors.As(err, &ne) || ne.Num != "x" {
+		t.Errorf("QueryFloats of a bad element: %v, want a *QueryError wrapping a *strconv.NumError", err)
+	}
+	if StatusOf(err) != ERROR_SYSTEM_ERROR {
+		t.Errorf("StatusOf a parse error = %v, want ERROR_SYSTEM_ERROR", StatusOf(err))
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	tests := []struct {
+		resp string
+		want bool
+		ok   bool
+	}{
+		{"ON", true, true},
+		{"off", false, true},
+		{"1", true, true},
+		{"0", false, true},
+		{"+1.00000000E+000", true, true},
+		{"+0.00000000E+000", false, true},
+		{"maybe", false, false},
+		{"0.5", false, false},
+	}
+	for _, tt := range tests {
+		d := &fakeDriver{resp: map[string]string{"OUTP?": tt.resp + "\n"}}
+		got, err := QueryBool(d, "OUTP?")
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("QueryBool of %q = %v, %v, want %v", tt.resp, got, err, tt.want)
+		}
+		var qe *QueryError
+		if !tt.ok && !errors.As(err, &qe) {
+			t.Errorf("QueryBool of %q: %v, want a *QueryError", tt.resp, err)
+		}
+	}
+}
+
+func TestQueryStrings(t *testing.T) {
+	tests := map[string][]string{
+		"A, B ,C\n":        {"A", "B", "C"},
+		"\"x\"\n":          {"\"x\""},
+		"1,,2\r\n":         {"1", "", "2"},
+		"  \n":             nil,
+		"ACME,1234,SN,1.0": {"ACME", "1234", "SN", "1.0"},
+	}
+	for resp, want := range tests {
+		d := &fakeDriver{resp: map[string]string{"*IDN?": resp}}
+		if got, err := QueryStrings(d, "*IDN?"); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("QueryStrings of %q = %q, %v, want %q", resp, got, err, want)
+		}
+	}
+}
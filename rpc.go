@@ -0,0 +1,283 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ONC RPC (RFC 5531) over TCP with record marking, and the XDR (RFC 4506)
+// encoding it uses. Only what VXI-11 needs is implemented: AUTH_NONE
+// credentials and one outstanding call per connection.
+
+const (
+	rpcCall  = 0
+	rpcReply = 1
+
+	rpcMsgAccepted = 0
+	rpcSuccess     = 0
+
+	pmapProg        = 100000
+	pmapVers        = 2
+	pmapPort        = 111
+	pmapProcGetport = 3
+	ipprotoTCP      = 6
+)
+
+// rpcMaxData bounds the data a call or reply carries, and rpcMaxRecord the
+// records accepted from a peer, so a broken fragment header can't make
+// readRecord allocate up to 2GB.
+const (
+	rpcMaxData   = 1 << 20
+	rpcMaxRecord = rpcMaxData + 1024
+)
+
+var (
+	errRPCReply  = errors.New("visa: malformed RPC reply")
+	errRPCRecord = errors.New("visa: RPC record too large")
+)
+
+// portmapperPort is where pmapGetport looks for the portmapper.
+var portmapperPort = pmapPort
+
+// xdrWriter encodes XDR values.
+type xdrWriter struct {
+	buf []byte
+}
+
+func (w *xdrWriter) putUint32(v uint32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, v)
+}
+
+func (w *xdrWriter) putBool(b bool) {
+	if b {
+		w.putUint32(1)
+	} else {
+		w.putUint32(0)
+	}
+}
+
+// putOpaque encodes variable length opaque data, strings use the same form.
+func (w *xdrWriter) putOpaque(b []byte) {
+	w.putUint32(uint32(len(b)))
+	w.buf = append(w.buf, b...)
+	for len(w.buf)%4 != 0 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *xdrWriter) putString(s string) {
+	w.putOpaque([]byte(s))
+}
+
+// xdrReader decodes XDR values. Decoding past the end of the data sets err
+// and yields zero values.
+type xdrReader struct {
+	buf []byte
+	err error
+}
+
+func (r *xdrReader) getUint32() uint32 {
+	if len(r.buf) < 4 {
+		r.err = errRPCReply
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *xdrReader) getBool() bool {
+	return r.getUint32() != 0
+}
+
+func (r *xdrReader) getOpaque() []byte {
+	n := int(r.getUint32())
+	padded := (n + 3) &^ 3
+	if r.err != nil || n < 0 || padded > len(r.buf) {
+		r.err = errRPCReply
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[padded:]
+	return b
+}
+
+// writeRecord sends msg as a single record marking fragment.
+func writeRecord(w io.Writer, msg []byte) error {
+	rec := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(rec, 0x80000000|uint32(len(msg)))
+	_, err := w.Write(append(rec, msg...))
+	return err
+}
+
+// readRecord reads the fragments of one record, which fails with
+// errRPCRecord if it's larger than max bytes.
+func readRecord(r io.Reader, max int) ([]byte, error) {
+	var rec []byte
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(hdr[:])
+		size := int(n & 0x7FFFFFFF)
+		if size > max-len(rec) {
+			return nil, errRPCRecord
+		}
+		frag := make([]byte, size)
+		if _, err := io.ReadFull(r, frag); err != nil {
+			return nil, err
+		}
+		rec = append(rec, frag...)
+		if n&0x80000000 != 0 {
+			return rec, nil
+		}
+	}
+}
+
+// rpcClient calls the procedures of one program over a TCP connection.
+type rpcClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	prog uint32
+	vers uint32
+	xid  uint32
+}
+
+func dialRPC(addr string, prog, vers uint32, deadline time.Time) (*rpcClient, error) {
+	d := net.Dialer{Deadline: deadline}
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcClient{conn: conn, prog: prog, vers: vers, xid: uint32(time.Now().UnixNano())}, nil
+}
+
+// call invokes proc with the encoded args and returns a reader positioned
+// at the results. A zero deadline waits forever.
+func (c *rpcClient) call(proc uint32, args []byte, deadline time.Time) (*xdrReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.xid++
+	var w xdrWriter
+	w.putUint32(c.xid)
+	w.putUint32(rpcCall)
+	w.putUint32(2)
+	w.putUint32(c.prog)
+	w.putUint32(c.vers)
+	w.putUint32(proc)
+	w.putUint32(0) // credentials AUTH_NONE
+	w.putUint32(0)
+	w.putUint32(0) // verifier AUTH_NONE
+	w.putUint32(0)
+	w.buf = append(w.buf, args...)
+
+	c.conn.SetDeadline(deadline)
+	if err := writeRecord(c.conn, w.buf); err != nil {
+		return nil, err
+	}
+	for {
+		rec, err := readRecord(c.conn, rpcMaxRecord)
+		if err != nil {
+			return nil, err
+		}
+		r := &xdrReader{buf: rec}
+		if r.getUint32() != c.xid {
+			continue
+		}
+		if r.getUint32() != rpcReply || r.getUint32() != rpcMsgAccepted {
+			return nil, errRPCReply
+		}
+		r.getUint32() // verifier
+		r.getOpaque()
+		if stat := r.getUint32(); stat != rpcSuccess || r.err != nil {
+			return nil, errors.New("visa: RPC call failed with accept status " + strconv.Itoa(int(stat)))
+		}
+		return r, nil
+	}
+}
+
+func (c *rpcClient) close() error {
+	return c.conn.Close()
+}
+
+// pmapGetport asks the portmapper on host for the TCP port of prog.
+func pmapGetport(host string, prog, vers uint32, deadline time.Time) (uint16, error) {
+	c, err := dialRPC(net.JoinHostPort(host, strconv.Itoa(portmapperPort)), pmapProg, pmapVers, deadline)
+	if err != nil {
+		return 0, err
+	}
+	defer c.close()
+	var w xdrWriter
+	w.putUint32(prog)
+	w.putUint32(vers)
+	w.putUint32(ipprotoTCP)
+	w.putUint32(0)
+	r, err := c.call(pmapProcGetport, w.buf, deadline)
+	if err != nil {
+		return 0, err
+	}
+	port := r.getUint32()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if port == 0 || port > 0xFFFF {
+		return 0, errors.New("visa: program not registered with the portmapper")
+	}
+	return uint16(port), nil
+}
+
+// rpcHandler serves one call. It returns the encoded results, or false if
+// the program or procedure isn't served.
+type rpcHandler func(prog, vers, proc uint32, args *xdrReader) ([]byte, bool)
+
+// serveRPC answers the calls arriving on conn until it's closed.
+func serveRPC(conn net.Conn, h rpcHandler) {
+	defer conn.Close()
+	for {
+		rec, err := readRecord(conn, rpcMaxRecord)
+		if err != nil {
+			return
+		}
+		r := &xdrReader{buf: rec}
+		xid := r.getUint32()
+		if r.getUint32() != rpcCall {
+			continue
+		}
+		r.getUint32() // RPC version
+		prog, vers, proc := r.getUint32(), r.getUint32(), r.getUint32()
+		r.getUint32() // credentials
+		r.getOpaque()
+		r.getUint32() // verifier
+		r.getOpaque()
+		if r.err != nil {
+			return
+		}
+		results, ok := h(prog, vers, proc, r)
+
+		var w xdrWriter
+		w.putUint32(xid)
+		w.putUint32(rpcReply)
+		w.putUint32(rpcMsgAccepted)
+		w.putUint32(0) // verifier AUTH_NONE
+		w.putUint32(0)
+		if ok {
+			w.putUint32(rpcSuccess)
+			w.buf = append(w.buf, results...)
+		} else {
+			w.putUint32(1) // PROG_UNAVAIL
+		}
+		if err := writeRecord(conn, w.buf); err != nil {
+			return
+		}
+	}
+}
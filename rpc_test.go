@@ -0,0 +1,111 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestXDR(t *testing.T) {
+	var w xdrWriter
+	w.putUint32(0xDEADBEEF)
+	w.putBool(true)
+	w.putString("gpib0,5")
+	w.putOpaque(nil)
+	if len(w.buf)%4 != 0 {
+		t.Fatalf("encoding isn't padded to 4 bytes: %d", len(w.buf))
+	}
+
+	r := &xdrReader{buf: w.buf}
+	if v := r.getUint32(); v != 0xDEADBEEF {
+		t.Errorf("getUint32 = %#x", v)
+	}
+	if !r.getBool() {
+		t.Error("getBool = false")
+	}
+	if s := string(r.getOpaque()); s != "gpib0,5" {
+		t.Errorf("getOpaque = %q", s)
+	}
+	if b := r.getOpaque(); len(b) != 0 || r.err != nil {
+		t.Errorf("empty getOpaque = %q, %v", b, r.err)
+	}
+	r.getUint32()
+	if r.err != errRPCReply {
+		t.Errorf("decoding past the end: %v, want errRPCReply", r.err)
+	}
+
+	r = &xdrReader{buf: []byte{0, 0, 0, 8, 'a', 'b'}}
+	if r.getOpaque(); r.err != errRPCReply {
+		t.Errorf("truncated opaque: %v, want errRPCReply", r.err)
+	}
+}
+
+// fragment encodes a record marking fragment.
+func fragment(data string, last bool) []byte {
+	hdr := uint32(len(data))
+	if last {
+		hdr |= 0x80000000
+	}
+	return append(binary.BigEndian.AppendUint32(nil, hdr), data...)
+}
+
+func TestReadRecord(t *testing.T) {
+	var in bytes.Buffer
+	in.Write(fragment("abc", false))
+	in.Write(fragment("de", true))
+	rec, err := readRecord(&in, 16)
+	if err != nil || string(rec) != "abcde" {
+		t.Errorf("readRecord = %q, %v, want \"abcde\"", rec, err)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		err  error
+	}{
+		{"huge fragment", binary.BigEndian.AppendUint32(nil, 0xFFFFFFFF), errRPCRecord},
+		{"huge record", append(fragment("0123456789", false), fragment("0123456789", true)...), errRPCRecord},
+		{"truncated fragment", fragment("abc", true)[:5], io.ErrUnexpectedEOF},
+		{"no header", nil, io.EOF},
+	}
+	for _, tt := range tests {
+		if _, err := readRecord(bytes.NewReader(tt.in), 16); err != tt.err {
+			t.Errorf("%s: readRecord error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRPCCall(t *testing.T) {
+	client, server := net.Pipe()
+	go serveRPC(server, func(prog, vers, proc uint32, args *xdrReader) ([]byte, bool) {
+		if prog != 42 || vers != 1 || proc != 7 {
+			return nil, false
+		}
+		var w xdrWriter
+		w.putUint32(args.getUint32() + 1)
+		return w.buf, true
+	})
+	c := &rpcClient{conn: client, prog: 42, vers: 1}
+	defer c.close()
+	deadline := time.Now().Add(time.Second)
+
+	var w xdrWriter
+	w.putUint32(9)
+	r, err := c.call(7, w.buf, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.getUint32(); v != 10 || r.err != nil {
+		t.Errorf("result = %d, %v, want 10", v, r.err)
+	}
+	if _, err := c.call(8, nil, deadline); err == nil {
+		t.Error("calling an unserved procedure didn't fail")
+	}
+}
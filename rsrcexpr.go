@@ -0,0 +1,288 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rsrcExpr is a compiled FindRsrc search expression: a regular expression
+// over resource names, optionally followed by an attribute expression in
+// braces, e.g. USB?*INSTR{VI_ATTR_MANF_ID==0x0957 && VI_ATTR_MODEL_CODE!=0}.
+type rsrcExpr struct {
+	re   *regexp.Regexp
+	cond attrCond // nil without an attribute expression
+}
+
+// attrCond evaluates an attribute expression against a resource. state
+// returns the state of an attribute as an int64 or a string, and false if
+// the resource doesn't have the attribute.
+type attrCond func(state func(attr uint32) (interface{}, bool)) bool
+
+// compileRsrcExpr compiles a search expression.
+func compileRsrcExpr(expr string) (*rsrcExpr, Status) {
+	pattern, attrs, status := splitRsrcExpr(expr)
+	if status != SUCCESS {
+		return nil, status
+	}
+	re, status := compileVISARegexp(pattern)
+	if status != SUCCESS {
+		return nil, status
+	}
+	e := &rsrcExpr{re: re}
+	if attrs != "" {
+		if e.cond, status = compileAttrExpr(attrs); status != SUCCESS {
+			return nil, status
+		}
+	}
+	return e, SUCCESS
+}
+
+// splitRsrcExpr splits expr at the first brace outside of a bracket
+// expression. The attribute expression must run to the end of expr.
+func splitRsrcExpr(expr string) (pattern, attrs string, status Status) {
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\':
+			i++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '{' && !inClass:
+			rest := strings.TrimSpace(expr[i+1:])
+			if !strings.HasSuffix(rest, "}") {
+				return "", "", ERROR_INV_EXPR
+			}
+			attrs = strings.TrimSpace(rest[:len(rest)-1])
+			if attrs == "" {
+				return "", "", ERROR_INV_EXPR
+			}
+			return expr[:i], attrs, SUCCESS
+		}
+	}
+	return expr, "", SUCCESS
+}
+
+// compileVISARegexp converts a VISA regular expression to a Go one that
+// matches whole strings case-insensitively. In VISA ? matches any one
+// character, * and + repeat the preceding character or group, [list] and
+// [^list] are character classes, | separates alternatives, ( ) group and
+// \ escapes. Every other character is literal.
+func compileVISARegexp(expr string) (*regexp.Regexp, Status) {
+	var buf strings.Builder
+	buf.WriteString("(?i)^(?:")
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(expr) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(expr[i : i+1]))
+			}
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			buf.WriteByte(c)
+		case c == '[':
+			inClass = true
+			buf.WriteByte(c)
+			if i+1 < len(expr) && expr[i+1] == '^' {
+				buf.WriteByte('^')
+				i++
+			}
+		case c == '?':
+			buf.WriteByte('.')
+		case strings.IndexByte("*+|()", c) >= 0:
+			buf.WriteByte(c)
+		default:
+			buf.WriteString(regexp.QuoteMeta(expr[i : i+1]))
+		}
+	}
+	buf.WriteString(")$")
+	re, err := regexp.Compile(buf.String())
+	if err != nil {
+		return nil, ERROR_INV_EXPR
+	}
+	return re, SUCCESS
+}
+
+// attrLexer splits an attribute expression into attribute names, numbers,
+// quoted strings and operators.
+type attrLexer struct {
+	s   string
+	tok string
+	err bool
+}
+
+func (l *attrLexer) next() {
+	l.s = strings.TrimLeft(l.s, " \t")
+	if l.s == "" {
+		l.tok = ""
+		return
+	}
+	n := 1
+	switch c := l.s[0]; {
+	case c == '"':
+		n = strings.IndexByte(l.s[1:], '"') + 2
+		if n == 1 {
+			l.err = true
+			n = len(l.s)
+		}
+	case strings.HasPrefix(l.s, "=="), strings.HasPrefix(l.s, "!="),
+		strings.HasPrefix(l.s, ">="), strings.HasPrefix(l.s, "<="),
+		strings.HasPrefix(l.s, "&&"), strings.HasPrefix(l.s, "||"):
+		n = 2
+	case strings.IndexByte("()!<>", c) >= 0:
+	default:
+		for n < len(l.s) && strings.IndexByte(" \t()!<>=&|\"", l.s[n]) < 0 {
+			n++
+		}
+	}
+	l.tok, l.s = l.s[:n], l.s[n:]
+}
+
+// compileAttrExpr compiles the attribute expression between the braces.
+// Comparisons name a VI_ATTR_ attribute on the left and a number, VI_TRUE,
+// VI_FALSE or a quoted VISA regular expression on the right, and combine
+// with &&, || and !. Strings only compare with == and !=.
+func compileAttrExpr(expr string) (attrCond, Status) {
+	l := &attrLexer{s: expr}
+	l.next()
+	cond := parseAttrOr(l)
+	if l.err || l.tok != "" {
+		return nil, ERROR_INV_EXPR
+	}
+	return cond, SUCCESS
+}
+
+func parseAttrOr(l *attrLexer) attrCond {
+	x := parseAttrAnd(l)
+	for l.tok == "||" && !l.err {
+		l.next()
+		a, b := x, parseAttrAnd(l)
+		x = func(st func(uint32) (interface{}, bool)) bool { return a(st) || b(st) }
+	}
+	return x
+}
+
+func parseAttrAnd(l *attrLexer) attrCond {
+	x := parseAttrUnary(l)
+	for l.tok == "&&" && !l.err {
+		l.next()
+		a, b := x, parseAttrUnary(l)
+		x = func(st func(uint32) (interface{}, bool)) bool { return a(st) && b(st) }
+	}
+	return x
+}
+
+func parseAttrUnary(l *attrLexer) attrCond {
+	switch l.tok {
+	case "!":
+		l.next()
+		x := parseAttrUnary(l)
+		return func(st func(uint32) (interface{}, bool)) bool { return !x(st) }
+	case "(":
+		l.next()
+		x := parseAttrOr(l)
+		if l.tok != ")" {
+			l.err = true
+		}
+		l.next()
+		return x
+	}
+	return parseAttrCompare(l)
+}
+
+func parseAttrCompare(l *attrLexer) attrCond {
+	never := func(func(uint32) (interface{}, bool)) bool { return false }
+	attr, ok := attrByName(l.tok)
+	l.next()
+	op := l.tok
+	l.next()
+	val := l.tok
+	l.next()
+	switch op {
+	case "==", "!=", ">", "<", ">=", "<=":
+	default:
+		ok = false
+	}
+	if !ok {
+		l.err = true
+		return never
+	}
+
+	if attrTable[attr].Type == AttrString {
+		if len(val) < 2 || val[0] != '"' || op != "==" && op != "!=" {
+			l.err = true
+			return never
+		}
+		re, status := compileVISARegexp(val[1 : len(val)-1])
+		if status != SUCCESS {
+			l.err = true
+			return never
+		}
+		return func(st func(uint32) (interface{}, bool)) bool {
+			v, ok := st(attr)
+			s, _ := v.(string)
+			return ok && re.MatchString(s) == (op == "==")
+		}
+	}
+
+	var want int64
+	switch strings.ToUpper(val) {
+	case "VI_TRUE":
+		want = TRUE
+	case "VI_FALSE":
+		want = FALSE
+	default:
+		n, err := strconv.ParseInt(val, 0, 64)
+		if err != nil {
+			u, uerr := strconv.ParseUint(val, 0, 64)
+			if uerr != nil {
+				l.err = true
+				return never
+			}
+			n = int64(u)
+		}
+		want = n
+	}
+	return func(st func(uint32) (interface{}, bool)) bool {
+		v, ok := st(attr)
+		n, isNum := v.(int64)
+		if !ok || !isNum {
+			return false
+		}
+		switch op {
+		case "==":
+			return n == want
+		case "!=":
+			return n != want
+		case ">":
+			return n > want
+		case "<":
+			return n < want
+		case ">=":
+			return n >= want
+		}
+		return n <= want
+	}
+}
+
+// attrByName returns the attribute with the VISA name, e.g.
+// VI_ATTR_MANF_ID.
+func attrByName(name string) (uint32, bool) {
+	for attr, info := range attrTable {
+		if strings.EqualFold(info.Name, name) {
+			return attr, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileVISARegexp(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match []string
+		miss  []string
+	}{
+		{"?*INSTR", []string{"GPIB0::5::INSTR", "instr", "usb0::1::2::sn::instr"}, []string{"TCPIP0::h::5025::SOCKET"}},
+		{"GPIB?*", []string{"GPIB0::1::INSTR", "gpib1::INTFC"}, []string{"VXI0::1"}},
+		{"GPIB[0-9]::?*", []string{"GPIB3::1::INSTR"}, []string{"GPIB10::1::INSTR"}},
+		{"ASRL[^2]*::INSTR", []string{"ASRL1::INSTR", "ASRL::INSTR"}, []string{"ASRL2::INSTR"}},
+		{"(GPIB|VXI)?*INSTR", []string{"VXI0::1::INSTR", "GPIB0::1::INSTR"}, []string{"USB0::1::INSTR"}},
+		{"GPIB0::1+::INSTR", []string{"GPIB0::11::INSTR"}, []string{"GPIB0::::INSTR"}},
+		{"a.b", []string{"A.B"}, []string{"axb"}},
+		{`\?\*`, []string{"?*"}, []string{"x"}},
+		{`TCPIP0::\[fe80::1\]::inst0::INSTR`, []string{"TCPIP0::[FE80::1]::INST0::INSTR"}, []string{"TCPIP0::f::inst0::INSTR"}},
+	}
+	for _, tt := range tests {
+		re, status := compileVISARegexp(tt.expr)
+		if status != SUCCESS {
+			t.Errorf("compileVISARegexp(%q): %v", tt.expr, status)
+			continue
+		}
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("%q doesn't match %q", tt.expr, s)
+			}
+		}
+		for _, s := range tt.miss {
+			if re.MatchString(s) {
+				t.Errorf("%q matches %q", tt.expr, s)
+			}
+		}
+	}
+
+	for _, expr := range []string{"(GPIB", "[0-9", "*"} {
+		if _, status := compileVISARegexp(expr); status != ERROR_INV_EXPR {
+			t.Errorf("compileVISARegexp(%q): %v, want ERROR_INV_EXPR", expr, status)
+		}
+	}
+}
+
+func TestCompileAttrExpr(t *testing.T) {
+	state := func(attr uint32) (interface{}, bool) {
+		switch attr {
+		case ATTR_MANF_ID:
+			return int64(0x0957), true
+		case ATTR_GPIB_PRIMARY_ADDR:
+			return int64(5), true
+		case ATTR_TERMCHAR_EN:
+			return int64(TRUE), true
+		case ATTR_MODEL_NAME:
+			return "DSO-X 3034A", true
+		}
+		return nil, false
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"VI_ATTR_MANF_ID==0x0957", true},
+		{"VI_ATTR_MANF_ID == 2391", true},
+		{"VI_ATTR_MANF_ID!=0x0957", false},
+		{"VI_ATTR_GPIB_PRIMARY_ADDR>4 && VI_ATTR_GPIB_PRIMARY_ADDR<=5", true},
+		{"VI_ATTR_GPIB_PRIMARY_ADDR>=6 || VI_ATTR_GPIB_PRIMARY_ADDR<5", false},
+		{"VI_ATTR_GPIB_PRIMARY_ADDR==1 || VI_ATTR_MANF_ID==0x0957 && VI_ATTR_GPIB_PRIMARY_ADDR==5", true},
+		{"(VI_ATTR_GPIB_PRIMARY_ADDR==1 || VI_ATTR_MANF_ID==0x0957) && VI_ATTR_GPIB_PRIMARY_ADDR==4", false},
+		{"!VI_ATTR_MANF_ID==1", true},
+		{"!(VI_ATTR_MANF_ID==0x0957 && VI_ATTR_GPIB_PRIMARY_ADDR==5)", false},
+		{"vi_attr_termchar_en==VI_TRUE", true},
+		{"VI_ATTR_TERMCHAR_EN==vi_false", false},
+		{`VI_ATTR_MODEL_NAME=="DSO-X?*"`, true},
+		{`VI_ATTR_MODEL_NAME!="MSO?*"`, true},
+		{`VI_ATTR_MODEL_NAME=="dso-x 3034a"`, true},
+		{`VI_ATTR_MODEL_NAME=="DSO"`, false},
+		// Attributes the resource doesn't have never compare true.
+		{"VI_ATTR_ASRL_BAUD==0 || VI_ATTR_ASRL_BAUD!=0", false},
+		{`VI_ATTR_USB_SERIAL_NUM!="x"`, false},
+	}
+	for _, tt := range tests {
+		cond, status := compileAttrExpr(tt.expr)
+		if status != SUCCESS {
+			t.Errorf("compileAttrExpr(%q): %v", tt.expr, status)
+			continue
+		}
+		if got := cond(state); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{
+		"VI_ATTR_MANF_ID",
+		"VI_ATTR_MANF_ID==",
+		"VI_ATTR_BOGUS==1",
+		"VI_ATTR_MANF_ID=1",
+		"VI_ATTR_MANF_ID==x",
+		"VI_ATTR_MANF_ID==1 &&",
+		"(VI_ATTR_MANF_ID==1",
+		"VI_ATTR_MANF_ID==1)",
+		"VI_ATTR_MANF_ID==1 VI_ATTR_SLOT==2",
+		`VI_ATTR_MODEL_NAME==DSO`,
+		`VI_ATTR_MODEL_NAME>"DSO"`,
+		`VI_ATTR_MODEL_NAME=="DSO`,
+		`VI_ATTR_MODEL_NAME=="(DSO"`,
+		"0x0957==VI_ATTR_MANF_ID",
+	} {
+		if _, status := compileAttrExpr(expr); status != ERROR_INV_EXPR {
+			t.Errorf("compileAttrExpr(%q): %v, want ERROR_INV_EXPR", expr, status)
+		}
+	}
+}
+
+func TestCompileRsrcExpr(t *testing.T) {
+	for _, expr := range []string{"?*{}", "?*{VI_ATTR_SLOT==1", "?*{VI_ATTR_SLOT==1} x", "(?*"} {
+		if _, status := compileRsrcExpr(expr); status != ERROR_INV_EXPR {
+			t.Errorf("compileRsrcExpr(%q): %v, want ERROR_INV_EXPR", expr, status)
+		}
+	}
+	e, status := compileRsrcExpr("ASRL[{]?* { VI_ATTR_INTF_NUM==1 }")
+	if status != SUCCESS || e.cond == nil || !e.re.MatchString("ASRL{::INSTR ") {
+		t.Errorf("compileRsrcExpr with a brace in a class = %+v, %v", e, status)
+	}
+}
+
+func TestFindRsrcAttrs(t *testing.T) {
+	rm := openTestRM(t)
+	withFinder(t, "VXI0::1::INSTR", "VXI1::2::INSTR")
+	withFound(t, foundRsrc{"VXI2::3::INSTR", map[uint32]interface{}{
+		ATTR_MANF_ID:    int64(0x0957),
+		ATTR_MODEL_NAME: "E1411",
+	}})
+	opens := 0
+	withTransport(t, "VXI::INSTR", func(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+		opens++
+		s.a.put(ATTR_TMO_VALUE, uint64(1000*p.board))
+		return &loopback{}, SUCCESS
+	})
+
+	find := func(expr string) []string {
+		t.Helper()
+		list, cnt, desc, status := rm.FindRsrc(expr)
+		if status == ERROR_RSRC_NFOUND {
+			return nil
+		}
+		if status != SUCCESS {
+			t.Fatalf("FindRsrc(%q): %v", expr, status)
+		}
+		defer Close(list)
+		names := []string{desc}
+		for i := uint32(1); i < cnt; i++ {
+			desc, _ = FindNext(list)
+			names = append(names, desc)
+		}
+		return names
+	}
+	tests := []struct {
+		expr  string
+		want  []string
+		opens int
+	}{
+		// The name and the finder answer these.
+		{"VXI?*{VI_ATTR_INTF_NUM>0}", []string{"VXI1::2::INSTR", "VXI2::3::INSTR"}, 0},
+		{"VXI?*{VI_ATTR_INTF_TYPE==2 && VI_ATTR_RSRC_CLASS==\"INSTR\"}", []string{"VXI0::1::INSTR", "VXI1::2::INSTR", "VXI2::3::INSTR"}, 0},
+		{"VXI2?*{VI_ATTR_MANF_ID==0x0957 && VI_ATTR_MODEL_NAME==\"E14?*\"}", []string{"VXI2::3::INSTR"}, 0},
+		// Others need a session, only to the resources left to decide.
+		{"VXI?*{VI_ATTR_MANF_ID==0x0957}", []string{"VXI2::3::INSTR"}, 2},
+		{"VXI?*{VI_ATTR_INTF_NUM<2 && VI_ATTR_TMO_VALUE==1000}", []string{"VXI1::2::INSTR"}, 2},
+		{"VXI?*{VI_ATTR_TMO_VALUE>0}", []string{"VXI1::2::INSTR", "VXI2::3::INSTR"}, 3},
+	}
+	for _, tt := range tests {
+		opens = 0
+		if got := find(tt.expr); !reflect.DeepEqual(got, tt.want) || opens != tt.opens {
+			t.Errorf("FindRsrc(%q) = %q opening %d sessions, want %q and %d", tt.expr, got, opens, tt.want, tt.opens)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InterfaceType is the interface of a resource, the INTF_* value reported
+// by ParseRsrc and ATTR_INTF_TYPE.
+type InterfaceType uint16
+
+// Interface types of resource names.
+const (
+	InterfaceGPIB    InterfaceType = INTF_GPIB
+	InterfaceVXI     InterfaceType = INTF_VXI
+	InterfaceGPIBVXI InterfaceType = INTF_GPIB_VXI
+	InterfaceASRL    InterfaceType = INTF_ASRL
+	InterfacePXI     InterfaceType = INTF_PXI
+	InterfaceTCPIP   InterfaceType = INTF_TCPIP
+	InterfaceUSB     InterfaceType = INTF_USB
+)
+
+// String returns the prefix resource names of the interface start with,
+// e.g. "GPIB-VXI".
+func (t InterfaceType) String() string {
+	for prefix, intf := range intfTypes {
+		if InterfaceType(intf) == t {
+			return prefix
+		}
+	}
+	return "INTF" + strconv.Itoa(int(t))
+}
+
+// intfClasses lists the resource classes each interface supports.
+var intfClasses = map[InterfaceType][]string{
+	InterfaceGPIB:    {"INSTR", "INTFC", "SERVANT"},
+	InterfaceVXI:     {"INSTR", "MEMACC", "BACKPLANE", "SERVANT"},
+	InterfaceGPIBVXI: {"INSTR", "MEMACC", "BACKPLANE"},
+	InterfaceASRL:    {"INSTR"},
+	InterfacePXI:     {"INSTR", "MEMACC", "BACKPLANE"},
+	InterfaceTCPIP:   {"INSTR", "SOCKET", "SERVANT"},
+	InterfaceUSB:     {"INSTR", "RAW"},
+}
+
+// ResourceName is a parsed VISA resource name. Only the fields of the
+// name's interface and class are meaningful, the others are zero.
+//
+//	GPIB[board]::primary[::secondary][::INSTR]
+//	GPIB[board]::INTFC
+//	VXI[board]::logical[::INSTR], GPIB-VXI[board]::logical[::INSTR]
+//	VXI[board][::logical]::BACKPLANE, VXI[board]::MEMACC
+//	PXI[bus]::device[::function][::INSTR]
+//	PXI[board]::bus-device[.function][::INSTR]
+//	PXI[board]::CHASSISn::SLOTm[::FUNCk][::INSTR]
+//	PXI[board][::chassis]::BACKPLANE, PXI[board]::MEMACC
+//	ASRL[board][::INSTR]
+//	TCPIP[board]::host[::device][::INSTR]
+//	TCPIP[board]::host::port::SOCKET
+//	USB[board]::vid::pid::serial[::interface][::INSTR | ::RAW]
+//
+// The pure-Go backend also accepts serial ports by device path, e.g.
+// ASRL/dev/ttyUSB0::INSTR.
+type ResourceName struct {
+	Interface InterfaceType
+	Board     uint16 // interface number
+	Class     string // INSTR, SOCKET, RAW, INTFC, BACKPLANE, MEMACC or SERVANT
+
+	// GPIB
+	PrimaryAddress   uint16
+	SecondaryAddress uint16 // NO_SEC_ADDR if the name has none
+
+	// VXI and GPIB-VXI
+	LogicalAddress uint16
+
+	// PXI, either the bus/device/function or the chassis/slot form
+	Bus      uint16
+	Device   uint16
+	Function uint16
+	Chassis  uint16 // chassis numbers start at 1, 0 selects bus-device
+	Slot     uint16
+
+	// ASRL by device path
+	Path string
+
+	// TCPIP
+	Host       string // host name or address, IPv6 without brackets
+	DeviceName string // LAN device name, e.g. inst0, gpib0,5 or hislip0
+	Port       uint16 // SOCKET port
+
+	// USB
+	ManufacturerID uint16
+	ModelCode      uint16
+	SerialNumber   string
+	USBInterface   int16 // -1 if the name has none
+}
+
+// ParseResourceName parses a resource name without a resource manager.
+// Keywords are matched case-insensitively and omitted parts take their
+// defaults: board 0, class INSTR and LAN device inst0. Failures wrap
+// ErrInvRsrcName.
+func ParseResourceName(name string) (ResourceName, error) {
+	var r ResourceName
+	err := r.Parse(name)
+	return r, err
+}
+
+// Parse sets r to the parsed resource name, see ParseResourceName.
+func (r *ResourceName) Parse(name string) error {
+	p, status := parseRsrcName(name)
+	if status == SUCCESS {
+		var n ResourceName
+		if n, status = p.resourceName(); status == SUCCESS {
+			*r = n
+			return nil
+		}
+	}
+	return status.Wrap("ParseResourceName", name)
+}
+
+// String returns the canonical form of the name, the one the pure-Go
+// backend reports as ATTR_RSRC_NAME.
+func (r ResourceName) String() string {
+	class := strings.ToUpper(r.Class)
+	if class == "" {
+		class = "INSTR"
+	}
+	s := []string{r.Interface.String() + strconv.Itoa(int(r.Board))}
+	num := func(v uint16) string { return strconv.Itoa(int(v)) }
+	switch r.Interface {
+	case InterfaceGPIB:
+		if class == "INSTR" {
+			s = append(s, num(r.PrimaryAddress))
+			if r.SecondaryAddress != NO_SEC_ADDR {
+				s = append(s, num(r.SecondaryAddress))
+			}
+		}
+	case InterfaceVXI, InterfaceGPIBVXI:
+		if class == "INSTR" || class == "BACKPLANE" {
+			s = append(s, num(r.LogicalAddress))
+		}
+	case InterfacePXI:
+		switch {
+		case class == "BACKPLANE":
+			s = append(s, num(r.Chassis))
+		case class != "INSTR":
+		case r.Chassis != 0:
+			s = append(s, "CHASSIS"+num(r.Chassis), "SLOT"+num(r.Slot))
+			if r.Function != 0 {
+				s = append(s, "FUNC"+num(r.Function))
+			}
+		default:
+			dev := num(r.Bus) + "-" + num(r.Device)
+			if r.Function != 0 {
+				dev += "." + num(r.Function)
+			}
+			s = append(s, dev)
+		}
+	case InterfaceASRL:
+		if r.Path != "" {
+			s[0] = r.Interface.String() + r.Path
+		}
+	case InterfaceTCPIP:
+		host := r.Host
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		switch class {
+		case "INSTR":
+			dev := lanDeviceName(r.DeviceName)
+			if dev == "" {
+				dev = "inst0"
+			}
+			s = append(s, host, dev)
+		case "SOCKET":
+			s = append(s, host, num(r.Port))
+		case "SERVANT":
+			if r.DeviceName != "" {
+				s = append(s, lanDeviceName(r.DeviceName))
+			}
+		}
+	case InterfaceUSB:
+		s = append(s, fmt.Sprintf("0x%04X", r.ManufacturerID),
+			fmt.Sprintf("0x%04X", r.ModelCode), r.SerialNumber)
+		if r.USBInterface >= 0 {
+			s = append(s, strconv.Itoa(int(r.USBInterface)))
+		}
+	}
+	return strings.Join(append(s, class), "::")
+}
+
+// lanDeviceKeywords start the LAN device names of VXI-11 and HiSLIP, their
+// canonical case is lower case.
+var lanDeviceKeywords = []string{"inst", "hislip", "gpib", "com", "usb"}
+
+// lanDeviceName returns name with its keyword in lower case, e.g. inst0 for
+// INST0. What follows the keyword, like a USB serial number, is kept.
+func lanDeviceName(name string) string {
+	for _, kw := range lanDeviceKeywords {
+		if len(name) >= len(kw) && strings.EqualFold(name[:len(kw)], kw) {
+			return kw + name[len(kw):]
+		}
+	}
+	return name
+}
+
+// IsHiSLIP reports whether the name is a TCPIP INSTR resource served over
+// HiSLIP.
+func (r ResourceName) IsHiSLIP() bool {
+	return r.Interface == InterfaceTCPIP && strings.ToUpper(r.Class) == "INSTR" &&
+		strings.HasPrefix(strings.ToLower(r.DeviceName), "hislip")
+}
+
+// resourceName interprets the address fields of the split name.
+func (p rsrcParts) resourceName() (ResourceName, Status) {
+	r := ResourceName{
+		Interface:        InterfaceType(intfTypes[p.intf]),
+		Board:            p.board,
+		Class:            p.class,
+		SecondaryAddress: NO_SEC_ADDR,
+		USBInterface:     -1,
+	}
+	supported := false
+	for _, c := range intfClasses[r.Interface] {
+		supported = supported || c == p.class
+	}
+	if !supported || p.path != "" && r.Interface != InterfaceASRL {
+		return r, ERROR_INV_RSRC_NAME
+	}
+	f := p.fields
+	ok := true
+	switch r.Interface {
+	case InterfaceGPIB:
+		switch {
+		case p.class != "INSTR":
+			ok = len(f) == 0
+		case len(f) == 1 || len(f) == 2:
+			r.PrimaryAddress, ok = rsrcNum(f[0], 30)
+			if ok && len(f) == 2 {
+				r.SecondaryAddress, ok = rsrcNum(f[1], 30)
+			}
+		default:
+			ok = false
+		}
+	case InterfaceVXI, InterfaceGPIBVXI:
+		switch {
+		case p.class == "INSTR":
+			ok = len(f) == 1
+			if ok {
+				r.LogicalAddress, ok = rsrcNum(f[0], 255)
+			}
+		case p.class == "BACKPLANE" && len(f) == 1:
+			r.LogicalAddress, ok = rsrcNum(f[0], 255)
+		default:
+			ok = len(f) == 0
+		}
+	case InterfacePXI:
+		ok = r.parsePXI(p)
+	case InterfaceASRL:
+		ok = len(f) == 0
+		r.Path = p.path
+	case InterfaceTCPIP:
+		switch {
+		case p.class == "INSTR" && (len(f) == 1 || len(f) == 2):
+			r.Host, r.DeviceName = f[0], "inst0"
+			if len(f) == 2 {
+				r.DeviceName = f[1]
+			}
+		case p.class == "SOCKET" && len(f) == 2:
+			r.Host = f[0]
+			port, err := strconv.ParseUint(f[1], 10, 16)
+			r.Port, ok = uint16(port), err == nil
+		case p.class == "SERVANT" && len(f) <= 1:
+			if len(f) == 1 {
+				r.DeviceName = f[0]
+			}
+		default:
+			ok = false
+		}
+	case InterfaceUSB:
+		if len(f) != 3 && len(f) != 4 {
+			return r, ERROR_INV_RSRC_NAME
+		}
+		vid, err1 := strconv.ParseUint(f[0], 0, 16)
+		pid, err2 := strconv.ParseUint(f[1], 0, 16)
+		r.ManufacturerID, r.ModelCode, r.SerialNumber = uint16(vid), uint16(pid), f[2]
+		ok = err1 == nil && err2 == nil
+		if ok && len(f) == 4 {
+			var intf uint16
+			intf, ok = rsrcNum(f[3], 254)
+			r.USBInterface = int16(intf)
+		}
+	}
+	if !ok {
+		return r, ERROR_INV_RSRC_NAME
+	}
+	return r, SUCCESS
+}
+
+// parsePXI interprets the fields of a PXI name. The number after PXI in the
+// PXI[bus]::device form is the bus, such names are on board 0.
+func (r *ResourceName) parsePXI(p rsrcParts) bool {
+	f := p.fields
+	ok := true
+	switch {
+	case p.class == "MEMACC":
+		return len(f) == 0
+	case p.class == "BACKPLANE":
+		if len(f) == 1 {
+			r.Chassis, ok = rsrcNum(f[0], 0xFFFF)
+		}
+		return ok && len(f) <= 1
+	case len(f) == 0 || len(f) > 3:
+		return false
+	}
+	upper := strings.ToUpper(f[0])
+	if strings.HasPrefix(upper, "CHASSIS") {
+		if len(f) < 2 || !strings.HasPrefix(strings.ToUpper(f[1]), "SLOT") {
+			return false
+		}
+		r.Chassis, ok = rsrcNum(upper[len("CHASSIS"):], 0xFFFF)
+		slot, ok2 := rsrcNum(f[1][len("SLOT"):], 0xFFFF)
+		r.Slot, ok = slot, ok && ok2 && r.Chassis != 0
+		if ok && len(f) == 3 {
+			if !strings.HasPrefix(strings.ToUpper(f[2]), "FUNC") {
+				return false
+			}
+			r.Function, ok = rsrcNum(f[2][len("FUNC"):], 7)
+		}
+		return ok
+	}
+	if i := strings.IndexByte(f[0], '-'); i >= 0 {
+		if len(f) != 1 {
+			return false
+		}
+		dev, fn := f[0][i+1:], ""
+		if j := strings.IndexByte(dev, '.'); j >= 0 {
+			dev, fn = dev[:j], dev[j+1:]
+		}
+		var ok1, ok2 bool
+		r.Bus, ok = rsrcNum(f[0][:i], 255)
+		r.Device, ok1 = rsrcNum(dev, 31)
+		ok = ok && ok1
+		if fn != "" {
+			r.Function, ok2 = rsrcNum(fn, 7)
+			ok = ok && ok2
+		}
+		return ok
+	}
+	if len(f) > 2 {
+		return false
+	}
+	r.Bus, r.Board = p.board, 0
+	r.Device, ok = rsrcNum(f[0], 31)
+	if ok && len(f) == 2 {
+		r.Function, ok = rsrcNum(f[1], 7)
+	}
+	return ok
+}
+
+// rsrcNum parses a decimal address field no larger than max.
+func rsrcNum(s string, max uint64) (uint16, bool) {
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || n > max {
+		return 0, false
+	}
+	return uint16(n), true
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseResourceName(t *testing.T) {
+	tests := []struct {
+		name  string
+		canon string
+		want  ResourceName // compared when Interface is set
+	}{
+		{"GPIB::5", "GPIB0::5::INSTR", ResourceName{Interface: InterfaceGPIB, Class: "INSTR",
+			PrimaryAddress: 5, SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"gpib1::5::3::instr", "GPIB1::5::3::INSTR", ResourceName{Interface: InterfaceGPIB, Board: 1,
+			Class: "INSTR", PrimaryAddress: 5, SecondaryAddress: 3, USBInterface: -1}},
+		{"GPIB0::INTFC", "GPIB0::INTFC", ResourceName{}},
+		{"GPIB-VXI::9", "GPIB-VXI0::9::INSTR", ResourceName{Interface: InterfaceGPIBVXI, Class: "INSTR",
+			LogicalAddress: 9, SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"vxi0::1::backplane", "VXI0::1::BACKPLANE", ResourceName{}},
+		{"VXI::MEMACC", "VXI0::MEMACC", ResourceName{}},
+		{"PXI3::5", "PXI0::3-5::INSTR", ResourceName{Interface: InterfacePXI, Class: "INSTR",
+			Bus: 3, Device: 5, SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"PXI2::5::1::INSTR", "PXI0::2-5.1::INSTR", ResourceName{}},
+		{"PXI1::3-18.2::INSTR", "PXI1::3-18.2::INSTR", ResourceName{Interface: InterfacePXI, Board: 1,
+			Class: "INSTR", Bus: 3, Device: 18, Function: 2, SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"PXI0::chassis1::slot4::func1", "PXI0::CHASSIS1::SLOT4::FUNC1::INSTR", ResourceName{}},
+		{"PXI::2::BACKPLANE", "PXI0::2::BACKPLANE", ResourceName{}},
+		{"ASRL3", "ASRL3::INSTR", ResourceName{}},
+		{"ASRL/dev/ttyUSB0::INSTR", "ASRL/dev/ttyUSB0::INSTR", ResourceName{Interface: InterfaceASRL,
+			Class: "INSTR", Path: "/dev/ttyUSB0", SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"TCPIP::10.0.0.2", "TCPIP0::10.0.0.2::inst0::INSTR", ResourceName{}},
+		{"TCPIP::10.0.0.2::INST1", "TCPIP0::10.0.0.2::inst1::INSTR", ResourceName{}},
+		{"tcpip0::host::HiSLIP0::instr", "TCPIP0::host::hislip0::INSTR", ResourceName{}},
+		{"TCPIP::host::GPIB0,5::INSTR", "TCPIP0::host::gpib0,5::INSTR", ResourceName{}},
+		{"TCPIP::host::USB0[2391::6038::MyS/N::0]", "TCPIP0::host::usb0[2391::6038::MyS/N::0]::INSTR", ResourceName{}},
+		{"TCPIP::host::myDevice::INSTR", "TCPIP0::host::myDevice::INSTR", ResourceName{}},
+		{"TCPIP::[fe80::1]::5025::SOCKET", "TCPIP0::[fe80::1]::5025::SOCKET", ResourceName{Interface: InterfaceTCPIP,
+			Class: "SOCKET", Host: "fe80::1", Port: 5025, SecondaryAddress: NO_SEC_ADDR, USBInterface: -1}},
+		{"USB::0x0957::0x1796::MY123::INSTR", "USB0::0x0957::0x1796::MY123::INSTR", ResourceName{}},
+		{"USB0::2391::6038::MY123::0::RAW", "USB0::0x0957::0x1796::MY123::0::RAW", ResourceName{Interface: InterfaceUSB,
+			Class: "RAW", ManufacturerID: 0x0957, ModelCode: 0x1796, SerialNumber: "MY123",
+			SecondaryAddress: NO_SEC_ADDR}},
+	}
+	for _, tt := range tests {
+		r, err := ParseResourceName(tt.name)
+		if err != nil {
+			t.Errorf("ParseResourceName(%q): %v", tt.name, err)
+			continue
+		}
+		if tt.want.Interface != 0 && r != tt.want {
+			t.Errorf("ParseResourceName(%q) = %+v, want %+v", tt.name, r, tt.want)
+		}
+		if got := r.String(); got != tt.canon {
+			t.Errorf("ParseResourceName(%q).String() = %q, want %q", tt.name, got, tt.canon)
+		}
+		// The canonical form parses to itself.
+		r2, err := ParseResourceName(tt.canon)
+		if err != nil || r2.String() != tt.canon {
+			t.Errorf("%q doesn't round trip: %q, %v", tt.canon, r2.String(), err)
+		}
+	}
+}
+
+func TestParseResourceNameInvalid(t *testing.T) {
+	for _, name := range []string{
+		"",
+		"FOO::1",
+		"GPIB0::31",
+		"GPIB0::5::RAW",
+		"GPIB0::1::2::3",
+		"VXI0::256",
+		"PXI0::CHASSIS0::SLOT1",
+		"PXI0::3-32",
+		"ASRL1::SOCKET",
+		"ASRL1::2",
+		"TCPIP::h::x::SOCKET",
+		"TCPIP::h::70000::SOCKET",
+		"USB::1::2",
+		"USB::vid::2::SN",
+		"USB::1::2::SN::255",
+	} {
+		_, err := ParseResourceName(name)
+		if !errors.Is(err, ErrInvRsrcName) {
+			t.Errorf("ParseResourceName(%q): %v, want ErrInvRsrcName", name, err)
+		}
+	}
+}
+
+func TestResourceNameIsHiSLIP(t *testing.T) {
+	for name, want := range map[string]bool{
+		"TCPIP::host::hislip0::INSTR": true,
+		"TCPIP::host::HISLIP1,4880":   true,
+		"TCPIP::host::inst0::INSTR":   false,
+		"TCPIP::host::5025::SOCKET":   false,
+	} {
+		r, err := ParseResourceName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.IsHiSLIP() != want {
+			t.Errorf("%q IsHiSLIP = %v, want %v", name, !want, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerTransport("TCPIP::SOCKET", openSocket)
+}
+
+// socketTransport serves TCPIP::host::port::SOCKET resources over a raw
+// TCP connection, the usual way to reach SCPI instruments on port 5025.
+type socketTransport struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// openSocket dials the host and port of a SOCKET resource name. The dial
+// is bounded by the session's timeout.
+func openSocket(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if len(p.fields) != 2 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	host := p.fields[0]
+	port, err := strconv.ParseUint(p.fields[1], 10, 16)
+	if err != nil {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	d := net.Dialer{Deadline: s.deadline()}
+	conn, err := d.Dial("tcp", net.JoinHostPort(host, p.fields[1]))
+	if err != nil {
+		return nil, ERROR_RSRC_NFOUND
+	}
+	t := &socketTransport{conn: conn, rd: bufio.NewReader(conn)}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetNoDelay(true)
+		tc.SetKeepAlive(false)
+	}
+
+	addr := host
+	if ra, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		addr = ra.IP.String()
+	}
+	s.a.putString(ATTR_TCPIP_ADDR, addr)
+	s.a.putString(ATTR_TCPIP_HOSTNAME, host)
+	s.a.put(ATTR_TCPIP_PORT, port)
+	s.a.put(ATTR_TCPIP_NODELAY, TRUE)
+	s.a.put(ATTR_TCPIP_KEEPALIVE, FALSE)
+	s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+	s.a.put(ATTR_SUPPRESS_END_EN, TRUE)
+	return t, SUCCESS
+}
+
+func (t *socketTransport) setAttribute(s *goSession, attr uint32, state uint64) Status {
+	tc, ok := t.conn.(*net.TCPConn)
+	if !ok {
+		return SUCCESS
+	}
+	var err error
+	switch attr {
+	case ATTR_TCPIP_NODELAY:
+		err = tc.SetNoDelay(state == TRUE)
+	case ATTR_TCPIP_KEEPALIVE:
+		err = tc.SetKeepAlive(state == TRUE)
+	}
+	if err != nil {
+		return ERROR_NSUP_ATTR_STATE
+	}
+	return SUCCESS
+}
+
+// read fills buf, stopping after the termination character when it's
+// enabled. Sockets carry no END indicator, so ATTR_SUPPRESS_END_EN is on
+// by default; turned off, running out of received data counts as END.
+func (t *socketTransport) read(s *goSession, buf []byte) (int, Status) {
+	t.conn.SetReadDeadline(s.deadline())
+	term, termEn := s.termChar()
+	endEn := s.a.num(ATTR_SUPPRESS_END_EN) == FALSE
+	n := 0
+	for n < len(buf) {
+		if t.rd.Buffered() == 0 {
+			if _, err := t.rd.Peek(1); err != nil {
+				return n, netStatus(err)
+			}
+		}
+		avail := t.rd.Buffered()
+		if avail > len(buf)-n {
+			avail = len(buf) - n
+		}
+		chunk, _ := t.rd.Peek(avail)
+		if termEn {
+			if i := bytes.IndexByte(chunk, term); i >= 0 {
+				n += copy(buf[n:], chunk[:i+1])
+				t.rd.Discard(i + 1)
+				return n, SUCCESS_TERM_CHAR
+			}
+		}
+		n += copy(buf[n:], chunk)
+		t.rd.Discard(len(chunk))
+		if endEn && n < len(buf) && t.rd.Buffered() == 0 {
+			return n, SUCCESS
+		}
+	}
+	return n, SUCCESS_MAX_CNT
+}
+
+func (t *socketTransport) write(s *goSession, buf []byte) (int, Status) {
+	t.conn.SetWriteDeadline(s.deadline())
+	n, err := t.conn.Write(buf)
+	if err != nil {
+		return n, netStatus(err)
+	}
+	return n, SUCCESS
+}
+
+func (t *socketTransport) close() Status {
+	if err := t.conn.Close(); err != nil {
+		return ERROR_CLOSING_FAILED
+	}
+	return SUCCESS
+}
+
+// abort unblocks a transfer in progress, it fails with ERROR_TMO.
+func (t *socketTransport) abort(s *goSession) {
+	t.conn.SetDeadline(time.Now())
+}
+
+// clear discards the input received but not yet read.
+func (t *socketTransport) clear(s *goSession) Status {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	t.rd.Discard(t.rd.Buffered())
+	return SUCCESS
+}
+
+// readSTB queries *STB? when the session uses the 488.2 string protocol,
+// as NI-VISA does for sockets. The response shares the connection with
+// those of other queries, which have to be read before a serial poll, it
+// would be taken for the status byte otherwise. Data already buffered by
+// an earlier read makes it fail with ERROR_RESP_PENDING.
+func (t *socketTransport) readSTB(s *goSession) (uint16, Status) {
+	if s.a.num(ATTR_IO_PROT) != PROT_4882_STRS {
+		return 0, ERROR_NSUP_OPER
+	}
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	if t.rd.Buffered() > 0 {
+		return 0, ERROR_RESP_PENDING
+	}
+	if _, status := t.write(s, []byte("*STB?\n")); status != SUCCESS {
+		return 0, status
+	}
+	t.conn.SetReadDeadline(s.deadline())
+	line, err := t.rd.ReadString('\n')
+	if err != nil {
+		return 0, netStatus(err)
+	}
+	stb, err := strconv.ParseUint(strings.TrimSpace(line), 10, 8)
+	if err != nil {
+		return 0, ERROR_IO
+	}
+	return uint16(stb), SUCCESS
+}
+
+// assertTrigger sends *TRG when the session uses the 488.2 string protocol.
+func (t *socketTransport) assertTrigger(s *goSession, protocol uint16) Status {
+	if s.a.num(ATTR_IO_PROT) != PROT_4882_STRS {
+		return ERROR_NSUP_OPER
+	}
+	if protocol != TRIG_PROT_DEFAULT {
+		return ERROR_INV_PROT
+	}
+	_, status := t.write(s, []byte("*TRG\n"))
+	return status
+}
+
+// netStatus maps a network error to a VISA status.
+func netStatus(err error) Status {
+	var ne net.Error
+	switch {
+	case err == nil:
+		return SUCCESS
+	case errors.As(err, &ne) && ne.Timeout():
+		return ERROR_TMO
+	case errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+		return ERROR_CONN_LOST
+	}
+	return ERROR_IO
+}
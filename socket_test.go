@@ -0,0 +1,184 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// serveSocket listens on a local port, runs serve on the first connection
+// and returns the SOCKET resource name to reach it.
+func serveSocket(t *testing.T, serve func(c net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		serve(c)
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	return "TCPIP0::127.0.0.1::" + strconv.Itoa(port) + "::SOCKET"
+}
+
+// openSocketTest opens name with a short timeout.
+func openSocketTest(t *testing.T, name string) Object {
+	t.Helper()
+	rm := openTestRM(t)
+	instr, status := rm.Open(name, NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open(%q): %v", name, status)
+	}
+	instr.SetAttribute(ATTR_TMO_VALUE, 200)
+	return instr
+}
+
+func TestSocketTermination(t *testing.T) {
+	done := make(chan struct{})
+	instr := openSocketTest(t, serveSocket(t, func(c net.Conn) {
+		c.Write([]byte("one\ntwo\nthree"))
+		<-done
+	}))
+	defer close(done)
+
+	instr.SetAttribute(ATTR_TERMCHAR_EN, TRUE)
+	for _, want := range []string{"one\n", "two\n"} {
+		buf, n, status := instr.Read(100)
+		if status != SUCCESS_TERM_CHAR || string(buf[:n]) != want {
+			t.Errorf("Read = %q, %v, want %q, SUCCESS_TERM_CHAR", buf[:n], status, want)
+		}
+	}
+	buf, n, status := instr.Read(2)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "th" {
+		t.Errorf("Read(2) = %q, %v, want \"th\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+
+	instr.SetAttribute(ATTR_TERMCHAR_EN, FALSE)
+	instr.SetAttribute(ATTR_SUPPRESS_END_EN, FALSE)
+	buf, n, status = instr.Read(100)
+	if status != SUCCESS || string(buf[:n]) != "ree" {
+		t.Errorf("Read with END = %q, %v, want \"ree\", SUCCESS", buf[:n], status)
+	}
+}
+
+func TestSocketTimeout(t *testing.T) {
+	done := make(chan struct{})
+	instr := openSocketTest(t, serveSocket(t, func(c net.Conn) {
+		c.Write([]byte("partial"))
+		<-done
+	}))
+	defer close(done)
+
+	start := time.Now()
+	buf, n, status := instr.Read(100)
+	if status != ERROR_TMO || string(buf[:n]) != "partial" {
+		t.Errorf("Read = %q, %v, want \"partial\", ERROR_TMO", buf[:n], status)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond || d > time.Second {
+		t.Errorf("Read timed out after %v, want 200ms", d)
+	}
+}
+
+func TestSocketClear(t *testing.T) {
+	next := make(chan struct{})
+	instr := openSocketTest(t, serveSocket(t, func(c net.Conn) {
+		b := make([]byte, 64)
+		c.Read(b)
+		c.Write([]byte("stale\nstale\n"))
+		<-next
+		c.Write([]byte("fresh\n"))
+		<-next
+	}))
+	defer close(next)
+
+	instr.SetAttribute(ATTR_TERMCHAR_EN, TRUE)
+	if _, status := instr.Write([]byte("*IDN?\n"), 6); status != SUCCESS {
+		t.Fatalf("Write: %v", status)
+	}
+	if buf, n, status := instr.Read(100); status != SUCCESS_TERM_CHAR || string(buf[:n]) != "stale\n" {
+		t.Fatalf("Read = %q, %v", buf[:n], status)
+	}
+	if status := instr.Clear(); status != SUCCESS {
+		t.Fatalf("Clear: %v", status)
+	}
+	next <- struct{}{}
+	if buf, n, status := instr.Read(100); status != SUCCESS_TERM_CHAR || string(buf[:n]) != "fresh\n" {
+		t.Errorf("Read after Clear = %q, %v, want \"fresh\\n\"", buf[:n], status)
+	}
+}
+
+func TestSocketAttributes(t *testing.T) {
+	done := make(chan struct{})
+	name := serveSocket(t, func(c net.Conn) { <-done })
+	defer close(done)
+	instr := openSocketTest(t, name)
+
+	if port, err := instr.AttrUint16(ATTR_TCPIP_PORT); err != nil || name != "TCPIP0::127.0.0.1::"+strconv.Itoa(int(port))+"::SOCKET" {
+		t.Errorf("ATTR_TCPIP_PORT = %d, %v", port, err)
+	}
+	if on, err := instr.AttrBool(ATTR_TCPIP_NODELAY); err != nil || !on {
+		t.Errorf("ATTR_TCPIP_NODELAY = %v, %v, want true", on, err)
+	}
+	for _, attr := range []uint32{ATTR_TCPIP_NODELAY, ATTR_TCPIP_KEEPALIVE} {
+		if err := instr.SetAttrBool(attr, attr == ATTR_TCPIP_KEEPALIVE); err != nil {
+			t.Errorf("SetAttrBool(%s): %v", attrName(attr), err)
+		}
+	}
+	if status := instr.SetAttribute(ATTR_TCPIP_PORT, 1); status != ERROR_ATTR_READONLY {
+		t.Errorf("setting ATTR_TCPIP_PORT: %v, want ERROR_ATTR_READONLY", status)
+	}
+}
+
+func TestSocketReadSTB(t *testing.T) {
+	instr := openSocketTest(t, serveSocket(t, func(c net.Conn) {
+		rd := bufio.NewReader(c)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch line {
+			case "*STB?\n":
+				c.Write([]byte("65\n"))
+			case "LIST?\n":
+				c.Write([]byte("A\nB\n"))
+			}
+		}
+	}))
+
+	if _, status := instr.ReadSTB(); status != ERROR_NSUP_OPER {
+		t.Errorf("ReadSTB with the normal protocol: %v, want ERROR_NSUP_OPER", status)
+	}
+	instr.SetAttribute(ATTR_IO_PROT, PROT_4882_STRS)
+	if stb, status := instr.ReadSTB(); status != SUCCESS || stb != 65 {
+		t.Errorf("ReadSTB = %d, %v, want 65", stb, status)
+	}
+
+	// A response left in the buffer isn't taken for the status byte.
+	instr.Write([]byte("LIST?\n"), 6)
+	instr.SetAttribute(ATTR_TERMCHAR_EN, TRUE)
+	if buf, n, status := instr.Read(100); status != SUCCESS_TERM_CHAR || string(buf[:n]) != "A\n" {
+		t.Fatalf("Read = %q, %v", buf[:n], status)
+	}
+	if _, status := instr.ReadSTB(); status != ERROR_RESP_PENDING {
+		t.Errorf("ReadSTB with a response pending: %v, want ERROR_RESP_PENDING", status)
+	}
+	if buf, n, status := instr.Read(100); status != SUCCESS_TERM_CHAR || string(buf[:n]) != "B\n" {
+		t.Errorf("Read = %q, %v, want the pending response", buf[:n], status)
+	}
+	if stb, status := instr.ReadSTB(); status != SUCCESS || stb != 65 {
+		t.Errorf("ReadSTB after reading the response = %d, %v, want 65", stb, status)
+	}
+}
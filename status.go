@@ -0,0 +1,265 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+)
+
+// Sentinel errors for the failures callers most often need to single out.
+// Use errors.Is to match them against a Status or an *Error.
+const (
+	ErrSystem          Status = ERROR_SYSTEM_ERROR
+	ErrInvObject       Status = ERROR_INV_OBJECT
+	ErrRsrcLocked      Status = ERROR_RSRC_LOCKED
+	ErrInvExpr         Status = ERROR_INV_EXPR
+	ErrRsrcNotFound    Status = ERROR_RSRC_NFOUND
+	ErrInvRsrcName     Status = ERROR_INV_RSRC_NAME
+	ErrInvAccessMode   Status = ERROR_INV_ACC_MODE
+	ErrTimeout         Status = ERROR_TMO
+	ErrClosingFailed   Status = ERROR_CLOSING_FAILED
+	ErrInvJobID        Status = ERROR_INV_JOB_ID
+	ErrNsupAttr        Status = ERROR_NSUP_ATTR
+	ErrNsupAttrState   Status = ERROR_NSUP_ATTR_STATE
+	ErrAttrReadOnly    Status = ERROR_ATTR_READONLY
+	ErrInvEvent        Status = ERROR_INV_EVENT
+	ErrInvMech         Status = ERROR_INV_MECH
+	ErrQueueOverflow   Status = ERROR_QUEUE_OVERFLOW
+	ErrNotEnabled      Status = ERROR_NENABLED
+	ErrAbort           Status = ERROR_ABORT
+	ErrInProgress      Status = ERROR_IN_PROGRESS
+	ErrAlloc           Status = ERROR_ALLOC
+	ErrIO              Status = ERROR_IO
+	ErrNoListeners     Status = ERROR_NLISTENERS
+	ErrNsupOper        Status = ERROR_NSUP_OPER
+	ErrRsrcBusy        Status = ERROR_RSRC_BUSY
+	ErrInvParameter    Status = ERROR_INV_PARAMETER
+	ErrInvSize         Status = ERROR_INV_SIZE
+	ErrNimplOper       Status = ERROR_NIMPL_OPER
+	ErrSessionNLocked  Status = ERROR_SESN_NLOCKED
+	ErrLibraryNotFound Status = ERROR_LIBRARY_NFOUND
+	ErrConnLost        Status = ERROR_CONN_LOST
+	ErrMachineNAvail   Status = ERROR_MACHINE_NAVAIL
+	ErrNPermission     Status = ERROR_NPERMISSION
+)
+
+type statusInfo struct {
+	name string
+	desc string
+}
+
+// statusTable holds the VISA name and description of every completion and
+// error code in defs.go.
+var statusTable = map[Status]statusInfo{
+	SUCCESS:                {"VI_SUCCESS", "Operation completed successfully."},
+	SUCCESS_EVENT_EN:       {"VI_SUCCESS_EVENT_EN", "Specified event is already enabled for at least one of the specified mechanisms."},
+	SUCCESS_EVENT_DIS:      {"VI_SUCCESS_EVENT_DIS", "Specified event is already disabled for at least one of the specified mechanisms."},
+	SUCCESS_QUEUE_EMPTY:    {"VI_SUCCESS_QUEUE_EMPTY", "Operation completed successfully, but queue was already empty."},
+	SUCCESS_TERM_CHAR:      {"VI_SUCCESS_TERM_CHAR", "The specified termination character was read."},
+	SUCCESS_MAX_CNT:        {"VI_SUCCESS_MAX_CNT", "The number of bytes read is equal to the input count."},
+	SUCCESS_DEV_NPRESENT:   {"VI_SUCCESS_DEV_NPRESENT", "Session opened successfully, but the device at the specified address is not responding."},
+	SUCCESS_TRIG_MAPPED:    {"VI_SUCCESS_TRIG_MAPPED", "The path from trigSrc to trigDest is already mapped."},
+	SUCCESS_QUEUE_NEMPTY:   {"VI_SUCCESS_QUEUE_NEMPTY", "Wait terminated successfully on receipt of an event notification. There is still at least one more event occurrence available for this session."},
+	SUCCESS_NCHAIN:         {"VI_SUCCESS_NCHAIN", "Event handled successfully. Do not invoke any other handlers on this session for this event."},
+	SUCCESS_NESTED_SHARED:  {"VI_SUCCESS_NESTED_SHARED", "Operation completed successfully, and this session has nested shared locks."},
+	SUCCESS_NESTED_EXCLUSI: {"VI_SUCCESS_NESTED_EXCLUSIVE", "Operation completed successfully, and this session has nested exclusive locks."},
+	SUCCESS_SYNC:           {"VI_SUCCESS_SYNC", "Asynchronous operation request was actually performed synchronously."},
+
+	WARN_QUEUE_OVERFLOW:  {"VI_WARN_QUEUE_OVERFLOW", "The event returned is valid. One or more events that occurred have not been raised because there was no room available on the queue at the time of their occurrence."},
+	WARN_CONFIG_NLOADED:  {"VI_WARN_CONFIG_NLOADED", "The specified configuration either does not exist or could not be loaded; using VISA-specified defaults."},
+	WARN_NULL_OBJECT:     {"VI_WARN_NULL_OBJECT", "The specified object reference is uninitialized."},
+	WARN_NSUP_ATTR_STATE: {"VI_WARN_NSUP_ATTR_STATE", "Although the specified state of the attribute is valid, it is not supported by this resource implementation."},
+	WARN_UNKNOWN_STATUS:  {"VI_WARN_UNKNOWN_STATUS", "The status code passed to the operation could not be interpreted."},
+	WARN_NSUP_BUF:        {"VI_WARN_NSUP_BUF", "The specified buffer is not supported."},
+	WARN_EXT_FUNC_NIMPL:  {"VI_WARN_EXT_FUNC_NIMPL", "The operation succeeded, but a lower level driver did not implement the extended functionality."},
+
+	ERROR_SYSTEM_ERROR:     {"VI_ERROR_SYSTEM_ERROR", "Unknown system error (miscellaneous error)."},
+	ERROR_INV_OBJECT:       {"VI_ERROR_INV_OBJECT", "The given session or object reference is invalid."},
+	ERROR_RSRC_LOCKED:      {"VI_ERROR_RSRC_LOCKED", "Specified type of lock cannot be obtained or specified operation cannot be performed, because the resource is locked."},
+	ERROR_INV_EXPR:         {"VI_ERROR_INV_EXPR", "Invalid expression specified for search."},
+	ERROR_RSRC_NFOUND:      {"VI_ERROR_RSRC_NFOUND", "Insufficient location information or the device or resource is not present in the system."},
+	ERROR_INV_RSRC_NAME:    {"VI_ERROR_INV_RSRC_NAME", "Invalid resource reference specified. Parsing error."},
+	ERROR_INV_ACC_MODE:     {"VI_ERROR_INV_ACC_MODE", "Invalid access mode."},
+	ERROR_TMO:              {"VI_ERROR_TMO", "Timeout expired before operation completed."},
+	ERROR_CLOSING_FAILED:   {"VI_ERROR_CLOSING_FAILED", "Unable to deallocate the previously allocated data structures corresponding to this session or object reference."},
+	ERROR_INV_DEGREE:       {"VI_ERROR_INV_DEGREE", "Specified degree is invalid."},
+	ERROR_INV_JOB_ID:       {"VI_ERROR_INV_JOB_ID", "Specified job identifier is invalid."},
+	ERROR_NSUP_ATTR:        {"VI_ERROR_NSUP_ATTR", "The specified attribute is not defined or supported by the referenced session, event, or find list."},
+	ERROR_NSUP_ATTR_STATE:  {"VI_ERROR_NSUP_ATTR_STATE", "The specified state of the attribute is not valid, or is not supported as defined by the session, event, or find list."},
+	ERROR_ATTR_READONLY:    {"VI_ERROR_ATTR_READONLY", "The specified attribute is Read Only."},
+	ERROR_INV_LOCK_TYPE:    {"VI_ERROR_INV_LOCK_TYPE", "The specified type of lock is not supported by this resource."},
+	ERROR_INV_ACCESS_KEY:   {"VI_ERROR_INV_ACCESS_KEY", "The access key to the resource associated with this session is invalid."},
+	ERROR_INV_EVENT:        {"VI_ERROR_INV_EVENT", "Specified event type is not supported by the resource."},
+	ERROR_INV_MECH:         {"VI_ERROR_INV_MECH", "Invalid mechanism specified."},
+	ERROR_HNDLR_NINSTALLED: {"VI_ERROR_HNDLR_NINSTALLED", "A handler is not currently installed for the specified event."},
+	ERROR_INV_HNDLR_REF:    {"VI_ERROR_INV_HNDLR_REF", "The given handler reference is invalid."},
+	ERROR_INV_CONTEXT:      {"VI_ERROR_INV_CONTEXT", "Specified event context is invalid."},
+	ERROR_QUEUE_OVERFLOW:   {"VI_ERROR_QUEUE_OVERFLOW", "The event queue for the specified type has overflowed (usually due to previous events not having been closed)."},
+	ERROR_NENABLED:         {"VI_ERROR_NENABLED", "The session must be enabled for events of the specified type in order to receive them."},
+	ERROR_ABORT:            {"VI_ERROR_ABORT", "The operation was aborted."},
+	ERROR_RAW_WR_PROT_VIOL: {"VI_ERROR_RAW_WR_PROT_VIOL", "Violation of raw write protocol occurred during transfer."},
+	ERROR_RAW_RD_PROT_VIOL: {"VI_ERROR_RAW_RD_PROT_VIOL", "Violation of raw read protocol occurred during transfer."},
+	ERROR_OUTP_PROT_VIOL:   {"VI_ERROR_OUTP_PROT_VIOL", "Device reported an output protocol error during transfer."},
+	ERROR_INP_PROT_VIOL:    {"VI_ERROR_INP_PROT_VIOL", "Device reported an input protocol error during transfer."},
+	ERROR_BERR:             {"VI_ERROR_BERR", "Bus error occurred during transfer."},
+	ERROR_IN_PROGRESS:      {"VI_ERROR_IN_PROGRESS", "Unable to queue the asynchronous operation because there is already an operation in progress."},
+	ERROR_INV_SETUP:        {"VI_ERROR_INV_SETUP", "Unable to start operation because setup is invalid (due to attributes being set to an inconsistent state)."},
+	ERROR_QUEUE_ERROR:      {"VI_ERROR_QUEUE_ERROR", "Unable to queue asynchronous operation."},
+	ERROR_ALLOC:            {"VI_ERROR_ALLOC", "Insufficient system resources to perform necessary memory allocation."},
+	ERROR_INV_MASK:         {"VI_ERROR_INV_MASK", "Invalid buffer mask specified."},
+	ERROR_IO:               {"VI_ERROR_IO", "Could not perform operation because of I/O error."},
+	ERROR_INV_FMT:          {"VI_ERROR_INV_FMT", "A format specifier in the format string is invalid."},
+	ERROR_NSUP_FMT:         {"VI_ERROR_NSUP_FMT", "A format specifier in the format string is not supported."},
+	ERROR_LINE_IN_USE:      {"VI_ERROR_LINE_IN_USE", "The specified trigger line is currently in use."},
+	ERROR_LINE_NRESERVED:   {"VI_ERROR_LINE_NRESERVED", "An attempt was made to use a PXI trigger line that was not reserved."},
+	ERROR_NSUP_MODE:        {"VI_ERROR_NSUP_MODE", "The specified mode is not supported by this VISA implementation."},
+	ERROR_SRQ_NOCCURRED:    {"VI_ERROR_SRQ_NOCCURRED", "Service request has not been received for the session."},
+	ERROR_INV_SPACE:        {"VI_ERROR_INV_SPACE", "Invalid address space specified."},
+	ERROR_INV_OFFSET:       {"VI_ERROR_INV_OFFSET", "Invalid offset specified."},
+	ERROR_INV_WIDTH:        {"VI_ERROR_INV_WIDTH", "Invalid access width specified."},
+	ERROR_NSUP_OFFSET:      {"VI_ERROR_NSUP_OFFSET", "Specified offset is not accessible from this hardware."},
+	ERROR_NSUP_VAR_WIDTH:   {"VI_ERROR_NSUP_VAR_WIDTH", "Cannot support source and destination widths that are different."},
+	ERROR_WINDOW_NMAPPED:   {"VI_ERROR_WINDOW_NMAPPED", "The specified session is not currently mapped."},
+	ERROR_RESP_PENDING:     {"VI_ERROR_RESP_PENDING", "A previous response is still pending, causing a multiple query error."},
+	ERROR_NLISTENERS:       {"VI_ERROR_NLISTENERS", "No listeners condition is detected (both NRFD and NDAC are deasserted)."},
+	ERROR_NCIC:             {"VI_ERROR_NCIC", "The interface associated with this session is not currently the controller in charge."},
+	ERROR_NSYS_CNTLR:       {"VI_ERROR_NSYS_CNTLR", "The interface associated with this session is not the system controller."},
+	ERROR_NSUP_OPER:        {"VI_ERROR_NSUP_OPER", "The given session or object reference does not support this operation."},
+	ERROR_INTR_PENDING:     {"VI_ERROR_INTR_PENDING", "An interrupt is still pending from a previous call."},
+	ERROR_ASRL_PARITY:      {"VI_ERROR_ASRL_PARITY", "A parity error occurred during transfer."},
+	ERROR_ASRL_FRAMING:     {"VI_ERROR_ASRL_FRAMING", "A framing error occurred during transfer."},
+	ERROR_ASRL_OVERRUN:     {"VI_ERROR_ASRL_OVERRUN", "An overrun error occurred during transfer. A character was not read from the hardware before the next character arrived."},
+	ERROR_TRIG_NMAPPED:     {"VI_ERROR_TRIG_NMAPPED", "The path from trigSrc to trigDest is not currently mapped."},
+	ERROR_NSUP_ALIGN_OFFSE: {"VI_ERROR_NSUP_ALIGN_OFFSET", "The specified offset is not properly aligned for the access width of the operation."},
+	ERROR_USER_BUF:         {"VI_ERROR_USER_BUF", "A specified user buffer is not valid or cannot be accessed for the required size."},
+	ERROR_RSRC_BUSY:        {"VI_ERROR_RSRC_BUSY", "The resource is valid, but VISA cannot currently access it."},
+	ERROR_NSUP_WIDTH:       {"VI_ERROR_NSUP_WIDTH", "Specified width is not supported by this hardware."},
+	ERROR_INV_PARAMETER:    {"VI_ERROR_INV_PARAMETER", "The value of some parameter (which parameter is not known) is invalid."},
+	ERROR_INV_PROT:         {"VI_ERROR_INV_PROT", "The protocol specified is invalid."},
+	ERROR_INV_SIZE:         {"VI_ERROR_INV_SIZE", "Invalid size of window specified."},
+	ERROR_WINDOW_MAPPED:    {"VI_ERROR_WINDOW_MAPPED", "The specified session currently contains a mapped window."},
+	ERROR_NIMPL_OPER:       {"VI_ERROR_NIMPL_OPER", "The given operation is not implemented."},
+	ERROR_INV_LENGTH:       {"VI_ERROR_INV_LENGTH", "Invalid length specified."},
+	ERROR_INV_MODE:         {"VI_ERROR_INV_MODE", "The specified mode is invalid."},
+	ERROR_SESN_NLOCKED:     {"VI_ERROR_SESN_NLOCKED", "The current session did not have a lock on the resource."},
+	ERROR_MEM_NSHARED:      {"VI_ERROR_MEM_NSHARED", "The device does not export any memory."},
+	ERROR_LIBRARY_NFOUND:   {"VI_ERROR_LIBRARY_NFOUND", "A code library required by VISA could not be located or loaded."},
+	ERROR_NSUP_INTR:        {"VI_ERROR_NSUP_INTR", "The interface cannot generate an interrupt on the requested level or with the requested statusID value."},
+	ERROR_INV_LINE:         {"VI_ERROR_INV_LINE", "The value specified by the line parameter is invalid."},
+	ERROR_FILE_ACCESS:      {"VI_ERROR_FILE_ACCESS", "An error occurred while trying to open the specified file. Possible reasons include an invalid path or lack of access rights."},
+	ERROR_FILE_IO:          {"VI_ERROR_FILE_IO", "An error occurred while performing I/O on the specified file."},
+	ERROR_NSUP_LINE:        {"VI_ERROR_NSUP_LINE", "One of the specified lines (trigSrc or trigDest) is not supported by this VISA implementation, or the combination of lines is not a valid mapping."},
+	ERROR_NSUP_MECH:        {"VI_ERROR_NSUP_MECH", "The specified mechanism is not supported for the given event type."},
+	ERROR_INTF_NUM_NCONFIG: {"VI_ERROR_INTF_NUM_NCONFIG", "The interface type is valid but the specified interface number is not configured."},
+	ERROR_CONN_LOST:        {"VI_ERROR_CONN_LOST", "The connection for the given session has been lost."},
+	ERROR_MACHINE_NAVAIL:   {"VI_ERROR_MACHINE_NAVAIL", "The remote machine does not exist or is not accepting any connections."},
+	ERROR_NPERMISSION:      {"VI_ERROR_NPERMISSION", "Access to the remote machine is denied."},
+}
+
+// Error implements the error interface. It returns the VISA name of the
+// status followed by its description.
+func (s Status) Error() string {
+	if info, ok := statusTable[s]; ok {
+		return info.name + ": " + info.desc
+	}
+	return fmt.Sprintf("VI status 0x%08X", uint32(s))
+}
+
+// Name returns the VISA name of the status, e.g. "VI_ERROR_TMO", or an
+// empty string if the code is unknown.
+func (s Status) Name() string {
+	return statusTable[s].name
+}
+
+// Description returns the description of the status, or an empty string
+// if the code is unknown.
+func (s Status) Description() string {
+	return statusTable[s].desc
+}
+
+// IsError reports whether the status is a failure.
+func (s Status) IsError() bool {
+	return s < SUCCESS
+}
+
+// IsWarning reports whether the operation completed but the status
+// carries a warning: any WARN_* code or SUCCESS_MAX_CNT, which signals
+// that more data may be pending.
+func (s Status) IsWarning() bool {
+	switch s {
+	case SUCCESS_MAX_CNT, WARN_QUEUE_OVERFLOW, WARN_CONFIG_NLOADED,
+		WARN_NULL_OBJECT, WARN_NSUP_ATTR_STATE, WARN_UNKNOWN_STATUS,
+		WARN_NSUP_BUF, WARN_EXT_FUNC_NIMPL:
+		return true
+	}
+	return false
+}
+
+// Err returns the status as an error if it's a failure and nil otherwise,
+// so that completion and warning codes don't masquerade as errors.
+func (s Status) Err() error {
+	if s < SUCCESS {
+		return s
+	}
+	return nil
+}
+
+// Wrap returns an *Error recording the operation and resource name if the
+// status is a failure and nil otherwise.
+func (s Status) Wrap(op, rsrc string) error {
+	if s < SUCCESS {
+		return &Error{Op: op, Rsrc: rsrc, Status: s}
+	}
+	return nil
+}
+
+// Error is a failed VISA operation on a resource.
+type Error struct {
+	Op     string // operation that failed, e.g. "Read"
+	Rsrc   string // resource name, may be empty
+	Status Status
+}
+
+func (e *Error) Error() string {
+	s := "visa: " + e.Op
+	if e.Rsrc != "" {
+		s += " " + e.Rsrc
+	}
+	return s + ": " + e.Status.Error()
+}
+
+// Unwrap returns the underlying status so errors.Is matches the sentinels.
+func (e *Error) Unwrap() error {
+	return e.Status
+}
+
+// Wrap returns status as an *Error recording the operation and the
+// session's resource name, or nil if status isn't a failure.
+func (instr Object) Wrap(op string, status Status) error {
+	if status >= SUCCESS {
+		return nil
+	}
+	return status.Wrap(op, instr.rsrcName())
+}
+
+// rsrcName returns the resource name of the session, or an empty string
+// if it can't be retrieved.
+func (instr Object) rsrcName() string {
+	b := make([]byte, FIND_BUFLEN)
+	if instr.GetAttribute(ATTR_RSRC_NAME, unsafe.Pointer(&b[0])) < SUCCESS {
+		return ""
+	}
+	return cString(b)
+}
+
+// cString returns the NUL terminated string in b.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
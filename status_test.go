@@ -0,0 +1,120 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		s    Status
+		name string
+	}{
+		{SUCCESS, "VI_SUCCESS"},
+		{SUCCESS_MAX_CNT, "VI_SUCCESS_MAX_CNT"},
+		{WARN_QUEUE_OVERFLOW, "VI_WARN_QUEUE_OVERFLOW"},
+		{ERROR_TMO, "VI_ERROR_TMO"},
+		{ERROR_NSUP_ALIGN_OFFSE, "VI_ERROR_NSUP_ALIGN_OFFSET"},
+		{ERROR_NPERMISSION, "VI_ERROR_NPERMISSION"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Name(); got != tt.name {
+			t.Errorf("Name of %#x = %q, want %q", uint32(tt.s), got, tt.name)
+		}
+		if tt.s.Description() == "" {
+			t.Errorf("%s has no description", tt.name)
+		}
+		if got, want := tt.s.Error(), tt.name+": "+tt.s.Description(); got != want {
+			t.Errorf("Error of %s = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	unknown := Status(-1)
+	if unknown.Name() != "" || unknown.Description() != "" {
+		t.Errorf("unknown status has name %q and description %q", unknown.Name(), unknown.Description())
+	}
+	if got := unknown.Error(); got != "VI status 0xFFFFFFFF" {
+		t.Errorf("Error of an unknown status = %q", got)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		s              Status
+		error, warning bool
+	}{
+		{SUCCESS, false, false},
+		{SUCCESS_TERM_CHAR, false, false},
+		{SUCCESS_MAX_CNT, false, true},
+		{WARN_QUEUE_OVERFLOW, false, true},
+		{WARN_NSUP_ATTR_STATE, false, true},
+		{WARN_EXT_FUNC_NIMPL, false, true},
+		{ERROR_TMO, true, false},
+		{ERROR_SYSTEM_ERROR, true, false},
+	}
+	for _, tt := range tests {
+		if tt.s.IsError() != tt.error || tt.s.IsWarning() != tt.warning {
+			t.Errorf("%s: IsError %v IsWarning %v, want %v and %v",
+				tt.s.Name(), tt.s.IsError(), tt.s.IsWarning(), tt.error, tt.warning)
+		}
+		if err := tt.s.Err(); (err != nil) != tt.error {
+			t.Errorf("%s: Err = %v", tt.s.Name(), err)
+		}
+	}
+}
+
+func TestStatusWrap(t *testing.T) {
+	if err := Status(SUCCESS_TERM_CHAR).Wrap("Read", "GPIB0::5::INSTR"); err != nil {
+		t.Errorf("Wrap of a completion code = %v, want nil", err)
+	}
+	if err := Status(WARN_NSUP_BUF).Wrap("SetBuf", ""); err != nil {
+		t.Errorf("Wrap of a warning = %v, want nil", err)
+	}
+
+	err := Status(ERROR_TMO).Wrap("Read", "GPIB0::5::INSTR")
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "Read" || e.Rsrc != "GPIB0::5::INSTR" || e.Status != ERROR_TMO {
+		t.Fatalf("Wrap = %#v", err)
+	}
+	if want := "visa: Read GPIB0::5::INSTR: VI_ERROR_TMO: "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error = %q, want prefix %q", err.Error(), want)
+	}
+	if got := Status(ERROR_IO).Wrap("Open", "").Error(); !strings.HasPrefix(got, "visa: Open: VI_ERROR_IO") {
+		t.Errorf("Error without a resource = %q", got)
+	}
+}
+
+func TestStatusSentinels(t *testing.T) {
+	wrapped := fmt.Errorf("talking to the scope: %w", Status(ERROR_TMO).Wrap("Read", "USB0::1::2::SN::INSTR"))
+	tests := []struct {
+		err    error
+		target error
+		want   bool
+	}{
+		{Status(ERROR_TMO), ErrTimeout, true},
+		{Status(ERROR_TMO).Err(), ErrTimeout, true},
+		{wrapped, ErrTimeout, true},
+		{wrapped, ErrIO, false},
+		{Status(ERROR_RSRC_LOCKED).Wrap("Lock", ""), ErrRsrcLocked, true},
+		{Status(ERROR_RSRC_NFOUND).Wrap("Open", "GPIB0::9"), ErrRsrcNotFound, true},
+		{Status(ERROR_CONN_LOST).Wrap("Write", ""), ErrConnLost, true},
+		{Status(ERROR_ABORT).Wrap("Read", ""), ErrAbort, true},
+		{Status(ERROR_ABORT).Wrap("Read", ""), ErrTimeout, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %s) = %v, want %v", tt.err, tt.target.(Status).Name(), got, tt.want)
+		}
+	}
+
+	var s Status
+	if !errors.As(wrapped, &s) || s != ERROR_TMO {
+		t.Errorf("errors.As found status %v, want VI_ERROR_TMO", s)
+	}
+}
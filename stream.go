@@ -0,0 +1,120 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import "io"
+
+// Stream adapts a Driver, e.g. an Object, to io.Reader, io.Writer and
+// io.Closer, for use with bufio, io.Copy, encoding/binary and the like.
+//
+// Reads follow the message boundaries of the instrument: a read that ends
+// a message, on END or on the termination character if ATTR_TERMCHAR_EN
+// is set, returns its data and the next read returns io.EOF. The read
+// after that starts the next message, so io.ReadAll reads one message.
+type Stream struct {
+	d   Driver
+	eom bool // the last read ended a message
+}
+
+// NewStream returns a Stream reading from and writing to d.
+func NewStream(d Driver) *Stream {
+	return &Stream{d: d}
+}
+
+// streamChunk is the size of each read of ReadMessage.
+const streamChunk = 4096
+
+// Read reads up to len(p) bytes of the current message.
+func (s *Stream) Read(p []byte) (int, error) {
+	if s.eom {
+		s.eom = false
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return s.read(p)
+}
+
+// read reads into p and records whether the read ended a message.
+func (s *Stream) read(p []byte) (int, error) {
+	b, cnt, status := s.d.Read(uint32(len(p)))
+	if int(cnt) < len(b) {
+		b = b[:cnt]
+	}
+	n := copy(p, b)
+	if status < SUCCESS {
+		return n, s.wrap("Read", status)
+	}
+	s.eom = status != SUCCESS_MAX_CNT
+	return n, nil
+}
+
+// ReadMessage reads the rest of the current message, or the next one if
+// the last read ended a message, in as many reads as it takes to reach
+// END or the termination character.
+func (s *Stream) ReadMessage() ([]byte, error) {
+	s.eom = false
+	var msg []byte
+	buf := make([]byte, streamChunk)
+	for {
+		n, err := s.read(buf)
+		msg = append(msg, buf[:n]...)
+		if err != nil {
+			return msg, err
+		}
+		if s.eom {
+			s.eom = false
+			return msg, nil
+		}
+	}
+}
+
+// EndOfMessage reports whether the last read ended a message, in which
+// case the next Read returns io.EOF.
+func (s *Stream) EndOfMessage() bool {
+	return s.eom
+}
+
+// Write writes p, in as many writes as it takes.
+func (s *Stream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		rest := p[written:]
+		n, status := s.d.Write(rest, uint32(len(rest)))
+		written += int(n)
+		if status < SUCCESS {
+			return written, s.wrap("Write", status)
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// WriteString writes str.
+func (s *Stream) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// Close closes the driver.
+func (s *Stream) Close() error {
+	return s.wrap("Close", s.d.Close())
+}
+
+// wrap returns status as an error.
+func (s *Stream) wrap(op string, status Status) error {
+	return wrapStatus(s.d, op, status)
+}
+
+// wrapStatus returns status as an error, naming the resource if d is a
+// session.
+func wrapStatus(d Driver, op string, status Status) error {
+	if instr, ok := d.(Object); ok {
+		return instr.Wrap(op, status)
+	}
+	return status.Wrap(op, "")
+}
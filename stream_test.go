@@ -0,0 +1,144 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeDriver is a Driver answering each command written to it, without
+// its terminator, with its entry in resp. Reads hand out at most chunk
+// bytes of the answer, if chunk is set, and end it with END. Reading with
+// nothing to answer times out. Successive writes accept at most the
+// counts in accept, then everything.
+type fakeDriver struct {
+	resp    map[string]string
+	chunk   int
+	accept  []int
+	pending []byte
+	written []string
+	reads   int
+}
+
+func (f *fakeDriver) Close() Status { return SUCCESS }
+
+func (f *fakeDriver) Write(buf []byte, cnt uint32) (uint32, Status) {
+	if len(f.accept) > 0 {
+		if int(cnt) > f.accept[0] {
+			cnt = uint32(f.accept[0])
+		}
+		f.accept = f.accept[1:]
+	}
+	cmd := string(buf[:cnt])
+	f.written = append(f.written, cmd)
+	f.pending = []byte(f.resp[strings.TrimRight(cmd, "\r\n")])
+	return cnt, SUCCESS
+}
+
+func (f *fakeDriver) Read(cnt uint32) ([]byte, uint32, Status) {
+	f.reads++
+	if len(f.pending) == 0 {
+		return nil, 0, ERROR_TMO
+	}
+	n := int(cnt)
+	if f.chunk > 0 && n > f.chunk {
+		n = f.chunk
+	}
+	if n > len(f.pending) {
+		n = len(f.pending)
+	}
+	b := append([]byte(nil), f.pending[:n]...)
+	f.pending = f.pending[n:]
+	if len(f.pending) > 0 {
+		return b, uint32(n), SUCCESS_MAX_CNT
+	}
+	return b, uint32(n), SUCCESS
+}
+
+func TestStreamRead(t *testing.T) {
+	d := &fakeDriver{pending: []byte("hello\n"), chunk: 4}
+	s := NewStream(d)
+	buf := make([]byte, 10)
+	for _, want := range []string{"hell", "o\n"} {
+		if n, err := s.Read(buf); err != nil || string(buf[:n]) != want {
+			t.Errorf("Read = %q, %v, want %q", buf[:n], err, want)
+		}
+	}
+	if !s.EndOfMessage() {
+		t.Error("EndOfMessage = false after the read that ended the message")
+	}
+	if n, err := s.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read at the end of the message = %d, %v, want io.EOF", n, err)
+	}
+	if s.EndOfMessage() {
+		t.Error("EndOfMessage = true after io.EOF")
+	}
+
+	// The next read starts the next message.
+	d.pending = []byte("next")
+	if b, err := io.ReadAll(s); err != nil || string(b) != "next" {
+		t.Errorf("ReadAll = %q, %v, want the next message", b, err)
+	}
+	if n, err := s.Read(nil); n != 0 || err != nil {
+		t.Errorf("Read(nil) = %d, %v", n, err)
+	}
+	if _, err := s.Read(buf); !errors.Is(err, Status(ERROR_TMO)) {
+		t.Errorf("Read with nothing to read: %v, want ERROR_TMO", err)
+	}
+}
+
+func TestStreamReadMessage(t *testing.T) {
+	long := strings.Repeat("0123456789", 1000)
+	d := &fakeDriver{pending: []byte(long), chunk: 1000}
+	s := NewStream(d)
+	if msg, err := s.ReadMessage(); err != nil || string(msg) != long || d.reads != 10 {
+		t.Errorf("ReadMessage = %d bytes in %d reads, %v", len(msg), d.reads, err)
+	}
+	if s.EndOfMessage() {
+		t.Error("EndOfMessage = true after ReadMessage")
+	}
+
+	// After a read that ended a message it reads the next one.
+	d.pending, d.chunk = []byte("a"), 0
+	s.Read(make([]byte, 4))
+	d.pending = []byte("b")
+	if msg, err := s.ReadMessage(); err != nil || string(msg) != "b" {
+		t.Errorf("ReadMessage after the end of a message = %q, %v, want \"b\"", msg, err)
+	}
+
+	// A failed read returns the data received before.
+	d.pending, d.chunk = []byte("part"), 2
+	d.reads = 0
+	msg, err := s.ReadMessage()
+	if string(msg) != "part" || err != nil {
+		t.Fatalf("ReadMessage = %q, %v", msg, err)
+	}
+	if msg, err := s.ReadMessage(); len(msg) != 0 || !errors.Is(err, Status(ERROR_TMO)) {
+		t.Errorf("ReadMessage with nothing to read = %q, %v, want ERROR_TMO", msg, err)
+	}
+}
+
+func TestStreamWrite(t *testing.T) {
+	d := &fakeDriver{accept: []int{2, 1}}
+	s := NewStream(d)
+	if n, err := s.WriteString("hello"); n != 5 || err != nil {
+		t.Errorf("Write = %d, %v, want 5", n, err)
+	}
+	if strings.Join(d.written, "|") != "he|l|lo" {
+		t.Errorf("the device received %q", d.written)
+	}
+
+	// A write that accepts nothing ends the Write.
+	d.accept, d.written = []int{3, 0}, nil
+	if n, err := s.Write([]byte("hello")); n != 3 || err != io.ErrShortWrite {
+		t.Errorf("Write = %d, %v, want 3, io.ErrShortWrite", n, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
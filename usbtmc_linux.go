@@ -0,0 +1,356 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+func init() {
+	registerTransport("USB::INSTR", openUSBTMC)
+	registerFinder(func() []foundRsrc {
+		var found []foundRsrc
+		for _, d := range usbtmcDevices() {
+			found = append(found, foundRsrc{d.name(), map[uint32]interface{}{
+				ATTR_MANF_ID:        int64(d.vid),
+				ATTR_MODEL_CODE:     int64(d.pid),
+				ATTR_MANF_NAME:      d.manf,
+				ATTR_MODEL_NAME:     d.model,
+				ATTR_USB_SERIAL_NUM: d.serial,
+				ATTR_USB_INTFC_NUM:  int64(d.intf),
+				ATTR_USB_PROTOCOL:   int64(d.protocol),
+			}})
+		}
+		return found
+	})
+}
+
+// sysfsRoot is where sysfs is mounted, usbtmcDev holds the usbtmc driver's
+// device nodes.
+var (
+	sysfsRoot = "/sys"
+	usbtmcDev = "/dev"
+)
+
+// usbtmc ioctl requests, see linux/usb/tmc.h.
+const (
+	usbtmcIocNr = 91
+
+	iocNone  = 0
+	iocWrite = 1
+	iocRead  = 2
+
+	usbtmcIoctlClear          = iocNone<<30 | usbtmcIocNr<<8 | 2
+	usbtmcIoctlSetTimeout     = iocWrite<<30 | 4<<16 | usbtmcIocNr<<8 | 10
+	usbtmcIoctlEOMEnable      = iocWrite<<30 | 1<<16 | usbtmcIocNr<<8 | 11
+	usbtmcIoctlConfigTermChar = iocWrite<<30 | 2<<16 | usbtmcIocNr<<8 | 12
+	usbtmcIoctlCancelIO       = iocNone<<30 | usbtmcIocNr<<8 | 35
+	usb488IoctlGetCaps        = iocRead<<30 | 1<<16 | usbtmcIocNr<<8 | 17
+	usb488IoctlReadSTB        = iocRead<<30 | 1<<16 | usbtmcIocNr<<8 | 18
+	usb488IoctlRENControl     = iocWrite<<30 | 1<<16 | usbtmcIocNr<<8 | 19
+	usb488IoctlGotoLocal      = iocNone<<30 | usbtmcIocNr<<8 | 20
+	usb488IoctlLocalLockout   = iocNone<<30 | usbtmcIocNr<<8 | 21
+	usb488IoctlTrigger        = iocNone<<30 | usbtmcIocNr<<8 | 22
+
+	// usb488CapIEEE is set in the capabilities of IEEE 488.2 interfaces.
+	usb488CapIEEE = 1 << 2
+
+	usbtmcMinTimeout = 100 // ms, the driver's lower bound
+)
+
+// usbtmcDevice describes a device node of the usbtmc driver.
+type usbtmcDevice struct {
+	node     string
+	vid, pid uint16
+	serial   string
+	intf     int
+	protocol int
+	manf     string
+	model    string
+}
+
+// name returns the VISA resource name of d.
+func (d usbtmcDevice) name() string {
+	name := fmt.Sprintf("USB0::0x%04X::0x%04X::%s", d.vid, d.pid, d.serial)
+	if d.intf != 0 {
+		name += "::" + strconv.Itoa(d.intf)
+	}
+	return name + "::INSTR"
+}
+
+// usbtmcDevices lists the usbtmc device nodes sysfs knows about, sorted by
+// node name.
+func usbtmcDevices() []usbtmcDevice {
+	var devs []usbtmcDevice
+	seen := make(map[string]bool)
+	for _, class := range []string{"class/usbmisc", "class/usb"} {
+		dir := filepath.Join(sysfsRoot, class)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range ents {
+			node := e.Name()
+			if !strings.HasPrefix(node, "usbtmc") || seen[node] {
+				continue
+			}
+			// device links to the USB interface, its parent is the device.
+			intfDir, err := filepath.EvalSymlinks(filepath.Join(dir, node, "device"))
+			if err != nil {
+				continue
+			}
+			usbDir := filepath.Dir(intfDir)
+			seen[node] = true
+			devs = append(devs, usbtmcDevice{
+				node:     node,
+				vid:      uint16(sysfsHex(usbDir, "idVendor")),
+				pid:      uint16(sysfsHex(usbDir, "idProduct")),
+				serial:   sysfsString(usbDir, "serial"),
+				intf:     int(sysfsHex(intfDir, "bInterfaceNumber")),
+				protocol: int(sysfsHex(intfDir, "bInterfaceProtocol")),
+				manf:     sysfsString(usbDir, "manufacturer"),
+				model:    sysfsString(usbDir, "product"),
+			})
+		}
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].node < devs[j].node })
+	return devs
+}
+
+func sysfsString(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func sysfsHex(dir, name string) uint64 {
+	v, _ := strconv.ParseUint(sysfsString(dir, name), 16, 32)
+	return v
+}
+
+// usbtmcTransport serves USB INSTR resources through a usbtmc device node.
+type usbtmcTransport struct {
+	fd   int
+	caps uint8
+
+	// The driver settings last applied, guarded by the session's ioMu.
+	applied bool
+	tmo     uint32
+	eom     bool
+	term    byte
+	termEn  bool
+}
+
+// openUSBTMC opens USBn::vid::pid::serial[::intf]::INSTR. Without an
+// interface number the first matching node is used.
+func openUSBTMC(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if len(p.fields) < 3 || len(p.fields) > 4 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	vid, err1 := strconv.ParseUint(p.fields[0], 0, 16)
+	pid, err2 := strconv.ParseUint(p.fields[1], 0, 16)
+	intf := int64(-1)
+	var err3 error
+	if len(p.fields) == 4 {
+		intf, err3 = strconv.ParseInt(p.fields[3], 10, 16)
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+
+	for _, d := range usbtmcDevices() {
+		if uint64(d.vid) != vid || uint64(d.pid) != pid || d.serial != p.fields[2] ||
+			intf >= 0 && int64(d.intf) != intf {
+			continue
+		}
+		fd, err := syscall.Open(filepath.Join(usbtmcDev, d.node), syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			if err == syscall.EBUSY {
+				return nil, ERROR_RSRC_BUSY
+			}
+			return nil, ERROR_RSRC_NFOUND
+		}
+		t := &usbtmcTransport{fd: fd}
+		t.ioctl(usb488IoctlGetCaps, unsafe.Pointer(&t.caps))
+
+		s.a.put(ATTR_MANF_ID, uint64(d.vid))
+		s.a.put(ATTR_MODEL_CODE, uint64(d.pid))
+		s.a.putString(ATTR_MANF_NAME, d.manf)
+		s.a.putString(ATTR_MODEL_NAME, d.model)
+		s.a.putString(ATTR_USB_SERIAL_NUM, d.serial)
+		s.a.put(ATTR_USB_INTFC_NUM, uint64(d.intf))
+		s.a.put(ATTR_USB_PROTOCOL, uint64(d.protocol))
+		s.a.put(ATTR_4882_COMPLIANT, boolState(t.caps&usb488CapIEEE != 0))
+		s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+		return t, SUCCESS
+	}
+	return nil, ERROR_RSRC_NFOUND
+}
+
+func (t *usbtmcTransport) ioctl(req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(t.fd), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// usbtmcStatus maps an errno of the usbtmc driver to a VISA status.
+func usbtmcStatus(err error) Status {
+	switch err {
+	case nil:
+		return SUCCESS
+	case syscall.ETIMEDOUT:
+		return ERROR_TMO
+	case syscall.ENOTTY:
+		return ERROR_NSUP_OPER
+	case syscall.ENODEV, syscall.ESHUTDOWN:
+		return ERROR_CONN_LOST
+	case syscall.ECANCELED:
+		return ERROR_ABORT
+	}
+	return ERROR_IO
+}
+
+// configure applies the session's timeout, END and termination character
+// settings to the driver when they changed. Drivers older than the ioctls
+// (ENOTTY) keep their defaults.
+func (t *usbtmcTransport) configure(s *goSession) Status {
+	tmo := uint32(s.a.num(ATTR_TMO_VALUE))
+	if tmo < usbtmcMinTimeout {
+		tmo = usbtmcMinTimeout
+	}
+	eom := s.sendEnd()
+	term, termEn := s.termChar()
+	if t.applied && tmo == t.tmo && eom == t.eom && term == t.term && termEn == t.termEn {
+		return SUCCESS
+	}
+	eomArg := uint8(boolState(eom))
+	termArg := [2]uint8{term, uint8(boolState(termEn))}
+	for _, c := range []struct {
+		req uintptr
+		arg unsafe.Pointer
+	}{
+		{usbtmcIoctlSetTimeout, unsafe.Pointer(&tmo)},
+		{usbtmcIoctlEOMEnable, unsafe.Pointer(&eomArg)},
+		{usbtmcIoctlConfigTermChar, unsafe.Pointer(&termArg)},
+	} {
+		if err := t.ioctl(c.req, c.arg); err != nil && err != syscall.ENOTTY {
+			return usbtmcStatus(err)
+		}
+	}
+	t.applied, t.tmo, t.eom, t.term, t.termEn = true, tmo, eom, term, termEn
+	return SUCCESS
+}
+
+// read returns what one read of the device node delivers, the driver
+// stops at END, the termination character or the count.
+func (t *usbtmcTransport) read(s *goSession, buf []byte) (int, Status) {
+	if status := t.configure(s); status != SUCCESS {
+		return 0, status
+	}
+	if len(buf) == 0 {
+		return 0, SUCCESS_MAX_CNT
+	}
+	n, err := syscall.Read(t.fd, buf)
+	for err == syscall.EINTR {
+		n, err = syscall.Read(t.fd, buf)
+	}
+	if err != nil {
+		return 0, usbtmcStatus(err)
+	}
+	term, termEn := s.termChar()
+	switch {
+	case n > 0 && termEn && buf[n-1] == term:
+		return n, SUCCESS_TERM_CHAR
+	case n == len(buf):
+		return n, SUCCESS_MAX_CNT
+	}
+	return n, SUCCESS
+}
+
+func (t *usbtmcTransport) write(s *goSession, buf []byte) (int, Status) {
+	if status := t.configure(s); status != SUCCESS {
+		return 0, status
+	}
+	n := 0
+	for n < len(buf) {
+		m, err := syscall.Write(t.fd, buf[n:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return n, usbtmcStatus(err)
+		}
+		n += m
+	}
+	return n, SUCCESS
+}
+
+func (t *usbtmcTransport) close() Status {
+	if err := syscall.Close(t.fd); err != nil {
+		return ERROR_CLOSING_FAILED
+	}
+	return SUCCESS
+}
+
+// abort cancels the transfer in progress, it fails with ERROR_ABORT.
+func (t *usbtmcTransport) abort(s *goSession) {
+	t.ioctl(usbtmcIoctlCancelIO, nil)
+}
+
+func (t *usbtmcTransport) readSTB(s *goSession) (uint16, Status) {
+	var stb uint8
+	if err := t.ioctl(usb488IoctlReadSTB, unsafe.Pointer(&stb)); err != nil {
+		return 0, usbtmcStatus(err)
+	}
+	return uint16(stb), SUCCESS
+}
+
+func (t *usbtmcTransport) clear(s *goSession) Status {
+	return usbtmcStatus(t.ioctl(usbtmcIoctlClear, nil))
+}
+
+func (t *usbtmcTransport) assertTrigger(s *goSession, protocol uint16) Status {
+	if protocol != TRIG_PROT_DEFAULT {
+		return ERROR_INV_PROT
+	}
+	return usbtmcStatus(t.ioctl(usb488IoctlTrigger, nil))
+}
+
+// controlREN maps the GPIB REN modes onto the USB488 REN_CONTROL,
+// GO_TO_LOCAL and LOCAL_LOCKOUT requests.
+func (t *usbtmcTransport) controlREN(s *goSession, mode uint16) Status {
+	ren := func(on uint8) error {
+		return t.ioctl(usb488IoctlRENControl, unsafe.Pointer(&on))
+	}
+	var err error
+	switch mode {
+	case GPIB_REN_DEASSERT:
+		err = ren(0)
+	case GPIB_REN_ASSERT, GPIB_REN_ASSERT_ADDRESS:
+		err = ren(1)
+	case GPIB_REN_DEASSERT_GTL:
+		if err = t.ioctl(usb488IoctlGotoLocal, nil); err == nil {
+			err = ren(0)
+		}
+	case GPIB_REN_ASSERT_LLO, GPIB_REN_ASSERT_ADDRESS_:
+		if err = ren(1); err == nil {
+			err = t.ioctl(usb488IoctlLocalLockout, nil)
+		}
+	case GPIB_REN_ADDRESS_GTL:
+		err = t.ioctl(usb488IoctlGotoLocal, nil)
+	}
+	return usbtmcStatus(err)
+}
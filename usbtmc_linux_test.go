@@ -0,0 +1,160 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// fakeSysfs builds a sysfs tree with a usbtmc node for each device and
+// points sysfsRoot and usbtmcDev at it for the duration of the test. The
+// device nodes are FIFOs, so what's written to one reads back from it and
+// the driver's ioctls fail with ENOTTY.
+func fakeSysfs(t *testing.T, devs ...usbtmcDevice) {
+	t.Helper()
+	root, dev := t.TempDir(), t.TempDir()
+	write := func(dir, name, value string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, d := range devs {
+		usbDir := filepath.Join(root, "devices/usb1", fmt.Sprintf("1-%d", i+1))
+		intfDir := filepath.Join(usbDir, "intf")
+		classDir := filepath.Join(root, "class/usbmisc", d.node)
+		for _, dir := range []string{intfDir, classDir} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write(usbDir, "idVendor", fmt.Sprintf("%04x", d.vid))
+		write(usbDir, "idProduct", fmt.Sprintf("%04x", d.pid))
+		write(usbDir, "serial", d.serial)
+		write(usbDir, "manufacturer", d.manf)
+		write(usbDir, "product", d.model)
+		write(intfDir, "bInterfaceNumber", fmt.Sprintf("%02x", d.intf))
+		write(intfDir, "bInterfaceProtocol", fmt.Sprintf("%02x", d.protocol))
+		if err := os.Symlink(intfDir, filepath.Join(classDir, "device")); err != nil {
+			t.Fatal(err)
+		}
+		if err := syscall.Mkfifo(filepath.Join(dev, d.node), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldRoot, oldDev := sysfsRoot, usbtmcDev
+	sysfsRoot, usbtmcDev = root, dev
+	t.Cleanup(func() { sysfsRoot, usbtmcDev = oldRoot, oldDev })
+}
+
+var testUSBTMCDevices = []usbtmcDevice{
+	{node: "usbtmc1", vid: 0x0957, pid: 0x1796, serial: "MY123", intf: 0, protocol: 1, manf: "Agilent", model: "DSO-X 2024A"},
+	{node: "usbtmc0", vid: 0x1AB1, pid: 0x04CE, serial: "DS1ZA", intf: 2, protocol: 1, manf: "Rigol", model: "DS1054Z"},
+}
+
+func TestUSBTMCDevices(t *testing.T) {
+	fakeSysfs(t, testUSBTMCDevices...)
+
+	devs := usbtmcDevices()
+	if len(devs) != 2 {
+		t.Fatalf("found %d devices, want 2", len(devs))
+	}
+	if devs[0] != testUSBTMCDevices[1] || devs[1] != testUSBTMCDevices[0] {
+		t.Errorf("usbtmcDevices = %+v, want both sorted by node", devs)
+	}
+	want := []string{
+		"USB0::0x1AB1::0x04CE::DS1ZA::2::INSTR",
+		"USB0::0x0957::0x1796::MY123::INSTR",
+	}
+	for i, d := range devs {
+		if d.name() != want[i] {
+			t.Errorf("name of %s = %q, want %q", d.node, d.name(), want[i])
+		}
+	}
+
+	rm := openTestRM(t)
+	_, cnt, desc, status := rm.FindRsrc("USB?*INSTR")
+	if status != SUCCESS || cnt != 2 || desc != want[1] { // sorted by name
+		t.Errorf("FindRsrc = %d, %q, %v", cnt, desc, status)
+	}
+}
+
+func TestUSBTMCOpen(t *testing.T) {
+	fakeSysfs(t, testUSBTMCDevices...)
+	rm := openTestRM(t)
+
+	tests := []struct {
+		name   string
+		status Status
+		model  string
+	}{
+		{"USB0::0x0957::0x1796::MY123::INSTR", SUCCESS, "DSO-X 2024A"},
+		{"usb0::2391::6038::MY123::0::instr", SUCCESS, "DSO-X 2024A"},
+		{"USB0::0x1AB1::0x04CE::DS1ZA::INSTR", SUCCESS, "DS1054Z"},
+		{"USB0::0x1AB1::0x04CE::DS1ZA::2::INSTR", SUCCESS, "DS1054Z"},
+		{"USB0::0x1AB1::0x04CE::DS1ZA::0::INSTR", ERROR_RSRC_NFOUND, ""},
+		{"USB0::0x0957::0x1797::MY123::INSTR", ERROR_RSRC_NFOUND, ""},
+		{"USB0::0x0957::0x1796::my123::INSTR", ERROR_RSRC_NFOUND, ""},
+		{"USB0::0x0957::0x1796::INSTR", ERROR_INV_RSRC_NAME, ""},
+		{"USB0::vid::0x1796::MY123::INSTR", ERROR_INV_RSRC_NAME, ""},
+	}
+	for _, tt := range tests {
+		instr, status := rm.Open(tt.name, NO_LOCK, 0)
+		if status != tt.status {
+			t.Errorf("Open(%q): %v, want %v", tt.name, status, tt.status)
+			continue
+		}
+		if status != SUCCESS {
+			continue
+		}
+		if model, _ := instr.AttrString(ATTR_MODEL_NAME); model != tt.model {
+			t.Errorf("Open(%q): ATTR_MODEL_NAME = %q, want %q", tt.name, model, tt.model)
+		}
+		instr.Close()
+	}
+}
+
+func TestUSBTMCReadWrite(t *testing.T) {
+	fakeSysfs(t, testUSBTMCDevices...)
+	rm := openTestRM(t)
+	instr, status := rm.Open("USB0::0x0957::0x1796::MY123::INSTR", NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open: %v", status)
+	}
+	defer instr.Close()
+
+	if mid, err := instr.AttrUint16(ATTR_MANF_ID); err != nil || mid != 0x0957 {
+		t.Errorf("ATTR_MANF_ID = %#x, %v", mid, err)
+	}
+	if n, status := instr.Write([]byte("*IDN?\n"), 6); status != SUCCESS || n != 6 {
+		t.Fatalf("Write = %d, %v", n, status)
+	}
+	buf, n, status := instr.Read(64)
+	if status != SUCCESS || string(buf[:n]) != "*IDN?\n" {
+		t.Errorf("Read = %q, %v", buf[:n], status)
+	}
+
+	instr.Write([]byte("ab\ncd"), 5)
+	instr.SetAttrBool(ATTR_TERMCHAR_EN, true)
+	buf, n, status = instr.Read(3)
+	if status != SUCCESS_TERM_CHAR || string(buf[:n]) != "ab\n" {
+		t.Errorf("Read up to the termination character = %q, %v", buf[:n], status)
+	}
+	buf, n, status = instr.Read(1)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "c" {
+		t.Errorf("Read(1) = %q, %v, want \"c\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+	buf, n, status = instr.Read(8)
+	if status != SUCCESS || string(buf[:n]) != "d" {
+		t.Errorf("Read of the rest = %q, %v", buf[:n], status)
+	}
+
+	if _, status := instr.ReadSTB(); status != ERROR_NSUP_OPER {
+		t.Errorf("ReadSTB without the driver: %v, want ERROR_NSUP_OPER", status)
+	}
+}
@@ -14,6 +14,12 @@
 // exported C functions it wraps. Clients would typically build an instrument
 // specific driver around the package but it can also be used directly.
 //
+// The operations are serviced by a Backend. Built with cgo the package links
+// against the VISA library, built with the novisa tag or with cgo disabled it
+// uses a pure-Go backend that needs no library at all:
+//     go build -tags novisa
+//     CGO_ENABLED=0 go build
+//
 // NI-VISA Drivers:
 //     http://www.ni.com/downloads/ni-drivers/
 //
@@ -31,32 +37,11 @@
 
 package visa
 
-/*
-#cgo linux LDFLAGS: -lvisa
-#cgo darwin LDFLAGS: -framework VISA
-#cgo windows LDFLAGS: -lvisa64 -L.
-#cgo windows CFLAGS: -IC:/Program\ Files/IVI\ Foundation/VISA/Win64/Include
-#cgo CFLAGS: -I.
-
-#include <stdlib.h>
-#include "visa.h"
-
-extern void go_cb(ViSession, ViEventType, ViEvent, ViAddr);
-ViHndlr get_go_cb(void) {
-	return (ViHndlr)go_cb;
-}
-
-ViStatus vi_printf(ViSession vi, ViString string) {
-	return viPrintf(vi, string);
-}
-
-ViStatus vi_sprintf(ViSession vi, ViPBuf buf, ViString string) {
-	return viSPrintf(vi, buf, string);
-}
-*/
-import "C"
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"unsafe"
 )
 
@@ -73,15 +58,15 @@ type Session uint32
 type Object uint32
 
 // Platform specific types, 32 or 64 bit, as determined at compile time.
-type BusAddress C.ViBusAddress
-type PBusAddress C.ViPBusAddress
-type BusSize C.ViBusSize
-type AttrState C.ViAttrState
-type Bool C.ViBoolean
-
-//
+type BusAddress uintptr
+type PBusAddress *BusAddress
+type BusSize uintptr
+type AttrState uintptr
+type Bool uint16
+
+// UserCallback is an event handler. The event context is only valid
+// until the handler returns.
 type UserCallback func(instr Object, etype, eventContext uint32)
-type PUserCallback *UserCallback
 
 // ----------------------------------------------------------------------------
 // Resource Manager Functions and Operations
@@ -89,8 +74,7 @@ type PUserCallback *UserCallback
 
 // OpenDefaultRM returns a session to the Default Resource Manager resource.
 func OpenDefaultRM() (rm Session, status Status) {
-	status = Status(C.viOpenDefaultRM((*C.ViSession)(unsafe.Pointer(&rm))))
-	return rm, status
+	return backend.OpenDefaultRM()
 }
 
 // legacy
@@ -101,68 +85,82 @@ var GetDefaultRM = OpenDefaultRM
 func (rm Session) FindRsrc(expr string) (findList, retCnt uint32, desc string,
 	status Status) {
 
-	cexpr := (*C.ViChar)(C.CString(expr))
-	defer C.free(unsafe.Pointer(cexpr))
-	d := make([]byte, 257)
-	status = Status(C.viFindRsrc(C.ViSession(rm),
-		cexpr,
-		(*C.ViFindList)(unsafe.Pointer(&findList)),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt)),
-		(*C.ViChar)(unsafe.Pointer(&d[0]))))
-	return findList, retCnt, string(d), status
+	return backend.FindRsrc(rm, expr)
 }
 
 // FindNext gets the next resource from the list of resources found during a
 // previous call to FindRsrc.
 func FindNext(findList uint32) (string, Status) {
-	d := make([]byte, 257)
-	status := Status(C.viFindNext((C.ViFindList)(findList),
-		(*C.ViChar)(unsafe.Pointer(&d[0]))))
-	return string(d), status
+	return backend.FindNext(findList)
+}
+
+// Resources returns the resources matching the search expression, e.g.
+// "?*INSTR" or "USB?*INSTR{VI_ATTR_MANF_ID==0x0957}". No match isn't an
+// error, it returns an empty list. Names ResourceName can't represent,
+// such as those of other interfaces, are reported in a *NamesError
+// returned along with the other names. The find list is always closed.
+func (rm Session) Resources(expr string) ([]ResourceName, error) {
+	findList, retCnt, desc, status := rm.FindRsrc(expr)
+	if status == ERROR_RSRC_NFOUND {
+		return nil, nil
+	}
+	if status < SUCCESS {
+		return nil, status.Wrap("FindRsrc", expr)
+	}
+	defer Close(findList)
+
+	names := make([]ResourceName, 0, retCnt)
+	var unparsed []string
+	for i := uint32(0); i < retCnt; i++ {
+		if i > 0 {
+			if desc, status = FindNext(findList); status < SUCCESS {
+				return names, status.Wrap("FindNext", expr)
+			}
+		}
+		if name, err := ParseResourceName(desc); err == nil {
+			names = append(names, name)
+		} else {
+			unparsed = append(unparsed, desc)
+		}
+	}
+	if unparsed != nil {
+		return names, &NamesError{Names: unparsed}
+	}
+	return names, nil
+}
+
+// NamesError lists the resources Resources found whose names
+// ResourceName can't represent. It wraps ErrInvRsrcName.
+type NamesError struct {
+	Names []string
+}
+
+func (e *NamesError) Error() string {
+	return "visa: unsupported resource names: " + strings.Join(e.Names, ", ")
+}
+
+// Unwrap returns ErrInvRsrcName.
+func (e *NamesError) Unwrap() error {
+	return ErrInvRsrcName
 }
 
 // ParseRsrc parses a resource string to get the interface information.
 func (rm Session) ParseRsrc(rsrcName string) (intfType, intfNum uint16, status Status) {
-	crsrcName := (*C.ViChar)(C.CString(rsrcName))
-	defer C.free(unsafe.Pointer(crsrcName))
-	status = Status(C.viParseRsrc(C.ViSession(rm),
-		crsrcName,
-		(*C.ViUInt16)(unsafe.Pointer(&intfType)),
-		(*C.ViUInt16)(unsafe.Pointer(&intfNum))))
-	return intfType, intfNum, status
+	return backend.ParseRsrc(rm, rsrcName)
 }
 
 // ParseRsrcEx parses a resource string to get extended interface information.
 func (rm Session) ParseRsrcEx(rsrcName string) (intfType, intfNum uint16, rsrcClass,
 	expandedUnaliasedName, aliasIfExists string, status Status) {
 
-	crsrcName := (*C.ViChar)(C.CString(rsrcName))
-	defer C.free(unsafe.Pointer(crsrcName))
-	r := make([]byte, 257)
-	e := make([]byte, 257)
-	a := make([]byte, 257)
-	status = Status(C.viParseRsrcEx(C.ViSession(rm),
-		crsrcName,
-		(*C.ViUInt16)(unsafe.Pointer(&intfType)),
-		(*C.ViUInt16)(unsafe.Pointer(&intfNum)),
-		(*C.ViChar)(unsafe.Pointer(&r[0])),
-		(*C.ViChar)(unsafe.Pointer(&e[0])),
-		(*C.ViChar)(unsafe.Pointer(&a[0]))))
-	return intfType, intfNum, string(r), string(e), string(a), status
+	return backend.ParseRsrcEx(rm, rsrcName)
 }
 
 // Open opens a session to the specified resource.
 func (rm Session) Open(name string, mode, timeout uint32) (instr Object,
 	status Status) {
 
-	cname := (*C.ViChar)(C.CString(name))
-	defer C.free(unsafe.Pointer(cname))
-	status = Status(C.viOpen(C.ViSession(rm),
-		cname,
-		(C.ViAccessMode)(mode),
-		(C.ViUInt32)(timeout),
-		(*C.ViSession)(unsafe.Pointer(&instr))))
-	return instr, status
+	return backend.Open(rm, name, mode, timeout)
 }
 
 // ----------------------------------------------------------------------------
@@ -171,101 +169,85 @@ func (rm Session) Open(name string, mode, timeout uint32) (instr Object,
 
 // Close closes the specified session.
 func (rm Session) Close() Status {
-	return Status(C.viClose((C.ViObject)(rm)))
+	closePools(rm)
+	status := backend.Close(uint32(rm))
+	if status >= SUCCESS {
+		dropHandlers(Object(rm))
+		dropJobs(Object(rm))
+	}
+	return status
 }
 
 // Close closes the specified instrument, or find list.
 func (instr Object) Close() Status {
-	return Status(C.viClose((C.ViObject)(instr)))
+	status := backend.Close(uint32(instr))
+	if status >= SUCCESS {
+		dropHandlers(instr)
+		dropJobs(instr)
+	}
+	return status
 }
 
 // Close closes the specified find list.
 func Close(list uint32) Status {
-	return Status(C.viClose((C.ViObject)(list)))
+	return backend.Close(list)
 }
 
 // SetAttribute sets the state of an attribute.
 func (instr Object) SetAttribute(attribute, attrState uint32) Status {
-	return Status(C.viSetAttribute((C.ViObject)(instr),
-		(C.ViAttr)(attribute),
-		(C.ViAttrState)(attrState)))
+	return backend.SetAttribute(uint32(instr), attribute, uint64(attrState))
 }
 
 // GetAttribute retrieves the state of an attribute.
 func (instr Object) GetAttribute(attrName uint32, addr unsafe.Pointer) Status {
-	return Status(C.viGetAttribute((C.ViObject)(instr), (C.ViAttr)(attrName), addr))
+	return backend.GetAttribute(uint32(instr), attrName, addr)
 }
 
 // StatusDesc returns a user-readable description of the
 // status code passed to the operation.
 func (instr Object) StatusDesc(status_in Status) (string, Status) {
-	d := make([]byte, 257)
-	status := Status(C.viStatusDesc((C.ViObject)(instr),
-		(C.ViStatus)(status_in),
-		(*C.ViChar)(unsafe.Pointer(&d[0]))))
-	return string(d), status
+	return backend.StatusDesc(uint32(instr), status_in)
 }
 
 // Terminate requests a VISA session to terminate normal
 // execution of an operation.
 func (instr Object) Terminate(degree, jobId uint16) Status {
-	return Status(C.viTerminate((C.ViObject)(instr),
-		(C.ViUInt16)(degree),
-		(C.ViJobId)(jobId)))
+	return backend.Terminate(uint32(instr), degree, jobId)
 }
 
 // Lock establishes an access mode to the specified resource.
 func (instr Object) LockExclusive(lockType, timeout uint32) Status {
-	return Status(C.viLock((C.ViSession)(instr),
-		(C.ViAccessMode)(lockType),
-		(C.ViUInt32)(timeout),
-		(*C.ViChar)(nil),
-		(*C.ViChar)(nil)))
+	_, status := backend.Lock(instr, lockType, timeout, "")
+	return status
 }
 
 // Lock establishes an access mode to the specified resource.
 func (instr Object) Lock(lockType, timeout uint32, requestedKey string) (string, Status) {
-
-	rk := (*C.ViChar)(C.CString(requestedKey))
-	defer C.free(unsafe.Pointer(rk))
-	a := make([]byte, 257)
-	status := Status(C.viLock((C.ViSession)(instr),
-		(C.ViAccessMode)(lockType),
-		(C.ViUInt32)(timeout),
-		rk,
-		(*C.ViChar)(unsafe.Pointer(&a[0]))))
-	return string(a), status
+	return backend.Lock(instr, lockType, timeout, requestedKey)
 }
 
 // Unlock relinquishes a lock for the specified resource.
 func (instr Object) Unlock() Status {
-	return Status(C.viUnlock((C.ViSession)(instr)))
+	return backend.Unlock(instr)
 }
 
 // EnableEvent enables notification of a specified event.
 func (instr Object) EnableEvent(eventType uint32, mechanism uint16,
 	context uint32) Status {
 
-	return Status(C.viEnableEvent((C.ViSession)(instr),
-		(C.ViEventType)(eventType),
-		(C.ViUInt16)(mechanism),
-		(C.ViEventFilter)(context)))
+	return backend.EnableEvent(instr, eventType, mechanism, context)
 }
 
 // DisableEvent disables notification of the specified event type(s)
 // via the specified mechanism(s).
 func (instr Object) DisableEvent(eventType uint32, mechanism uint16) Status {
-	return Status(C.viDisableEvent((C.ViSession)(instr),
-		(C.ViEventType)(eventType),
-		(C.ViUInt16)(mechanism)))
+	return backend.DisableEvent(instr, eventType, mechanism)
 }
 
 // DiscardEvents discards event occurrences for specified event types
 // and mechanisms in a session.
 func (instr Object) DiscardEvents(eventType uint32, mechanism uint16) Status {
-	return Status(C.viDiscardEvents((C.ViSession)(instr),
-		(C.ViEventType)(eventType),
-		(C.ViUInt16)(mechanism)))
+	return backend.DiscardEvents(instr, eventType, mechanism)
 }
 
 // WaitOnEvent waits for an occurrence of the specified
@@ -273,29 +255,23 @@ func (instr Object) DiscardEvents(eventType uint32, mechanism uint16) Status {
 func (instr Object) WaitOnEvent(inEventType, timeout uint32) (outEventType,
 	outContext uint32, status Status) {
 
-	status = Status(C.viWaitOnEvent((C.ViSession)(instr),
-		(C.ViEventType)(inEventType),
-		(C.ViUInt32)(timeout),
-		(*C.ViEventType)(unsafe.Pointer(&outEventType)),
-		(*C.ViEvent)(unsafe.Pointer(&outContext))))
-	return outEventType, outContext, status
+	return backend.WaitOnEvent(instr, inEventType, timeout)
 }
 
-// InstallHandler installs handlers for event callbacks.
-func (instr Object) InstallHandler(eventType uint32, userHandle UserCallback) Status {
-	return Status(C.viInstallHandler((C.ViSession)(instr),
-		(C.ViEventType)(eventType),
-		(C.ViHndlr)(C.get_go_cb()),
-		(C.ViAddr)(unsafe.Pointer(&userHandle))))
+// InstallHandler installs handlers for event callbacks. Several handlers
+// can be installed for an event type, the returned ID identifies this one.
+func (instr Object) InstallHandler(eventType uint32, userHandle UserCallback) (HandlerID, Status) {
+	return instr.installHandler(eventType, userHandle, nil)
 }
 
-// UninstallHandler uninstalls handlers for events.
-// Note that VISA identifies handlers uniquely using the userHandle reference.
-func (instr Object) UninstallHandler(eventType uint32, userHandle UserCallback) Status {
-	return Status(C.viUninstallHandler((C.ViSession)(instr),
-		(C.ViEventType)(eventType),
-		(C.ViHndlr)(C.get_go_cb()),
-		(C.ViAddr)(unsafe.Pointer(&userHandle))))
+// UninstallHandler uninstalls the handler with the ID returned by
+// InstallHandler, or every handler for eventType if id is ANY_HNDLR.
+func (instr Object) UninstallHandler(eventType uint32, id HandlerID) Status {
+	status := backend.UninstallHandler(instr, eventType, id)
+	if status >= SUCCESS {
+		unregisterHandler(instr, eventType, id)
+	}
+	return status
 }
 
 // ----------------------------------------------------------------------------
@@ -305,20 +281,16 @@ func (instr Object) UninstallHandler(eventType uint32, userHandle UserCallback)
 // Read reads data from device or interface synchronously.
 func (instr Object) Read(cnt uint32) (buf []byte, retCnt uint32, status Status) {
 	buf = make([]byte, cnt)
-	status = Status(C.viRead((C.ViSession)(instr),
-		(*C.ViByte)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	retCnt, status = backend.Read(instr, buf)
 	return buf, retCnt, status
 }
 
-// ReadAsync reads data from device or interface asynchronously.
+// ReadAsync reads data from device or interface asynchronously. VISA
+// writes to buf after ReadAsync returns, which cgo doesn't allow for Go
+// memory, use StartRead with the NI-VISA backend.
 func (instr Object) ReadAsync(cnt uint32) (buf []byte, jobId uint32, status Status) {
 	buf = make([]byte, cnt)
-	status = Status(C.viReadAsync((C.ViSession)(instr),
-		(*C.ViByte)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViJobId)(unsafe.Pointer(&jobId))))
+	jobId, status = backend.ReadAsync(instr, buf)
 	return buf, jobId, status
 }
 
@@ -326,62 +298,72 @@ func (instr Object) ReadAsync(cnt uint32) (buf []byte, jobId uint32, status Stat
 func (instr Object) ReadToFile(filename string, cnt uint32) (retCnt uint32,
 	status Status) {
 
-	cfilename := (*C.ViChar)(C.CString(filename))
-	defer C.free(unsafe.Pointer(cfilename))
-	status = Status(C.viReadToFile((C.ViSession)(instr),
-		cfilename,
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	if fb, ok := backend.(FileBackend); ok {
+		return fb.ReadToFile(instr, filename, cnt)
+	}
+	var appendEn Bool
+	instr.GetAttribute(ATTR_FILE_APPEND_EN, unsafe.Pointer(&appendEn))
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendEn == TRUE {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(filename, flag, 0666)
+	if err != nil {
+		return 0, ERROR_FILE_ACCESS
+	}
+	defer f.Close()
+	buf, retCnt, status := instr.Read(cnt)
+	if _, err := f.Write(buf[:retCnt]); err != nil {
+		return retCnt, ERROR_FILE_IO
+	}
 	return retCnt, status
 }
 
 // Write writes data to a device or interface synchronously.
 func (instr Object) Write(buf []byte, cnt uint32) (retCnt uint32, status Status) {
-	status = Status(C.viWrite((C.ViSession)(instr),
-		(C.ViBuf)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
-	return retCnt, status
+	return backend.Write(instr, buf[:cnt])
 }
 
-// WriteAsync writes data to a device or interface asynchronously.
+// WriteAsync writes data to a device or interface asynchronously. VISA
+// reads buf after WriteAsync returns, which cgo doesn't allow for Go
+// memory, use StartWrite with the NI-VISA backend.
 func (instr Object) WriteAsync(buf []byte, cnt uint32) (jobId uint32, status Status) {
-	status = Status(C.viWriteAsync((C.ViSession)(instr),
-		(C.ViBuf)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViJobId)(unsafe.Pointer(&jobId))))
-	return jobId, status
+	return backend.WriteAsync(instr, buf[:cnt])
 }
 
 // WriteFromFile take data from a file and write it out synchronously.
 func (instr Object) WriteFromFile(filename string, cnt uint32) (retCnt uint32,
 	status Status) {
 
-	cfilename := (*C.ViChar)(C.CString(filename))
-	defer C.free(unsafe.Pointer(cfilename))
-	status = Status(C.viWriteFromFile((C.ViSession)(instr),
-		cfilename,
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
-	return retCnt, status
+	if fb, ok := backend.(FileBackend); ok {
+		return fb.WriteFromFile(instr, filename, cnt)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, ERROR_FILE_ACCESS
+	}
+	defer f.Close()
+	buf := make([]byte, cnt)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, ERROR_FILE_IO
+	}
+	return instr.Write(buf, uint32(n))
 }
 
 // AssertTrigger asserts software or hardware trigger.
 func (instr Object) AssertTrigger(protocol uint16) Status {
-	return Status(C.viAssertTrigger((C.ViSession)(instr),
-		(C.ViUInt16)(protocol)))
+	return backend.AssertTrigger(instr, protocol)
 }
 
 // ReadSTB reads a status byte of the service request.
 func (instr Object) ReadSTB() (stb_stat uint16, status Status) {
-	status = Status(C.viReadSTB((C.ViSession)(instr),
-		(*C.ViUInt16)(unsafe.Pointer(&stb_stat))))
-	return stb_stat, status
+	return backend.ReadSTB(instr)
 }
 
 // Clear clears a device.
 func (instr Object) Clear() Status {
-	return Status(C.viClear((C.ViSession)(instr)))
+	return backend.Clear(instr)
 }
 
 // ----------------------------------------------------------------------------
@@ -391,35 +373,38 @@ func (instr Object) Clear() Status {
 // ViSetBuf sets the size for the formatted I/O and/or low-level
 // I/O communication buffer(s).
 func (instr Object) SetBuf(mask uint16, size uint32) Status {
-	return Status(C.viSetBuf((C.ViSession)(instr),
-		(C.ViUInt16)(mask),
-		(C.ViUInt32)(size)))
+	if bb, ok := backend.(BufferedBackend); ok {
+		return bb.SetBuf(instr, mask, size)
+	}
+	return ERROR_NSUP_OPER
 }
 
 // Flush manually flushes the specified buffers associated with
 // formatted I/O operations and/or serial communication.
 func (instr Object) Flush(mask uint16) Status {
-	return Status(C.viFlush((C.ViSession)(instr),
-		(C.ViUInt16)(mask)))
+	if bb, ok := backend.(BufferedBackend); ok {
+		return bb.Flush(instr, mask)
+	}
+	return ERROR_NSUP_OPER
 }
 
 // BufWrite writes data to a formatted I/O write buffer synchronously.
 func (instr Object) BufWrite(buf []byte, cnt uint32) (retCnt uint32, status Status) {
-	status = Status(C.viBufWrite((C.ViSession)(instr),
-		(C.ViBuf)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
-	return retCnt, status
+	if bb, ok := backend.(BufferedBackend); ok {
+		return bb.BufWrite(instr, buf[:cnt])
+	}
+	return instr.Write(buf, cnt)
 }
 
 // BufRead reads data from a device or interface through the
 // use of a formatted I/O read buffer.
 func (instr Object) BufRead(cnt uint32) (buf []byte, retCnt uint32, status Status) {
+	bb, ok := backend.(BufferedBackend)
+	if !ok {
+		return instr.Read(cnt)
+	}
 	buf = make([]byte, cnt)
-	status = Status(C.viBufRead((C.ViSession)(instr),
-		(C.ViBuf)(unsafe.Pointer(&buf[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(unsafe.Pointer(&retCnt))))
+	retCnt, status = bb.BufRead(instr, buf)
 	return buf, retCnt, status
 }
 
@@ -429,18 +414,21 @@ func (instr Object) BufRead(cnt uint32) (buf []byte, retCnt uint32, status Statu
 // Printf converts, formats, and sends the parameters (designated by args)
 // to the device as specified by the format string.
 func (instr Object) Printf(writeFmt string, args ...interface{}) Status {
-	cstr := (*C.ViChar)(C.CString(fmt.Sprintf(writeFmt, args...)))
-	defer C.free(unsafe.Pointer(cstr))
-	return Status(C.vi_printf((C.ViSession)(instr), cstr))
+	s := fmt.Sprintf(writeFmt, args...)
+	if bb, ok := backend.(BufferedBackend); ok {
+		return bb.Printf(instr, s)
+	}
+	_, status := instr.Write([]byte(s), uint32(len(s)))
+	return status
 }
 
 // SPrintf converts, formats, and sends the parameters (designated by args)
 // to a user-specified buffer as specified by the format string.
 func (instr Object) SPrintf(buf *uint8, writeFmt string, args ...interface{}) Status {
-	cstr := (*C.ViChar)(C.CString(fmt.Sprintf(writeFmt, args)))
-	defer C.free(unsafe.Pointer(cstr))
-	return Status(C.vi_sprintf((C.ViSession)(instr),
-		(*C.ViByte)(unsafe.Pointer(buf)), cstr))
+	if bb, ok := backend.(BufferedBackend); ok {
+		return bb.SPrintf(instr, buf, fmt.Sprintf(writeFmt, args...))
+	}
+	return ERROR_NSUP_OPER
 }
 
 // Scanf reads, converts, and formats data using the format specifier.
@@ -475,55 +463,66 @@ func (instr Object) SPrintf(buf *uint8, writeFmt string, args ...interface{}) St
 // Memory I/O Operations
 //
 
+// memory returns the backend's memory operations, if it has them.
+func memory() (MemoryBackend, Status) {
+	if mb, ok := backend.(MemoryBackend); ok {
+		return mb, SUCCESS
+	}
+	return nil, ERROR_NSUP_OPER
+}
+
 // In8 reads in an 8-bit value from the specified memory space and offset.
 func (instr Object) In8(space uint16, offset BusAddress) (val uint8, status Status) {
-	status = Status(C.viIn8((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(*C.ViUInt8)(&val)))
-	return val, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return mb.In8(instr, space, offset)
 }
 
 // Out8 writes an 8-bit value to the specified memory space and offset.
 func (instr Object) Out8(space uint16, offset BusAddress, val uint8) Status {
-	return Status(C.viOut8((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViUInt8)(val)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.Out8(instr, space, offset, val)
 }
 
 // In16 reads in an 16-bit value from the specified memory space and offset.
 func (instr Object) In16(space uint16, offset BusAddress) (val uint16, status Status) {
-	status = Status(C.viIn16((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(*C.ViUInt16)(&val)))
-	return val, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return mb.In16(instr, space, offset)
 }
 
 // Out16 writes an 16-bit value to the specified memory space and offset.
 func (instr Object) Out16(space uint16, offset BusAddress, val uint16) Status {
-	return Status(C.viOut16((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViUInt16)(val)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.Out16(instr, space, offset, val)
 }
 
 // In32 reads in an 32-bit value from the specified memory space and offset.
 func (instr Object) In32(space uint16, offset BusAddress) (val uint32, status Status) {
-	status = Status(C.viIn32((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(*C.ViUInt32)(&val)))
-	return val, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return mb.In32(instr, space, offset)
 }
 
 // Out32 writes an 32-bit value to the specified memory space and offset.
 func (instr Object) Out32(space uint16, offset BusAddress, val uint32) Status {
-	return Status(C.viOut32((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViUInt32)(val)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.Out32(instr, space, offset, val)
 }
 
 // MoveIn8 moves a block of data from the specified address
@@ -531,24 +530,23 @@ func (instr Object) Out32(space uint16, offset BusAddress, val uint32) Status {
 func (instr Object) MoveIn8(space uint16, offset BusAddress,
 	length BusSize) ([]uint8, Status) {
 
+	mb, status := memory()
+	if status != SUCCESS {
+		return nil, status
+	}
 	buf := make([]uint8, length)
-	status := Status(C.viMoveIn8((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt8)(unsafe.Pointer(&buf[0]))))
-	return buf, status
+	return buf, mb.MoveIn8(instr, space, offset, buf)
 }
 
 // MoveOut8 moves a block of data from local memory to the specified
 func (instr Object) MoveOut8(space uint16, offset BusAddress, length BusSize,
 	buf []uint8) Status {
 
-	return Status(C.viMoveOut8((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt8)(unsafe.Pointer(&buf[0]))))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.MoveOut8(instr, space, offset, buf[:length])
 }
 
 // MoveIn16 moves a block of data from the specified
@@ -556,13 +554,12 @@ func (instr Object) MoveOut8(space uint16, offset BusAddress, length BusSize,
 func (instr Object) MoveIn16(space uint16, offset BusAddress,
 	length BusSize) ([]uint16, Status) {
 
+	mb, status := memory()
+	if status != SUCCESS {
+		return nil, status
+	}
 	buf := make([]uint16, length)
-	status := Status(C.viMoveIn16((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt16)(unsafe.Pointer(&buf[0]))))
-	return buf, status
+	return buf, mb.MoveIn16(instr, space, offset, buf)
 }
 
 // MoveOut16 moves a block of data from local memory to
@@ -570,11 +567,11 @@ func (instr Object) MoveIn16(space uint16, offset BusAddress,
 func (instr Object) MoveOut16(space uint16, offset BusAddress, length BusSize,
 	buf []uint16) Status {
 
-	return Status(C.viMoveOut16((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt16)(unsafe.Pointer(&buf[0]))))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.MoveOut16(instr, space, offset, buf[:length])
 }
 
 // MoveIn32 moves a block of data from the specified address
@@ -582,13 +579,12 @@ func (instr Object) MoveOut16(space uint16, offset BusAddress, length BusSize,
 func (instr Object) MoveIn32(space uint16, offset BusAddress,
 	length BusSize) ([]uint32, Status) {
 
+	mb, status := memory()
+	if status != SUCCESS {
+		return nil, status
+	}
 	buf := make([]uint32, length)
-	status := Status(C.viMoveIn32((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt32)(unsafe.Pointer(&buf[0]))))
-	return buf, status
+	return buf, mb.MoveIn32(instr, space, offset, buf)
 }
 
 // MoveOut32 moves a block of data from local memory to
@@ -596,11 +592,11 @@ func (instr Object) MoveIn32(space uint16, offset BusAddress,
 func (instr Object) MoveOut32(space uint16, offset BusAddress, length BusSize,
 	buf []uint32) Status {
 
-	return Status(C.viMoveOut32((C.ViSession)(instr),
-		(C.ViUInt16)(space),
-		(C.ViBusAddress)(offset),
-		(C.ViBusSize)(length),
-		(C.ViAUInt32)(unsafe.Pointer(&buf[0]))))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.MoveOut32(instr, space, offset, buf[:length])
 }
 
 // Move moves a block of data.
@@ -608,83 +604,91 @@ func (instr Object) Move(srcSpace uint16, srcOffset BusAddress, srcWidth uint16,
 	destSpace uint16, destOffset BusAddress, destWidth uint16,
 	srcLength BusSize) Status {
 
-	return Status(C.viMove((C.ViSession)(instr),
-		(C.ViUInt16)(srcSpace),
-		(C.ViBusAddress)(srcOffset),
-		(C.ViUInt16)(srcWidth),
-		(C.ViUInt16)(destSpace),
-		(C.ViBusAddress)(destOffset),
-		(C.ViUInt16)(destWidth),
-		(C.ViBusSize)(srcLength)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.Move(instr, srcSpace, srcOffset, srcWidth, destSpace, destOffset,
+		destWidth, srcLength)
 }
 
-// MoveAsync moves a block of data asynchronously.
+// MoveAsync moves a block of data asynchronously. StartMoveIn and
+// StartMoveOut move to and from memory the job owns.
 func (instr Object) MoveAsync(srcSpace uint16, srcOffset BusAddress, srcWidth,
 	destSpace uint16, destOffset BusAddress, destWidth uint16,
 	srcLength BusSize) (jobId uint32, status Status) {
 
-	status = Status(C.viMoveAsync((C.ViSession)(instr),
-		(C.ViUInt16)(srcSpace),
-		(C.ViBusAddress)(srcOffset),
-		(C.ViUInt16)(srcWidth),
-		(C.ViUInt16)(destSpace),
-		(C.ViBusAddress)(destOffset),
-		(C.ViUInt16)(destWidth),
-		(C.ViBusSize)(srcLength),
-		(*C.ViJobId)(unsafe.Pointer(&jobId))))
-	return jobId, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return mb.MoveAsync(instr, srcSpace, srcOffset, srcWidth, destSpace,
+		destOffset, destWidth, srcLength)
 }
 
 // MapAddress maps the specified memory space into the process’s address space.
 func (instr Object) MapAddress(mapSpace uint16, mapOffset BusAddress, mapSize BusSize,
 	access uint16, suggested *byte) (address *byte, status Status) {
 
-	status = Status(C.viMapAddress((C.ViSession)(instr),
-		(C.ViUInt16)(mapSpace),
-		(C.ViBusAddress)(mapOffset),
-		(C.ViBusSize)(mapSize),
-		(C.ViBoolean)(access),
-		(C.ViAddr)(unsafe.Pointer(suggested)),
-		(*C.ViAddr)(unsafe.Pointer(&address))))
-	return address, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return nil, status
+	}
+	return mb.MapAddress(instr, mapSpace, mapOffset, mapSize, access, suggested)
 }
 
 // UnmapAddress unmaps memory space previously mapped by ViMapAddress.
 func (instr Object) UnmapAddress() Status {
-	return Status(C.viUnmapAddress((C.ViSession)(instr)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.UnmapAddress(instr)
 }
 
 // Peek8 reads an 8-bit value from the specified address.
 func (instr Object) Peek8(address unsafe.Pointer) (val uint8) {
-	C.viPeek8((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt8)(&val))
+	if mb, status := memory(); status == SUCCESS {
+		val = mb.Peek8(instr, address)
+	}
 	return val
 }
 
 // Poke8 writes an 8-bit value to the specified address.
 func (instr Object) Poke8(address unsafe.Pointer, val uint8) {
-	C.viPoke8((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt8)(val))
+	if mb, status := memory(); status == SUCCESS {
+		mb.Poke8(instr, address, val)
+	}
 }
 
 // Peek16 reads an 16-bit value from the specified address.
 func (instr Object) Peek16(address unsafe.Pointer) (val uint16) {
-	C.viPeek16((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt16)(&val))
+	if mb, status := memory(); status == SUCCESS {
+		val = mb.Peek16(instr, address)
+	}
 	return val
 }
 
 // Poke16 writes an 16-bit value to the specified address.
 func (instr Object) Poke16(address unsafe.Pointer, val uint16) {
-	C.viPoke16((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt16)(val))
+	if mb, status := memory(); status == SUCCESS {
+		mb.Poke16(instr, address, val)
+	}
 }
 
 // Peek32 reads an 32-bit value from the specified address.
 func (instr Object) Peek32(address unsafe.Pointer) (val uint32) {
-	C.viPeek32((C.ViSession)(instr), (C.ViAddr)(address), (*C.ViUInt32)(&val))
+	if mb, status := memory(); status == SUCCESS {
+		val = mb.Peek32(instr, address)
+	}
 	return val
 }
 
 // Poke32 writes an 32-bit value to the specified address.
 func (instr Object) Poke32(address unsafe.Pointer, val uint32) {
-	C.viPoke32((C.ViSession)(instr), (C.ViAddr)(address), (C.ViUInt32)(val))
+	if mb, status := memory(); status == SUCCESS {
+		mb.Poke32(instr, address, val)
+	}
 }
 
 // ----------------------------------------------------------------------------
@@ -693,130 +697,174 @@ func (instr Object) Poke32(address unsafe.Pointer, val uint32) {
 
 // MemAlloc allocates memory from a device’s memory region.
 func (instr Object) MemAlloc(size BusSize) (offset BusAddress, status Status) {
-	status = Status(C.viMemAlloc((C.ViSession)(instr),
-		(C.ViBusSize)(size),
-		(*C.ViBusAddress)(&offset)))
-	return offset, status
+	mb, status := memory()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return mb.MemAlloc(instr, size)
 }
 
 // MemFree frees memory previously allocated using the viMemAlloc() operation.
 func (instr Object) MemFree(offset BusAddress) Status {
-	return Status(C.viMemFree((C.ViSession)(instr), (C.ViBusAddress)(offset)))
+	mb, status := memory()
+	if status != SUCCESS {
+		return status
+	}
+	return mb.MemFree(instr, offset)
 }
 
 // ----------------------------------------------------------------------------
 // Interface Specific Operations
 //
 
+// gpib returns the backend's GPIB operations, if it has them.
+func gpib() (GPIBBackend, Status) {
+	if gb, ok := backend.(GPIBBackend); ok {
+		return gb, SUCCESS
+	}
+	return nil, ERROR_NSUP_OPER
+}
+
+// backplane returns the backend's VXI and PXI operations, if it has them.
+func backplane() (BackplaneBackend, Status) {
+	if bb, ok := backend.(BackplaneBackend); ok {
+		return bb, SUCCESS
+	}
+	return nil, ERROR_NSUP_OPER
+}
+
+// usb returns the backend's USB control pipe operations, if it has them.
+func usb() (USBBackend, Status) {
+	if ub, ok := backend.(USBBackend); ok {
+		return ub, SUCCESS
+	}
+	return nil, ERROR_NSUP_OPER
+}
+
 // GpibControlREN controls the state of the GPIB Remote Enable (REN)
 // interface line, and optionally the remote/local state of the device.
 func (instr Object) GpibControlREN(mode uint16) Status {
-	return Status(C.viGpibControlREN((C.ViSession)(instr),
-		(C.ViUInt16)(mode)))
+	gb, status := gpib()
+	if status != SUCCESS {
+		return status
+	}
+	return gb.GpibControlREN(instr, mode)
 }
 
 // GpibControlATN specifies the state of the ATN line and the local
 // active controller state.
 func (instr Object) GpibControlATN(mode uint16) Status {
-	return Status(C.viGpibControlATN((C.ViSession)(instr),
-		(C.ViUInt16)(mode)))
+	gb, status := gpib()
+	if status != SUCCESS {
+		return status
+	}
+	return gb.GpibControlATN(instr, mode)
 }
 
 // GpibSendIFC pulses the interface clear line (IFC) for at least 100 microseconds.
 func (instr Object) GpibSendIFC() Status {
-	return Status(C.viGpibSendIFC((C.ViSession)(instr)))
+	gb, status := gpib()
+	if status != SUCCESS {
+		return status
+	}
+	return gb.GpibSendIFC(instr)
 }
 
 // GpibCommand writes GPIB command bytes on the bus.
 // ViStatus _VI_FUNC  viGpibCommand   (ViSession vi, ViBuf cmd, ViUInt32 cnt, ViPUInt32 retCnt);
 func (instr Object) GpibCommand(cmd []byte, cnt uint32) (retCnt uint32, status Status) {
-	status = Status(C.viGpibCommand((C.ViSession)(instr),
-		(C.ViBuf)(unsafe.Pointer(&cmd[0])),
-		(C.ViUInt32)(cnt),
-		(*C.ViUInt32)(&retCnt)))
-	return retCnt, status
+	gb, status := gpib()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return gb.GpibCommand(instr, cmd[:cnt])
 }
 
 // GpibPassControl tells the GPIB device at the specified address to
 // become controller in charge (CIC).
 func (instr Object) GpibPassControl(primAddr, secAddr uint16) Status {
-	return Status(C.viGpibPassControl((C.ViSession)(instr),
-		(C.ViUInt16)(primAddr),
-		(C.ViUInt16)(secAddr)))
+	gb, status := gpib()
+	if status != SUCCESS {
+		return status
+	}
+	return gb.GpibPassControl(instr, primAddr, secAddr)
 }
 
 // VxiCommandQuery sends the device a miscellaneous command or query and/or
 // retrieves the response to a previous query.
 func (instr Object) VxiCommandQuery(mode uint16, cmd uint32) (response uint32, status Status) {
-	status = Status(C.viVxiCommandQuery((C.ViSession)(instr),
-		(C.ViUInt16)(mode),
-		(C.ViUInt32)(cmd),
-		(*C.ViUInt32)(&response)))
-	return response, status
+	bb, status := backplane()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return bb.VxiCommandQuery(instr, mode, cmd)
 }
 
 // AssertUtilSignal asserts or deasserts the specified utility bus signal.
 func (instr Object) AssertUtilSignal(line uint16) Status {
-	return Status(C.viAssertUtilSignal((C.ViSession)(instr),
-		(C.ViUInt16)(line)))
+	bb, status := backplane()
+	if status != SUCCESS {
+		return status
+	}
+	return bb.AssertUtilSignal(instr, line)
 }
 
 // AssertIntrSignal asserts the specified interrupt or signal.
 func (instr Object) AssertIntrSignal(mode int16, statusID uint16) Status {
-	return Status(C.viAssertIntrSignal((C.ViSession)(instr),
-		(C.ViInt16)(mode),
-		(C.ViUInt32)(statusID)))
+	bb, status := backplane()
+	if status != SUCCESS {
+		return status
+	}
+	return bb.AssertIntrSignal(instr, mode, statusID)
 }
 
 // MapTrigger maps the specified trigger source line to the specified
 // destination line.
 func (instr Object) MapTrigger(trigSrc, trigDest int16, mode uint16) Status {
-	return Status(C.viMapTrigger((C.ViSession)(instr),
-		(C.ViInt16)(trigSrc),
-		(C.ViInt16)(trigDest),
-		(C.ViUInt16)(mode)))
+	bb, status := backplane()
+	if status != SUCCESS {
+		return status
+	}
+	return bb.MapTrigger(instr, trigSrc, trigDest, mode)
 }
 
 // UnmapTrigger undoes a previous map from the specified trigger source
 // line to the specified destination line.
 func (instr Object) UnmapTrigger(trigSrc, trigDest int16) Status {
-	return Status(C.viUnmapTrigger((C.ViSession)(instr),
-		(C.ViInt16)(trigSrc),
-		(C.ViInt16)(trigDest)))
+	bb, status := backplane()
+	if status != SUCCESS {
+		return status
+	}
+	return bb.UnmapTrigger(instr, trigSrc, trigDest)
 }
 
 // UsbControlOut performs a USB control pipe transfer to the device.
 func (instr Object) UsbControlOut(bmRequestType, bRequest int16, wValue, wIndex,
 	wLength uint16, buf []byte) Status {
 
-	return Status(C.viUsbControlOut((C.ViSession)(instr),
-		(C.ViInt16)(bmRequestType),
-		(C.ViInt16)(bRequest),
-		(C.ViUInt16)(wValue),
-		(C.ViUInt16)(wIndex),
-		(C.ViUInt16)(wLength),
-		(*C.ViByte)(unsafe.Pointer(&buf[0]))))
+	ub, status := usb()
+	if status != SUCCESS {
+		return status
+	}
+	return ub.UsbControlOut(instr, bmRequestType, bRequest, wValue, wIndex, buf[:wLength])
 }
 
 // UsbControlIn performs a USB control pipe transfer from the device.
 func (instr Object) UsbControlIn(bmRequestType, bRequest int16, wValue, wIndex,
 	wLength uint16) (buf []byte, retCnt uint16, status Status) {
 
+	ub, status := usb()
+	if status != SUCCESS {
+		return nil, 0, status
+	}
 	buf = make([]byte, wLength)
-	status = Status(C.viUsbControlIn((C.ViSession)(instr),
-		(C.ViInt16)(bmRequestType),
-		(C.ViInt16)(bRequest),
-		(C.ViUInt16)(wValue),
-		(C.ViUInt16)(wIndex),
-		(C.ViUInt16)(wLength),
-		(*C.ViByte)(unsafe.Pointer(&buf[0])),
-		(*C.ViUInt16)(&retCnt)))
+	retCnt, status = ub.UsbControlIn(instr, bmRequestType, bRequest, wValue, wIndex, buf)
 	return buf, retCnt, status
 }
 
 // Version returns the unformatted resource version number.
 func Version() uint32 {
-	return uint32(C.VI_SPEC_VERSION)
+	return uint32(SPEC_VERSION)
 }
 
 // VersMajor returns the major resource version number.
@@ -836,12 +884,11 @@ func VersSubMinor() uint32 {
 
 // PxiReserveTriggers reserves multiple trigger lines that the caller can then map and/or assert.
 func (instr Object) PxiReserveTriggers(cnt int16, trigBuses, trigLines *int16) (failureIndex int16, status Status) {
-	status = Status(C.viPxiReserveTriggers((C.ViSession)(instr),
-		(C.ViInt16)(cnt),
-		(*C.ViInt16)(trigBuses),
-		(*C.ViInt16)(trigLines),
-		(*C.ViInt16)(&failureIndex)))
-	return failureIndex, status
+	bb, status := backplane()
+	if status != SUCCESS {
+		return 0, status
+	}
+	return bb.PxiReserveTriggers(instr, cnt, trigBuses, trigLines)
 }
 
 // VxiServantResponse ?
@@ -0,0 +1,453 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	registerTransport("TCPIP::INSTR", openTCPIPInstr)
+}
+
+// VXI-11 programs, procedures and flags, see the VXI-11 specification
+// rev 1.0, appendix B.
+const (
+	vxiCoreProg  = 0x0607AF
+	vxiAbortProg = 0x0607B0
+	vxiIntrProg  = 0x0607B1
+	vxiVers      = 1
+
+	vxiDeviceAbort     = 1
+	vxiCreateLink      = 10
+	vxiDeviceWrite     = 11
+	vxiDeviceRead      = 12
+	vxiDeviceReadstb   = 13
+	vxiDeviceTrigger   = 14
+	vxiDeviceClear     = 15
+	vxiDeviceLock      = 18
+	vxiDeviceUnlock    = 19
+	vxiDeviceEnableSrq = 20
+	vxiDestroyLink     = 23
+	vxiCreateIntrChan  = 25
+	vxiDestroyIntrChan = 26
+	vxiDeviceIntrSrq   = 30
+
+	vxiFlagWaitLock   = 1
+	vxiFlagEnd        = 8
+	vxiFlagTermChrSet = 128
+
+	vxiReasonReqCnt = 1
+	vxiReasonChr    = 2
+	vxiReasonEnd    = 4
+
+	vxiErrDeviceLocked = 11
+)
+
+// vxiErrors maps the VXI-11 Device_ErrorCode values to VISA status.
+var vxiErrors = map[uint32]Status{
+	1:  ERROR_INV_SETUP,     // syntax error
+	3:  ERROR_RSRC_NFOUND,   // device not accessible
+	4:  ERROR_CONN_LOST,     // invalid link identifier
+	5:  ERROR_INV_PARAMETER, // parameter error
+	6:  ERROR_INV_SETUP,     // channel not established
+	8:  ERROR_NSUP_OPER,     // operation not supported
+	9:  ERROR_ALLOC,         // out of resources
+	11: ERROR_RSRC_LOCKED,   // device locked by another link
+	12: ERROR_SESN_NLOCKED,  // no lock held by this link
+	15: ERROR_TMO,           // I/O timeout
+	17: ERROR_IO,            // I/O error
+	21: ERROR_INV_RSRC_NAME, // invalid address
+	23: ERROR_ABORT,         // abort
+	29: ERROR_INV_SETUP,     // channel already established
+}
+
+func vxiStatus(code uint32) Status {
+	if status, ok := vxiErrors[code]; ok {
+		return status
+	}
+	return ERROR_IO
+}
+
+// vxiSlack is added to the session timeout for the RPC round trip; the
+// device enforces the timeout itself.
+const vxiSlack = 2 * time.Second
+
+// openTCPIPInstr opens a TCPIP::host[::device]::INSTR resource.
+// Device names hislipN[,port] select HiSLIP, anything else VXI-11.
+func openTCPIPInstr(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if len(p.fields) == 2 && strings.HasPrefix(strings.ToLower(p.fields[1]), "hislip") {
+		return openHiSLIP(s, p, timeout)
+	}
+	return openVXI11(s, p, timeout)
+}
+
+// vxi11Transport serves TCPIP INSTR resources over VXI-11. LAN/GPIB
+// gateways are reached with device names like gpib0,5.
+type vxi11Transport struct {
+	host      string
+	core      *rpcClient
+	lid       uint32
+	abortPort uint16
+	maxRecv   uint32
+	locked    bool
+
+	mu    sync.Mutex
+	abrt  *rpcClient
+	intr  net.Listener
+	conns []net.Conn
+}
+
+func openVXI11(s *goSession, p rsrcParts, timeout uint32) (transport, Status) {
+	if len(p.fields) < 1 || len(p.fields) > 2 {
+		return nil, ERROR_INV_RSRC_NAME
+	}
+	host, device := p.fields[0], "inst0"
+	if len(p.fields) == 2 {
+		device = p.fields[1]
+	}
+	deadline := s.deadline()
+	port, err := pmapGetport(host, vxiCoreProg, vxiVers, deadline)
+	if err != nil {
+		return nil, ERROR_RSRC_NFOUND
+	}
+	core, err := dialRPC(net.JoinHostPort(host, strconv.Itoa(int(port))), vxiCoreProg, vxiVers, deadline)
+	if err != nil {
+		return nil, ERROR_RSRC_NFOUND
+	}
+
+	var w xdrWriter
+	w.putUint32(s.vi) // clientId
+	w.putBool(false)  // lockDevice
+	w.putUint32(0)    // lock_timeout
+	w.putString(device)
+	r, err := core.call(vxiCreateLink, w.buf, deadline)
+	if err != nil {
+		core.close()
+		return nil, ERROR_RSRC_NFOUND
+	}
+	code := r.getUint32()
+	t := &vxi11Transport{
+		host:      host,
+		core:      core,
+		lid:       r.getUint32(),
+		abortPort: uint16(r.getUint32()),
+		maxRecv:   r.getUint32(),
+	}
+	if code != 0 || r.err != nil {
+		core.close()
+		if code == 0 {
+			return nil, ERROR_IO
+		}
+		return nil, vxiStatus(code)
+	}
+
+	addr := host
+	if ra, ok := core.conn.RemoteAddr().(*net.TCPAddr); ok {
+		addr = ra.IP.String()
+	}
+	s.a.putString(ATTR_TCPIP_ADDR, addr)
+	s.a.putString(ATTR_TCPIP_HOSTNAME, host)
+	s.a.putString(ATTR_TCPIP_DEVICE_NAME, device)
+	s.a.put(ATTR_TCPIP_IS_HISLIP, FALSE)
+	s.a.put(ATTR_IO_PROT, PROT_NORMAL)
+	return t, SUCCESS
+}
+
+// call runs a core channel procedure whose result starts with a
+// Device_ErrorCode, and returns the reader positioned after it. The reader
+// is nil only if the call itself failed.
+func (t *vxi11Transport) call(s *goSession, proc uint32, args []byte) (*xdrReader, Status) {
+	deadline := s.deadline()
+	if !deadline.IsZero() {
+		deadline = deadline.Add(vxiSlack)
+	}
+	r, err := t.core.call(proc, args, deadline)
+	if err != nil {
+		return nil, netStatus(err)
+	}
+	if code := r.getUint32(); code != 0 {
+		return r, vxiStatus(code)
+	}
+	return r, SUCCESS
+}
+
+// ioTimeout returns the session timeout in the form sent to the device.
+func ioTimeout(s *goSession) uint32 {
+	return uint32(s.a.num(ATTR_TMO_VALUE))
+}
+
+// generic encodes Device_GenericParms.
+func (t *vxi11Transport) generic(s *goSession) []byte {
+	var w xdrWriter
+	w.putUint32(t.lid)
+	w.putUint32(0) // flags
+	w.putUint32(0) // lock_timeout
+	w.putUint32(ioTimeout(s))
+	return w.buf
+}
+
+func (t *vxi11Transport) read(s *goSession, buf []byte) (int, Status) {
+	term, termEn := s.termChar()
+	var flags uint32
+	if termEn {
+		flags |= vxiFlagTermChrSet
+	}
+	n := 0
+	for {
+		size := len(buf) - n
+		if size > rpcMaxData {
+			size = rpcMaxData
+		}
+		var w xdrWriter
+		w.putUint32(t.lid)
+		w.putUint32(uint32(size))
+		w.putUint32(ioTimeout(s))
+		w.putUint32(0) // lock_timeout
+		w.putUint32(flags)
+		w.putUint32(uint32(term))
+		r, status := t.call(s, vxiDeviceRead, w.buf)
+		if r == nil {
+			return n, status
+		}
+		reason := r.getUint32()
+		got := copy(buf[n:], r.getOpaque())
+		n += got
+		switch {
+		case status != SUCCESS:
+			return n, status
+		case r.err != nil:
+			return n, ERROR_IO
+		case reason&vxiReasonEnd != 0:
+			return n, SUCCESS
+		case reason&vxiReasonChr != 0:
+			return n, SUCCESS_TERM_CHAR
+		case n >= len(buf) || reason&vxiReasonReqCnt != 0 && got < size:
+			return n, SUCCESS_MAX_CNT
+		}
+	}
+}
+
+// write sends buf in pieces of at most the device's maxRecvSize, END goes
+// with the last one if ATTR_SEND_END_EN is set.
+func (t *vxi11Transport) write(s *goSession, buf []byte) (int, Status) {
+	max := rpcMaxData
+	if t.maxRecv > 0 && t.maxRecv < rpcMaxData {
+		max = int(t.maxRecv)
+	}
+	n := 0
+	for {
+		chunk, last := buf[n:], true
+		if len(chunk) > max {
+			chunk, last = chunk[:max], false
+		}
+		var flags uint32
+		if last && s.sendEnd() {
+			flags |= vxiFlagEnd
+		}
+		var w xdrWriter
+		w.putUint32(t.lid)
+		w.putUint32(ioTimeout(s))
+		w.putUint32(0) // lock_timeout
+		w.putUint32(flags)
+		w.putOpaque(chunk)
+		r, status := t.call(s, vxiDeviceWrite, w.buf)
+		size := 0
+		if r != nil {
+			if size = int(r.getUint32()); size > len(chunk) {
+				size = len(chunk)
+			}
+		}
+		n += size
+		switch {
+		case status != SUCCESS:
+			return n, status
+		case n >= len(buf):
+			return n, SUCCESS
+		case r.err != nil, size == 0:
+			// A device taking none of the data would be sent it forever.
+			return n, ERROR_IO
+		}
+	}
+}
+
+func (t *vxi11Transport) readSTB(s *goSession) (uint16, Status) {
+	r, status := t.call(s, vxiDeviceReadstb, t.generic(s))
+	if status != SUCCESS {
+		return 0, status
+	}
+	return uint16(r.getUint32() & 0xFF), SUCCESS
+}
+
+func (t *vxi11Transport) clear(s *goSession) Status {
+	_, status := t.call(s, vxiDeviceClear, t.generic(s))
+	return status
+}
+
+func (t *vxi11Transport) assertTrigger(s *goSession, protocol uint16) Status {
+	if protocol != TRIG_PROT_DEFAULT {
+		return ERROR_INV_PROT
+	}
+	_, status := t.call(s, vxiDeviceTrigger, t.generic(s))
+	return status
+}
+
+// lock takes the device lock, waiting up to timeout for other links to
+// release it. VXI-11 has no shared locks, those stay local.
+func (t *vxi11Transport) lock(s *goSession, lockType, timeout uint32, key string) Status {
+	if lockType != EXCLUSIVE_LOCK {
+		return SUCCESS
+	}
+	var w xdrWriter
+	w.putUint32(t.lid)
+	if timeout != TMO_IMMEDIATE {
+		w.putUint32(vxiFlagWaitLock)
+	} else {
+		w.putUint32(0)
+	}
+	w.putUint32(timeout)
+	var deadline time.Time
+	if timeout != TMO_INFINITE {
+		deadline = time.Now().Add(time.Duration(timeout)*time.Millisecond + vxiSlack)
+	}
+	r, err := t.core.call(vxiDeviceLock, w.buf, deadline)
+	if err != nil {
+		return netStatus(err)
+	}
+	code := r.getUint32()
+	if code == vxiErrDeviceLocked && timeout != TMO_IMMEDIATE {
+		return ERROR_TMO
+	}
+	if code != 0 {
+		return vxiStatus(code)
+	}
+	t.locked = true
+	return SUCCESS
+}
+
+func (t *vxi11Transport) unlock(s *goSession) Status {
+	if !t.locked {
+		return SUCCESS
+	}
+	var w xdrWriter
+	w.putUint32(t.lid)
+	_, status := t.call(s, vxiDeviceUnlock, w.buf)
+	t.locked = false
+	return status
+}
+
+// abort sends device_abort on the abort channel, which makes the core
+// channel call in progress fail with ERROR_ABORT.
+func (t *vxi11Transport) abort(s *goSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	deadline := time.Now().Add(vxiSlack)
+	if t.abrt == nil {
+		addr := net.JoinHostPort(t.host, strconv.Itoa(int(t.abortPort)))
+		c, err := dialRPC(addr, vxiAbortProg, vxiVers, deadline)
+		if err != nil {
+			return
+		}
+		t.abrt = c
+	}
+	var w xdrWriter
+	w.putUint32(t.lid)
+	t.abrt.call(vxiDeviceAbort, w.buf, deadline)
+}
+
+// enableEvent arms service requests: the first time it creates the
+// interrupt channel, a local RPC server the device calls back with
+// device_intr_srq.
+func (t *vxi11Transport) enableEvent(s *goSession, etype uint32, enable bool) Status {
+	if etype != EVENT_SERVICE_REQ {
+		return SUCCESS
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if enable && t.intr == nil {
+		local, ok := t.core.conn.LocalAddr().(*net.TCPAddr)
+		if !ok || local.IP.To4() == nil {
+			return ERROR_NSUP_OPER
+		}
+		ln, err := net.Listen("tcp4", net.JoinHostPort(local.IP.String(), "0"))
+		if err != nil {
+			return ERROR_IO
+		}
+		go t.serveIntr(s, ln)
+
+		var w xdrWriter
+		w.putUint32(binary.BigEndian.Uint32(local.IP.To4()))
+		w.putUint32(uint32(ln.Addr().(*net.TCPAddr).Port))
+		w.putUint32(vxiIntrProg)
+		w.putUint32(vxiVers)
+		w.putUint32(0) // DEVICE_TCP
+		if _, status := t.call(s, vxiCreateIntrChan, w.buf); status != SUCCESS {
+			ln.Close()
+			return status
+		}
+		t.intr = ln
+	}
+	if t.intr == nil {
+		return SUCCESS
+	}
+	var w xdrWriter
+	w.putUint32(t.lid)
+	w.putBool(enable)
+	handle := make([]byte, 4)
+	binary.BigEndian.PutUint32(handle, t.lid)
+	w.putOpaque(handle)
+	_, status := t.call(s, vxiDeviceEnableSrq, w.buf)
+	return status
+}
+
+// serveIntr accepts the interrupt channel connections of the device and
+// posts EVENT_SERVICE_REQ for every device_intr_srq.
+func (t *vxi11Transport) serveIntr(s *goSession, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		t.conns = append(t.conns, conn)
+		t.mu.Unlock()
+		go serveRPC(conn, func(prog, vers, proc uint32, args *xdrReader) ([]byte, bool) {
+			if prog != vxiIntrProg || proc != vxiDeviceIntrSrq {
+				return nil, false
+			}
+			s.postEvent(EVENT_SERVICE_REQ, nil)
+			return nil, true
+		})
+	}
+}
+
+func (t *vxi11Transport) close() Status {
+	deadline := time.Now().Add(vxiSlack)
+	var w xdrWriter
+	w.putUint32(t.lid)
+	t.mu.Lock()
+	if t.intr != nil {
+		t.core.call(vxiDestroyIntrChan, nil, deadline)
+		t.intr.Close()
+		for _, c := range t.conns {
+			c.Close()
+		}
+	}
+	if t.abrt != nil {
+		t.abrt.close()
+	}
+	t.mu.Unlock()
+	_, err := t.core.call(vxiDestroyLink, w.buf, deadline)
+	t.core.close()
+	if err != nil {
+		return netStatus(err)
+	}
+	return SUCCESS
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2014 Joseph D Poirier
+// Distributable under the terms of The simplified BSD License
+// that can be found in the LICENSE file.
+
+package visa
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVXI11 is an in-process VXI-11 instrument: a portmapper, the core and
+// abort channels, and a client for the interrupt channel it's given.
+type fakeVXI11 struct {
+	t         *testing.T
+	abortPort uint32
+	maxRecv   uint32 // maxRecvSize reported by create_link
+	maxRead   int    // bytes returned per device_read
+	maxWrite  int    // bytes taken per device_write, all of them if negative
+
+	mu      sync.Mutex
+	device  string   // from create_link
+	writes  []string // device_write data
+	ends    []bool   // whether the writes had END
+	out     []byte   // data to read
+	locked  bool     // locked by another link
+	holding bool     // locked by the test's link
+	block   bool     // device_read waits for device_abort
+	aborted chan struct{}
+	intr    *rpcClient
+	procs   []uint32
+}
+
+// serveRPCListener serves the connections accepted on ln with h.
+func serveRPCListener(ln net.Listener, h rpcHandler) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go serveRPC(conn, h)
+	}
+}
+
+// listenRPC serves h on a local port that's closed at the end of the test.
+func listenRPC(t *testing.T, h rpcHandler) int {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go serveRPCListener(ln, h)
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// startVXI11 starts a fake instrument and points the portmapper lookups
+// at it.
+func startVXI11(t *testing.T) *fakeVXI11 {
+	f := &fakeVXI11{t: t, maxRecv: 1024, maxRead: 1024, maxWrite: -1, aborted: make(chan struct{}, 1)}
+	core := listenRPC(t, f.core)
+	f.abortPort = uint32(listenRPC(t, f.abort))
+	pmap := listenRPC(t, func(prog, vers, proc uint32, args *xdrReader) ([]byte, bool) {
+		if prog != pmapProg || proc != pmapProcGetport {
+			return nil, false
+		}
+		var w xdrWriter
+		if args.getUint32() == vxiCoreProg {
+			w.putUint32(uint32(core))
+		} else {
+			w.putUint32(0)
+		}
+		return w.buf, true
+	})
+	old := portmapperPort
+	portmapperPort = pmap
+	t.Cleanup(func() { portmapperPort = old })
+	return f
+}
+
+func (f *fakeVXI11) core(prog, vers, proc uint32, args *xdrReader) ([]byte, bool) {
+	if prog != vxiCoreProg {
+		return nil, false
+	}
+	f.mu.Lock()
+	f.procs = append(f.procs, proc)
+	f.mu.Unlock()
+	var w xdrWriter
+	switch proc {
+	case vxiCreateLink:
+		args.getUint32() // clientId
+		args.getBool()   // lockDevice
+		args.getUint32() // lock_timeout
+		device := string(args.getOpaque())
+		f.mu.Lock()
+		f.device = device
+		f.mu.Unlock()
+		if device == "missing" {
+			w.putUint32(3) // device not accessible
+			w.putUint32(0)
+			w.putUint32(0)
+			w.putUint32(0)
+			break
+		}
+		w.putUint32(0)
+		w.putUint32(1) // lid
+		w.putUint32(f.abortPort)
+		w.putUint32(f.maxRecv)
+
+	case vxiDeviceWrite:
+		args.getUint32() // lid
+		args.getUint32() // io_timeout
+		args.getUint32() // lock_timeout
+		flags := args.getUint32()
+		data := args.getOpaque()
+		f.mu.Lock()
+		if f.maxWrite >= 0 && len(data) > f.maxWrite {
+			data = data[:f.maxWrite]
+		}
+		f.writes = append(f.writes, string(data))
+		f.ends = append(f.ends, flags&vxiFlagEnd != 0)
+		f.mu.Unlock()
+		w.putUint32(0)
+		w.putUint32(uint32(len(data)))
+
+	case vxiDeviceRead:
+		args.getUint32() // lid
+		size := int(args.getUint32())
+		args.getUint32() // io_timeout
+		args.getUint32() // lock_timeout
+		flags := args.getUint32()
+		term := byte(args.getUint32())
+		f.mu.Lock()
+		block := f.block
+		f.mu.Unlock()
+		if block {
+			select {
+			case <-f.aborted:
+				w.putUint32(23) // abort
+			case <-time.After(5 * time.Second):
+				w.putUint32(15) // I/O timeout
+			}
+			w.putUint32(0)
+			w.putOpaque(nil)
+			break
+		}
+		f.mu.Lock()
+		n, reason := len(f.out), uint32(vxiReasonEnd)
+		if n > f.maxRead {
+			n, reason = f.maxRead, 0
+		}
+		if n >= size {
+			n, reason = size, vxiReasonReqCnt
+		}
+		if flags&vxiFlagTermChrSet != 0 {
+			for i, c := range f.out[:n] {
+				if c == term {
+					n, reason = i+1, vxiReasonChr
+					break
+				}
+			}
+		}
+		data := f.out[:n]
+		f.out = f.out[n:]
+		f.mu.Unlock()
+		w.putUint32(0)
+		w.putUint32(reason)
+		w.putOpaque(data)
+
+	case vxiDeviceReadstb:
+		w.putUint32(0)
+		w.putUint32(0x40)
+
+	case vxiDeviceLock:
+		f.mu.Lock()
+		if f.locked {
+			w.putUint32(vxiErrDeviceLocked)
+		} else {
+			f.holding = true
+			w.putUint32(0)
+		}
+		f.mu.Unlock()
+
+	case vxiDeviceUnlock:
+		f.mu.Lock()
+		if f.holding {
+			f.holding = false
+			w.putUint32(0)
+		} else {
+			w.putUint32(12) // no lock held
+		}
+		f.mu.Unlock()
+
+	case vxiCreateIntrChan:
+		ip := args.getUint32()
+		port := args.getUint32()
+		prog, vers := args.getUint32(), args.getUint32()
+		host := net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+		c, err := dialRPC(net.JoinHostPort(host, strconv.Itoa(int(port))), prog, vers, time.Now().Add(time.Second))
+		if err != nil {
+			f.t.Errorf("dialing the interrupt channel: %v", err)
+			w.putUint32(6)
+			break
+		}
+		f.mu.Lock()
+		f.intr = c
+		f.mu.Unlock()
+		w.putUint32(0)
+
+	case vxiDeviceEnableSrq:
+		args.getUint32() // lid
+		if args.getBool() {
+			go f.requestService(args.getOpaque())
+		}
+		w.putUint32(0)
+
+	case vxiDeviceTrigger, vxiDeviceClear, vxiDestroyLink:
+		w.putUint32(0)
+
+	case vxiDestroyIntrChan:
+		f.mu.Lock()
+		if f.intr != nil {
+			f.intr.close()
+			f.intr = nil
+		}
+		f.mu.Unlock()
+		w.putUint32(0)
+
+	default:
+		return nil, false
+	}
+	return w.buf, true
+}
+
+func (f *fakeVXI11) abort(prog, vers, proc uint32, args *xdrReader) ([]byte, bool) {
+	if prog != vxiAbortProg || proc != vxiDeviceAbort {
+		return nil, false
+	}
+	select {
+	case f.aborted <- struct{}{}:
+	default:
+	}
+	var w xdrWriter
+	w.putUint32(0)
+	return w.buf, true
+}
+
+// requestService calls device_intr_srq on the interrupt channel.
+func (f *fakeVXI11) requestService(handle []byte) {
+	f.mu.Lock()
+	c := f.intr
+	f.mu.Unlock()
+	if c == nil {
+		f.t.Error("service request enabled without an interrupt channel")
+		return
+	}
+	var w xdrWriter
+	w.putOpaque(handle)
+	if _, err := c.call(vxiDeviceIntrSrq, w.buf, time.Now().Add(time.Second)); err != nil {
+		f.t.Errorf("device_intr_srq: %v", err)
+	}
+}
+
+// called reports whether proc was called.
+func (f *fakeVXI11) called(proc uint32) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.procs {
+		if p == proc {
+			return true
+		}
+	}
+	return false
+}
+
+// openVXI11Test opens the device of the fake instrument.
+func openVXI11Test(t *testing.T, device string) Object {
+	t.Helper()
+	rm := openTestRM(t)
+	instr, status := rm.Open("TCPIP0::127.0.0.1::"+device+"::INSTR", NO_LOCK, 0)
+	if status != SUCCESS {
+		t.Fatalf("Open: %v", status)
+	}
+	return instr
+}
+
+func TestVXI11CreateLink(t *testing.T) {
+	f := startVXI11(t)
+	instr := openVXI11Test(t, "gpib0,5")
+	if f.device != "gpib0,5" {
+		t.Errorf("create_link device = %q, want gpib0,5", f.device)
+	}
+	if name, err := instr.AttrString(ATTR_TCPIP_DEVICE_NAME); err != nil || name != "gpib0,5" {
+		t.Errorf("ATTR_TCPIP_DEVICE_NAME = %q, %v", name, err)
+	}
+	if status := instr.Close(); status != SUCCESS || !f.called(vxiDestroyLink) {
+		t.Errorf("Close = %v, destroy_link called %v", status, f.called(vxiDestroyLink))
+	}
+
+	rm := openTestRM(t)
+	if _, status := rm.Open("TCPIP0::127.0.0.1::missing::INSTR", NO_LOCK, 0); status != ERROR_RSRC_NFOUND {
+		t.Errorf("Open of a missing device: %v, want ERROR_RSRC_NFOUND", status)
+	}
+}
+
+func TestVXI11WriteRead(t *testing.T) {
+	f := startVXI11(t)
+	f.maxRecv, f.maxRead = 4, 3
+	instr := openVXI11Test(t, "inst0")
+
+	if n, status := instr.Write([]byte("*IDN?\n"), 6); status != SUCCESS || n != 6 {
+		t.Fatalf("Write = %d, %v", n, status)
+	}
+	if len(f.writes) != 2 || f.writes[0] != "*IDN" || f.writes[1] != "?\n" {
+		t.Errorf("device_write data = %q, want pieces of maxRecvSize", f.writes)
+	}
+	if len(f.ends) != 2 || f.ends[0] || !f.ends[1] {
+		t.Errorf("device_write END flags = %v, want END on the last piece", f.ends)
+	}
+
+	// A device taking part of a piece gets the rest, one taking none of
+	// it fails the write.
+	f.writes, f.ends, f.maxWrite = nil, nil, 1
+	if n, status := instr.Write([]byte("ab"), 2); status != SUCCESS || n != 2 || len(f.writes) != 2 {
+		t.Errorf("Write to a slow device = %d, %v in %q", n, status, f.writes)
+	}
+	f.writes, f.maxWrite = nil, 0
+	if n, status := instr.Write([]byte("ab"), 2); status != ERROR_IO || n != 0 || len(f.writes) != 1 {
+		t.Errorf("Write to a device taking nothing = %d, %v after %d calls, want ERROR_IO after 1", n, status, len(f.writes))
+	}
+	f.maxWrite = -1
+
+	f.out = []byte("ACME,X\nrest")
+	instr.SetAttribute(ATTR_TERMCHAR_EN, TRUE)
+	buf, n, status := instr.Read(100)
+	if status != SUCCESS_TERM_CHAR || string(buf[:n]) != "ACME,X\n" {
+		t.Errorf("Read = %q, %v, want \"ACME,X\\n\", SUCCESS_TERM_CHAR", buf[:n], status)
+	}
+	buf, n, status = instr.Read(2)
+	if status != SUCCESS_MAX_CNT || string(buf[:n]) != "re" {
+		t.Errorf("Read(2) = %q, %v, want \"re\", SUCCESS_MAX_CNT", buf[:n], status)
+	}
+	buf, n, status = instr.Read(100)
+	if status != SUCCESS || string(buf[:n]) != "st" {
+		t.Errorf("Read = %q, %v, want \"st\", SUCCESS", buf[:n], status)
+	}
+
+	if stb, status := instr.ReadSTB(); status != SUCCESS || stb != 0x40 {
+		t.Errorf("ReadSTB = %#x, %v", stb, status)
+	}
+	if status := instr.Clear(); status != SUCCESS || !f.called(vxiDeviceClear) {
+		t.Errorf("Clear = %v", status)
+	}
+	if status := instr.AssertTrigger(TRIG_PROT_DEFAULT); status != SUCCESS || !f.called(vxiDeviceTrigger) {
+		t.Errorf("AssertTrigger = %v", status)
+	}
+}
+
+func TestVXI11Lock(t *testing.T) {
+	f := startVXI11(t)
+	instr := openVXI11Test(t, "inst0")
+
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, TMO_IMMEDIATE); status != SUCCESS || !f.holding {
+		t.Fatalf("LockExclusive = %v, device locked %v", status, f.holding)
+	}
+	if status := instr.Unlock(); status != SUCCESS || f.holding {
+		t.Errorf("Unlock = %v, device locked %v", status, f.holding)
+	}
+
+	f.locked = true
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, TMO_IMMEDIATE); status != ERROR_RSRC_LOCKED {
+		t.Errorf("LockExclusive of a locked device: %v, want ERROR_RSRC_LOCKED", status)
+	}
+	if status := instr.LockExclusive(EXCLUSIVE_LOCK, 100); status != ERROR_TMO {
+		t.Errorf("LockExclusive with a timeout: %v, want ERROR_TMO", status)
+	}
+	if state, _ := instr.AttrUint32(ATTR_RSRC_LOCK_STATE); state != NO_LOCK {
+		t.Errorf("ATTR_RSRC_LOCK_STATE after a refused lock = %d, want NO_LOCK", state)
+	}
+}
+
+func TestVXI11Abort(t *testing.T) {
+	f := startVXI11(t)
+	f.block = true
+	instr := openVXI11Test(t, "inst0")
+
+	if status := instr.EnableEvent(EVENT_IO_COMPLETION, QUEUE, NULL); status != SUCCESS {
+		t.Fatalf("EnableEvent: %v", status)
+	}
+	_, job, status := instr.ReadAsync(10)
+	if status != SUCCESS {
+		t.Fatalf("ReadAsync: %v", status)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if status := instr.Terminate(0, uint16(job)); status != SUCCESS {
+		t.Fatalf("Terminate: %v", status)
+	}
+	e, err := instr.WaitEvent(EVENT_IO_COMPLETION, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if io := e.(IOCompletionEvent); io.JobID != job || io.Status != ERROR_ABORT {
+		t.Errorf("completion = job %d, %v, want job %d, ERROR_ABORT", io.JobID, io.Status, job)
+	}
+}
+
+func TestVXI11ServiceRequest(t *testing.T) {
+	startVXI11(t)
+	instr := openVXI11Test(t, "inst0")
+
+	if status := instr.EnableEvent(EVENT_SERVICE_REQ, QUEUE, NULL); status != SUCCESS {
+		t.Fatalf("EnableEvent: %v", status)
+	}
+	e, err := instr.WaitEvent(EVENT_SERVICE_REQ, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(ServiceRequestEvent); !ok {
+		t.Errorf("event = %T, want ServiceRequestEvent", e)
+	}
+	if status := instr.DisableEvent(EVENT_SERVICE_REQ, QUEUE); status != SUCCESS {
+		t.Errorf("DisableEvent: %v", status)
+	}
+}